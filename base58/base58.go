@@ -0,0 +1,158 @@
+// Package base58 implements Monero's base58 encoding. Unlike Bitcoin's base58,
+// which treats the entire input as one big number, Monero encodes the input in
+// 8-byte blocks, each of which is converted to exactly 11 base58 symbols. The
+// final block can be shorter and is encoded to the fixed number of symbols
+// needed to represent its size. This keeps the encoded length a function of
+// the input length, which is convenient for fixed-size data like addresses and
+// keys.
+package base58
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"strings"
+)
+
+const (
+	alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+	// fullBlockSize is the number of binary bytes in a full block
+	fullBlockSize = 8
+
+	// fullEncodedBlockSize is the number of base58 symbols that a full block
+	// is encoded to.
+	fullEncodedBlockSize = 11
+)
+
+// encodedBlockSizes maps the size of a binary block (0-8 bytes) to the number
+// of base58 symbols it is encoded to.
+var encodedBlockSizes = [fullBlockSize + 1]int{0, 2, 3, 5, 6, 7, 9, 10, 11}
+
+// Errors returned when decoding invalid input
+var (
+	ErrInvalidLength = errors.New("invalid monero base58 length")
+	ErrInvalidSymbol = errors.New("invalid monero base58 symbol")
+	ErrOverflow      = errors.New("monero base58 block overflow")
+)
+
+// reverseAlphabet maps base58 symbols back to their values. Bytes that are not
+// in the alphabet map to -1.
+var reverseAlphabet [256]int8
+
+func init() {
+	for i := range reverseAlphabet {
+		reverseAlphabet[i] = -1
+	}
+	for i := 0; i < len(alphabet); i++ {
+		reverseAlphabet[alphabet[i]] = int8(i)
+	}
+}
+
+// EncodedLen returns the length of the base58 encoding of n binary bytes.
+func EncodedLen(n int) int {
+	return (n/fullBlockSize)*fullEncodedBlockSize + encodedBlockSizes[n%fullBlockSize]
+}
+
+// DecodedLen returns the number of binary bytes represented by n base58
+// symbols. Not every length is a valid encoded length, in which case
+// ErrInvalidLength is returned.
+func DecodedLen(n int) (int, error) {
+	lastEncodedBlockSize := n % fullEncodedBlockSize
+	for blockSize, encodedSize := range encodedBlockSizes {
+		if encodedSize == lastEncodedBlockSize {
+			return (n/fullEncodedBlockSize)*fullBlockSize + blockSize, nil
+		}
+	}
+	return 0, ErrInvalidLength
+}
+
+// Encode returns the Monero base58 encoding of data.
+func Encode(data []byte) string {
+	var sb strings.Builder
+	sb.Grow(EncodedLen(len(data)))
+
+	for len(data) > 0 {
+		blockSize := min(len(data), fullBlockSize)
+		encodeBlock(&sb, data[:blockSize])
+		data = data[blockSize:]
+	}
+
+	return sb.String()
+}
+
+// encodeBlock appends the encoding of the passed block, which must be 1 to 8
+// bytes in length, to the string builder.
+func encodeBlock(sb *strings.Builder, block []byte) {
+	// Treat the block as a big-endian number
+	var buf [fullBlockSize]byte
+	copy(buf[fullBlockSize-len(block):], block)
+	num := binary.BigEndian.Uint64(buf[:])
+
+	// Symbols are produced least significant first, so fill from the right.
+	// Unused leading positions stay as "1", which is zero in base58.
+	var encoded [fullEncodedBlockSize]byte
+	encodedSize := encodedBlockSizes[len(block)]
+	for i := encodedSize - 1; i >= 0; i-- {
+		encoded[i] = alphabet[num%58]
+		num /= 58
+	}
+
+	sb.Write(encoded[:encodedSize])
+}
+
+// Decode returns the bytes represented by the Monero base58 string s. Any block
+// whose value does not fit in the number of bytes that the block represents is
+// rejected, so that every binary value has exactly one valid encoding.
+func Decode(s string) ([]byte, error) {
+	decodedLen, err := DecodedLen(len(s))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]byte, 0, decodedLen)
+	for len(s) > 0 {
+		encodedSize := min(len(s), fullEncodedBlockSize)
+		result, err = decodeBlock(result, s[:encodedSize])
+		if err != nil {
+			return nil, err
+		}
+		s = s[encodedSize:]
+	}
+
+	return result, nil
+}
+
+// decodeBlock appends the decoded bytes of the encoded block to dst. The length
+// of the encoded block must already be validated.
+func decodeBlock(dst []byte, encoded string) ([]byte, error) {
+	blockSize, _ := DecodedLen(len(encoded))
+
+	var num uint64
+	for i := 0; i < len(encoded); i++ {
+		digit := reverseAlphabet[encoded[i]]
+		if digit < 0 {
+			return nil, ErrInvalidSymbol
+		}
+
+		hi, lo := bits.Mul64(num, 58)
+		if hi != 0 {
+			return nil, ErrOverflow
+		}
+		var carry uint64
+		num, carry = bits.Add64(lo, uint64(digit), 0)
+		if carry != 0 {
+			return nil, ErrOverflow
+		}
+	}
+
+	// A partial block must not hold a value requiring more bytes than the
+	// block represents.
+	if blockSize < fullBlockSize && num>>(8*blockSize) != 0 {
+		return nil, ErrOverflow
+	}
+
+	var buf [fullBlockSize]byte
+	binary.BigEndian.PutUint64(buf[:], num)
+	return append(dst, buf[fullBlockSize-blockSize:]...), nil
+}
@@ -0,0 +1,107 @@
+package base58
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test vectors are from the encode/decode tests here:
+// https://github.com/monero-project/monero/blob/v0.18.2.2/tests/unit_tests/base58.cpp
+var encodingTests = []struct {
+	hex     string
+	encoded string
+}{
+	{"", ""},
+	{"00", "11"},
+	{"39", "1z"},
+	{"ff", "5Q"},
+	{"0000", "111"},
+	{"0039", "11z"},
+	{"0100", "15R"},
+	{"ffff", "LUv"},
+	{"000000", "11111"},
+	{"ffffff", "2UzHL"},
+	{"00000000", "111111"},
+	{"ffffffff", "7YXq9G"},
+	{"ffffffffff", "VtB5VXc"},
+	{"ffffffffffff", "3CUsUpv9t"},
+	{"ffffffffffffff", "Ahg1opVcGW"},
+	{"06156013762879f7", "22222222222"},
+	{"ffffffffffffffff", "jpXCZedGfVQ"},
+	{"000000000000000000", "1111111111111"},
+	{"06156013762879f7ffffffffff", "22222222222VtB5VXc"},
+}
+
+func TestEncode(t *testing.T) {
+	for _, tt := range encodingTests {
+		data, err := hex.DecodeString(tt.hex)
+		require.NoError(t, err)
+		require.Equal(t, tt.encoded, Encode(data), tt.hex)
+		require.Equal(t, len(tt.encoded), EncodedLen(len(data)), tt.hex)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, tt := range encodingTests {
+		data, err := Decode(tt.encoded)
+		require.NoError(t, err, tt.encoded)
+		require.Equal(t, tt.hex, hex.EncodeToString(data), tt.encoded)
+	}
+}
+
+func TestDecode_invalidLength(t *testing.T) {
+	for _, encoded := range []string{"1", "1111", "11111111", "111111111111", "1111111111111111111"} {
+		_, err := Decode(encoded)
+		require.ErrorIs(t, err, ErrInvalidLength, encoded)
+	}
+}
+
+func TestDecode_invalidSymbol(t *testing.T) {
+	// 0, O, I and l are excluded from the base58 alphabet
+	for _, encoded := range []string{"10", "1O", "1I", "1l", "1111111111l", "111111111111l"} {
+		_, err := Decode(encoded)
+		require.ErrorIs(t, err, ErrInvalidSymbol, encoded)
+	}
+}
+
+func TestDecode_overflow(t *testing.T) {
+	for _, encoded := range []string{
+		"5R",          // 0x100 does not fit in 1 byte
+		"LUw",         // 0x10000 does not fit in 2 bytes
+		"VtB5VXd",     // 0x10000000000 does not fit in 5 bytes
+		"jpXCZedGfVR", // 2^64 does not fit in 8 bytes
+		"zzzzzzzzzzz", // overflows 64 bits while decoding
+		"11111111111zzzzzzzzzzz",
+	} {
+		_, err := Decode(encoded)
+		require.ErrorIs(t, err, ErrOverflow, encoded)
+	}
+}
+
+func TestDecodedLen(t *testing.T) {
+	for blockSize, encodedSize := range encodedBlockSizes {
+		for numFullBlocks := 0; numFullBlocks < 3; numFullBlocks++ {
+			n, err := DecodedLen(numFullBlocks*fullEncodedBlockSize + encodedSize)
+			require.NoError(t, err)
+			require.Equal(t, numFullBlocks*fullBlockSize+blockSize, n)
+		}
+	}
+}
+
+func TestEncodeDecode_random(t *testing.T) {
+	for size := 0; size < 100; size++ {
+		data := make([]byte, size)
+		_, err := rand.Read(data)
+		require.NoError(t, err)
+
+		encoded := Encode(data)
+		require.Len(t, encoded, EncodedLen(size))
+
+		decoded, err := Decode(encoded)
+		require.NoError(t, err)
+		require.Equal(t, data, decoded)
+	}
+}
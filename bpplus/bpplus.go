@@ -0,0 +1,354 @@
+// Package bpplus implements Bulletproofs+ aggregated range proofs, compatible
+// with Monero's bulletproofs_plus.cc. A proof shows that each of up to 16
+// Pedersen commitments, mask*G + amount*H, commits to an amount in [0, 2^64)
+// without revealing the amounts.
+package bpplus
+
+import (
+	"errors"
+	"math"
+
+	ed25519 "filippo.io/edwards25519"
+
+	"github.com/dimalinux/gopherphis/mcrypto"
+)
+
+const (
+	// MaxOutputs is the maximum number of commitments in an aggregated proof
+	MaxOutputs = 16
+
+	logN  = 6
+	n     = 1 << logN // bits per amount
+	maxMN = MaxOutputs * n
+)
+
+var (
+	errNoAmounts      = errors.New("no amounts to prove")
+	errTooManyAmounts = errors.New("too many amounts for one proof")
+	errMaskCount      = errors.New("number of masks does not match the number of amounts")
+	errProofSize      = errors.New("proof is not the expected size for its commitments")
+	errInvalidProof   = errors.New("invalid Bulletproofs+ proof")
+)
+
+// Useful scalar constants
+var (
+	scOne         = mcrypto.ScalarFromUint64(1)
+	scTwo         = mcrypto.ScalarFromUint64(2)
+	scEight       = mcrypto.ScalarFromUint64(8)
+	scInvEight    = new(ed25519.Scalar).Invert(scEight)
+	scMinusOne    = new(ed25519.Scalar).Negate(scOne)
+	scMaxUint64   = mcrypto.ScalarFromUint64(math.MaxUint64) // 2^n - 1
+	scMinusInv8   = new(ed25519.Scalar).Negate(scInvEight)
+	identityPoint = ed25519.NewIdentityPoint()
+)
+
+// Proof is a Bulletproofs+ aggregated range proof
+type Proof struct {
+	// V holds the commitments covered by the proof, multiplied by 1/8 as
+	// Monero does. Transactions do not store them, as they are the output
+	// commitments.
+	V          []*ed25519.Point
+	A, A1, B   *ed25519.Point
+	R1, S1, D1 *ed25519.Scalar
+	L, R       []*ed25519.Point
+}
+
+// Commitments returns the commitments, mask*G + amount*H, covered by the
+// proof.
+func (p *Proof) Commitments() []*ed25519.Point {
+	commitments := make([]*ed25519.Point, len(p.V))
+	for i, v := range p.V {
+		commitments[i] = new(ed25519.Point).MultByCofactor(v)
+	}
+	return commitments
+}
+
+// logM returns the log of the number of amounts, padded to a power of 2
+func logM(numAmounts int) int {
+	lm := 0
+	for 1<<lm < numAmounts {
+		lm++
+	}
+	return lm
+}
+
+// powers returns [1, x, x^2, ..., x^(count-1)]
+func powers(x *ed25519.Scalar, count int) []*ed25519.Scalar {
+	res := make([]*ed25519.Scalar, count)
+	res[0] = new(ed25519.Scalar).Set(scOne)
+	for i := 1; i < count; i++ {
+		res[i] = new(ed25519.Scalar).Multiply(res[i-1], x)
+	}
+	return res
+}
+
+// windowedVector returns d, where d[j*n+i] = z^(2*(j+1)) * 2^i
+func windowedVector(zSquared *ed25519.Scalar, m int) []*ed25519.Scalar {
+	d := make([]*ed25519.Scalar, m*n)
+	d[0] = new(ed25519.Scalar).Set(zSquared)
+	for i := 1; i < n; i++ {
+		d[i] = new(ed25519.Scalar).Multiply(d[i-1], scTwo)
+	}
+	for j := 1; j < m; j++ {
+		for i := 0; i < n; i++ {
+			d[j*n+i] = new(ed25519.Scalar).Multiply(d[(j-1)*n+i], zSquared)
+		}
+	}
+	return d
+}
+
+// weightedInnerProduct returns sum(a[i] * b[i] * y^(i+1))
+func weightedInnerProduct(a, b []*ed25519.Scalar, y *ed25519.Scalar) *ed25519.Scalar {
+	res := ed25519.NewScalar()
+	yPower := new(ed25519.Scalar).Set(scOne)
+	temp := ed25519.NewScalar()
+	for i := range a {
+		temp.Multiply(a[i], b[i])
+		yPower.Multiply(yPower, y)
+		res.MultiplyAdd(temp, yPower, res)
+	}
+	return res
+}
+
+// computeLR is Monero's compute_LR, returning
+// (sum(a[i]*y*G[i] + b[i]*H[i]) + c*H + d*G) / 8
+func computeLR(
+	y *ed25519.Scalar,
+	g, h []*ed25519.Point,
+	a, b []*ed25519.Scalar,
+	c, d *ed25519.Scalar,
+) *ed25519.Point {
+	scalars := make([]*ed25519.Scalar, 0, 2*len(a)+2)
+	points := make([]*ed25519.Point, 0, 2*len(a)+2)
+	for i := range a {
+		s := new(ed25519.Scalar).Multiply(a[i], y)
+		scalars = append(scalars, s.Multiply(s, scInvEight), new(ed25519.Scalar).Multiply(b[i], scInvEight))
+		points = append(points, g[i], h[i])
+	}
+	scalars = append(scalars, new(ed25519.Scalar).Multiply(c, scInvEight), new(ed25519.Scalar).Multiply(d, scInvEight))
+	points = append(points, mcrypto.H(), ed25519.NewGeneratorPoint())
+	// MultiScalarMult adds to its receiver in edwards25519 v1.0.0, so it
+	// must start as the identity.
+	return ed25519.NewIdentityPoint().MultiScalarMult(scalars, points)
+}
+
+// foldPoints sets v[i] = a*v[i] + b*v[len/2+i] and returns the first half
+func foldPoints(v []*ed25519.Point, a, b *ed25519.Scalar) []*ed25519.Point {
+	half := len(v) / 2
+	folded := make([]*ed25519.Point, half)
+	for i := range folded {
+		folded[i] = new(ed25519.Point).VarTimeMultiScalarMult(
+			[]*ed25519.Scalar{a, b},
+			[]*ed25519.Point{v[i], v[half+i]},
+		)
+	}
+	return folded
+}
+
+// foldScalars returns a*v[i] + b*v[len/2+i] for the first half of v
+func foldScalars(v []*ed25519.Scalar, a, b *ed25519.Scalar) []*ed25519.Scalar {
+	half := len(v) / 2
+	folded := make([]*ed25519.Scalar, half)
+	for i := range folded {
+		folded[i] = new(ed25519.Scalar).Multiply(a, v[i])
+		folded[i].MultiplyAdd(b, v[half+i], folded[i])
+	}
+	return folded
+}
+
+// Prove is Monero's bulletproof_plus_PROVE. It returns an aggregated proof
+// that each commitment, masks[i]*G + amounts[i]*H, commits to an amount in
+// [0, 2^64).
+func Prove(amounts []uint64, masks []*ed25519.Scalar) (*Proof, error) {
+	if len(amounts) == 0 {
+		return nil, errNoAmounts
+	}
+	if len(amounts) > MaxOutputs {
+		return nil, errTooManyAmounts
+	}
+	if len(masks) != len(amounts) {
+		return nil, errMaskCount
+	}
+
+	for {
+		// A zero challenge, which has a negligible chance of happening,
+		// requires starting over with new randomness.
+		if p := tryProve(amounts, masks); p != nil {
+			return p, nil
+		}
+	}
+}
+
+func tryProve(amounts []uint64, masks []*ed25519.Scalar) *Proof {
+	gen := getGenerators()
+	m := 1 << logM(len(amounts))
+	mn := m * n
+
+	// V = (mask*G + amount*H) / 8
+	v := make([]*ed25519.Point, len(amounts))
+	for i, amount := range amounts {
+		v[i] = mcrypto.Commit(masks[i], amount)
+		v[i].ScalarMult(scInvEight, v[i])
+	}
+
+	// aL holds the bits of the amounts, aR = aL - 1
+	aL := make([]*ed25519.Scalar, mn)
+	aR := make([]*ed25519.Scalar, mn)
+	aL8 := make([]*ed25519.Scalar, mn)
+	aR8 := make([]*ed25519.Scalar, mn)
+	for j := 0; j < m; j++ {
+		for i := 0; i < n; i++ {
+			k := j*n + i
+			if j < len(amounts) && amounts[j]>>i&1 == 1 {
+				aL[k], aL8[k] = scOne, scInvEight
+				aR[k], aR8[k] = ed25519.NewScalar(), ed25519.NewScalar()
+			} else {
+				aL[k], aL8[k] = ed25519.NewScalar(), ed25519.NewScalar()
+				aR[k], aR8[k] = scMinusOne, scMinusInv8
+			}
+		}
+	}
+
+	tr := newTranscript(v)
+
+	// A = (aL*Gi + aR*Hi + alpha*G) / 8
+	alpha := mcrypto.RandomScalar()
+	aScalars := append(append([]*ed25519.Scalar{}, aL8...), aR8...)
+	aScalars = append(aScalars, new(ed25519.Scalar).Multiply(alpha, scInvEight))
+	aPoints := append(append([]*ed25519.Point{}, gen.gi[:mn]...), gen.hi[:mn]...)
+	aPoints = append(aPoints, ed25519.NewGeneratorPoint())
+	bigA := ed25519.NewIdentityPoint().MultiScalarMult(aScalars, aPoints)
+
+	y := tr.update(bigA)
+	if y.Equal(ed25519.NewScalar()) == 1 {
+		return nil
+	}
+	z := tr.rehash()
+	if z.Equal(ed25519.NewScalar()) == 1 {
+		return nil
+	}
+	zSquared := new(ed25519.Scalar).Multiply(z, z)
+
+	d := windowedVector(zSquared, m)
+	yPowers := powers(y, mn+2)
+
+	// aL1 = aL - z, aR1 = aR + z + d*y^(MN-i)
+	aPrime := make([]*ed25519.Scalar, mn)
+	bPrime := make([]*ed25519.Scalar, mn)
+	for i := 0; i < mn; i++ {
+		aPrime[i] = new(ed25519.Scalar).Subtract(aL[i], z)
+		bPrime[i] = new(ed25519.Scalar).Add(aR[i], z)
+		bPrime[i].MultiplyAdd(d[i], yPowers[mn-i], bPrime[i])
+	}
+
+	// alpha1 = alpha + y^(MN+1) * sum(z^(2*(j+1)) * gamma_j)
+	alpha1 := new(ed25519.Scalar).Set(alpha)
+	zPower := new(ed25519.Scalar).Set(scOne)
+	for j := range amounts {
+		zPower.Multiply(zPower, zSquared)
+		temp := new(ed25519.Scalar).Multiply(yPowers[mn+1], zPower)
+		alpha1.MultiplyAdd(temp, masks[j], alpha1)
+	}
+
+	yInv := new(ed25519.Scalar).Invert(y)
+	yInvPowers := powers(yInv, mn)
+
+	gPrime := gen.gi[:mn]
+	hPrime := gen.hi[:mn]
+	var proofL, proofR []*ed25519.Point
+
+	// inner product rounds
+	for nPrime := mn / 2; nPrime >= 1; nPrime /= 2 {
+		a1, a2 := aPrime[:nPrime], aPrime[nPrime:]
+		b1, b2 := bPrime[:nPrime], bPrime[nPrime:]
+		g1, g2 := gPrime[:nPrime], gPrime[nPrime:]
+		h1, h2 := hPrime[:nPrime], hPrime[nPrime:]
+
+		cL := weightedInnerProduct(a1, b2, y)
+		a2y := make([]*ed25519.Scalar, nPrime)
+		for i := range a2 {
+			a2y[i] = new(ed25519.Scalar).Multiply(a2[i], yPowers[nPrime])
+		}
+		cR := weightedInnerProduct(a2y, b1, y)
+
+		dL, dR := mcrypto.RandomScalar(), mcrypto.RandomScalar()
+		l := computeLR(yInvPowers[nPrime], g2, h1, a1, b2, cL, dL)
+		r := computeLR(yPowers[nPrime], g1, h2, a2, b1, cR, dR)
+		proofL = append(proofL, l)
+		proofR = append(proofR, r)
+
+		challenge := tr.update(l, r)
+		if challenge.Equal(ed25519.NewScalar()) == 1 {
+			return nil
+		}
+		challengeInv := new(ed25519.Scalar).Invert(challenge)
+
+		temp := new(ed25519.Scalar).Multiply(yInvPowers[nPrime], challenge)
+		gPrime = foldPoints(gPrime, challengeInv, temp)
+		hPrime = foldPoints(hPrime, challenge, challengeInv)
+
+		temp = new(ed25519.Scalar).Multiply(challengeInv, yPowers[nPrime])
+		aPrime = foldScalars(aPrime, challenge, temp)
+		bPrime = foldScalars(bPrime, challengeInv, challenge)
+
+		challengeSquared := new(ed25519.Scalar).Multiply(challenge, challenge)
+		challengeSquaredInv := new(ed25519.Scalar).Multiply(challengeInv, challengeInv)
+		alpha1.MultiplyAdd(dL, challengeSquared, alpha1)
+		alpha1.MultiplyAdd(dR, challengeSquaredInv, alpha1)
+	}
+
+	// final round
+	r, s := mcrypto.RandomScalar(), mcrypto.RandomScalar()
+	dFinal, eta := mcrypto.RandomScalar(), mcrypto.RandomScalar()
+
+	// A1 = (r*G' + s*H' + d*G + (r*y*b' + s*y*a')*H) / 8
+	ryb := new(ed25519.Scalar).Multiply(r, y)
+	ryb.Multiply(ryb, bPrime[0])
+	sya := new(ed25519.Scalar).Multiply(s, y)
+	sya.Multiply(sya, aPrime[0])
+	hCoeff := new(ed25519.Scalar).Add(ryb, sya)
+	a1 := ed25519.NewIdentityPoint().MultiScalarMult(
+		[]*ed25519.Scalar{
+			new(ed25519.Scalar).Multiply(r, scInvEight),
+			new(ed25519.Scalar).Multiply(s, scInvEight),
+			new(ed25519.Scalar).Multiply(dFinal, scInvEight),
+			hCoeff.Multiply(hCoeff, scInvEight),
+		},
+		[]*ed25519.Point{gPrime[0], hPrime[0], ed25519.NewGeneratorPoint(), mcrypto.H()},
+	)
+
+	// B = (eta*G + r*y*s*H) / 8
+	rys := new(ed25519.Scalar).Multiply(r, y)
+	rys.Multiply(rys, s)
+	b := ed25519.NewIdentityPoint().MultiScalarMult(
+		[]*ed25519.Scalar{
+			new(ed25519.Scalar).Multiply(eta, scInvEight),
+			rys.Multiply(rys, scInvEight),
+		},
+		[]*ed25519.Point{ed25519.NewGeneratorPoint(), mcrypto.H()},
+	)
+
+	e := tr.update(a1, b)
+	if e.Equal(ed25519.NewScalar()) == 1 {
+		return nil
+	}
+	eSquared := new(ed25519.Scalar).Multiply(e, e)
+
+	// r1 = r + a'*e, s1 = s + b'*e, d1 = eta + d*e + alpha1*e^2
+	r1 := new(ed25519.Scalar).MultiplyAdd(aPrime[0], e, r)
+	s1 := new(ed25519.Scalar).MultiplyAdd(bPrime[0], e, s)
+	d1 := new(ed25519.Scalar).MultiplyAdd(dFinal, e, eta)
+	d1.MultiplyAdd(alpha1, eSquared, d1)
+
+	return &Proof{
+		V:  v,
+		A:  bigA,
+		A1: a1,
+		B:  b,
+		R1: r1,
+		S1: s1,
+		D1: d1,
+		L:  proofL,
+		R:  proofR,
+	}
+}
@@ -0,0 +1,116 @@
+package bpplus
+
+import (
+	"math"
+	"testing"
+
+	ed25519 "filippo.io/edwards25519"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/mcrypto"
+)
+
+func randomMasks(count int) []*ed25519.Scalar {
+	masks := make([]*ed25519.Scalar, count)
+	for i := range masks {
+		masks[i] = mcrypto.RandomScalar()
+	}
+	return masks
+}
+
+func TestProve(t *testing.T) {
+	testCases := [][]uint64{
+		{0},
+		{math.MaxUint64},
+		{1, 2, 3},
+		{123456789, 0, math.MaxUint64, 42, 7},
+	}
+	for _, amounts := range testCases {
+		masks := randomMasks(len(amounts))
+		proof, err := Prove(amounts, masks)
+		require.NoError(t, err)
+		require.Len(t, proof.L, logN+logM(len(amounts)))
+		require.True(t, Verify(proof), amounts)
+
+		for i, c := range proof.Commitments() {
+			require.Equal(t, 1, c.Equal(mcrypto.Commit(masks[i], amounts[i])))
+		}
+	}
+}
+
+func TestProve_maxOutputs(t *testing.T) {
+	amounts := make([]uint64, MaxOutputs)
+	for i := range amounts {
+		amounts[i] = uint64(i) << 40
+	}
+	proof, err := Prove(amounts, randomMasks(MaxOutputs))
+	require.NoError(t, err)
+	require.True(t, Verify(proof))
+
+	_, err = Prove(append(amounts, 1), randomMasks(MaxOutputs+1))
+	require.ErrorIs(t, err, errTooManyAmounts)
+	_, err = Prove(nil, nil)
+	require.ErrorIs(t, err, errNoAmounts)
+	_, err = Prove(amounts, randomMasks(1))
+	require.ErrorIs(t, err, errMaskCount)
+}
+
+func TestVerify_tampered(t *testing.T) {
+	amounts := []uint64{1000, 2000}
+	masks := randomMasks(len(amounts))
+	proof, err := Prove(amounts, masks)
+	require.NoError(t, err)
+
+	// a commitment to a different amount
+	tampered := *proof
+	tampered.V = []*ed25519.Point{proof.V[0], new(ed25519.Point).ScalarMult(scInvEight, mcrypto.Commit(masks[1], 2001))}
+	require.False(t, Verify(&tampered))
+
+	tampered = *proof
+	tampered.R1 = mcrypto.RandomScalar()
+	require.False(t, Verify(&tampered))
+
+	tampered = *proof
+	tampered.A1 = new(ed25519.Point).Add(proof.A1, ed25519.NewGeneratorPoint())
+	require.False(t, Verify(&tampered))
+
+	tampered = *proof
+	tampered.L = append([]*ed25519.Point{}, proof.L...)
+	tampered.L[3] = ed25519.NewGeneratorPoint()
+	require.False(t, Verify(&tampered))
+
+	tampered = *proof
+	tampered.V = proof.V[:1]
+	require.ErrorIs(t, BatchVerify([]*Proof{&tampered}), errProofSize)
+}
+
+// TestVerify_outOfRange proves an amount that is out of range by lying about
+// its bits, which the verifier must reject.
+func TestVerify_outOfRange(t *testing.T) {
+	masks := randomMasks(1)
+	proof, err := Prove([]uint64{5}, masks)
+	require.NoError(t, err)
+
+	// 5 - 2^64 in the commitment, which is "negative" and not in range
+	minusAmount := new(ed25519.Scalar).Subtract(mcrypto.ScalarFromUint64(5), scMaxUint64)
+	minusAmount.Subtract(minusAmount, scOne)
+	c := new(ed25519.Point).ScalarMult(minusAmount, mcrypto.H())
+	c.Add(c, new(ed25519.Point).ScalarBaseMult(masks[0]))
+	proof.V[0] = new(ed25519.Point).ScalarMult(scInvEight, c)
+	require.False(t, Verify(proof))
+}
+
+func TestBatchVerify(t *testing.T) {
+	var proofs []*Proof
+	for _, amounts := range [][]uint64{{1}, {2, 3}, {4, 5, 6, 7, 8}} {
+		proof, err := Prove(amounts, randomMasks(len(amounts)))
+		require.NoError(t, err)
+		proofs = append(proofs, proof)
+	}
+	require.NoError(t, BatchVerify(proofs))
+
+	// one bad proof fails the batch
+	bad := *proofs[1]
+	bad.D1 = mcrypto.RandomScalar()
+	require.ErrorIs(t, BatchVerify([]*Proof{proofs[0], &bad, proofs[2]}), errInvalidProof)
+}
@@ -0,0 +1,88 @@
+package bpplus
+
+import (
+	"encoding/binary"
+	"sync"
+
+	ed25519 "filippo.io/edwards25519"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/dimalinux/gopherphis/mcrypto"
+)
+
+// Domain separators, from Monero's cryptonote_config.h
+const (
+	exponentDomain   = "bulletproof_plus"
+	transcriptDomain = "bulletproof_plus_transcript"
+)
+
+// generators holds the Gi and Hi vector generators, shared by all proofs
+type generators struct {
+	gi []*ed25519.Point
+	hi []*ed25519.Point
+}
+
+var (
+	gensOnce sync.Once
+	gens     *generators
+)
+
+// exponent is Monero's get_exponent. It derives the generator at index idx by
+// hashing the base point, the domain separator and the varint encoded index,
+// then mapping the hash to a point with hash_to_p3 (mcrypto.HashToEC).
+func exponent(base *ed25519.Point, idx uint64) *ed25519.Point {
+	return mcrypto.HashToEC(ethcrypto.Keccak256(base.Bytes(), []byte(exponentDomain), binary.AppendUvarint(nil, idx)))
+}
+
+// getGenerators returns the Gi and Hi generators, deriving them on first use
+func getGenerators() *generators {
+	gensOnce.Do(func() {
+		g := &generators{
+			gi: make([]*ed25519.Point, maxMN),
+			hi: make([]*ed25519.Point, maxMN),
+		}
+		h := mcrypto.H()
+		for i := 0; i < maxMN; i++ {
+			g.hi[i] = exponent(h, uint64(2*i))
+			g.gi[i] = exponent(h, uint64(2*i+1))
+		}
+		gens = g
+	})
+	return gens
+}
+
+// initialTranscript is the starting point of the Fiat-Shamir transcript
+var initialTranscript = mcrypto.HashToEC(ethcrypto.Keccak256([]byte(transcriptDomain))).Bytes()
+
+// transcript is the Fiat-Shamir transcript. Each update hashes the current
+// state with the new elements to derive the next challenge.
+type transcript struct {
+	state *ed25519.Scalar
+}
+
+func newTranscript(v []*ed25519.Point) *transcript {
+	vKeys := make([][]byte, 0, len(v))
+	for _, p := range v {
+		vKeys = append(vKeys, p.Bytes())
+	}
+	return &transcript{
+		state: mcrypto.HashToScalar(initialTranscript, mcrypto.HashToScalar(vKeys...).Bytes()),
+	}
+}
+
+// update hashes the transcript with the passed points and returns the new
+// state as the challenge.
+func (t *transcript) update(points ...*ed25519.Point) *ed25519.Scalar {
+	data := [][]byte{t.state.Bytes()}
+	for _, p := range points {
+		data = append(data, p.Bytes())
+	}
+	t.state = mcrypto.HashToScalar(data...)
+	return t.state
+}
+
+// rehash sets the transcript to the hash of its current state
+func (t *transcript) rehash() *ed25519.Scalar {
+	t.state = mcrypto.HashToScalar(t.state.Bytes())
+	return t.state
+}
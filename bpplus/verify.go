@@ -0,0 +1,188 @@
+package bpplus
+
+import (
+	ed25519 "filippo.io/edwards25519"
+
+	"github.com/dimalinux/gopherphis/mcrypto"
+)
+
+// Verify returns true if the proof is valid
+func Verify(proof *Proof) bool {
+	return BatchVerify([]*Proof{proof}) == nil
+}
+
+// BatchVerify is Monero's bulletproof_plus_VERIFY. It verifies all of the
+// proofs at once, which is much faster than verifying them one at a time. The
+// verification equation of each proof is multiplied by a random weight, so
+// that the weighted sum, a single multiscalar multiplication, is only zero if
+// every proof is valid. An error is returned if any proof is invalid.
+func BatchVerify(proofs []*Proof) error {
+	gen := getGenerators()
+
+	var (
+		batchMN  int
+		gScalar  = ed25519.NewScalar()
+		hScalar  = ed25519.NewScalar()
+		giScalar = make([]*ed25519.Scalar, maxMN)
+		hiScalar = make([]*ed25519.Scalar, maxMN)
+		scalars  []*ed25519.Scalar
+		points   []*ed25519.Point
+	)
+	for i := range giScalar {
+		giScalar[i] = ed25519.NewScalar()
+		hiScalar[i] = ed25519.NewScalar()
+	}
+
+	for _, p := range proofs {
+		lm := logM(len(p.V))
+		if len(p.V) == 0 || len(p.V) > MaxOutputs || len(p.L) != logN+lm || len(p.R) != len(p.L) {
+			return errProofSize
+		}
+		m := 1 << lm
+		mn := m * n
+		batchMN = max(batchMN, mn)
+
+		// replay the transcript to get the challenges
+		tr := newTranscript(p.V)
+		y := tr.update(p.A)
+		z := tr.rehash()
+		challenges := make([]*ed25519.Scalar, len(p.L))
+		for j := range p.L {
+			challenges[j] = tr.update(p.L[j], p.R[j])
+		}
+		e := tr.update(p.A1, p.B)
+
+		zero := ed25519.NewScalar()
+		if y.Equal(zero) == 1 || z.Equal(zero) == 1 || e.Equal(zero) == 1 {
+			return errInvalidProof
+		}
+		challengesInv := make([]*ed25519.Scalar, len(challenges))
+		for j, c := range challenges {
+			if c.Equal(zero) == 1 {
+				return errInvalidProof
+			}
+			challengesInv[j] = new(ed25519.Scalar).Invert(c)
+		}
+
+		weight := mcrypto.RandomScalar()
+		w8 := new(ed25519.Scalar).Multiply(weight, scEight)
+		eSquared := new(ed25519.Scalar).Multiply(e, e)
+		zSquared := new(ed25519.Scalar).Multiply(z, z)
+		yPowers := powers(y, mn+2)
+		yInv := new(ed25519.Scalar).Invert(y)
+
+		// V_j: 8*w*e^2*y^(MN+1)*z^(2*(j+1))
+		temp := new(ed25519.Scalar).Multiply(w8, eSquared)
+		temp.Multiply(temp, yPowers[mn+1])
+		for _, v := range p.V {
+			temp.Multiply(temp, zSquared)
+			scalars = append(scalars, new(ed25519.Scalar).Set(temp))
+			points = append(points, v)
+		}
+
+		// A: 8*w*e^2, A1: 8*w*e, B: 8*w
+		scalars = append(scalars,
+			new(ed25519.Scalar).Multiply(w8, eSquared),
+			new(ed25519.Scalar).Multiply(w8, e),
+			new(ed25519.Scalar).Set(w8),
+		)
+		points = append(points, p.A, p.A1, p.B)
+
+		// L_j: 8*w*e^2*e_j^2, R_j: 8*w*e^2*e_j^-2
+		w8eSquared := new(ed25519.Scalar).Multiply(w8, eSquared)
+		for j := range p.L {
+			cSquared := new(ed25519.Scalar).Multiply(challenges[j], challenges[j])
+			cSquaredInv := new(ed25519.Scalar).Multiply(challengesInv[j], challengesInv[j])
+			scalars = append(scalars,
+				cSquared.Multiply(cSquared, w8eSquared),
+				cSquaredInv.Multiply(cSquaredInv, w8eSquared),
+			)
+			points = append(points, p.L[j], p.R[j])
+		}
+
+		// G: -w*d1
+		gScalar.Subtract(gScalar, new(ed25519.Scalar).Multiply(weight, p.D1))
+
+		// H: w*(e^2*((z - z^2)*sum_y - z*y^(MN+1)*sum_d) - r1*y*s1)
+		sumY := ed25519.NewScalar()
+		for i := 1; i <= mn; i++ {
+			sumY.Add(sumY, yPowers[i])
+		}
+		sumD := ed25519.NewScalar()
+		zPower := new(ed25519.Scalar).Set(scOne)
+		for j := 0; j < m; j++ {
+			zPower.Multiply(zPower, zSquared)
+			sumD.Add(sumD, zPower)
+		}
+		sumD.Multiply(sumD, scMaxUint64)
+
+		hTerm := new(ed25519.Scalar).Subtract(z, zSquared)
+		hTerm.Multiply(hTerm, sumY)
+		zyd := new(ed25519.Scalar).Multiply(z, yPowers[mn+1])
+		zyd.Multiply(zyd, sumD)
+		hTerm.Subtract(hTerm, zyd)
+		hTerm.Multiply(hTerm, eSquared)
+		rys := new(ed25519.Scalar).Multiply(p.R1, y)
+		rys.Multiply(rys, p.S1)
+		hTerm.Subtract(hTerm, rys)
+		hScalar.MultiplyAdd(weight, hTerm, hScalar)
+
+		// Gi and Hi: the folded generators' coefficients are products of
+		// the round challenges, selected by the bits of the index.
+		cache := challengeCache(challenges, challengesInv)
+		d := windowedVector(zSquared, m)
+		wESquaredZ := new(ed25519.Scalar).Multiply(weight, eSquared)
+		wESquaredZ.Multiply(wESquaredZ, z)
+		weR1 := new(ed25519.Scalar).Multiply(weight, e)
+		weR1.Multiply(weR1, p.R1)
+		weS1 := new(ed25519.Scalar).Multiply(weight, e)
+		weS1.Multiply(weS1, p.S1)
+		wESquared := new(ed25519.Scalar).Multiply(weight, eSquared)
+		yInvPower := new(ed25519.Scalar).Set(scOne)
+
+		for i := 0; i < mn; i++ {
+			// Gi: -w*(e^2*z + e*r1*y^-i*cache[i])
+			g := new(ed25519.Scalar).Multiply(weR1, yInvPower)
+			g.MultiplyAdd(g, cache[i], wESquaredZ)
+			giScalar[i].Subtract(giScalar[i], g)
+			yInvPower.Multiply(yInvPower, yInv)
+
+			// Hi: w*(e^2*(z + d[i]*y^(MN-i)) - e*s1*cache[~i])
+			h := new(ed25519.Scalar).Multiply(d[i], yPowers[mn-i])
+			h.Multiply(h, wESquared)
+			h.Add(h, wESquaredZ)
+			h.Subtract(h, new(ed25519.Scalar).Multiply(weS1, cache[^i&(mn-1)]))
+			hiScalar[i].Add(hiScalar[i], h)
+		}
+	}
+
+	scalars = append(scalars, gScalar, hScalar)
+	points = append(points, ed25519.NewGeneratorPoint(), mcrypto.H())
+	scalars = append(scalars, giScalar[:batchMN]...)
+	points = append(points, gen.gi[:batchMN]...)
+	scalars = append(scalars, hiScalar[:batchMN]...)
+	points = append(points, gen.hi[:batchMN]...)
+
+	if new(ed25519.Point).VarTimeMultiScalarMult(scalars, points).Equal(identityPoint) != 1 {
+		return errInvalidProof
+	}
+	return nil
+}
+
+// challengeCache returns, for each index i of the original generator
+// vectors, the product of the round challenges (where the round's bit of i,
+// starting from the most significant, is 1) and inverse challenges (where it
+// is 0).
+func challengeCache(challenges, challengesInv []*ed25519.Scalar) []*ed25519.Scalar {
+	cache := make([]*ed25519.Scalar, 1<<len(challenges))
+	cache[0] = new(ed25519.Scalar).Set(challengesInv[0])
+	cache[1] = new(ed25519.Scalar).Set(challenges[0])
+	for j := 1; j < len(challenges); j++ {
+		slots := 1 << (j + 1)
+		for s := slots - 1; s > 0; s -= 2 {
+			cache[s] = new(ed25519.Scalar).Multiply(cache[s/2], challenges[j])
+			cache[s-1] = new(ed25519.Scalar).Multiply(cache[s/2], challengesInv[j])
+		}
+	}
+	return cache
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"slices"
+)
+
+// protect wraps the handler with the checks done before a request reaches a
+// method: cross-origin requests are rejected unless their origin was allowed
+// with --rpc-access-control-origins, requests must have a valid digest
+// authorization if the server has a login, and bodies must be JSON.
+func (s *server) protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && !slices.Contains(s.allowedOrigins, origin) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		if s.auth != nil && !s.auth.Check(w, r) {
+			return
+		}
+		if r.Method == http.MethodPost {
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mediaType != "application/json" {
+				http.Error(w, "requests must have Content-Type application/json", http.StatusUnsupportedMediaType)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
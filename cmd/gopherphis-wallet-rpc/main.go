@@ -0,0 +1,200 @@
+// Command gopherphis-wallet-rpc serves a subset of monero-wallet-rpc's
+// JSON-RPC interface, for a wallet restored from a seed, so that tools written
+// for monero-wallet-rpc can use gopherphis for read-mostly workloads. The
+// wallet scans the chain with a monerod RPC server in the background.
+//
+// Like monero-wallet-rpc, requests require digest authentication with the
+// --rpc-login credentials, unless --disable-rpc-login is set, and
+// cross-origin requests are rejected unless their origin is listed in
+// --rpc-access-control-origins.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+	"github.com/dimalinux/gopherphis/daemon"
+	"github.com/dimalinux/gopherphis/wallet"
+)
+
+const (
+	defaultBindIP          = "127.0.0.1"
+	defaultBindPort        = 18083
+	defaultRefreshInterval = 20 * time.Second
+	shutdownTimeout        = 5 * time.Second
+)
+
+var (
+	errNoSeedFile   = errors.New("--seed-file is required")
+	errNetworkFlags = errors.New("only one of --stagenet and --testnet can be set")
+	errNoRPCLogin   = errors.New("--rpc-login is required unless --disable-rpc-login is set")
+	errRPCLogin     = errors.New("--rpc-login must be username:password")
+)
+
+type config struct {
+	seedFile        string
+	restoreHeight   uint64
+	daemonAddress   string
+	daemonLogin     string
+	bindIP          string
+	bindPort        uint
+	rpcLogin        string
+	allowedOrigins  []string
+	net             cryptonote.Network
+	refreshInterval time.Duration
+}
+
+func parseFlags(args []string) (*config, error) {
+	fs := flag.NewFlagSet("gopherphis-wallet-rpc", flag.ContinueOnError)
+	cfg := new(config)
+	fs.StringVar(&cfg.seedFile, "seed-file", "", "file with the wallet's polyseed or legacy mnemonic seed")
+	fs.Uint64Var(&cfg.restoreHeight, "restore-height", 0, "block height to start scanning the chain from")
+	fs.StringVar(&cfg.daemonAddress, "daemon-address", "", "URL of the monerod RPC server, like http://127.0.0.1:18081")
+	fs.StringVar(&cfg.daemonLogin, "daemon-login", "", "username:password of the monerod RPC server")
+	fs.StringVar(&cfg.bindIP, "rpc-bind-ip", defaultBindIP, "IP address to listen on")
+	fs.UintVar(&cfg.bindPort, "rpc-bind-port", defaultBindPort, "port to listen on")
+	fs.StringVar(&cfg.rpcLogin, "rpc-login", "", "username:password required to use the RPC server")
+	disableLogin := fs.Bool("disable-rpc-login", false, "don't require a login to use the RPC server")
+	origins := fs.String(
+		"rpc-access-control-origins", "", "comma separated origins allowed to make cross-origin requests",
+	)
+	fs.DurationVar(&cfg.refreshInterval, "refresh-interval", defaultRefreshInterval, "time between refreshes")
+	stagenet := fs.Bool("stagenet", false, "use the stagenet network")
+	testnet := fs.Bool("testnet", false, "use the testnet network")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if cfg.seedFile == "" {
+		return nil, errNoSeedFile
+	}
+	switch {
+	case cfg.rpcLogin == "" && !*disableLogin:
+		return nil, errNoRPCLogin
+	case cfg.rpcLogin != "" && !strings.Contains(cfg.rpcLogin, ":"):
+		return nil, errRPCLogin
+	}
+	for _, origin := range strings.Split(*origins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			cfg.allowedOrigins = append(cfg.allowedOrigins, origin)
+		}
+	}
+	cfg.net = cryptonote.Mainnet
+	switch {
+	case *stagenet && *testnet:
+		return nil, errNetworkFlags
+	case *stagenet:
+		cfg.net = cryptonote.Stagenet
+	case *testnet:
+		cfg.net = cryptonote.Testnet
+	}
+	return cfg, nil
+}
+
+// loadWallet restores the wallet from the seed file
+func loadWallet(cfg *config) (*wallet.Wallet, error) {
+	seed, err := os.ReadFile(cfg.seedFile)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := wallet.KeysFromSeed(strings.Fields(string(seed)), "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", cfg.seedFile, err)
+	}
+	return wallet.New(keys, cfg.net, cfg.restoreHeight), nil
+}
+
+// newDaemonClient returns a client for the daemon, or nil if no daemon
+// address was set
+func newDaemonClient(cfg *config) (*daemon.Client, error) {
+	if cfg.daemonAddress == "" {
+		return nil, nil
+	}
+	var opts []daemon.ClientOption
+	if cfg.daemonLogin != "" {
+		username, password, _ := strings.Cut(cfg.daemonLogin, ":")
+		opts = append(opts, daemon.WithDigestAuth(username, password))
+	}
+	return daemon.NewClient(cfg.daemonAddress, opts...)
+}
+
+// refreshLoop refreshes the wallet until the context is cancelled
+func refreshLoop(ctx context.Context, w *wallet.Wallet, client *daemon.Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := w.Refresh(ctx, client); err != nil && ctx.Err() == nil {
+			log.Printf("refresh failed: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func run(ctx context.Context, cfg *config) error {
+	w, err := loadWallet(cfg)
+	if err != nil {
+		return err
+	}
+	client, err := newDaemonClient(cfg)
+	if err != nil {
+		return err
+	}
+	if client != nil {
+		go refreshLoop(ctx, w, client, cfg.refreshInterval)
+	} else {
+		log.Print("no --daemon-address set, the wallet will not scan the chain")
+	}
+
+	httpServer := &http.Server{
+		Addr:              net.JoinHostPort(cfg.bindIP, strconv.FormatUint(uint64(cfg.bindPort), 10)),
+		Handler:           newServer(w, cfg).handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("shutdown failed: %s", err)
+		}
+	}()
+
+	log.Printf("wallet %s listening on %s", w.Address(0, 0), httpServer.Addr)
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func main() {
+	cfg, err := parseFlags(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err = run(ctx, cfg)
+	stop()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
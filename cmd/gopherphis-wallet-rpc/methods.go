@@ -0,0 +1,486 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+)
+
+// maxCreateAddressCount is the maximum number of subaddresses created by one
+// create_address call
+const maxCreateAddressCount = 64
+
+// Values of incoming_transfers' transfer_type
+const (
+	transferTypeAll         = "all"
+	transferTypeAvailable   = "available"
+	transferTypeUnavailable = "unavailable"
+)
+
+// Values of sign's and verify's signature_type
+const (
+	signatureTypeSpend = "spend"
+	signatureTypeView  = "view"
+)
+
+var (
+	errAddressIndex       = errors.New("address index is out of bounds")
+	errCreateAddressCount = fmt.Errorf("count must be between 1 and %d", maxCreateAddressCount)
+	errTransferType       = errors.New("transfer type must be one of: all, available, or unavailable")
+	errSignatureType      = errors.New("signature type must be spend or view")
+	errIntegratedAddress  = errors.New("address is already an integrated address")
+	errURIRecipients      = errors.New("URIs with multiple recipients are not supported")
+)
+
+// numSubaddresses returns the number of subaddresses created in the account
+func (s *server) numSubaddresses(account uint32) (uint32, *rpcError) {
+	n, err := s.wallet.NumSubaddresses(account)
+	if err != nil {
+		return 0, newRPCError(errCodeAccountIndexOutOfBounds, err)
+	}
+	return n, nil
+}
+
+type getAddressRequest struct {
+	AccountIndex uint32   `json:"account_index"`
+	AddressIndex []uint32 `json:"address_index"`
+}
+
+type addressInfo struct {
+	Address      string `json:"address"`
+	Label        string `json:"label"`
+	AddressIndex uint32 `json:"address_index"`
+	Used         bool   `json:"used"`
+}
+
+type getAddressResponse struct {
+	Address   string         `json:"address"`
+	Addresses []*addressInfo `json:"addresses"`
+}
+
+// getAddress returns the account's main address, and its subaddresses
+// listed in address_index, or all of its created subaddresses
+func (s *server) getAddress(req *getAddressRequest) (any, *rpcError) {
+	n, rpcErr := s.numSubaddresses(req.AccountIndex)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	indices := req.AddressIndex
+	if len(indices) == 0 {
+		for i := uint32(0); i < n; i++ {
+			indices = append(indices, i)
+		}
+	}
+
+	resp := &getAddressResponse{Address: s.wallet.Address(req.AccountIndex, 0).String()}
+	for _, index := range indices {
+		label, err := s.wallet.SubaddressLabel(req.AccountIndex, index)
+		if err != nil {
+			return nil, newRPCError(errCodeAddressIndexOutOfBounds, errAddressIndex)
+		}
+		resp.Addresses = append(resp.Addresses, &addressInfo{
+			Address:      s.wallet.Address(req.AccountIndex, index).String(),
+			Label:        label,
+			AddressIndex: index,
+			Used:         s.wallet.SubaddressUsed(req.AccountIndex, index),
+		})
+	}
+	return resp, nil
+}
+
+type createAddressRequest struct {
+	AccountIndex uint32 `json:"account_index"`
+	Count        uint32 `json:"count"`
+	Label        string `json:"label"`
+}
+
+type createAddressResponse struct {
+	Address        string   `json:"address"`
+	AddressIndex   uint32   `json:"address_index"`
+	Addresses      []string `json:"addresses"`
+	AddressIndices []uint32 `json:"address_indices"`
+}
+
+// createAddress creates count subaddresses, one by default, in the account
+func (s *server) createAddress(req *createAddressRequest) (any, *rpcError) {
+	count := max(req.Count, 1)
+	if count > maxCreateAddressCount {
+		return nil, newRPCError(errCodeUnknown, errCreateAddressCount)
+	}
+
+	resp := new(createAddressResponse)
+	for i := uint32(0); i < count; i++ {
+		index, err := s.wallet.CreateSubaddress(req.AccountIndex, req.Label)
+		if err != nil {
+			return nil, newRPCError(errCodeAccountIndexOutOfBounds, err)
+		}
+		resp.Addresses = append(resp.Addresses, s.wallet.Address(req.AccountIndex, index).String())
+		resp.AddressIndices = append(resp.AddressIndices, index)
+	}
+	resp.Address = resp.Addresses[0]
+	resp.AddressIndex = resp.AddressIndices[0]
+	return resp, nil
+}
+
+type getBalanceRequest struct {
+	AccountIndex   uint32   `json:"account_index"`
+	AddressIndices []uint32 `json:"address_indices"`
+	AllAccounts    bool     `json:"all_accounts"`
+}
+
+type subaddressBalance struct {
+	AccountIndex      uint32 `json:"account_index"`
+	AddressIndex      uint32 `json:"address_index"`
+	Address           string `json:"address"`
+	Balance           uint64 `json:"balance"`
+	UnlockedBalance   uint64 `json:"unlocked_balance"`
+	Label             string `json:"label"`
+	NumUnspentOutputs int    `json:"num_unspent_outputs"`
+	BlocksToUnlock    uint64 `json:"blocks_to_unlock"`
+}
+
+type getBalanceResponse struct {
+	Balance         uint64               `json:"balance"`
+	UnlockedBalance uint64               `json:"unlocked_balance"`
+	BlocksToUnlock  uint64               `json:"blocks_to_unlock"`
+	PerSubaddress   []*subaddressBalance `json:"per_subaddress"`
+}
+
+// getBalance returns the balance of the account, or of all accounts, with
+// the balances of the subaddresses that have unspent outputs. Like
+// monero-wallet-rpc, address_indices only filters the per subaddress
+// balances.
+func (s *server) getBalance(req *getBalanceRequest) (any, *rpcError) {
+	accounts := []uint32{req.AccountIndex}
+	if req.AllAccounts {
+		accounts = accounts[:0]
+		for i := uint32(0); i < s.wallet.NumAccounts(); i++ {
+			accounts = append(accounts, i)
+		}
+	}
+
+	resp := new(getBalanceResponse)
+	for _, account := range accounts {
+		balances, err := s.wallet.SubaddressBalances(account)
+		if err != nil {
+			return nil, newRPCError(errCodeAccountIndexOutOfBounds, err)
+		}
+		for index, b := range balances {
+			resp.Balance += uint64(b.Total)
+			resp.UnlockedBalance += uint64(b.Unlocked)
+			resp.BlocksToUnlock = max(resp.BlocksToUnlock, b.BlocksToUnlock)
+			if b.NumUnspent == 0 {
+				continue
+			}
+			if !req.AllAccounts && len(req.AddressIndices) > 0 && !slices.Contains(req.AddressIndices, uint32(index)) {
+				continue
+			}
+			label, _ := s.wallet.SubaddressLabel(account, uint32(index))
+			resp.PerSubaddress = append(resp.PerSubaddress, &subaddressBalance{
+				AccountIndex:      account,
+				AddressIndex:      uint32(index),
+				Address:           s.wallet.Address(account, uint32(index)).String(),
+				Balance:           uint64(b.Total),
+				UnlockedBalance:   uint64(b.Unlocked),
+				Label:             label,
+				NumUnspentOutputs: b.NumUnspent,
+				BlocksToUnlock:    b.BlocksToUnlock,
+			})
+		}
+	}
+	return resp, nil
+}
+
+type getHeightResponse struct {
+	Height uint64 `json:"height"`
+}
+
+// getHeight returns the number of blocks scanned by the wallet
+func (s *server) getHeight(*struct{}) (any, *rpcError) {
+	return &getHeightResponse{Height: s.wallet.Height()}, nil
+}
+
+type incomingTransfersRequest struct {
+	TransferType   string   `json:"transfer_type"`
+	AccountIndex   uint32   `json:"account_index"`
+	SubaddrIndices []uint32 `json:"subaddr_indices"`
+}
+
+type subaddressIndex struct {
+	Major uint32 `json:"major"`
+	Minor uint32 `json:"minor"`
+}
+
+type incomingTransfer struct {
+	Amount       uint64          `json:"amount"`
+	BlockHeight  uint64          `json:"block_height"`
+	GlobalIndex  uint64          `json:"global_index"`
+	KeyImage     string          `json:"key_image"`
+	PubKey       string          `json:"pubkey"`
+	Spent        bool            `json:"spent"`
+	SubaddrIndex subaddressIndex `json:"subaddr_index"`
+	TxHash       string          `json:"tx_hash"`
+	Unlocked     bool            `json:"unlocked"`
+}
+
+type incomingTransfersResponse struct {
+	Transfers []*incomingTransfer `json:"transfers,omitempty"`
+}
+
+// incomingTransfers returns the outputs received by the account, filtered
+// by whether they were spent, and optionally by subaddress
+func (s *server) incomingTransfers(req *incomingTransfersRequest) (any, *rpcError) {
+	var wantSpent func(spent bool) bool
+	switch req.TransferType {
+	case transferTypeAll, "":
+		wantSpent = func(bool) bool { return true }
+	case transferTypeAvailable:
+		wantSpent = func(spent bool) bool { return !spent }
+	case transferTypeUnavailable:
+		wantSpent = func(spent bool) bool { return spent }
+	default:
+		return nil, newRPCError(errCodeTransferType, errTransferType)
+	}
+	if _, rpcErr := s.numSubaddresses(req.AccountIndex); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	resp := new(incomingTransfersResponse)
+	for _, t := range s.wallet.Transfers() {
+		if t.AccountIndex != req.AccountIndex || !wantSpent(t.Spent) {
+			continue
+		}
+		if len(req.SubaddrIndices) > 0 && !slices.Contains(req.SubaddrIndices, t.SubAddrIndex) {
+			continue
+		}
+		resp.Transfers = append(resp.Transfers, &incomingTransfer{
+			Amount:       t.Amount,
+			BlockHeight:  t.BlockHeight,
+			GlobalIndex:  t.GlobalIndex,
+			KeyImage:     t.KeyImage.Hex(),
+			PubKey:       t.Key.Hex(),
+			Spent:        t.Spent,
+			SubaddrIndex: subaddressIndex{Major: t.AccountIndex, Minor: t.SubAddrIndex},
+			TxHash:       hex.EncodeToString(t.Tx.ID[:]),
+			Unlocked:     s.wallet.IsUnlocked(t),
+		})
+	}
+	return resp, nil
+}
+
+type validateAddressRequest struct {
+	Address    string `json:"address"`
+	AnyNetType bool   `json:"any_net_type"`
+}
+
+type validateAddressResponse struct {
+	Valid      bool   `json:"valid"`
+	Integrated bool   `json:"integrated"`
+	Subaddress bool   `json:"subaddress"`
+	Nettype    string `json:"nettype"`
+}
+
+// validateAddress checks an address, which must be of the wallet's network
+// unless any_net_type is set. Invalid addresses are not an error.
+func (s *server) validateAddress(req *validateAddressRequest) (any, *rpcError) {
+	addr := new(cryptonote.Address)
+	if err := addr.UnmarshalText([]byte(req.Address)); err != nil {
+		return new(validateAddressResponse), nil
+	}
+	if !req.AnyNetType && addr.Network() != s.wallet.Network() {
+		return new(validateAddressResponse), nil
+	}
+	return &validateAddressResponse{
+		Valid:      true,
+		Integrated: addr.Type() == cryptonote.Integrated,
+		Subaddress: addr.Type() == cryptonote.Subaddress,
+		Nettype:    string(addr.Network()),
+	}, nil
+}
+
+// parseAddress parses an address of the wallet's network
+func (s *server) parseAddress(address string) (*cryptonote.Address, *rpcError) {
+	addr, err := cryptonote.NewAddress(address, s.wallet.Network())
+	if err != nil {
+		return nil, newRPCError(errCodeWrongAddress, err)
+	}
+	return addr, nil
+}
+
+type makeIntegratedAddressRequest struct {
+	StandardAddress string `json:"standard_address"`
+	PaymentID       string `json:"payment_id"`
+}
+
+type makeIntegratedAddressResponse struct {
+	IntegratedAddress string `json:"integrated_address"`
+	PaymentID         string `json:"payment_id"`
+}
+
+// makeIntegratedAddress combines a standard address, by default the wallet's
+// primary address, with a payment ID, which is random if not passed
+func (s *server) makeIntegratedAddress(req *makeIntegratedAddressRequest) (any, *rpcError) {
+	addr := s.wallet.Address(0, 0)
+	if req.StandardAddress != "" {
+		var rpcErr *rpcError
+		if addr, rpcErr = s.parseAddress(req.StandardAddress); rpcErr != nil {
+			return nil, rpcErr
+		}
+		if addr.Type() == cryptonote.Integrated {
+			return nil, newRPCError(errCodeWrongAddress, errIntegratedAddress)
+		}
+	}
+
+	var paymentID cryptonote.PaymentID
+	if req.PaymentID == "" {
+		if _, err := rand.Read(paymentID[:]); err != nil {
+			return nil, newRPCError(errCodeUnknown, err)
+		}
+	} else {
+		var err error
+		if paymentID, err = cryptonote.NewPaymentID(req.PaymentID); err != nil {
+			return nil, newRPCError(errCodeWrongPaymentID, err)
+		}
+	}
+
+	pubKeys, err := addr.PublicKeyPair()
+	if err != nil {
+		return nil, newRPCError(errCodeWrongAddress, err)
+	}
+	integrated, err := pubKeys.IntegratedAddress(s.wallet.Network(), paymentID)
+	if err != nil {
+		return nil, newRPCError(errCodeWrongAddress, err)
+	}
+	return &makeIntegratedAddressResponse{IntegratedAddress: integrated.String(), PaymentID: paymentID.Hex()}, nil
+}
+
+type signRequest struct {
+	Data          string `json:"data"`
+	AccountIndex  uint32 `json:"account_index"`
+	AddressIndex  uint32 `json:"address_index"`
+	SignatureType string `json:"signature_type"`
+}
+
+type signResponse struct {
+	Signature string `json:"signature"`
+}
+
+// sign signs data with the spend key, or the view key, of a subaddress
+func (s *server) sign(req *signRequest) (any, *rpcError) {
+	var useViewKey bool
+	switch req.SignatureType {
+	case signatureTypeSpend, "":
+	case signatureTypeView:
+		useViewKey = true
+	default:
+		return nil, newRPCError(errCodeUnknown, errSignatureType)
+	}
+	sig := s.wallet.Keys().SignMessage([]byte(req.Data), useViewKey, req.AccountIndex, req.AddressIndex)
+	return &signResponse{Signature: sig}, nil
+}
+
+type verifyRequest struct {
+	Data      string `json:"data"`
+	Address   string `json:"address"`
+	Signature string `json:"signature"`
+}
+
+type verifyResponse struct {
+	Good          bool   `json:"good"`
+	Version       int    `json:"version"`
+	Old           bool   `json:"old"`
+	SignatureType string `json:"signature_type"`
+}
+
+// verify checks a signature of data by an address. Invalid signatures are
+// not an error.
+func (s *server) verify(req *verifyRequest) (any, *rpcError) {
+	addr, rpcErr := s.parseAddress(req.Address)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	info, err := cryptonote.VerifyMessage(addr, []byte(req.Data), req.Signature)
+	if err != nil {
+		return new(verifyResponse), nil
+	}
+	resp := &verifyResponse{Good: true, Version: info.Version, Old: info.Version == 1, SignatureType: signatureTypeSpend}
+	if info.ViewKey {
+		resp.SignatureType = signatureTypeView
+	}
+	return resp, nil
+}
+
+// uriSpec is a payment request of make_uri and parse_uri, with the amount in
+// piconero
+type uriSpec struct {
+	Address       string `json:"address"`
+	Amount        uint64 `json:"amount"`
+	PaymentID     string `json:"payment_id"`
+	RecipientName string `json:"recipient_name"`
+	TxDescription string `json:"tx_description"`
+}
+
+type makeURIResponse struct {
+	URI string `json:"uri"`
+}
+
+// makeURI formats a payment request URI
+func (s *server) makeURI(req *uriSpec) (any, *rpcError) {
+	addr, err := cryptonote.NewAddress(req.Address, s.wallet.Network())
+	if err != nil {
+		return nil, newRPCError(errCodeWrongURI, err)
+	}
+	uri := &cryptonote.MoneroURI{
+		Recipients: []*cryptonote.URIRecipient{{
+			Address: addr,
+			Amount:  cryptonote.Amount(req.Amount),
+			Name:    req.RecipientName,
+		}},
+		PaymentID:   req.PaymentID,
+		Description: req.TxDescription,
+	}
+	if err := uri.Validate(s.wallet.Network()); err != nil {
+		return nil, newRPCError(errCodeWrongURI, err)
+	}
+	return &makeURIResponse{URI: uri.String()}, nil
+}
+
+type parseURIRequest struct {
+	URI string `json:"uri"`
+}
+
+type parseURIResponse struct {
+	URI               *uriSpec `json:"uri"`
+	UnknownParameters []string `json:"unknown_parameters"`
+}
+
+// parseURI parses a payment request URI with a single recipient
+func (s *server) parseURI(req *parseURIRequest) (any, *rpcError) {
+	uri, err := cryptonote.ParseMoneroURI(req.URI, s.wallet.Network())
+	if err != nil {
+		return nil, newRPCError(errCodeWrongURI, err)
+	}
+	if len(uri.Recipients) != 1 {
+		return nil, newRPCError(errCodeWrongURI, errURIRecipients)
+	}
+
+	recipient := uri.Recipients[0]
+	resp := &parseURIResponse{URI: &uriSpec{
+		Address:       recipient.Address.String(),
+		Amount:        uint64(recipient.Amount),
+		PaymentID:     uri.PaymentID,
+		RecipientName: recipient.Name,
+		TxDescription: uri.Description,
+	}}
+	for key, values := range uri.UnknownParams {
+		for _, value := range values {
+			resp.UnknownParameters = append(resp.UnknownParameters, key+"="+value)
+		}
+	}
+	slices.Sort(resp.UnknownParameters)
+	return resp, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/dimalinux/gopherphis/digest"
+	"github.com/dimalinux/gopherphis/wallet"
+)
+
+// JSON-RPC error codes. The codes above -32000 are those of
+// monero-wallet-rpc.
+const (
+	errCodeUnknown                 = -1
+	errCodeWrongAddress            = -2
+	errCodeWrongPaymentID          = -5
+	errCodeTransferType            = -6
+	errCodeWrongURI                = -11
+	errCodeAccountIndexOutOfBounds = -14
+	errCodeAddressIndexOutOfBounds = -15
+	errCodeParse                   = -32700
+	errCodeMethodNotFound          = -32601
+	errCodeInvalidParams           = -32602
+)
+
+// maxRequestSize is the maximum size of a request body
+const maxRequestSize = 1 << 20
+
+// rpcError is a JSON-RPC error
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// newRPCError returns an rpcError with the code and the error's message
+func newRPCError(code int, err error) *rpcError {
+	return &rpcError{Code: code, Message: err.Error()}
+}
+
+// methodFunc is a JSON-RPC method, which is passed the raw params
+type methodFunc func(params json.RawMessage) (any, *rpcError)
+
+// server serves a subset of monero-wallet-rpc's JSON-RPC interface for a
+// wallet
+type server struct {
+	wallet  *wallet.Wallet
+	methods map[string]methodFunc
+
+	// auth checks the digest authorization of requests. It is nil if there
+	// is no login.
+	auth *digest.Server
+	// allowedOrigins are the origins of the cross-origin requests that are
+	// allowed
+	allowedOrigins []string
+}
+
+// newServer returns a server for the wallet with the login and allowed
+// origins of the config
+func newServer(w *wallet.Wallet, cfg *config) *server {
+	s := &server{wallet: w, allowedOrigins: cfg.allowedOrigins}
+	if cfg.rpcLogin != "" {
+		username, password, _ := strings.Cut(cfg.rpcLogin, ":")
+		s.auth = digest.NewServer(username, password)
+	}
+	s.methods = map[string]methodFunc{
+		"get_address":             method(s.getAddress),
+		"create_address":          method(s.createAddress),
+		"get_balance":             method(s.getBalance),
+		"get_height":              method(s.getHeight),
+		"incoming_transfers":      method(s.incomingTransfers),
+		"validate_address":        method(s.validateAddress),
+		"make_integrated_address": method(s.makeIntegratedAddress),
+		"sign":                    method(s.sign),
+		"verify":                  method(s.verify),
+		"make_uri":                method(s.makeURI),
+		"parse_uri":               method(s.parseURI),
+	}
+	return s
+}
+
+// method adapts a handler, whose params are decoded into a new value of type
+// T, to a methodFunc. Like monero-wallet-rpc, missing params are treated as
+// an empty object.
+func method[T any](handle func(req *T) (any, *rpcError)) methodFunc {
+	return func(params json.RawMessage) (any, *rpcError) {
+		req := new(T)
+		if len(params) > 0 && string(params) != "null" {
+			if err := json.Unmarshal(params, req); err != nil {
+				return nil, newRPCError(errCodeInvalidParams, err)
+			}
+		}
+		return handle(req)
+	}
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json_rpc", s.handleJSONRPC)
+	return s.protect(mux)
+}
+
+func (s *server) handleJSONRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "JSON-RPC requests must use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	resp := struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Result  any             `json:"result,omitempty"`
+		Error   *rpcError       `json:"error,omitempty"`
+	}{JSONRPC: "2.0"}
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req); err != nil {
+		resp.Error = &rpcError{Code: errCodeParse, Message: "Parse error"}
+	} else if method, ok := s.methods[req.Method]; ok {
+		resp.ID = req.ID
+		resp.Result, resp.Error = method(req.Params)
+	} else {
+		resp.ID = req.ID
+		resp.Error = &rpcError{Code: errCodeMethodNotFound, Message: "Method not found"}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+	"github.com/dimalinux/gopherphis/daemon"
+	"github.com/dimalinux/gopherphis/daemon/fakedaemon"
+	"github.com/dimalinux/gopherphis/digest"
+	"github.com/dimalinux/gopherphis/wallet"
+)
+
+// newTestServer starts a server for a wallet with new keys
+func newTestServer(t *testing.T) (*httptest.Server, *wallet.Wallet) {
+	keys, err := cryptonote.GenerateKeys()
+	require.NoError(t, err)
+	w := wallet.New(keys, cryptonote.Stagenet, 0)
+	ts := httptest.NewServer(newServer(w, new(config)).handler())
+	t.Cleanup(ts.Close)
+	return ts, w
+}
+
+// post sends a raw JSON-RPC request and decodes the response
+func post(t *testing.T, ts *httptest.Server, body string) (json.RawMessage, *rpcError) {
+	resp, err := http.Post(ts.URL+"/json_rpc", "application/json", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var decoded struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Result  json.RawMessage `json:"result"`
+		Error   *rpcError       `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+	require.Equal(t, "2.0", decoded.JSONRPC)
+	return decoded.Result, decoded.Error
+}
+
+// call calls a method and decodes its result, returning the error if any
+func call(t *testing.T, ts *httptest.Server, method string, params any, result any) *rpcError {
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": "0", "method": method, "params": params})
+	require.NoError(t, err)
+	raw, rpcErr := post(t, ts, string(body))
+	if rpcErr != nil {
+		return rpcErr
+	}
+	require.NoError(t, json.Unmarshal(raw, result))
+	return nil
+}
+
+func TestServer_jsonRPCErrors(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	_, rpcErr := post(t, ts, `{"jsonrpc":"2.0","id":1,"method":"transfer"}`)
+	require.Equal(t, errCodeMethodNotFound, rpcErr.Code)
+	_, rpcErr = post(t, ts, `{"jsonrpc":"2.0"`)
+	require.Equal(t, errCodeParse, rpcErr.Code)
+	_, rpcErr = post(t, ts, `{"jsonrpc":"2.0","id":1,"method":"get_address","params":{"account_index":"0"}}`)
+	require.Equal(t, errCodeInvalidParams, rpcErr.Code)
+
+	// params are optional
+	result, rpcErr := post(t, ts, `{"jsonrpc":"2.0","id":1,"method":"get_height"}`)
+	require.Nil(t, rpcErr)
+	require.JSONEq(t, `{"height":0}`, string(result))
+
+	resp, err := http.Get(ts.URL + "/json_rpc")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestServer_addresses(t *testing.T) {
+	ts, w := newTestServer(t)
+
+	var created createAddressResponse
+	require.Nil(t, call(t, ts, "create_address", map[string]any{"label": "shop", "count": 2}, &created))
+	require.Equal(t, uint32(1), created.AddressIndex)
+	require.Equal(t, []uint32{1, 2}, created.AddressIndices)
+	require.Equal(t, w.Address(0, 1).String(), created.Address)
+	require.Equal(t, w.Address(0, 2).String(), created.Addresses[1])
+
+	var addresses getAddressResponse
+	require.Nil(t, call(t, ts, "get_address", map[string]any{}, &addresses))
+	require.Equal(t, w.Address(0, 0).String(), addresses.Address)
+	require.Len(t, addresses.Addresses, 3)
+	require.Equal(t, wallet.PrimaryAccountLabel, addresses.Addresses[0].Label)
+	require.Equal(t, &addressInfo{Address: created.Address, Label: "shop", AddressIndex: 1}, addresses.Addresses[1])
+
+	require.Nil(t, call(t, ts, "get_address", map[string]any{"address_index": []uint32{2}}, &addresses))
+	require.Len(t, addresses.Addresses, 1)
+	require.Equal(t, uint32(2), addresses.Addresses[0].AddressIndex)
+
+	rpcErr := call(t, ts, "get_address", map[string]any{"address_index": []uint32{3}}, &addresses)
+	require.Equal(t, errCodeAddressIndexOutOfBounds, rpcErr.Code)
+	rpcErr = call(t, ts, "get_address", map[string]any{"account_index": 1}, &addresses)
+	require.Equal(t, errCodeAccountIndexOutOfBounds, rpcErr.Code)
+	rpcErr = call(t, ts, "create_address", map[string]any{"account_index": 1}, &created)
+	require.Equal(t, errCodeAccountIndexOutOfBounds, rpcErr.Code)
+	rpcErr = call(t, ts, "create_address", map[string]any{"count": 65}, &created)
+	require.Equal(t, errCodeUnknown, rpcErr.Code)
+}
+
+func TestServer_validateAddress(t *testing.T) {
+	ts, w := newTestServer(t)
+	keys, err := cryptonote.GenerateKeys()
+	require.NoError(t, err)
+
+	var resp validateAddressResponse
+	require.Nil(t, call(t, ts, "validate_address", map[string]any{"address": w.Address(0, 1).String()}, &resp))
+	require.Equal(t, validateAddressResponse{Valid: true, Subaddress: true, Nettype: "stagenet"}, resp)
+
+	mainnet := keys.PublicKeyPair().Address(cryptonote.Mainnet).String()
+	require.Nil(t, call(t, ts, "validate_address", map[string]any{"address": mainnet}, &resp))
+	require.Equal(t, validateAddressResponse{}, resp)
+	require.Nil(t, call(t, ts, "validate_address", map[string]any{"address": mainnet, "any_net_type": true}, &resp))
+	require.Equal(t, validateAddressResponse{Valid: true, Nettype: "mainnet"}, resp)
+
+	require.Nil(t, call(t, ts, "validate_address", map[string]any{"address": "4abc"}, &resp))
+	require.False(t, resp.Valid)
+}
+
+func TestServer_makeIntegratedAddress(t *testing.T) {
+	ts, w := newTestServer(t)
+
+	var resp makeIntegratedAddressResponse
+	require.Nil(t, call(t, ts, "make_integrated_address", map[string]any{"payment_id": "0102030405060708"}, &resp))
+	addr, err := cryptonote.NewAddress(resp.IntegratedAddress, cryptonote.Stagenet)
+	require.NoError(t, err)
+	require.Equal(t, "0102030405060708", addr.PaymentID().Hex())
+	require.Equal(t, w.Address(0, 0), addr.StandardAddress())
+	require.Equal(t, "0102030405060708", resp.PaymentID)
+
+	// a random payment ID is used if none is passed
+	require.Nil(t, call(t, ts, "make_integrated_address", map[string]any{}, &resp))
+	require.Len(t, resp.PaymentID, 16)
+
+	for _, test := range []struct {
+		params map[string]any
+		code   int
+	}{
+		{map[string]any{"payment_id": "01"}, errCodeWrongPaymentID},
+		{map[string]any{"standard_address": w.Address(0, 1).String()}, errCodeWrongAddress},
+		{map[string]any{"standard_address": resp.IntegratedAddress}, errCodeWrongAddress},
+		{map[string]any{"standard_address": "5abc"}, errCodeWrongAddress},
+	} {
+		rpcErr := call(t, ts, "make_integrated_address", test.params, &resp)
+		require.Equal(t, test.code, rpcErr.Code, test.params)
+	}
+}
+
+func TestServer_signVerify(t *testing.T) {
+	ts, w := newTestServer(t)
+
+	for _, sigType := range []string{signatureTypeSpend, signatureTypeView} {
+		var signed signResponse
+		params := map[string]any{"data": "hello", "address_index": 1, "signature_type": sigType}
+		require.Nil(t, call(t, ts, "sign", params, &signed))
+
+		var verified verifyResponse
+		params = map[string]any{"data": "hello", "address": w.Address(0, 1).String(), "signature": signed.Signature}
+		require.Nil(t, call(t, ts, "verify", params, &verified))
+		require.Equal(t, verifyResponse{Good: true, Version: 2, SignatureType: sigType}, verified)
+
+		params["address"] = w.Address(0, 0).String()
+		require.Nil(t, call(t, ts, "verify", params, &verified))
+		require.Equal(t, verifyResponse{}, verified)
+	}
+
+	var signed signResponse
+	rpcErr := call(t, ts, "sign", map[string]any{"data": "hello", "signature_type": "other"}, &signed)
+	require.Equal(t, errCodeUnknown, rpcErr.Code)
+	var verified verifyResponse
+	rpcErr = call(t, ts, "verify", map[string]any{"data": "hello", "address": "x", "signature": "SigV2"}, &verified)
+	require.Equal(t, errCodeWrongAddress, rpcErr.Code)
+}
+
+func TestServer_uri(t *testing.T) {
+	ts, w := newTestServer(t)
+	spec := &uriSpec{
+		Address:       w.Address(0, 1).String(),
+		Amount:        uint64(cryptonote.XMR + cryptonote.XMR/2),
+		RecipientName: "Alice Smith",
+		TxDescription: "coffee",
+	}
+
+	var made makeURIResponse
+	require.Nil(t, call(t, ts, "make_uri", spec, &made))
+	require.Equal(t, "monero:"+spec.Address+"?tx_amount=1.5&recipient_name=Alice%20Smith&tx_description=coffee", made.URI)
+
+	var parsed parseURIResponse
+	require.Nil(t, call(t, ts, "parse_uri", map[string]any{"uri": made.URI + "&label=x"}, &parsed))
+	require.Equal(t, spec, parsed.URI)
+	require.Equal(t, []string{"label=x"}, parsed.UnknownParameters)
+
+	rpcErr := call(t, ts, "make_uri", &uriSpec{Address: spec.Address, PaymentID: "xyz"}, &made)
+	require.Equal(t, errCodeWrongURI, rpcErr.Code)
+	rpcErr = call(t, ts, "make_uri", &uriSpec{Address: "x"}, &made)
+	require.Equal(t, errCodeWrongURI, rpcErr.Code)
+	rpcErr = call(t, ts, "parse_uri", map[string]any{"uri": "bitcoin:" + spec.Address}, &parsed)
+	require.Equal(t, errCodeWrongURI, rpcErr.Code)
+	rpcErr = call(t, ts, "parse_uri", map[string]any{"uri": "monero:" + spec.Address + ";" + spec.Address}, &parsed)
+	require.Equal(t, errCodeWrongURI, rpcErr.Code)
+}
+
+func TestServer_balance(t *testing.T) {
+	ts, w := newTestServer(t)
+	daemonServer := fakedaemon.New()
+	t.Cleanup(daemonServer.Close)
+	client, err := daemon.NewClient(daemonServer.URL)
+	require.NoError(t, err)
+
+	// mine a block to the wallet, whose reward unlocks at height 61
+	daemonServer.SetMiner(w.Keys().PublicKeyPair())
+	require.NoError(t, daemonServer.AddBlocks(1))
+	daemonServer.SetMiner(nil)
+	require.NoError(t, daemonServer.AddBlocks(10))
+	require.NoError(t, w.Refresh(context.Background(), client))
+
+	var balance getBalanceResponse
+	require.Nil(t, call(t, ts, "get_balance", map[string]any{}, &balance))
+	require.Equal(t, uint64(600_000_000_000), balance.Balance)
+	require.Zero(t, balance.UnlockedBalance)
+	require.Equal(t, uint64(49), balance.BlocksToUnlock)
+	require.Len(t, balance.PerSubaddress, 1)
+	require.Equal(t, &subaddressBalance{
+		Address:           w.Address(0, 0).String(),
+		Balance:           600_000_000_000,
+		Label:             wallet.PrimaryAccountLabel,
+		NumUnspentOutputs: 1,
+		BlocksToUnlock:    49,
+	}, balance.PerSubaddress[0])
+
+	require.Nil(t, call(t, ts, "get_balance", map[string]any{"address_indices": []uint32{1}}, &balance))
+	require.Equal(t, uint64(600_000_000_000), balance.Balance)
+	require.Empty(t, balance.PerSubaddress)
+	rpcErr := call(t, ts, "get_balance", map[string]any{"account_index": 1}, &balance)
+	require.Equal(t, errCodeAccountIndexOutOfBounds, rpcErr.Code)
+
+	var transfers incomingTransfersResponse
+	require.Nil(t, call(t, ts, "incoming_transfers", map[string]any{"transfer_type": "available"}, &transfers))
+	require.Len(t, transfers.Transfers, 1)
+	transfer := transfers.Transfers[0]
+	walletTransfer := w.Transfers()[0]
+	require.Equal(t, uint64(600_000_000_000), transfer.Amount)
+	require.Equal(t, uint64(1), transfer.BlockHeight)
+	require.Equal(t, uint64(1), transfer.GlobalIndex)
+	require.Equal(t, walletTransfer.KeyImage.Hex(), transfer.KeyImage)
+	require.Equal(t, walletTransfer.Key.Hex(), transfer.PubKey)
+	require.False(t, transfer.Unlocked)
+
+	var spent incomingTransfersResponse
+	require.Nil(t, call(t, ts, "incoming_transfers", map[string]any{"transfer_type": "unavailable"}, &spent))
+	require.Empty(t, spent.Transfers)
+	rpcErr = call(t, ts, "incoming_transfers", map[string]any{"transfer_type": "spent"}, &transfers)
+	require.Equal(t, errCodeTransferType, rpcErr.Code)
+
+	var height getHeightResponse
+	require.Nil(t, call(t, ts, "get_height", nil, &height))
+	require.Equal(t, daemonServer.Height(), height.Height)
+}
+
+func TestServer_protect(t *testing.T) {
+	keys, err := cryptonote.GenerateKeys()
+	require.NoError(t, err)
+	cfg := &config{rpcLogin: "user:pass", allowedOrigins: []string{"https://allowed.example"}}
+	ts := httptest.NewServer(newServer(wallet.New(keys, cryptonote.Stagenet, 0), cfg).handler())
+	t.Cleanup(ts.Close)
+
+	newRequest := func(contentType string, origin string) *http.Request {
+		body := `{"jsonrpc":"2.0","id":1,"method":"get_height"}`
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/json_rpc", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		return req
+	}
+	do := func(req *http.Request) *http.Response {
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp
+	}
+
+	// authorization is the Authorization header of the last request that
+	// answered a challenge
+	var authorization string
+	send := func(contentType string, origin string, password string) *http.Response {
+		resp := do(newRequest(contentType, origin))
+		if resp.StatusCode != http.StatusUnauthorized || password == "" {
+			return resp
+		}
+
+		challenge, err := digest.ParseChallenges(resp.Header.Values("WWW-Authenticate"))
+		require.NoError(t, err)
+		authorization = challenge.Authorization("user", password, http.MethodPost, "/json_rpc")
+		req := newRequest(contentType, origin)
+		req.Header.Set("Authorization", authorization)
+		return do(req)
+	}
+
+	require.Equal(t, http.StatusOK, send("application/json", "", "pass").StatusCode)
+
+	// a captured authorization can't be replayed
+	req := newRequest("application/json", "")
+	req.Header.Set("Authorization", authorization)
+	require.Equal(t, http.StatusUnauthorized, do(req).StatusCode)
+
+	require.Equal(t, http.StatusOK, send("application/json; charset=utf-8", "https://allowed.example", "pass").StatusCode)
+	require.Equal(t, http.StatusUnauthorized, send("application/json", "", "").StatusCode)
+	require.Equal(t, http.StatusUnauthorized, send("application/json", "", "wrong").StatusCode)
+	require.Equal(t, http.StatusUnsupportedMediaType, send("text/plain", "", "pass").StatusCode)
+	require.Equal(t, http.StatusForbidden, send("application/json", "https://evil.example", "pass").StatusCode)
+}
+
+func TestParseFlags(t *testing.T) {
+	cfg, err := parseFlags([]string{
+		"--seed-file", "seed.txt", "--stagenet", "--restore-height", "5", "--rpc-login", "user:pass",
+		"--rpc-access-control-origins", "https://a.example, https://b.example",
+	})
+	require.NoError(t, err)
+	require.Equal(t, cryptonote.Stagenet, cfg.net)
+	require.Equal(t, uint64(5), cfg.restoreHeight)
+	require.Equal(t, uint(defaultBindPort), cfg.bindPort)
+	require.Equal(t, "user:pass", cfg.rpcLogin)
+	require.Equal(t, []string{"https://a.example", "https://b.example"}, cfg.allowedOrigins)
+
+	cfg, err = parseFlags([]string{"--seed-file", "seed.txt", "--disable-rpc-login"})
+	require.NoError(t, err)
+	require.Empty(t, cfg.rpcLogin)
+
+	_, err = parseFlags([]string{"--seed-file", "seed.txt", "--disable-rpc-login", "--stagenet", "--testnet"})
+	require.ErrorIs(t, err, errNetworkFlags)
+	_, err = parseFlags([]string{"--seed-file", "seed.txt"})
+	require.ErrorIs(t, err, errNoRPCLogin)
+	_, err = parseFlags([]string{"--seed-file", "seed.txt", "--rpc-login", "user"})
+	require.ErrorIs(t, err, errRPCLogin)
+	_, err = parseFlags(nil)
+	require.ErrorIs(t, err, errNoSeedFile)
+}
+
+func TestLoadWallet(t *testing.T) {
+	seedFile := filepath.Join(t.TempDir(), "seed.txt")
+	seed := "filter vocal snow cupboard volume avoid sign slot drum replace shrug resist pear kiwi bag bring\n"
+	require.NoError(t, os.WriteFile(seedFile, []byte(seed), 0o600))
+
+	w, err := loadWallet(&config{seedFile: seedFile, net: cryptonote.Stagenet, restoreHeight: 7})
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), w.Height())
+	require.Equal(t, cryptonote.Stagenet, w.Network())
+
+	_, err = loadWallet(&config{seedFile: filepath.Join(t.TempDir(), "missing")})
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
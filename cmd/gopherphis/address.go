@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"math"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+)
+
+var (
+	errValidateArgs = errors.New("address validate takes exactly one address")
+	errIndexRange   = errors.New("--account and --index must be at most 4294967295")
+)
+
+type addressInfo struct {
+	Address string `json:"address"`
+	Account uint32 `json:"account"`
+	Index   uint32 `json:"index"`
+}
+
+// addressValidation is the result of validating an address. Invalid addresses
+// only set the error.
+type addressValidation struct {
+	Valid          bool   `json:"valid"`
+	Error          string `json:"error,omitempty"`
+	Network        string `json:"network,omitempty"`
+	Type           string `json:"type,omitempty"`
+	PaymentID      string `json:"paymentID,omitempty"`
+	PublicSpendKey string `json:"publicSpendKey,omitempty"`
+	PublicViewKey  string `json:"publicViewKey,omitempty"`
+}
+
+// writeAddress prints the address of the seed on stdin with the passed
+// account and index, which are zero for the primary address
+func writeAddress(stdin io.Reader, stdout io.Writer, seedOffset string, network string, account, index uint32) error {
+	net, err := parseNetwork(network)
+	if err != nil {
+		return err
+	}
+	keys, err := readKeys(stdin, seedOffset)
+	if err != nil {
+		return err
+	}
+	return writeJSON(stdout, &addressInfo{
+		Address: keys.SubAddrPubKeyPair(account, index).Address(net).String(),
+		Account: account,
+		Index:   index,
+	})
+}
+
+func addressPrimary(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := newFlagSet("address primary")
+	seedOffset := seedFlags(fs)
+	network := networkFlag(fs, cryptonote.Mainnet)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return writeAddress(stdin, stdout, *seedOffset, *network, 0, 0)
+}
+
+func addressSubaddress(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := newFlagSet("address subaddress")
+	seedOffset := seedFlags(fs)
+	network := networkFlag(fs, cryptonote.Mainnet)
+	account := fs.Uint("account", 0, "account index of the subaddress")
+	index := fs.Uint("index", 0, "index of the subaddress in its account")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *account > math.MaxUint32 || *index > math.MaxUint32 {
+		return errIndexRange
+	}
+	return writeAddress(stdin, stdout, *seedOffset, *network, uint32(*account), uint32(*index))
+}
+
+func addressValidate(args []string, _ io.Reader, stdout io.Writer) error {
+	fs := newFlagSet("address validate")
+	network := networkFlag(fs, "")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errValidateArgs
+	}
+
+	// without --network, addresses of any network are valid
+	var net cryptonote.Network
+	if *network != "" {
+		var err error
+		if net, err = parseNetwork(*network); err != nil {
+			return err
+		}
+	}
+
+	addr := new(cryptonote.Address)
+	err := addr.UnmarshalText([]byte(fs.Arg(0)))
+	if err == nil && net != "" {
+		err = addr.ValidateNet(net)
+	}
+	if err != nil {
+		return writeJSON(stdout, &addressValidation{Error: err.Error()})
+	}
+
+	pubKeys, err := addr.PublicKeyPair()
+	if err != nil {
+		return writeJSON(stdout, &addressValidation{Error: err.Error()})
+	}
+	result := &addressValidation{
+		Valid:          true,
+		Network:        string(addr.Network()),
+		Type:           string(addr.Type()),
+		PublicSpendKey: pubKeys.SpendKey().Hex(),
+		PublicViewKey:  pubKeys.ViewKey().Hex(),
+	}
+	if paymentID := addr.PaymentID(); paymentID != nil {
+		result.PaymentID = paymentID.Hex()
+	}
+	return writeJSON(stdout, result)
+}
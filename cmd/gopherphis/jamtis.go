@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+
+	"github.com/dimalinux/gopherphis/jamtis"
+)
+
+// jamtisAddressInfo holds the hex encoded parts of a jamtis address, as the
+// address doesn't have a string encoding yet
+type jamtisAddressInfo struct {
+	Index          uint64 `json:"index"`
+	SpendPublicKey string `json:"spendPublicKey"`
+	ViewPublicKey  string `json:"viewPublicKey"`
+	DHBaseKey      string `json:"dhBaseKey"`
+	AddressTag     string `json:"addressTag"`
+}
+
+// genJamtisAddress returns the jamtis address with the passed index, using
+// the private spend key of the legacy keys as the master key
+func genJamtisAddress(masterKey []byte, index uint64) (*jamtis.Address, error) {
+	keys, err := jamtis.DeriveKeys(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	// address indexes are little endian
+	var j [jamtis.AddressIndexLen]byte
+	binary.LittleEndian.PutUint64(j[:], index)
+	return keys.Address(j[:])
+}
+
+func jamtisAddress(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := newFlagSet("jamtis address")
+	seedOffset := seedFlags(fs)
+	index := fs.Uint64("index", 0, "index of the address")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	keys, err := readKeys(stdin, *seedOffset)
+	if err != nil {
+		return err
+	}
+	addr, err := genJamtisAddress(keys.SpendKey().Bytes(), *index)
+	if err != nil {
+		return err
+	}
+	return writeJSON(stdout, &jamtisAddressInfo{
+		Index:          *index,
+		SpendPublicKey: hex.EncodeToString(addr.K1),
+		ViewPublicKey:  hex.EncodeToString(addr.K2),
+		DHBaseKey:      hex.EncodeToString(addr.K3),
+		AddressTag:     hex.EncodeToString(addr.Tag),
+	})
+}
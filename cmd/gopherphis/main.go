@@ -0,0 +1,119 @@
+// Command gopherphis converts between Monero seeds, keys and addresses, and
+// prints its results as JSON for scripting. Seeds are read from standard
+// input, so that they don't end up in the shell history or the process list.
+// Run it without arguments for its usage.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+	"github.com/dimalinux/gopherphis/wallet"
+)
+
+const usage = `usage:
+  gopherphis seed new [--polyseed|--legacy] [--lang English]
+  gopherphis seed restore [--seed-offset PASSWORD] [--network mainnet]
+  gopherphis keys from-seed [--seed-offset PASSWORD] [--network mainnet]
+  gopherphis address primary [--seed-offset PASSWORD] [--network mainnet]
+  gopherphis address subaddress [--account N] [--index N] [--seed-offset PASSWORD] [--network mainnet]
+  gopherphis address validate [--network NETWORK] ADDRESS
+  gopherphis jamtis address [--index N] [--seed-offset PASSWORD]
+
+Seeds are read from standard input.`
+
+var (
+	errUsage     = errors.New(usage)
+	errEmptySeed = errors.New("no seed words were read from standard input")
+)
+
+// command runs a subcommand with the arguments that follow its name
+type command func(args []string, stdin io.Reader, stdout io.Writer) error
+
+var commands = map[string]command{
+	"seed new":           seedNew,
+	"seed restore":       seedRestore,
+	"keys from-seed":     keysFromSeed,
+	"address primary":    addressPrimary,
+	"address subaddress": addressSubaddress,
+	"address validate":   addressValidate,
+	"jamtis address":     jamtisAddress,
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	cmd, ok := commands[args[0]+" "+args[1]]
+	if !ok {
+		return fmt.Errorf("unknown command %q\n%w", args[0]+" "+args[1], errUsage)
+	}
+	return cmd(args[2:], stdin, stdout)
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("gopherphis "+name, flag.ContinueOnError)
+}
+
+// seedFlags adds the flags of the subcommands that read a seed
+func seedFlags(fs *flag.FlagSet) (seedOffset *string) {
+	return fs.String("seed-offset", "", "seed offset of a legacy seed, or passphrase of an encrypted polyseed")
+}
+
+func networkFlag(fs *flag.FlagSet, defaultNet cryptonote.Network) *string {
+	return fs.String("network", string(defaultNet), "network of the address: mainnet, stagenet or testnet")
+}
+
+func parseNetwork(net string) (cryptonote.Network, error) {
+	switch n := cryptonote.Network(net); n {
+	case cryptonote.Mainnet, cryptonote.Stagenet, cryptonote.Testnet:
+		return n, nil
+	default:
+		return "", fmt.Errorf("unknown network %q", net)
+	}
+}
+
+// readSeed returns the whitespace separated seed words of stdin
+func readSeed(stdin io.Reader) ([]string, error) {
+	input, err := io.ReadAll(stdin)
+	if err != nil {
+		return nil, err
+	}
+	words := strings.Fields(string(input))
+	if len(words) == 0 {
+		return nil, errEmptySeed
+	}
+	return words, nil
+}
+
+// readKeys returns the keys of the seed on stdin
+func readKeys(stdin io.Reader, seedOffset string) (*cryptonote.PrivateKeyPair, error) {
+	words, err := readSeed(stdin)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.KeysFromSeed(words, seedOffset)
+}
+
+func writeJSON(stdout io.Writer, v any) error {
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gopherphis:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+	"github.com/dimalinux/gopherphis/wallet"
+)
+
+// the polyseed package's test vector
+const testPolyseed = "filter vocal snow cupboard volume avoid sign slot drum replace shrug resist pear kiwi bag bring"
+
+// runJSON runs the command with the passed stdin and decodes its output into
+// result
+func runJSON(t *testing.T, stdin string, result any, args ...string) {
+	var stdout bytes.Buffer
+	require.NoError(t, run(args, strings.NewReader(stdin), &stdout))
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), result))
+}
+
+func TestRun_errors(t *testing.T) {
+	var stdout bytes.Buffer
+	require.ErrorIs(t, run(nil, nil, &stdout), errUsage)
+	require.ErrorIs(t, run([]string{"seed", "plant"}, nil, &stdout), errUsage)
+	require.ErrorIs(t, run([]string{"seed", "new", "--polyseed", "--legacy"}, nil, &stdout), errSeedTypeFlags)
+	require.Error(t, run([]string{"seed", "new", "--lang", "Klingon"}, nil, &stdout))
+	require.ErrorIs(t, run([]string{"keys", "from-seed"}, strings.NewReader(" \n"), &stdout), errEmptySeed)
+	require.Error(t, run([]string{"keys", "from-seed", "--network", "moonnet"}, strings.NewReader(testPolyseed), &stdout))
+	require.ErrorIs(t, run([]string{"address", "validate"}, nil, &stdout), errValidateArgs)
+	for _, flag := range []string{"--account", "--index"} {
+		args := []string{"address", "subaddress", flag, "4294967296"}
+		require.ErrorIs(t, run(args, strings.NewReader(testPolyseed), &stdout), errIndexRange)
+	}
+	require.Empty(t, stdout.String())
+}
+
+func TestSeedNew_polyseed(t *testing.T) {
+	var created seedInfo
+	runJSON(t, "", &created, "seed", "new", "--lang", "japanese")
+	require.Equal(t, seedTypePolyseed, created.Type)
+	require.Equal(t, "Japanese", created.Language)
+	require.NotZero(t, created.Birthday)
+
+	var restored seedInfo
+	runJSON(t, created.Seed, &restored, "seed", "restore", "--network", "stagenet")
+	require.Equal(t, created.Type, restored.Type)
+	require.Equal(t, created.Language, restored.Language)
+	require.Equal(t, created.Birthday, restored.Birthday)
+	require.False(t, restored.Encrypted)
+	require.Empty(t, restored.Seed)
+
+	keys, err := wallet.KeysFromSeed(strings.Fields(created.Seed), "")
+	require.NoError(t, err)
+	require.Equal(t, keys.PublicKeyPair().Address(cryptonote.Stagenet).String(), restored.Address)
+}
+
+func TestSeedNew_legacy(t *testing.T) {
+	var created seedInfo
+	runJSON(t, "", &created, "seed", "new", "--legacy", "--lang", "Deutsch")
+	require.Equal(t, seedTypeLegacy, created.Type)
+	require.Equal(t, "German", created.Language)
+	require.Len(t, strings.Fields(created.Seed), 25)
+	require.Zero(t, created.Birthday)
+
+	var restored seedInfo
+	runJSON(t, created.Seed, &restored, "seed", "restore")
+	require.Equal(t, seedInfo{Type: seedTypeLegacy, Language: "German", Address: restored.Address}, restored)
+	require.Equal(t, cryptonote.Mainnet, mustAddress(t, restored.Address).Network())
+}
+
+func TestKeysFromSeed(t *testing.T) {
+	keys, err := wallet.KeysFromSeed(strings.Fields(testPolyseed), "")
+	require.NoError(t, err)
+
+	var info keysInfo
+	runJSON(t, testPolyseed, &info, "keys", "from-seed")
+	require.Equal(t, keysInfo{
+		PrivateSpendKey: keys.SpendKey().Hex(),
+		PrivateViewKey:  keys.PrivateViewKey().Hex(),
+		PublicSpendKey:  keys.PublicKeyPair().SpendKey().Hex(),
+		PublicViewKey:   keys.PublicKeyPair().ViewKey().Hex(),
+		Address:         keys.PublicKeyPair().Address(cryptonote.Mainnet).String(),
+	}, info)
+
+	// the seed offset changes the keys of legacy seeds
+	legacy := strings.Repeat("abbey ", 25)
+	var plain, offset keysInfo
+	runJSON(t, legacy, &plain, "keys", "from-seed")
+	runJSON(t, legacy, &offset, "keys", "from-seed", "--seed-offset", "password")
+	require.NotEqual(t, plain.PrivateSpendKey, offset.PrivateSpendKey)
+}
+
+func TestAddress(t *testing.T) {
+	keys, err := wallet.KeysFromSeed(strings.Fields(testPolyseed), "")
+	require.NoError(t, err)
+
+	var primary addressInfo
+	runJSON(t, testPolyseed, &primary, "address", "primary", "--network", "testnet")
+	require.Equal(t, keys.PublicKeyPair().Address(cryptonote.Testnet).String(), primary.Address)
+
+	var sub addressInfo
+	runJSON(t, testPolyseed, &sub, "address", "subaddress", "--account", "2", "--index", "5")
+	require.Equal(t, addressInfo{
+		Address: keys.SubAddrPubKeyPair(2, 5).Address(cryptonote.Mainnet).String(),
+		Account: 2,
+		Index:   5,
+	}, sub)
+
+	var valid addressValidation
+	runJSON(t, "", &valid, "address", "validate", "--network", "mainnet", sub.Address)
+	require.Equal(t, addressValidation{
+		Valid:          true,
+		Network:        "mainnet",
+		Type:           "subaddress",
+		PublicSpendKey: keys.SubAddrPubKeyPair(2, 5).SpendKey().Hex(),
+		PublicViewKey:  keys.SubAddrPubKeyPair(2, 5).ViewKey().Hex(),
+	}, valid)
+
+	integrated, err := keys.PublicKeyPair().IntegratedAddress(cryptonote.Stagenet, cryptonote.PaymentID{1, 2, 3})
+	require.NoError(t, err)
+	var validIntegrated addressValidation
+	runJSON(t, "", &validIntegrated, "address", "validate", integrated.String())
+	require.True(t, validIntegrated.Valid)
+	require.Equal(t, "stagenet", validIntegrated.Network)
+	require.Equal(t, "integrated", validIntegrated.Type)
+	require.Equal(t, "0102030000000000", validIntegrated.PaymentID)
+
+	var mismatch addressValidation
+	runJSON(t, "", &mismatch, "address", "validate", "--network", "testnet", sub.Address)
+	require.False(t, mismatch.Valid)
+	require.NotEmpty(t, mismatch.Error)
+
+	var invalid addressValidation
+	runJSON(t, "", &invalid, "address", "validate", sub.Address[:len(sub.Address)-1]+"x")
+	require.Equal(t, addressValidation{Error: invalid.Error}, invalid)
+	require.NotEmpty(t, invalid.Error)
+}
+
+func TestGenJamtisAddress(t *testing.T) {
+	// the jamtis package's test vector, which uses address index 1
+	masterKey, err := hex.DecodeString("56dca2096a788c5b7c1f2bbd34a7ce1e25eb51c723121b9874d33d7c7fe1b407")
+	require.NoError(t, err)
+	addr, err := genJamtisAddress(masterKey, 1)
+	require.NoError(t, err)
+	require.Equal(t, "2a253091a8005d6ccb3326bb92b0b04eb3e6f0028abe1a66d242e2c5683efe47", hex.EncodeToString(addr.K1))
+	require.Equal(t, "dfd0a4f919cef620405ebbd27efe084b7bfb149a01365a13c14a2a3d0ffb4c75", hex.EncodeToString(addr.K2))
+	require.Equal(t, "5f5174d730d818f30de3a814c1148b488ed3addb2547f73f0e744ef3e7878246", hex.EncodeToString(addr.K3))
+	require.Equal(t, "a435a7bf8076247e6976d48f32e003adcaa9", hex.EncodeToString(addr.Tag))
+
+	var info jamtisAddressInfo
+	runJSON(t, testPolyseed, &info, "jamtis", "address", "--index", "7")
+	require.EqualValues(t, 7, info.Index)
+	require.Len(t, info.SpendPublicKey, 64)
+	require.Len(t, info.AddressTag, 36)
+
+	var other jamtisAddressInfo
+	runJSON(t, testPolyseed, &other, "jamtis", "address", "--index", "8")
+	require.NotEqual(t, info.SpendPublicKey, other.SpendPublicKey)
+}
+
+func mustAddress(t *testing.T, addr string) *cryptonote.Address {
+	a := new(cryptonote.Address)
+	require.NoError(t, a.UnmarshalText([]byte(addr)))
+	return a
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+	"github.com/dimalinux/gopherphis/mnemonic"
+	"github.com/dimalinux/gopherphis/polyseed"
+	"github.com/dimalinux/gopherphis/wallet"
+)
+
+const (
+	seedTypePolyseed = "polyseed"
+	seedTypeLegacy   = "legacy"
+	defaultLang      = "English"
+)
+
+var errSeedTypeFlags = errors.New("only one of --polyseed and --legacy can be set")
+
+// seedInfo describes a seed. The seed words are only set for new seeds.
+type seedInfo struct {
+	Type      string `json:"type"`
+	Language  string `json:"language"`
+	Seed      string `json:"seed,omitempty"`
+	Birthday  int64  `json:"birthday,omitempty"`
+	Encrypted bool   `json:"encrypted,omitempty"`
+	Address   string `json:"address,omitempty"`
+}
+
+type keysInfo struct {
+	PrivateSpendKey string `json:"privateSpendKey"`
+	PrivateViewKey  string `json:"privateViewKey"`
+	PublicSpendKey  string `json:"publicSpendKey"`
+	PublicViewKey   string `json:"publicViewKey"`
+	Address         string `json:"address"`
+}
+
+// findPolyseedLang returns the polyseed language with the passed native or
+// English name
+func findPolyseedLang(name string) (*polyseed.Lang, error) {
+	for _, l := range polyseed.Languages() {
+		if strings.EqualFold(name, l.Name) || strings.EqualFold(name, l.EnglishName) {
+			return l, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown polyseed language %q", name)
+}
+
+// findWordList returns the legacy word list with the passed native or English
+// name
+func findWordList(name string) (*mnemonic.WordList, error) {
+	for _, wl := range mnemonic.WordLists {
+		if strings.EqualFold(name, wl.Name) || strings.EqualFold(name, wl.EnglishName) {
+			return wl, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown legacy seed language %q", name)
+}
+
+func seedNew(args []string, _ io.Reader, stdout io.Writer) error {
+	fs := newFlagSet("seed new")
+	usePolyseed := fs.Bool("polyseed", false, "create a 16 word polyseed (the default)")
+	legacy := fs.Bool("legacy", false, "create a legacy 25 word seed")
+	lang := fs.String("lang", defaultLang, "language of the seed words")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *usePolyseed && *legacy {
+		return errSeedTypeFlags
+	}
+
+	if *legacy {
+		wl, err := findWordList(*lang)
+		if err != nil {
+			return err
+		}
+		keys, err := cryptonote.GenerateKeys()
+		if err != nil {
+			return err
+		}
+		return writeJSON(stdout, &seedInfo{
+			Type:     seedTypeLegacy,
+			Language: wl.EnglishName,
+			Seed:     strings.Join(wl.CreateSeedsFromKey(keys.SpendKey().Bytes()), " "),
+		})
+	}
+
+	l, err := findPolyseedLang(*lang)
+	if err != nil {
+		return err
+	}
+	words, err := polyseed.CreateNewSeedPhrase(l)
+	if err != nil {
+		return err
+	}
+	seed, err := polyseed.CreateSeedData(words)
+	if err != nil {
+		return err
+	}
+	defer seed.Clear()
+	return writeJSON(stdout, &seedInfo{
+		Type:     seedTypePolyseed,
+		Language: l.EnglishName,
+		Seed:     strings.Join(words, l.Separator),
+		Birthday: seed.BirthDate(),
+	})
+}
+
+// describeSeed returns the type, language and polyseed metadata of the seed
+func describeSeed(words []string) (*seedInfo, error) {
+	if len(words) != polyseed.NumSeedWords {
+		wl, err := mnemonic.FindLanguage(words)
+		if err != nil {
+			return nil, err
+		}
+		return &seedInfo{Type: seedTypeLegacy, Language: wl.EnglishName}, nil
+	}
+
+	l, err := polyseed.FindLanguage(words)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := polyseed.CreateSeedData(words)
+	if err != nil {
+		return nil, err
+	}
+	defer seed.Clear()
+	return &seedInfo{
+		Type:      seedTypePolyseed,
+		Language:  l.EnglishName,
+		Birthday:  seed.BirthDate(),
+		Encrypted: seed.IsEncrypted(),
+	}, nil
+}
+
+func seedRestore(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := newFlagSet("seed restore")
+	seedOffset := seedFlags(fs)
+	network := networkFlag(fs, cryptonote.Mainnet)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	net, err := parseNetwork(*network)
+	if err != nil {
+		return err
+	}
+
+	words, err := readSeed(stdin)
+	if err != nil {
+		return err
+	}
+	info, err := describeSeed(words)
+	if err != nil {
+		return err
+	}
+	keys, err := wallet.KeysFromSeed(words, *seedOffset)
+	if err != nil {
+		return err
+	}
+	info.Address = keys.PublicKeyPair().Address(net).String()
+	return writeJSON(stdout, info)
+}
+
+func keysFromSeed(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := newFlagSet("keys from-seed")
+	seedOffset := seedFlags(fs)
+	network := networkFlag(fs, cryptonote.Mainnet)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	net, err := parseNetwork(*network)
+	if err != nil {
+		return err
+	}
+
+	keys, err := readKeys(stdin, *seedOffset)
+	if err != nil {
+		return err
+	}
+	pubKeys := keys.PublicKeyPair()
+	return writeJSON(stdout, &keysInfo{
+		PrivateSpendKey: keys.SpendKey().Hex(),
+		PrivateViewKey:  keys.PrivateViewKey().Hex(),
+		PublicSpendKey:  pubKeys.SpendKey().Hex(),
+		PublicViewKey:   pubKeys.ViewKey().Hex(),
+		Address:         pubKeys.Address(net).String(),
+	})
+}
@@ -18,12 +18,14 @@ const (
 	Testnet  Network = "testnet"
 )
 
-// AddressType is the type of Monero address: Standard or Subaddress
+// AddressType is the type of Monero address: Standard, Integrated or
+// Subaddress
 type AddressType string
 
-// Monero address types. We don't support Integrated.
+// Monero address types
 const (
 	Standard   AddressType = "standard"
+	Integrated AddressType = "integrated"
 	Subaddress AddressType = "subaddress"
 )
 
@@ -32,29 +34,44 @@ const (
 // integrated, and subaddress).
 const (
 	netPrefixStdAddrMainnet  = 18
+	netPrefixIntAddrMainnet  = 19
 	netPrefixSubAddrMainnet  = 42
 	netPrefixStdAddrStagenet = 24
+	netPrefixIntAddrStagenet = 25
 	netPrefixSubAddrStagenet = 36
 	netPrefixStdAddrTestnet  = 53
+	netPrefixIntAddrTestnet  = 54
 	netPrefixSubAddrTestnet  = 63
 )
 
 var (
-	errAddressNotInitialized    = errors.New("monero address is not initialized")
-	errChecksumMismatch         = errors.New("invalid address checksum")
-	errInvalidAddressLength     = errors.New("invalid monero address length")
-	errInvalidAddressEncoding   = errors.New("invalid monero address encoding")
-	errInvalidPrefixGotMainnet  = errors.New("invalid monero address: expected stagenet, got mainnet")
-	errInvalidPrefixGotStagenet = errors.New("invalid monero address: expected mainnet, got stagenet")
-	errInvalidPrefixGotTestnet  = errors.New("invalid monero address: monero testnet not yet supported")
+	errAddressNotInitialized  = errors.New("monero address is not initialized")
+	errChecksumMismatch       = errors.New("invalid address checksum")
+	errInvalidAddressLength   = errors.New("invalid monero address length")
+	errInvalidAddressEncoding = errors.New("invalid monero address encoding")
+	errIntegratedSubaddress   = errors.New("integrated addresses cannot be created from subaddresses")
 )
 
+// NetworkMismatchError is returned when an address is validated against a
+// different network than the one it belongs to. Use errors.As to access the
+// expected and actual networks.
+type NetworkMismatchError struct {
+	Expected Network
+	Actual   Network
+}
+
+func (e *NetworkMismatchError) Error() string {
+	return fmt.Sprintf("invalid monero address: expected %s, got %s", e.Expected, e.Actual)
+}
+
 // Address represents a Monero address
 type Address struct {
-	// decoded is the bytes (prefix, pub spend key, pub view key, checksum) that
-	// get base58 encoded. Package private, as it is a semi-arbitrary
-	// implementation detail.
-	decoded [addressBytesLen]byte
+	// decoded is the bytes (prefix, pub spend key, pub view key, optional
+	// payment ID, checksum) that get base58 encoded. Package private, as it is
+	// a semi-arbitrary implementation detail. Standard addresses and
+	// subaddresses only use the first addressBytesLen bytes, the remaining
+	// bytes are left zeroed.
+	decoded [integratedAddrBytesLen]byte
 }
 
 // NewAddress converts a string to a monero Address with validation.
@@ -68,17 +85,36 @@ func NewAddress(addrStr string, net Network) (*Address, error) {
 }
 
 func (a *Address) String() string {
-	return addrBytesToBase58(a.decoded[:])
+	return addrBytesToBase58(a.bytes())
+}
+
+// bytes returns the used portion of the decoded bytes. The length is
+// determined by the network prefix, as only integrated addresses have a
+// payment ID.
+func (a *Address) bytes() []byte {
+	return a.decoded[:decodedLenForPrefix(a.decoded[0])]
+}
+
+// decodedLenForPrefix returns the length of the decoded address bytes for the
+// passed network prefix. Unknown prefixes are given the standard address
+// length.
+func decodedLenForPrefix(netPrefix byte) int {
+	switch netPrefix {
+	case netPrefixIntAddrMainnet, netPrefixIntAddrStagenet, netPrefixIntAddrTestnet:
+		return integratedAddrBytesLen
+	default:
+		return addressBytesLen
+	}
 }
 
 // Network returns the Monero network of the address
 func (a *Address) Network() Network {
 	switch a.decoded[0] {
-	case netPrefixStdAddrMainnet, netPrefixSubAddrMainnet:
+	case netPrefixStdAddrMainnet, netPrefixIntAddrMainnet, netPrefixSubAddrMainnet:
 		return Mainnet
-	case netPrefixStdAddrStagenet, netPrefixSubAddrStagenet:
+	case netPrefixStdAddrStagenet, netPrefixIntAddrStagenet, netPrefixSubAddrStagenet:
 		return Stagenet
-	case netPrefixStdAddrTestnet, netPrefixSubAddrTestnet:
+	case netPrefixStdAddrTestnet, netPrefixIntAddrTestnet, netPrefixSubAddrTestnet:
 		return Testnet
 	default:
 		// Our methods to deserialize and create Address values all verify
@@ -92,6 +128,8 @@ func (a *Address) Type() AddressType {
 	switch a.decoded[0] {
 	case netPrefixStdAddrMainnet, netPrefixStdAddrStagenet, netPrefixStdAddrTestnet:
 		return Standard
+	case netPrefixIntAddrMainnet, netPrefixIntAddrStagenet, netPrefixIntAddrTestnet:
+		return Integrated
 	case netPrefixSubAddrTestnet, netPrefixSubAddrStagenet, netPrefixSubAddrMainnet:
 		return Subaddress
 	default:
@@ -105,16 +143,18 @@ func (a *Address) Type() AddressType {
 // are valid. The Network() and Type() methods are not safe to use until
 // this base level validation is performed.
 func (a *Address) validateDecoded() error {
-	checksum := getChecksum(a.decoded[:65])
-	if !bytes.Equal(checksum[:], a.decoded[65:69]) {
+	decoded := a.bytes()
+	checksumStart := len(decoded) - checksumLen
+	checksum := getChecksum(decoded[:checksumStart])
+	if !bytes.Equal(checksum[:], decoded[checksumStart:]) {
 		return errChecksumMismatch
 	}
 
 	netPrefix := a.decoded[0]
 	switch netPrefix {
-	case netPrefixStdAddrMainnet, netPrefixSubAddrMainnet,
-		netPrefixStdAddrStagenet, netPrefixSubAddrStagenet,
-		netPrefixStdAddrTestnet, netPrefixSubAddrTestnet:
+	case netPrefixStdAddrMainnet, netPrefixIntAddrMainnet, netPrefixSubAddrMainnet,
+		netPrefixStdAddrStagenet, netPrefixIntAddrStagenet, netPrefixSubAddrStagenet,
+		netPrefixStdAddrTestnet, netPrefixIntAddrTestnet, netPrefixSubAddrTestnet:
 		// we are good, do nothing
 	default:
 		return fmt.Errorf("monero address has unknown network prefix %d", netPrefix)
@@ -131,29 +171,73 @@ func (a *Address) Equal(b *Address) bool {
 	return a.decoded == b.decoded
 }
 
-// ValidateNet validates that the monero network matches the passed network.
-// This validation can't be performed when decoding JSON, as the environment is
-// not known at that time.
-func (a *Address) ValidateNet(net Network) error {
-	if a == nil || a.decoded == new(Address).decoded {
-		return errAddressNotInitialized
+// PaymentID returns the 8-byte payment ID of an integrated address. Nil is
+// returned for standard addresses and subaddresses.
+func (a *Address) PaymentID() *PaymentID {
+	if a.Type() != Integrated {
+		return nil
+	}
+	paymentID := new(PaymentID)
+	copy(paymentID[:], a.decoded[65:65+PaymentIDLen])
+	return paymentID
+}
+
+// StandardAddress returns the standard address that an integrated address
+// was created from by removing the payment ID. Standard addresses and
+// subaddresses are returned unchanged.
+func (a *Address) StandardAddress() *Address {
+	if a.Type() != Integrated {
+		return a
 	}
 
+	var prefix byte
 	switch a.Network() {
 	case Mainnet:
-		if net != Mainnet {
-			return errInvalidPrefixGotMainnet
-		}
+		prefix = netPrefixStdAddrMainnet
 	case Stagenet:
-		if net != Stagenet {
-			return errInvalidPrefixGotStagenet
-		}
+		prefix = netPrefixStdAddrStagenet
 	case Testnet:
-		return errInvalidPrefixGotTestnet
+		prefix = netPrefixStdAddrTestnet
 	default:
 		panic("unhandled network")
 	}
 
+	return newAddress(prefix, a.decoded[1:33], a.decoded[33:65], nil)
+}
+
+// PublicKeyPair returns the public spend and view keys of the address. An
+// error is only possible if the address holds bytes that are not valid curve
+// points.
+func (a *Address) PublicKeyPair() (*PublicKeyPair, error) {
+	sk, err := NewPublicKey(a.decoded[1:33])
+	if err != nil {
+		return nil, fmt.Errorf("invalid address spend key: %w", err)
+	}
+	vk, err := NewPublicKey(a.decoded[33:65])
+	if err != nil {
+		return nil, fmt.Errorf("invalid address view key: %w", err)
+	}
+
+	return &PublicKeyPair{
+		isSubAddress: a.Type() == Subaddress,
+		sk:           sk,
+		vk:           vk,
+	}, nil
+}
+
+// ValidateNet validates that the monero network matches the passed network.
+// This validation can't be performed when decoding JSON, as the environment is
+// not known at that time. On a mismatch, the returned error is a
+// *NetworkMismatchError.
+func (a *Address) ValidateNet(net Network) error {
+	if a == nil || a.decoded == new(Address).decoded {
+		return errAddressNotInitialized
+	}
+
+	if addrNet := a.Network(); addrNet != net {
+		return &NetworkMismatchError{Expected: net, Actual: addrNet}
+	}
+
 	return nil
 }
 
@@ -165,8 +249,6 @@ func getChecksum(data ...[]byte) (result [4]byte) {
 
 // Address returns the address as bytes for a PublicKeyPair with the given environment (ie. mainnet or stagenet)
 func (kp *PublicKeyPair) Address(net Network) *Address {
-	address := new(Address)
-
 	var prefix byte
 	switch {
 	case net == Mainnet && !kp.isSubAddress:
@@ -185,14 +267,47 @@ func (kp *PublicKeyPair) Address(net Network) *Address {
 		panic(fmt.Sprintf("unhandled net %s", net))
 	}
 
+	return newAddress(prefix, kp.sk.Bytes(), kp.vk.Bytes(), nil)
+}
+
+// IntegratedAddress returns the integrated address, combining the standard
+// address of the PublicKeyPair with the passed payment ID, for the given
+// environment. Subaddresses do not have integrated addresses.
+func (kp *PublicKeyPair) IntegratedAddress(net Network, paymentID PaymentID) (*Address, error) {
+	if kp.isSubAddress {
+		return nil, errIntegratedSubaddress
+	}
+
+	var prefix byte
+	switch net {
+	case Mainnet:
+		prefix = netPrefixIntAddrMainnet
+	case Stagenet:
+		prefix = netPrefixIntAddrStagenet
+	case Testnet:
+		prefix = netPrefixIntAddrTestnet
+	default:
+		panic(fmt.Sprintf("unhandled net %s", net))
+	}
+
+	return newAddress(prefix, kp.sk.Bytes(), kp.vk.Bytes(), paymentID[:]), nil
+}
+
+// newAddress assembles an address from its parts and computes the checksum.
+// The paymentID is nil, except when creating integrated addresses.
+func newAddress(prefix byte, spendKey []byte, viewKey []byte, paymentID []byte) *Address {
+	address := new(Address)
+
 	// address encoding is:
 	// (network_prefix) + (32-byte public spend key) + (32-byte-byte public view key)
-	// + first_4_Bytes(Hash(network_prefix + (32-byte public spend key) + (32-byte public view key)))
-	address.decoded[0] = prefix                 // 1-byte network prefix
-	copy(address.decoded[1:33], kp.sk.Bytes())  // 32-byte public spend key
-	copy(address.decoded[33:65], kp.vk.Bytes()) // 32-byte public view key
-	checksum := getChecksum(address.decoded[0:65])
-	copy(address.decoded[65:69], checksum[:])
+	// + [8-byte payment ID, integrated addresses only]
+	// + first_4_Bytes(Hash(all_previous_bytes))
+	address.decoded[0] = prefix                     // 1-byte network prefix
+	copy(address.decoded[1:33], spendKey)           // 32-byte public spend key
+	copy(address.decoded[33:65], viewKey)           // 32-byte public view key
+	n := 65 + copy(address.decoded[65:], paymentID) // 8-byte payment ID or nothing
+	checksum := getChecksum(address.decoded[0:n])
+	copy(address.decoded[n:n+checksumLen], checksum[:])
 
 	return address
 }
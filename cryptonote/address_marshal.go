@@ -5,13 +5,14 @@ func (a *Address) MarshalText() ([]byte, error) {
 	if err := a.validateDecoded(); err != nil {
 		return nil, err
 	}
-	return []byte(addrBytesToBase58(a.decoded[:])), nil
+	return []byte(addrBytesToBase58(a.bytes())), nil
 }
 
-// UnmarshalText converts a base58 encoded monero address to our Address type.
-// The encoding, length and checksum are all validated, but not the network, as
-// it is unknown by the JSON parser. Empty strings are not allowed. Use an
-// address pointer in your serialized types if the Address is optional.
+// UnmarshalText converts a base58 encoded monero address, including integrated
+// addresses, to our Address type. The encoding, length and checksum are all
+// validated, but not the network, as it is unknown by the JSON parser. Empty
+// strings are not allowed. Use an address pointer in your serialized types if
+// the Address is optional.
 func (a *Address) UnmarshalText(base58Input []byte) error {
 	base58Str := string(base58Input)
 	addrBytes, err := addrBase58ToBytes(base58Str)
@@ -20,10 +21,12 @@ func (a *Address) UnmarshalText(base58Input []byte) error {
 	}
 
 	newAddr := new(Address)
-	n := copy(newAddr.decoded[:], addrBytes)
-	if n != addressBytesLen {
-		// addrBase58ToBytes already verified the decoded length
-		panic("bytes to address conversion is broken")
+	copy(newAddr.decoded[:], addrBytes)
+
+	// Standard addresses and subaddresses can't be integrated address
+	// length, and vice-versa.
+	if len(addrBytes) != decodedLenForPrefix(newAddr.decoded[0]) {
+		return errInvalidAddressLength
 	}
 
 	if err := newAddr.validateDecoded(); err != nil {
@@ -78,6 +78,30 @@ func TestAddress_UnmarshalText_integratedAddress(t *testing.T) {
 	const integratedAddress = "4BxSHvcgTwu25WooY4BVmgdcKwZu5EksVZSZkDd6ooxSVVqQ4ubxXkhLF6hEqtw96i9cf3cVfLw8UWe95bdDKfRQeYtPwLm1Jiw7AKt2LY" //nolint:lll
 	address := new(Address)
 	err := address.UnmarshalText([]byte(integratedAddress))
+	require.NoError(t, err)
+	require.Equal(t, Mainnet, address.Network())
+	require.Equal(t, Integrated, address.Type())
+	require.NotNil(t, address.PaymentID())
+	require.Equal(t, integratedAddress, address.String())
+
+	data, err := address.MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, integratedAddress, string(data))
+}
+
+func TestAddress_UnmarshalText_integratedLengthMismatch(t *testing.T) {
+	keys, err := GenerateKeys()
+	require.NoError(t, err)
+
+	// Give a standard address the length of an integrated address by adding
+	// a payment ID, while leaving the standard address network prefix.
+	address, err := keys.PublicKeyPair().IntegratedAddress(Mainnet, PaymentID{1, 2, 3, 4, 5, 6, 7, 8})
+	require.NoError(t, err)
+	address.decoded[0] = netPrefixStdAddrMainnet
+	checksum := getChecksum(address.decoded[0:73])
+	copy(address.decoded[73:77], checksum[:])
+	badLengthAddr := addrBytesToBase58(address.decoded[:])
+
+	err = address.UnmarshalText([]byte(badLengthAddr))
 	require.ErrorIs(t, err, errInvalidAddressLength)
-	require.ErrorContains(t, err, "integrated addresses not supported")
 }
@@ -19,6 +19,14 @@ func TestNewAddress(t *testing.T) {
 	require.Equal(t, addrStr, addr.String())
 }
 
+func TestNewAddress_allNetworks(t *testing.T) {
+	for _, tt := range addressEncodingTests {
+		addr, err := NewAddress(tt.address, tt.network)
+		require.NoError(t, err, tt.name)
+		require.Equal(t, tt.address, addr.String(), tt.name)
+	}
+}
+
 func TestNewAddress_fail(t *testing.T) {
 	_, err := NewAddress("fake", Mainnet)
 	require.ErrorIs(t, err, errInvalidAddressLength)
@@ -28,21 +36,32 @@ func TestValidateAddress(t *testing.T) {
 	kp, err := GenerateKeys()
 	require.NoError(t, err)
 	pubKeys := kp.PublicKeyPair()
+	networks := []Network{Mainnet, Stagenet, Testnet}
+
+	for _, addrNet := range networks {
+		addr := pubKeys.Address(addrNet)
+		for _, expectedNet := range networks {
+			err := addr.ValidateNet(expectedNet)
+			if addrNet == expectedNet {
+				require.NoError(t, err)
+				continue
+			}
 
-	// mainnet address checks
-	addr := pubKeys.Address(Mainnet)
-	require.NoError(t, addr.ValidateNet(Mainnet))
-	require.ErrorIs(t, addr.ValidateNet(Stagenet), errInvalidPrefixGotMainnet)
-
-	// stagenet address checks
-	addr = pubKeys.Address(Stagenet)
-	require.NoError(t, addr.ValidateNet(Stagenet))
-	require.ErrorIs(t, addr.ValidateNet(Mainnet), errInvalidPrefixGotStagenet)
+			var netErr *NetworkMismatchError
+			require.ErrorAs(t, err, &netErr)
+			require.Equal(t, expectedNet, netErr.Expected)
+			require.Equal(t, addrNet, netErr.Actual)
+			require.EqualError(t, err,
+				fmt.Sprintf("invalid monero address: expected %s, got %s", expectedNet, addrNet))
+		}
+	}
 
 	// testnet address check
 	const testnetAddress = "9ujeXrjzf7bfeK3KZdCqnYaMwZVFuXemPU8Ubw335rj2FN1CdMiWNyFV3ksEfMFvRp9L9qum5UxkP5rN9aLcPxbH1au4WAB" //nolint:lll
-	require.NoError(t, addr.UnmarshalText([]byte(testnetAddress)))
-	require.ErrorIs(t, addr.ValidateNet(Mainnet), errInvalidPrefixGotTestnet)
+	addr, err := NewAddress(testnetAddress, Testnet)
+	require.NoError(t, err)
+	require.Equal(t, testnetAddress, addr.String())
+	require.EqualError(t, addr.ValidateNet(Mainnet), "invalid monero address: expected mainnet, got testnet")
 
 	// uninitialized address validation
 	addr = new(Address) // empty
@@ -51,14 +70,16 @@ func TestValidateAddress(t *testing.T) {
 
 func TestValidateAddress_loop(t *testing.T) {
 	// Tests our address encoding/decoding with randomised data
+	networks := []Network{Mainnet, Stagenet, Testnet}
 	for i := 0; i < 1000; i++ {
 		kp, err := GenerateKeys() // create random key
 		require.NoError(t, err)
 		// Generate the address, convert it to its base58 string form,
 		// then convert the base58 form back into a new address, then
 		// verify that the bytes of the 2 addresses are identical.
-		addr1 := kp.PublicKeyPair().Address(Mainnet)
-		addr2, err := NewAddress(addr1.String(), Mainnet)
+		net := networks[i%len(networks)]
+		addr1 := kp.PublicKeyPair().Address(net)
+		addr2, err := NewAddress(addr1.String(), net)
 		require.NoError(t, err)
 		require.Equal(t, addr1.String(), addr2.String())
 	}
@@ -159,3 +180,64 @@ func TestWordList_CreateKeysAndAddressesFromSeeds(t *testing.T) {
 		}
 	}
 }
+
+func TestPublicKeyPair_IntegratedAddress(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	pubKeys := kp.PublicKeyPair()
+
+	paymentID, err := NewPaymentID("0123456789abcdef")
+	require.NoError(t, err)
+
+	for _, net := range []Network{Mainnet, Stagenet, Testnet} {
+		addr, err := pubKeys.IntegratedAddress(net, paymentID)
+		require.NoError(t, err)
+		require.Len(t, addr.String(), encodedIntegratedAddrLen)
+		require.Equal(t, net, addr.Network())
+		require.Equal(t, Integrated, addr.Type())
+		require.Equal(t, paymentID, *addr.PaymentID())
+
+		// the standard address is recovered by removing the payment ID
+		require.True(t, pubKeys.Address(net).Equal(addr.StandardAddress()))
+		require.False(t, addr.Equal(addr.StandardAddress()))
+
+		// round trip through the string form
+		addr2, err := NewAddress(addr.String(), net)
+		require.NoError(t, err)
+		require.True(t, addr.Equal(addr2))
+	}
+}
+
+func TestPublicKeyPair_IntegratedAddress_subaddress(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+
+	_, err = kp.SubAddrPubKeyPair(0, 1).IntegratedAddress(Mainnet, PaymentID{})
+	require.ErrorIs(t, err, errIntegratedSubaddress)
+}
+
+func TestAddress_PaymentID_nonIntegrated(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+
+	addr := kp.PublicKeyPair().Address(Mainnet)
+	require.Nil(t, addr.PaymentID())
+	require.True(t, addr == addr.StandardAddress())
+
+	subAddr := kp.SubAddrPubKeyPair(1, 1).Address(Mainnet)
+	require.Nil(t, subAddr.PaymentID())
+	require.True(t, subAddr == subAddr.StandardAddress())
+}
+
+func TestNewPaymentID(t *testing.T) {
+	paymentID, err := NewPaymentID("0123456789abcdef")
+	require.NoError(t, err)
+	require.Equal(t, PaymentID{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}, paymentID)
+	require.Equal(t, "0123456789abcdef", paymentID.String())
+
+	_, err = NewPaymentID("0123456789abcdef00") // too long
+	require.ErrorIs(t, err, errInvalidPaymentID)
+
+	_, err = NewPaymentID("0123456789abcdeg") // not hex
+	require.ErrorIs(t, err, errInvalidPaymentID)
+}
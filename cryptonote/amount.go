@@ -0,0 +1,184 @@
+package cryptonote
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// AmountDecimals is the number of decimal places of an XMR amount. One XMR is
+// 10^12 piconero, the atomic unit used on-chain.
+const AmountDecimals = 12
+
+// Common amounts
+const (
+	Piconero Amount = 1
+	XMR      Amount = 1_000_000_000_000
+)
+
+var (
+	errInvalidAmount     = errors.New("invalid XMR amount")
+	errAmountTooLarge    = errors.New("XMR amount exceeds 64 bits of piconero")
+	errAmountUnderflow   = errors.New("XMR amount subtraction underflow")
+	errZeroTxWeight      = errors.New("transaction weight is zero")
+	errZeroFeeQuantizer  = errors.New("fee quantization mask is zero")
+	errFeeWeightOverflow = errors.New("fee for transaction weight exceeds 64 bits of piconero")
+)
+
+// Amount is an amount of XMR in piconero. Its text form, used by String and
+// by JSON and text marshalling, is the exact decimal amount in XMR, like
+// "1.5". Monero's RPC interfaces use integer piconero amounts, which can be
+// converted directly, as in Amount(n).
+type Amount uint64
+
+// ParseAmount converts a decimal XMR amount, like "1.5", into an Amount. It
+// is exact, with no floating point rounding. Like Monero's parse_amount,
+// digits after the 12th decimal place are only allowed if they are zero.
+func ParseAmount(s string) (Amount, error) {
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return 0, errInvalidAmount
+	}
+	if !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, errInvalidAmount
+	}
+
+	if len(fracPart) > AmountDecimals {
+		if strings.Trim(fracPart[AmountDecimals:], "0") != "" {
+			return 0, errInvalidAmount
+		}
+		fracPart = fracPart[:AmountDecimals]
+	}
+	fracPart += strings.Repeat("0", AmountDecimals-len(fracPart))
+
+	var whole uint64
+	if intPart != "" {
+		var err error
+		whole, err = strconv.ParseUint(intPart, 10, 64)
+		if err != nil || whole > math.MaxUint64/uint64(XMR) {
+			return 0, errAmountTooLarge
+		}
+	}
+	frac, err := strconv.ParseUint(fracPart, 10, 64)
+	if err != nil {
+		return 0, errInvalidAmount
+	}
+
+	return Amount(whole * uint64(XMR)).Add(Amount(frac))
+}
+
+// isDigits returns true if s only holds the ASCII digits 0-9. The empty string
+// returns true.
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the amount as a decimal XMR string with no trailing zeros
+// after the decimal point, like "1.5" or "2".
+func (a Amount) String() string {
+	whole := strconv.FormatUint(uint64(a/XMR), 10)
+	frac := uint64(a % XMR)
+	if frac == 0 {
+		return whole
+	}
+
+	fracStr := strconv.FormatUint(frac, 10)
+	fracStr = strings.Repeat("0", AmountDecimals-len(fracStr)) + fracStr
+	return whole + "." + strings.TrimRight(fracStr, "0")
+}
+
+// Add returns a + b, or an error if the sum overflows.
+func (a Amount) Add(b Amount) (Amount, error) {
+	sum, carry := bits.Add64(uint64(a), uint64(b), 0)
+	if carry != 0 {
+		return 0, errAmountTooLarge
+	}
+	return Amount(sum), nil
+}
+
+// Sub returns a - b, or an error if b is larger than a.
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if b > a {
+		return 0, errAmountUnderflow
+	}
+	return a - b, nil
+}
+
+// Mul returns a * n, or an error if the product overflows.
+func (a Amount) Mul(n uint64) (Amount, error) {
+	hi, lo := bits.Mul64(uint64(a), n)
+	if hi != 0 {
+		return 0, errAmountTooLarge
+	}
+	return Amount(lo), nil
+}
+
+// SumAmounts returns the sum of the passed amounts, or an error if the sum
+// overflows.
+func SumAmounts(amounts ...Amount) (Amount, error) {
+	var sum Amount
+	for _, a := range amounts {
+		var err error
+		if sum, err = sum.Add(a); err != nil {
+			return 0, err
+		}
+	}
+	return sum, nil
+}
+
+// MarshalText serializes the amount as a decimal XMR string
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText converts a decimal XMR string to our Amount type, with the
+// same validation as ParseAmount.
+func (a *Amount) UnmarshalText(text []byte) error {
+	amount, err := ParseAmount(string(text))
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
+
+// FeeForWeight returns the fee of a transaction with the passed weight, in
+// bytes, at a fee of feePerByte. Like wallet2's calculate_fee_from_weight, the
+// fee is rounded up to a multiple of the quantization mask returned by the
+// daemon's get_fee_estimate RPC method.
+func FeeForWeight(feePerByte Amount, weight uint64, quantizationMask uint64) (Amount, error) {
+	if quantizationMask == 0 {
+		return 0, errZeroFeeQuantizer
+	}
+
+	fee, err := feePerByte.Mul(weight)
+	if err != nil {
+		return 0, errFeeWeightOverflow
+	}
+
+	remainder := uint64(fee) % quantizationMask
+	if remainder == 0 {
+		return fee, nil
+	}
+	fee, err = fee.Add(Amount(quantizationMask - remainder))
+	if err != nil {
+		return 0, errFeeWeightOverflow
+	}
+	return fee, nil
+}
+
+// FeePerByte returns the fee per byte paid by a transaction with the passed
+// fee and weight, rounded down.
+func FeePerByte(fee Amount, weight uint64) (Amount, error) {
+	if weight == 0 {
+		return 0, errZeroTxWeight
+	}
+	return fee / Amount(weight), nil
+}
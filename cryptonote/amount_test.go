@@ -0,0 +1,122 @@
+package cryptonote
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		str    string
+		amount Amount
+	}{
+		{"0", 0},
+		{"1", XMR},
+		{"1.5", 1_500_000_000_000},
+		{".5", 500_000_000_000},
+		{"2.", 2 * XMR},
+		{"0.000000000001", Piconero},
+		{"0.0000000000010000", Piconero},
+		{"18446744.073709551615", math.MaxUint64},
+	}
+	for _, tt := range tests {
+		amount, err := ParseAmount(tt.str)
+		require.NoError(t, err, tt.str)
+		require.Equal(t, tt.amount, amount, tt.str)
+	}
+}
+
+func TestParseAmount_fail(t *testing.T) {
+	for _, str := range []string{"", ".", "-1", "+1", " 1", "1.2.3", "1e3", "0.0000000000001"} {
+		_, err := ParseAmount(str)
+		require.ErrorIs(t, err, errInvalidAmount, str)
+	}
+	for _, str := range []string{"18446744.073709551616", "18446745", "99999999999999999999"} {
+		_, err := ParseAmount(str)
+		require.ErrorIs(t, err, errAmountTooLarge, str)
+	}
+}
+
+func TestAmount_String(t *testing.T) {
+	require.Equal(t, "0", Amount(0).String())
+	require.Equal(t, "0.000000000001", Piconero.String())
+	require.Equal(t, "1.5", Amount(1_500_000_000_000).String())
+	require.Equal(t, "2", (2 * XMR).String())
+	require.Equal(t, "18446744.073709551615", Amount(math.MaxUint64).String())
+}
+
+func TestAmount_arithmetic(t *testing.T) {
+	sum, err := XMR.Add(Piconero)
+	require.NoError(t, err)
+	require.Equal(t, "1.000000000001", sum.String())
+
+	_, err = Amount(math.MaxUint64).Add(Piconero)
+	require.ErrorIs(t, err, errAmountTooLarge)
+
+	diff, err := sum.Sub(XMR)
+	require.NoError(t, err)
+	require.Equal(t, Piconero, diff)
+
+	_, err = Piconero.Sub(XMR)
+	require.ErrorIs(t, err, errAmountUnderflow)
+
+	product, err := XMR.Mul(3)
+	require.NoError(t, err)
+	require.Equal(t, 3*XMR, product)
+
+	_, err = XMR.Mul(math.MaxUint64 / 1000)
+	require.ErrorIs(t, err, errAmountTooLarge)
+
+	total, err := SumAmounts(XMR, 2*XMR, Piconero)
+	require.NoError(t, err)
+	require.Equal(t, 3*XMR+Piconero, total)
+
+	_, err = SumAmounts(XMR, math.MaxUint64)
+	require.ErrorIs(t, err, errAmountTooLarge)
+}
+
+func TestAmount_MarshalText_roundTrip(t *testing.T) {
+	type MyStruct struct {
+		Amount Amount  `json:"amount"`
+		Fee    *Amount `json:"fee,omitempty"`
+	}
+
+	s1 := &MyStruct{Amount: 1_250_000_000_000}
+	data, err := json.Marshal(s1)
+	require.NoError(t, err)
+	require.Equal(t, `{"amount":"1.25"}`, string(data))
+
+	s2 := new(MyStruct)
+	require.NoError(t, json.Unmarshal(data, s2))
+	require.Equal(t, s1, s2)
+
+	err = json.Unmarshal([]byte(`{"amount":"1.5x"}`), s2)
+	require.ErrorIs(t, err, errInvalidAmount)
+}
+
+func TestFeeForWeight(t *testing.T) {
+	// 20 piconero/byte for a 1500 byte tx, quantized to 10000 piconero
+	fee, err := FeeForWeight(20, 1500, 10000)
+	require.NoError(t, err)
+	require.Equal(t, Amount(30000), fee)
+
+	fee, err = FeeForWeight(20, 1501, 10000)
+	require.NoError(t, err)
+	require.Equal(t, Amount(40000), fee)
+
+	_, err = FeeForWeight(20, 1500, 0)
+	require.ErrorIs(t, err, errZeroFeeQuantizer)
+
+	_, err = FeeForWeight(XMR, math.MaxUint64, 1)
+	require.ErrorIs(t, err, errFeeWeightOverflow)
+
+	perByte, err := FeePerByte(40000, 1501)
+	require.NoError(t, err)
+	require.Equal(t, Amount(26), perByte)
+
+	_, err = FeePerByte(40000, 0)
+	require.ErrorIs(t, err, errZeroTxWeight)
+}
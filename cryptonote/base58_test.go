@@ -5,10 +5,12 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/base58"
 )
 
-// Test addresses were taken from here with the integrated addresses, which we do not want
-// to support, removed:
+// Test addresses were taken from here with the integrated addresses removed, as
+// they are tested separately:
 // https://github.com/monero-project/monero/blob/v0.18.1.0/tests/functional_tests/validate_address.py#L68-L71
 // Hex values were computed here:
 // https://xmr.llcoins.net/addresstests.html
@@ -130,3 +132,14 @@ func TestMoneroAddrBase58ToBytes_BadEncoding(t *testing.T) {
 	_, err = addrBase58ToBytes(lastBlockBad)
 	require.ErrorIs(t, err, errInvalidAddressEncoding)
 }
+
+func TestMoneroAddrBase58ToBytes_Overflow(t *testing.T) {
+	// The first block is replaced with an 11 symbol sequence whose value does
+	// not fit in 8 bytes. Monero wallets reject these non-canonical encodings.
+	validAddr := "42ey1afDFnn4886T7196doS9GPMzexD9gXpsZJDwVjeRVdFCSoHnv7KPbBeGpzJBzHRCAs9UxqeoyFQMYbqSWYTfJJQAWDm"
+	overflowAddr := "zzzzzzzzzzz" + validAddr[11:]
+
+	_, err := addrBase58ToBytes(overflowAddr)
+	require.ErrorIs(t, err, errInvalidAddressEncoding)
+	require.ErrorIs(t, err, base58.ErrOverflow)
+}
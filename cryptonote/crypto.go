@@ -69,9 +69,10 @@ func (kp *PrivateKeyPair) PublicKeyPair() *PublicKeyPair {
 	}
 }
 
-// subAddressSecretKey creates and returns the private key used when generating the
-// public keys for a subaddress.
-func (kp *PrivateKeyPair) subAddressSecret(accountIndex uint32, subAddrIndex uint32) *ed25519.Scalar {
+// subAddressSecret creates and returns the private key used when generating the
+// public keys for a subaddress. Only the private view key is needed, so
+// view-only wallets can also derive subaddresses.
+func (k *PrivateViewKey) subAddressSecret(accountIndex uint32, subAddrIndex uint32) *ed25519.Scalar {
 	if accountIndex == 0 && subAddrIndex == 0 {
 		panic("accountIndex=0, subAddrIndex=0 is not a subaddress")
 	}
@@ -80,7 +81,7 @@ func (kp *PrivateKeyPair) subAddressSecret(accountIndex uint32, subAddrIndex uin
 	const hashSize = len(prefix) + 32 + 2*4
 	b := make([]byte, 0, hashSize)
 	b = append(b, []byte(prefix)...)
-	b = append(b, kp.PrivateViewKey().Bytes()...)
+	b = append(b, k.Bytes()...)
 	b = binary.LittleEndian.AppendUint32(b, accountIndex)
 	b = binary.LittleEndian.AppendUint32(b, subAddrIndex)
 
@@ -93,25 +94,28 @@ func (kp *PrivateKeyPair) subAddressSecret(accountIndex uint32, subAddrIndex uin
 	return s
 }
 
-// SubAddrPubKeyPair returns the PublicKeyPair of the requested subaddress.
-func (kp *PrivateKeyPair) SubAddrPubKeyPair(accountIndex uint32, subAddrIndex uint32) *PublicKeyPair {
+// subAddressSpendKey returns the public spend key of the requested subaddress,
+// B + Hs("SubAddr" || a || account || index)*G, where B is the public spend
+// key of the primary address.
+func (k *PrivateViewKey) subAddressSpendKey(spendKey *PublicKey, accountIndex uint32, subAddrIndex uint32) *ed25519.Point {
+	p := new(ed25519.Point).ScalarBaseMult(k.subAddressSecret(accountIndex, subAddrIndex))
+	return p.Add(p, spendKey.key)
+}
 
+// subAddrSpendKey returns the private spend key, b + m, of the requested
+// subaddress, where b is the private spend key of the primary address and m is
+// the subaddress secret. For account 0, index 0, b is returned.
+func (kp *PrivateKeyPair) subAddrSpendKey(accountIndex uint32, subAddrIndex uint32) *PrivateSpendKey {
 	if accountIndex == 0 && subAddrIndex == 0 {
-		// It's a primary key pair, not a subaddress
-		return kp.PublicKeyPair()
+		return kp.sk
 	}
+	m := kp.vk.subAddressSecret(accountIndex, subAddrIndex)
+	return &PrivateSpendKey{key: m.Add(m, kp.sk.key)}
+}
 
-	subAddrSecret := kp.subAddressSecret(accountIndex, subAddrIndex)
-	subAddrSecretPub := new(ed25519.Point).ScalarBaseMult(subAddrSecret)
-
-	spendKeyPub := new(ed25519.Point).Add(kp.sk.Public().key, subAddrSecretPub)
-	viewKeyPub := new(ed25519.Point).ScalarMult(kp.vk.key, spendKeyPub)
-
-	return &PublicKeyPair{
-		isSubAddress: true,
-		sk:           &PublicKey{key: spendKeyPub},
-		vk:           &PublicKey{key: viewKeyPub},
-	}
+// SubAddrPubKeyPair returns the PublicKeyPair of the requested subaddress.
+func (kp *PrivateKeyPair) SubAddrPubKeyPair(accountIndex uint32, subAddrIndex uint32) *PublicKeyPair {
+	return kp.ViewKeyPair().SubAddrPubKeyPair(accountIndex, subAddrIndex)
 }
 
 // SpendKey returns the key pair's private spend key
@@ -236,6 +240,15 @@ type PublicKeyPair struct {
 	vk           *PublicKey
 }
 
+// NewPublicKeyPair returns the PublicKeyPair of a standard address with the
+// passed public spend and view keys.
+func NewPublicKeyPair(spendKey, viewKey *PublicKey) *PublicKeyPair {
+	return &PublicKeyPair{
+		sk: spendKey,
+		vk: viewKey,
+	}
+}
+
 // SpendKey returns the key pair's spend key.
 func (kp *PublicKeyPair) SpendKey() *PublicKey {
 	return kp.sk
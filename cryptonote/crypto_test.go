@@ -42,7 +42,7 @@ func TestPrivateKeyPairToAddress(t *testing.T) {
 	require.NoError(t, err)
 	address := kp.PublicKeyPair().Address(Mainnet)
 
-	require.EqualValues(t, addressBytes, address.decoded[:])
+	require.EqualValues(t, addressBytes, address.bytes())
 	require.Equal(t, addressStr, address.String())
 
 	// check public key derivation
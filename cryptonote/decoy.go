@@ -0,0 +1,297 @@
+package cryptonote
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// Parameters of wallet2's decoy selection. The age of spent outputs, in
+// seconds, is modelled by a gamma distribution over its logarithm, fitted to
+// observed spends in "An Empirical Analysis of Traceability in the Monero
+// Blockchain".
+const (
+	gammaShape = 19.28
+	gammaScale = 1 / 1.61
+
+	// blockTime is Monero's target block time in seconds
+	blockTime = 120
+	// spendableAge is the number of blocks before a new output can be spent,
+	// so the newest outputs are never decoys.
+	spendableAge = 10
+	// defaultUnlockTime is the age, in seconds, of the newest spendable
+	// outputs. It is subtracted from picked ages, and picked ages below it
+	// are replaced by a uniformly random age in the recent spend window.
+	defaultUnlockTime = spendableAge * blockTime
+	recentSpendWindow = 15 * blockTime
+	blocksPerYear     = 86400 * 365 / blockTime
+)
+
+var (
+	errShortDistribution = errors.New("output distribution is shorter than the spendable age")
+	errNoRCTOutputs      = errors.New("output distribution has no spendable RingCT outputs")
+	errNoDecoyFetcher    = errors.New("decoy provider requires an output fetcher")
+	errNotEnoughDecoys   = errors.New("not enough unlocked outputs to use as decoys")
+	errFetchedCount      = errors.New("output fetcher returned the wrong number of outputs")
+)
+
+// GammaPicker is wallet2's gamma_picker, which picks the global indices of
+// decoys so that their ages follow the same distribution as real spends.
+//
+// An age in seconds is picked from the gamma distribution and converted to a
+// number of outputs using the chain's average time between outputs over the
+// past year. The decoy is a random output of the block containing the output
+// that far from the newest spendable output.
+type GammaPicker struct {
+	rng *rand.Rand
+	// rctOffsets holds the cumulative number of RingCT outputs at each block
+	// height
+	rctOffsets []uint64
+	// numRCTOutputs is the number of spendable RingCT outputs, those that
+	// are at least spendableAge blocks old
+	numRCTOutputs     uint64
+	averageOutputTime float64
+}
+
+// NewGammaPicker returns a picker over rctOffsets, the cumulative number of
+// RingCT outputs at each block height, as returned by the daemon's
+// get_output_distribution RPC method with amount 0, a starting height of 0
+// and cumulative set. If rng is nil, a source backed by crypto/rand is used.
+// Tests can pass a source with a fixed seed to get the same picks on every
+// run.
+func NewGammaPicker(rctOffsets []uint64, rng *rand.Rand) (*GammaPicker, error) {
+	if len(rctOffsets) <= spendableAge {
+		return nil, errShortDistribution
+	}
+	if rng == nil {
+		rng = newCryptoRand()
+	}
+
+	blocksToConsider := min(len(rctOffsets), blocksPerYear)
+	outputsToConsider := rctOffsets[len(rctOffsets)-1]
+	if blocksToConsider < len(rctOffsets) {
+		outputsToConsider -= rctOffsets[len(rctOffsets)-blocksToConsider-1]
+	}
+
+	p := &GammaPicker{
+		rng:           rng,
+		rctOffsets:    rctOffsets,
+		numRCTOutputs: rctOffsets[len(rctOffsets)-spendableAge-1],
+	}
+	if p.numRCTOutputs == 0 {
+		return nil, errNoRCTOutputs
+	}
+	// this assumes that the block time was constant over the past year
+	p.averageOutputTime = float64(blockTime*blocksToConsider) / float64(outputsToConsider)
+	return p, nil
+}
+
+// NumOutputs returns the number of spendable RingCT outputs that the picker
+// picks from. Picked global indices are below this number.
+func (p *GammaPicker) NumOutputs() uint64 {
+	return p.numRCTOutputs
+}
+
+// Pick returns the global index of a decoy. The boolean is false for a bad
+// pick, an age older than the chain or a block with no RingCT outputs, in
+// which case the caller picks again.
+func (p *GammaPicker) Pick() (uint64, bool) {
+	age := math.Exp(p.gamma())
+	if age > defaultUnlockTime {
+		age -= defaultUnlockTime
+	} else {
+		age = float64(p.rng.Int63n(recentSpendWindow))
+	}
+
+	outputIndex := uint64(age / p.averageOutputTime)
+	if outputIndex >= p.numRCTOutputs {
+		return 0, false
+	}
+	return p.pickInBlock(p.numRCTOutputs - 1 - outputIndex)
+}
+
+// pickInBlock returns a random output of the block containing the output at
+// global index outputIndex, which must be spendable. The boolean is false if
+// the block has no RingCT outputs.
+func (p *GammaPicker) pickInBlock(outputIndex uint64) (uint64, bool) {
+	// the first block with more than outputIndex outputs, like the
+	// upper_bound call in wallet2. It can't be one of the last spendableAge
+	// blocks.
+	spendable := p.rctOffsets[:len(p.rctOffsets)-spendableAge]
+	height, _ := slices.BinarySearch(spendable, outputIndex+1)
+	if height == len(spendable) {
+		panic("output index is not in the spendable blocks")
+	}
+
+	var firstRCT uint64
+	if height > 0 {
+		firstRCT = p.rctOffsets[height-1]
+	}
+	numRCT := p.rctOffsets[height] - firstRCT
+	if numRCT == 0 {
+		return 0, false
+	}
+	return firstRCT + uint64(p.rng.Int63n(int64(numRCT))), true
+}
+
+// gamma returns a value from the gamma distribution with wallet2's shape and
+// scale, using the method of Marsaglia and Tsang, which requires a shape of at
+// least 1.
+func (p *GammaPicker) gamma() float64 {
+	d := gammaShape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := p.rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := p.rng.Float64()
+		if u < 1-0.0331*x*x*x*x || math.Log(u) < x*x/2+d*(1-v+math.Log(v)) {
+			return d * v * gammaScale
+		}
+	}
+}
+
+// ChainOutput is an output fetched from the chain, which can only be used as
+// a decoy if it is unlocked
+type ChainOutput struct {
+	RingOutput
+	// Unlocked is true if the output's unlock time has passed, as reported by
+	// the daemon's get_outs RPC method
+	Unlocked bool
+}
+
+// OutputFetcher gets RingCT outputs from the chain by global index, like the
+// daemon's get_outs RPC method. The outputs are returned in the order of
+// indices.
+type OutputFetcher interface {
+	Outputs(ctx context.Context, indices []uint64) ([]*ChainOutput, error)
+}
+
+// GammaDecoyProvider is a DecoyProvider that selects decoys like wallet2: it
+// picks more candidates than needed with a GammaPicker, fetches them, and
+// uses the first unlocked candidates as decoys. It is not safe for concurrent
+// use.
+type GammaDecoyProvider struct {
+	picker  *GammaPicker
+	fetcher OutputFetcher
+}
+
+// NewGammaDecoyProvider returns a decoy provider picking from the output
+// distribution rctOffsets, described by NewGammaPicker, and fetching outputs
+// with fetcher. If rng is nil, a source backed by crypto/rand is used.
+func NewGammaDecoyProvider(rctOffsets []uint64, fetcher OutputFetcher, rng *rand.Rand) (*GammaDecoyProvider, error) {
+	if fetcher == nil {
+		return nil, errNoDecoyFetcher
+	}
+	picker, err := NewGammaPicker(rctOffsets, rng)
+	if err != nil {
+		return nil, err
+	}
+	return &GammaDecoyProvider{picker: picker, fetcher: fetcher}, nil
+}
+
+// Decoys implements DecoyProvider
+func (d *GammaDecoyProvider) Decoys(ctx context.Context, globalIndex uint64, count int) ([]*RingOutput, error) {
+	candidates := d.pickCandidates(globalIndex, count)
+	fetched, err := d.fetcher.Outputs(ctx, candidates)
+	if err != nil {
+		return nil, fmt.Errorf("fetching decoys: %w", err)
+	}
+	if len(fetched) != len(candidates) {
+		return nil, errFetchedCount
+	}
+
+	decoys := make([]*RingOutput, 0, count)
+	seenKeys := make(map[[KeySize]byte]struct{}, count)
+	for _, out := range fetched {
+		if len(decoys) == count {
+			break
+		}
+		if !out.Unlocked || out.Key == nil {
+			continue
+		}
+		key := [KeySize]byte(out.Key.Bytes())
+		if _, ok := seenKeys[key]; ok {
+			continue
+		}
+		seenKeys[key] = struct{}{}
+		decoys = append(decoys, &out.RingOutput)
+	}
+	if len(decoys) < count {
+		return nil, errNotEnoughDecoys
+	}
+	return decoys, nil
+}
+
+// pickCandidates returns the unique global indices of the candidate decoys for
+// the real output at globalIndex, in the order they were picked. Like
+// wallet2, it picks half as many again as the ring size, plus spendableAge
+// more, as some candidates may be locked.
+func (d *GammaDecoyProvider) pickCandidates(globalIndex uint64, count int) []uint64 {
+	requested := uint64(float64(count+1)*1.5+1) + spendableAge - 1
+	seen := map[uint64]struct{}{globalIndex: {}}
+
+	// if there are too few outputs to choose from, they are all candidates
+	numOutputs := d.picker.NumOutputs()
+	if numOutputs <= requested {
+		candidates := make([]uint64, 0, numOutputs)
+		for i := uint64(0); i < numOutputs; i++ {
+			if i != globalIndex {
+				candidates = append(candidates, i)
+			}
+		}
+		d.picker.rng.Shuffle(len(candidates), func(i, j int) {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		})
+		return candidates
+	}
+
+	// the real output is one of the requested outputs
+	candidates := make([]uint64, 0, requested-1)
+	for uint64(len(candidates)) < requested-1 {
+		idx, ok := d.picker.Pick()
+		if !ok {
+			continue
+		}
+		if _, ok := seen[idx]; ok {
+			continue
+		}
+		seen[idx] = struct{}{}
+		candidates = append(candidates, idx)
+	}
+	return candidates
+}
+
+// cryptoSource is a math/rand source reading from crypto/rand, so that the
+// picks can't be predicted from earlier ones
+type cryptoSource struct{}
+
+// Int63 implements rand.Source
+func (s cryptoSource) Int63() int64 {
+	return int64(s.Uint64() & math.MaxInt64)
+}
+
+// Uint64 implements rand.Source64
+func (cryptoSource) Uint64() uint64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// Seed implements rand.Source. It does nothing, as the source can't be seeded.
+func (cryptoSource) Seed(int64) {}
+
+// newCryptoRand returns a math/rand generator backed by crypto/rand
+func newCryptoRand() *rand.Rand {
+	return rand.New(cryptoSource{})
+}
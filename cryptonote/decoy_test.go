@@ -0,0 +1,226 @@
+package cryptonote
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"testing"
+
+	ed25519 "filippo.io/edwards25519"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/mcrypto"
+)
+
+// newTestDistribution returns the cumulative RingCT output counts of a chain
+// with numBlocks blocks, where every fourth block has no outputs and the
+// others have 3.
+func newTestDistribution(numBlocks int) []uint64 {
+	offsets := make([]uint64, numBlocks)
+	var total uint64
+	for i := range offsets {
+		if i%4 != 0 {
+			total += 3
+		}
+		offsets[i] = total
+	}
+	return offsets
+}
+
+// testOutputFetcher creates outputs when they are fetched, and adds them to
+// the decoys known to verifyTestTx.
+type testOutputFetcher struct {
+	decoys  *testDecoys
+	locked  func(globalIndex uint64) bool
+	fetched [][]uint64
+}
+
+func (f *testOutputFetcher) Outputs(_ context.Context, indices []uint64) ([]*ChainOutput, error) {
+	f.fetched = append(f.fetched, indices)
+	outs := make([]*ChainOutput, len(indices))
+	for i, idx := range indices {
+		out, ok := f.decoys.outputs[idx]
+		if !ok {
+			out = &RingOutput{
+				GlobalIndex: idx,
+				Key:         &PublicKey{key: new(ed25519.Point).ScalarBaseMult(mcrypto.RandomScalar())},
+				Commitment:  Commit(mcrypto.RandomScalar(), idx),
+			}
+			f.decoys.outputs[idx] = out
+		}
+		outs[i] = &ChainOutput{RingOutput: *out, Unlocked: !f.locked(idx)}
+	}
+	return outs, nil
+}
+
+func TestGammaPicker_Pick(t *testing.T) {
+	offsets := newTestDistribution(500_000)
+	picker, err := NewGammaPicker(offsets, rand.New(rand.NewSource(1)))
+	require.NoError(t, err)
+	require.Equal(t, offsets[len(offsets)-spendableAge-1], picker.NumOutputs())
+
+	const numPicks = 10_000
+	ages := make([]uint64, 0, numPicks)
+	badPicks := 0
+	for len(ages) < numPicks {
+		idx, ok := picker.Pick()
+		if !ok {
+			badPicks++
+			continue
+		}
+		require.Less(t, idx, picker.NumOutputs())
+		// the picked output is in a block with outputs
+		height, _ := slices.BinarySearch(offsets, idx+1)
+		require.NotZero(t, height%4)
+		ages = append(ages, picker.NumOutputs()-1-idx)
+	}
+	// about 2% of ages, those over e^17.6 seconds, are older than the chain
+	require.Less(t, badPicks, numPicks/20)
+
+	// There are 2.25 outputs per block, so outputs are 53 seconds apart on
+	// average. The median age of e^11.79 seconds, less the unlock time, is
+	// about 2400 outputs, but without the bad picks, it is about 2200.
+	slices.Sort(ages)
+	require.InDelta(t, 2200, ages[numPicks/2], 150)
+	require.Less(t, ages[0], uint64(recentSpendWindow/53))
+}
+
+func TestGammaPicker_pickInBlock(t *testing.T) {
+	// Outputs 0 and 1 are in block 0, block 1 has none, output 2 is in
+	// block 2 and outputs [3, 6) are in block 3.
+	offsets := []uint64{2, 2, 3, 6}
+	for i := 0; i < spendableAge; i++ {
+		offsets = append(offsets, 10)
+	}
+	picker, err := NewGammaPicker(offsets, rand.New(rand.NewSource(4)))
+	require.NoError(t, err)
+	require.EqualValues(t, 6, picker.NumOutputs())
+
+	for i := 0; i < 20; i++ {
+		// the last output of a block is picked from that block
+		idx, ok := picker.pickInBlock(1)
+		require.True(t, ok)
+		require.Less(t, idx, uint64(2))
+
+		// the first output of a block, right after an empty block
+		idx, ok = picker.pickInBlock(2)
+		require.True(t, ok)
+		require.EqualValues(t, 2, idx)
+
+		idx, ok = picker.pickInBlock(3)
+		require.True(t, ok)
+		require.GreaterOrEqual(t, idx, uint64(3))
+		require.Less(t, idx, uint64(6))
+	}
+}
+
+func TestCryptoSource(t *testing.T) {
+	rng := newCryptoRand()
+	seen := make(map[int64]struct{})
+	for i := 0; i < 100; i++ {
+		n := rng.Int63()
+		require.GreaterOrEqual(t, n, int64(0))
+		seen[n] = struct{}{}
+	}
+	require.Len(t, seen, 100)
+}
+
+func TestGammaPicker_Pick_deterministic(t *testing.T) {
+	offsets := newTestDistribution(20_000)
+	picks := func(seed int64) []uint64 {
+		picker, err := NewGammaPicker(offsets, rand.New(rand.NewSource(seed)))
+		require.NoError(t, err)
+		var picks []uint64
+		for i := 0; i < 100; i++ {
+			idx, ok := picker.Pick()
+			if ok {
+				picks = append(picks, idx)
+			}
+		}
+		return picks
+	}
+	require.Equal(t, picks(7), picks(7))
+	require.NotEqual(t, picks(7), picks(8))
+}
+
+func TestNewGammaPicker_errors(t *testing.T) {
+	_, err := NewGammaPicker(make([]uint64, spendableAge), nil)
+	require.ErrorIs(t, err, errShortDistribution)
+
+	// the only outputs are too new to spend
+	offsets := make([]uint64, 30)
+	for i := 25; i < len(offsets); i++ {
+		offsets[i] = uint64(i)
+	}
+	_, err = NewGammaPicker(offsets, nil)
+	require.ErrorIs(t, err, errNoRCTOutputs)
+
+	_, err = NewGammaDecoyProvider(newTestDistribution(100), nil, nil)
+	require.ErrorIs(t, err, errNoDecoyFetcher)
+}
+
+func TestGammaDecoyProvider_Decoys(t *testing.T) {
+	locked := func(idx uint64) bool { return idx%5 == 0 }
+	fetcher := &testOutputFetcher{decoys: newTestDecoys(), locked: locked}
+	provider, err := NewGammaDecoyProvider(newTestDistribution(100_000), fetcher, rand.New(rand.NewSource(2)))
+	require.NoError(t, err)
+
+	const realIndex = 150_000
+	decoys, err := provider.Decoys(context.Background(), realIndex, DefaultRingSize-1)
+	require.NoError(t, err)
+	require.Len(t, decoys, DefaultRingSize-1)
+
+	// wallet2 requests (16*1.5+1)+9 outputs, including the real one
+	require.Len(t, fetcher.fetched, 1)
+	candidates := fetcher.fetched[0]
+	require.Len(t, candidates, 33)
+	require.NotContains(t, candidates, uint64(realIndex))
+
+	seen := make(map[uint64]bool)
+	for _, decoy := range decoys {
+		require.False(t, locked(decoy.GlobalIndex))
+		require.False(t, seen[decoy.GlobalIndex])
+		seen[decoy.GlobalIndex] = true
+	}
+}
+
+func TestGammaDecoyProvider_Decoys_fewOutputs(t *testing.T) {
+	// 9 spendable outputs, of which 1 is locked and 1 is the real output
+	fetcher := &testOutputFetcher{
+		decoys: newTestDecoys(),
+		locked: func(idx uint64) bool { return idx == 5 },
+	}
+	provider, err := NewGammaDecoyProvider(newTestDistribution(spendableAge+4), fetcher, nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(9), provider.picker.NumOutputs())
+
+	decoys, err := provider.Decoys(context.Background(), 3, 7)
+	require.NoError(t, err)
+	require.Len(t, decoys, 7)
+	require.Len(t, fetcher.fetched[0], 8)
+
+	_, err = provider.Decoys(context.Background(), 3, 8)
+	require.ErrorIs(t, err, errNotEnoughDecoys)
+}
+
+func TestPrivateKeyPair_BuildTransaction_gammaDecoys(t *testing.T) {
+	sender, err := GenerateKeys()
+	require.NoError(t, err)
+	fetcher := &testOutputFetcher{
+		decoys: newTestDecoys(),
+		locked: func(idx uint64) bool { return idx%10 == 0 },
+	}
+	provider, err := NewGammaDecoyProvider(newTestDistribution(10_000), fetcher, rand.New(rand.NewSource(3)))
+	require.NoError(t, err)
+
+	params := &TxParams{
+		Sources:             newTestSources(t, sender, fetcher.decoys),
+		Destinations:        []*TxDestination{{Address: sender.PublicKeyPair().Address(Mainnet), Amount: 100}},
+		FeeQuantizationMask: 1,
+		RingSize:            DefaultRingSize,
+		Decoys:              provider,
+	}
+	signed, err := sender.BuildTransaction(context.Background(), params)
+	require.NoError(t, err)
+	verifyTestTx(t, signed, fetcher.decoys)
+}
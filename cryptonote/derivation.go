@@ -0,0 +1,159 @@
+package cryptonote
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+
+	ed25519 "filippo.io/edwards25519"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/dimalinux/gopherphis/mcrypto"
+)
+
+// KeyDerivation is the shared secret between the sender and the recipient of
+// a transaction output. The recipient computes it as 8*a*R from their private
+// view key a and the transaction public key R. The sender computes the same
+// value as 8*r*A from the transaction private key r and the recipient's public
+// view key A.
+type KeyDerivation struct {
+	key *ed25519.Point
+}
+
+// newKeyDerivation is Monero's generate_key_derivation
+func newKeyDerivation(pubKey *ed25519.Point, secret *ed25519.Scalar) *KeyDerivation {
+	d := new(ed25519.Point).ScalarMult(secret, pubKey)
+	d.MultByCofactor(d)
+	return &KeyDerivation{key: d}
+}
+
+// KeyDerivation returns the derivation shared with the sender of a transaction
+// whose public key is txPubKey.
+func (k *PrivateViewKey) KeyDerivation(txPubKey *PublicKey) *KeyDerivation {
+	return newKeyDerivation(txPubKey.key, k.key)
+}
+
+// Bytes returns the 32-byte encoding of the KeyDerivation.
+func (d *KeyDerivation) Bytes() []byte {
+	return d.key.Bytes()
+}
+
+// Hex formats the derivation as a hex string
+func (d *KeyDerivation) Hex() string {
+	return hex.EncodeToString(d.Bytes())
+}
+
+// String formats the derivation as a 0x-prefixed hex string
+func (d *KeyDerivation) String() string {
+	return "0x" + d.Hex()
+}
+
+// scalar is Monero's derivation_to_scalar. The output index is varint encoded
+// before hashing, so that each output of a transaction gets a unique scalar.
+func (d *KeyDerivation) scalar(outputIndex uint64) *ed25519.Scalar {
+	return mcrypto.HashToScalar(d.Bytes(), binary.AppendUvarint(nil, outputIndex))
+}
+
+// ViewTag returns the 1-byte view tag of the output at outputIndex. View tags
+// let the recipient skip the more expensive output key check for ~255/256 of
+// the outputs that are not theirs.
+func (d *KeyDerivation) ViewTag(outputIndex uint64) byte {
+	const salt = "view_tag"
+	return ethcrypto.Keccak256([]byte(salt), d.Bytes(), binary.AppendUvarint(nil, outputIndex))[0]
+}
+
+// DerivePublicKey returns the one-time output public key, Hs(d || i)*G + B, for
+// the output at outputIndex sent to the public spend key B.
+func (d *KeyDerivation) DerivePublicKey(outputIndex uint64, spendKey *PublicKey) *PublicKey {
+	p := new(ed25519.Point).ScalarBaseMult(d.scalar(outputIndex))
+	return &PublicKey{key: p.Add(p, spendKey.key)}
+}
+
+// DeriveSecretKey returns the one-time output private key, Hs(d || i) + b, for
+// the output at outputIndex sent to the private spend key b.
+func (d *KeyDerivation) DeriveSecretKey(outputIndex uint64, spendKey *PrivateSpendKey) *OneTimePrivateKey {
+	x := d.scalar(outputIndex)
+	return &OneTimePrivateKey{key: x.Add(x, spendKey.key)}
+}
+
+// OutputSpendKey reverses DerivePublicKey, returning the public spend key,
+// P - Hs(d || i)*G, that the output was sent to. If the output has a view tag
+// and it does not match, false is returned without computing the key.
+func (d *KeyDerivation) OutputSpendKey(out *TxOutput) (*PublicKey, bool) {
+	if out.ViewTag != nil && *out.ViewTag != d.ViewTag(out.Index) {
+		return nil, false
+	}
+
+	hsG := new(ed25519.Point).ScalarBaseMult(d.scalar(out.Index))
+	return &PublicKey{key: hsG.Subtract(out.Key.key, hsG)}, true
+}
+
+// TxOutput holds the fields of a transaction output needed to determine if it
+// belongs to a wallet.
+type TxOutput struct {
+	// Index is the position of the output in the transaction
+	Index uint64
+	// Key is the one-time public key of the output
+	Key *PublicKey
+	// ViewTag is nil for outputs created before view tags were added to
+	// Monero in the v15 hard fork.
+	ViewTag *byte
+	// Amount is the cleartext amount of outputs created before RingCT and of
+	// coinbase outputs. It is zero for other outputs.
+	Amount uint64
+	// Commitment is the amount commitment of RingCT outputs. It is nil for
+	// outputs with a cleartext amount.
+	Commitment *Commitment
+	// EncryptedAmount is the encrypted amount of RingCT outputs with a
+	// commitment. It holds the 8-byte compact encrypted amount used since
+	// Bulletproofs v2 or, for older outputs, the 32-byte encrypted mask
+	// followed by the 32-byte encrypted amount.
+	EncryptedAmount []byte
+}
+
+// OwnsOutput returns true if the output, from the transaction whose public
+// key is txPubKey, was sent to the public spend key spendKey. Only the private
+// view key is required for this check.
+func (k *PrivateViewKey) OwnsOutput(spendKey *PublicKey, txPubKey *PublicKey, out *TxOutput) bool {
+	outSpendKey, ok := k.KeyDerivation(txPubKey).OutputSpendKey(out)
+	return ok && outSpendKey.Equal(spendKey)
+}
+
+// ScanOutput checks if the output, from the transaction whose public key is
+// txPubKey, was sent to the primary address of the key pair. If it was, the
+// one-time private key needed to spend the output is returned.
+func (kp *PrivateKeyPair) ScanOutput(txPubKey *PublicKey, out *TxOutput) (*OneTimePrivateKey, bool) {
+	derivation := kp.vk.KeyDerivation(txPubKey)
+	outSpendKey, ok := derivation.OutputSpendKey(out)
+	if !ok || !outSpendKey.Equal(kp.sk.Public()) {
+		return nil, false
+	}
+
+	return derivation.DeriveSecretKey(out.Index, kp.sk), true
+}
+
+// OneTimePrivateKey is the private key of a single transaction output, also
+// called the output's ephemeral secret key. It is needed to spend the output
+// and to compute the output's key image.
+type OneTimePrivateKey struct {
+	key *ed25519.Scalar
+}
+
+// Public returns the one-time public key of the output.
+func (k *OneTimePrivateKey) Public() *PublicKey {
+	return &PublicKey{key: new(ed25519.Point).ScalarBaseMult(k.key)}
+}
+
+// Bytes returns the canonical 32-byte little-endian encoding of the key.
+func (k *OneTimePrivateKey) Bytes() []byte {
+	return k.key.Bytes()
+}
+
+// Hex formats the key as a hex string
+func (k *OneTimePrivateKey) Hex() string {
+	return hex.EncodeToString(k.key.Bytes())
+}
+
+// String formats the key as a 0x-prefixed hex string
+func (k *OneTimePrivateKey) String() string {
+	return "0x" + k.Hex()
+}
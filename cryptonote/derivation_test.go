@@ -0,0 +1,130 @@
+package cryptonote
+
+import (
+	"testing"
+
+	ed25519 "filippo.io/edwards25519"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTxKeys returns a random transaction private key and its public key
+func newTestTxKeys(t *testing.T) (*ed25519.Scalar, *PublicKey) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	r := kp.sk.key
+	return r, kp.sk.Public()
+}
+
+func TestKeyDerivation_senderAndRecipientMatch(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	r, txPubKey := newTestTxKeys(t)
+
+	senderDerivation := newKeyDerivation(kp.vk.Public().key, r)
+	recipientDerivation := kp.PrivateViewKey().KeyDerivation(txPubKey)
+	require.Equal(t, senderDerivation.Hex(), recipientDerivation.Hex())
+	require.Equal(t, "0x"+recipientDerivation.Hex(), recipientDerivation.String())
+}
+
+func TestKeyDerivation_DeriveSecretKey(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	_, txPubKey := newTestTxKeys(t)
+
+	derivation := kp.PrivateViewKey().KeyDerivation(txPubKey)
+	for i := uint64(0); i < 16; i++ {
+		pubKey := derivation.DerivePublicKey(i, kp.PublicKeyPair().SpendKey())
+		privKey := derivation.DeriveSecretKey(i, kp.SpendKey())
+		require.True(t, pubKey.Equal(privKey.Public()))
+	}
+}
+
+func TestPrivateKeyPair_ScanOutput(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	r, txPubKey := newTestTxKeys(t)
+
+	// sender side
+	derivation := newKeyDerivation(kp.vk.Public().key, r)
+	const outputIndex = 3
+	viewTag := derivation.ViewTag(outputIndex)
+	out := &TxOutput{
+		Index:   outputIndex,
+		Key:     derivation.DerivePublicKey(outputIndex, kp.sk.Public()),
+		ViewTag: &viewTag,
+	}
+
+	// recipient side
+	oneTimeKey, ok := kp.ScanOutput(txPubKey, out)
+	require.True(t, ok)
+	require.True(t, out.Key.Equal(oneTimeKey.Public()))
+	require.True(t, kp.PrivateViewKey().OwnsOutput(kp.sk.Public(), txPubKey, out))
+
+	// outputs created before view tags existed are still found
+	out.ViewTag = nil
+	_, ok = kp.ScanOutput(txPubKey, out)
+	require.True(t, ok)
+
+	// wrong view tag
+	badViewTag := viewTag + 1
+	out.ViewTag = &badViewTag
+	_, ok = kp.ScanOutput(txPubKey, out)
+	require.False(t, ok)
+
+	// wrong output index
+	out.ViewTag = nil
+	out.Index++
+	_, ok = kp.ScanOutput(txPubKey, out)
+	require.False(t, ok)
+	out.Index--
+
+	// output belongs to someone else
+	otherKP, err := GenerateKeys()
+	require.NoError(t, err)
+	_, ok = otherKP.ScanOutput(txPubKey, out)
+	require.False(t, ok)
+	require.False(t, otherKP.PrivateViewKey().OwnsOutput(otherKP.sk.Public(), txPubKey, out))
+}
+
+func TestKeyDerivation_OutputSpendKey(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	r, _ := newTestTxKeys(t)
+
+	// Send to a subaddress, then recover the subaddress spend key from the
+	// output using only the private view key. Transactions to a subaddress
+	// use r*D as the transaction public key, where D is the subaddress spend
+	// key.
+	subAddrKeys := kp.SubAddrPubKeyPair(2, 7)
+	txPubKey := &PublicKey{key: new(ed25519.Point).ScalarMult(r, subAddrKeys.SpendKey().key)}
+	senderDerivation := newKeyDerivation(subAddrKeys.ViewKey().key, r)
+	out := &TxOutput{
+		Index: 0,
+		Key:   senderDerivation.DerivePublicKey(0, subAddrKeys.SpendKey()),
+	}
+
+	derivation := kp.PrivateViewKey().KeyDerivation(txPubKey)
+	require.Equal(t, senderDerivation.Hex(), derivation.Hex())
+	spendKey, ok := derivation.OutputSpendKey(out)
+	require.True(t, ok)
+	require.True(t, subAddrKeys.SpendKey().Equal(spendKey))
+
+	// not sent to the primary address
+	_, ok = kp.ScanOutput(txPubKey, out)
+	require.False(t, ok)
+}
+
+func TestNewPublicKey(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+
+	pubKey := kp.sk.Public()
+	pubKey2, err := NewPublicKey(pubKey.Bytes())
+	require.NoError(t, err)
+	require.True(t, pubKey.Equal(pubKey2))
+	require.False(t, pubKey.Equal(nil))
+	require.False(t, pubKey.Equal(kp.vk.Public()))
+
+	_, err = NewPublicKey(pubKey.Bytes()[1:])
+	require.ErrorIs(t, err, errInvalidInput)
+}
@@ -0,0 +1,68 @@
+package cryptonote
+
+import (
+	"encoding/hex"
+
+	ed25519 "filippo.io/edwards25519"
+
+	"github.com/dimalinux/gopherphis/mcrypto"
+)
+
+// KeyImage is the key image, x*Hp(P), of a transaction output with one-time
+// private key x and one-time public key P. Each output has exactly one key
+// image, which is revealed when the output is spent, so the network can reject
+// double spends and wallets can detect which of their outputs are spent.
+type KeyImage struct {
+	key *ed25519.Point
+}
+
+// GenerateKeyImage is Monero's generate_key_image. It returns the key image of
+// the output with the passed one-time private key.
+func GenerateKeyImage(oneTimePrivKey *OneTimePrivateKey) *KeyImage {
+	hp := mcrypto.HashToEC(oneTimePrivKey.Public().Bytes())
+	return &KeyImage{key: hp.ScalarMult(oneTimePrivKey.key, hp)}
+}
+
+// KeyImage returns the key image of the output.
+func (k *OneTimePrivateKey) KeyImage() *KeyImage {
+	return GenerateKeyImage(k)
+}
+
+// NewKeyImage returns a new KeyImage from its 32-byte encoding.
+func NewKeyImage(b []byte) (*KeyImage, error) {
+	if len(b) != KeySize {
+		return nil, errInvalidInput
+	}
+
+	ki, err := new(ed25519.Point).SetBytes(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyImage{
+		key: ki,
+	}, nil
+}
+
+// Equal returns true if the key images are identical, otherwise false.
+func (k *KeyImage) Equal(other *KeyImage) bool {
+	if other == nil {
+		return false
+	}
+	return k.key.Equal(other.key) == 1
+}
+
+// Bytes returns the canonical 32-byte encoding of the key image.
+func (k *KeyImage) Bytes() []byte {
+	return k.key.Bytes()
+}
+
+// Hex formats the key image as a hex string
+func (k *KeyImage) Hex() string {
+	return hex.EncodeToString(k.key.Bytes())
+}
+
+// String formats the key image as a 0x-prefixed hex string
+func (k *KeyImage) String() string {
+	return "0x" + k.Hex()
+}
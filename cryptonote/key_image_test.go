@@ -0,0 +1,69 @@
+package cryptonote
+
+import (
+	"testing"
+
+	ed25519 "filippo.io/edwards25519"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/mcrypto"
+)
+
+func TestGenerateKeyImage(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	_, txPubKey := newTestTxKeys(t)
+	derivation := kp.PrivateViewKey().KeyDerivation(txPubKey)
+
+	out0 := derivation.DeriveSecretKey(0, kp.SpendKey())
+	out1 := derivation.DeriveSecretKey(1, kp.SpendKey())
+
+	// I = x * Hp(P)
+	keyImage := GenerateKeyImage(out0)
+	hp := mcrypto.HashToEC(out0.Public().Bytes())
+	expected := new(ed25519.Point).ScalarMult(out0.key, hp)
+	require.Equal(t, expected.Bytes(), keyImage.Bytes())
+
+	// The key image only depends on the output, not on how it was computed
+	require.True(t, keyImage.Equal(out0.KeyImage()))
+	require.False(t, keyImage.Equal(out1.KeyImage()))
+	require.False(t, keyImage.Equal(nil))
+}
+
+func TestPrivateKeyPair_ScanOutput_keyImage(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	r, txPubKey := newTestTxKeys(t)
+
+	// sender side
+	derivation := newKeyDerivation(kp.vk.Public().key, r)
+	out := &TxOutput{
+		Index: 0,
+		Key:   derivation.DerivePublicKey(0, kp.sk.Public()),
+	}
+
+	// recipient side
+	oneTimeKey, ok := kp.ScanOutput(txPubKey, out)
+	require.True(t, ok)
+	keyImage := oneTimeKey.KeyImage()
+
+	// the key image commits to the one-time public key of the output
+	hp := mcrypto.HashToEC(out.Key.Bytes())
+	require.Equal(t, new(ed25519.Point).ScalarMult(oneTimeKey.key, hp).Bytes(), keyImage.Bytes())
+}
+
+func TestNewKeyImage(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	_, txPubKey := newTestTxKeys(t)
+	oneTimeKey := kp.PrivateViewKey().KeyDerivation(txPubKey).DeriveSecretKey(0, kp.SpendKey())
+
+	keyImage := oneTimeKey.KeyImage()
+	keyImage2, err := NewKeyImage(keyImage.Bytes())
+	require.NoError(t, err)
+	require.True(t, keyImage.Equal(keyImage2))
+	require.Equal(t, "0x"+keyImage.Hex(), keyImage2.String())
+
+	_, err = NewKeyImage(keyImage.Bytes()[1:])
+	require.ErrorIs(t, err, errInvalidInput)
+}
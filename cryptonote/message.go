@@ -0,0 +1,125 @@
+package cryptonote
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	ed25519 "filippo.io/edwards25519"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/dimalinux/gopherphis/base58"
+)
+
+// Message signature headers. SigV1 signatures only sign the hash of the
+// message, so the same signature is valid for any address with the same key.
+// SigV2 signatures also sign the address and the key type.
+const (
+	messageSigV1Header = "SigV1"
+	messageSigV2Header = "SigV2"
+)
+
+// Values for the key type (mode) byte in the SigV2 message hash
+const (
+	messageSigSpendKeyMode byte = 0
+	messageSigViewKeyMode  byte = 1
+)
+
+var (
+	errInvalidMessageSigFormat = errors.New("invalid message signature format")
+	errInvalidMessageSig       = errors.New("invalid message signature")
+)
+
+// MessageSignatureInfo describes a valid message signature
+type MessageSignatureInfo struct {
+	// Version is 1 for SigV1 signatures and 2 for SigV2 signatures
+	Version int
+	// ViewKey is true if the message was signed with the view key, and false
+	// if it was signed with the spend key.
+	ViewKey bool
+}
+
+// messageHash is wallet2's get_message_hash, the hash signed by SigV2
+// signatures.
+func messageHash(msg []byte, spendKey *PublicKey, viewKey *PublicKey, mode byte) []byte {
+	const domainSep = "MoneroMessageSignature\x00"
+	return ethcrypto.Keccak256(
+		[]byte(domainSep),
+		spendKey.Bytes(),
+		viewKey.Bytes(),
+		[]byte{mode},
+		binary.AppendUvarint(nil, uint64(len(msg))),
+		msg,
+	)
+}
+
+// SignMessage returns a SigV2 signature of the message that proves ownership
+// of the address at the account and subaddress index. Account 0, index 0 is
+// the primary address. The message is signed with the spend key, unless
+// useViewKey is true. The result is compatible with monero-wallet-cli's sign
+// command.
+func (kp *PrivateKeyPair) SignMessage(msg []byte, useViewKey bool, accountIndex uint32, subAddrIndex uint32) string {
+	spendSecret := kp.subAddrSpendKey(accountIndex, subAddrIndex).key
+	viewSecret := kp.vk.key
+	if accountIndex != 0 || subAddrIndex != 0 {
+		// the private view key of a subaddress is a*(b + m)
+		viewSecret = new(ed25519.Scalar).Multiply(viewSecret, spendSecret)
+	}
+	pubKeys := kp.SubAddrPubKeyPair(accountIndex, subAddrIndex)
+
+	mode, secret, pub := messageSigSpendKeyMode, spendSecret, pubKeys.sk.key
+	if useViewKey {
+		mode, secret, pub = messageSigViewKeyMode, viewSecret, pubKeys.vk.key
+	}
+
+	hash := messageHash(msg, pubKeys.sk, pubKeys.vk, mode)
+	return messageSigV2Header + base58.Encode(generateSignature(hash, pub, secret))
+}
+
+// VerifyMessage verifies a SigV1 or SigV2 signature, created by
+// monero-wallet-cli's sign command or by SignMessage, of the message by the
+// address. Both spend key and view key signatures are accepted, the returned
+// MessageSignatureInfo tells you which key was used.
+func VerifyMessage(addr *Address, msg []byte, sig string) (*MessageSignatureInfo, error) {
+	pubKeys, err := addr.PublicKeyPair()
+	if err != nil {
+		return nil, err
+	}
+
+	var version int
+	switch {
+	case strings.HasPrefix(sig, messageSigV1Header):
+		version = 1
+	case strings.HasPrefix(sig, messageSigV2Header):
+		version = 2
+	default:
+		return nil, errInvalidMessageSigFormat
+	}
+
+	// both headers have the same length
+	sigBytes, err := base58.Decode(sig[len(messageSigV2Header):])
+	if err != nil || len(sigBytes) != SignatureLen {
+		return nil, errInvalidMessageSigFormat
+	}
+
+	for _, mode := range []byte{messageSigSpendKeyMode, messageSigViewKeyMode} {
+		hash := ethcrypto.Keccak256(msg)
+		if version == 2 {
+			hash = messageHash(msg, pubKeys.sk, pubKeys.vk, mode)
+		}
+
+		pub := pubKeys.sk.key
+		if mode == messageSigViewKeyMode {
+			pub = pubKeys.vk.key
+		}
+
+		if checkSignature(hash, pub, sigBytes) {
+			return &MessageSignatureInfo{
+				Version: version,
+				ViewKey: mode == messageSigViewKeyMode,
+			}, nil
+		}
+	}
+
+	return nil, errInvalidMessageSig
+}
@@ -0,0 +1,108 @@
+package cryptonote
+
+import (
+	"strings"
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/base58"
+)
+
+func TestSignMessage(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	msg := []byte("I own this address")
+
+	type testCase struct {
+		account    uint32
+		index      uint32
+		useViewKey bool
+	}
+	testCases := []testCase{
+		{0, 0, false},
+		{0, 0, true},
+		{0, 1, false},
+		{3, 5, true},
+	}
+
+	for _, tc := range testCases {
+		addr := kp.SubAddrPubKeyPair(tc.account, tc.index).Address(Mainnet)
+		sig := kp.SignMessage(msg, tc.useViewKey, tc.account, tc.index)
+		require.True(t, strings.HasPrefix(sig, "SigV2"))
+
+		info, err := VerifyMessage(addr, msg, sig)
+		require.NoError(t, err)
+		require.Equal(t, 2, info.Version)
+		require.Equal(t, tc.useViewKey, info.ViewKey)
+
+		// wrong message
+		_, err = VerifyMessage(addr, []byte("I own this address!"), sig)
+		require.ErrorIs(t, err, errInvalidMessageSig)
+
+		// SigV2 signatures are bound to the address
+		otherAddr := kp.SubAddrPubKeyPair(tc.account, tc.index+1).Address(Mainnet)
+		_, err = VerifyMessage(otherAddr, msg, sig)
+		require.ErrorIs(t, err, errInvalidMessageSig)
+	}
+}
+
+func TestVerifyMessage_sigV1(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	msg := []byte("legacy signature")
+	addr := kp.PublicKeyPair().Address(Stagenet)
+
+	// SigV1 signatures are over the keccak hash of only the message
+	hash := ethcrypto.Keccak256(msg)
+	sig := "SigV1" + base58.Encode(generateSignature(hash, kp.sk.Public().key, kp.sk.key))
+
+	info, err := VerifyMessage(addr, msg, sig)
+	require.NoError(t, err)
+	require.Equal(t, 1, info.Version)
+	require.False(t, info.ViewKey)
+
+	// the same signature bytes are not valid as SigV2
+	_, err = VerifyMessage(addr, msg, "SigV2"+sig[len("SigV1"):])
+	require.ErrorIs(t, err, errInvalidMessageSig)
+}
+
+func TestVerifyMessage_invalidFormat(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	msg := []byte("hello")
+	addr := kp.PublicKeyPair().Address(Mainnet)
+	sig := kp.SignMessage(msg, false, 0, 0)
+
+	badSigs := []string{
+		"",
+		"SigV3" + sig[len("SigV2"):],
+		sig[len("SigV2"):],
+		sig[:len(sig)-1],
+		sig + "1",
+		"SigV2" + base58.Encode(make([]byte, SignatureLen)),
+	}
+	for _, badSig := range badSigs {
+		_, err = VerifyMessage(addr, msg, badSig)
+		require.Error(t, err)
+	}
+}
+
+func TestCheckSignature_tampered(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	hash := ethcrypto.Keccak256([]byte("data"))
+	pub := kp.sk.Public().key
+
+	sig := generateSignature(hash, pub, kp.sk.key)
+	require.True(t, checkSignature(hash, pub, sig))
+	require.False(t, checkSignature(hash, kp.vk.Public().key, sig))
+	require.False(t, checkSignature(hash, pub, sig[1:]))
+
+	for _, i := range []int{0, 32} {
+		tampered := append([]byte{}, sig...)
+		tampered[i]++
+		require.False(t, checkSignature(hash, pub, tampered))
+	}
+}
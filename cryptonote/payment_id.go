@@ -0,0 +1,59 @@
+package cryptonote
+
+import (
+	"encoding/hex"
+	"errors"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// PaymentIDLen is the length, in bytes, of the payment ID embedded in an
+// integrated address.
+const PaymentIDLen = 8
+
+var errInvalidPaymentID = errors.New("payment ID is not 8 hex-encoded bytes")
+
+// PaymentID is the 8-byte payment ID of an integrated address. Senders encrypt
+// the payment ID into the transaction so that the recipient can identify the
+// payment.
+type PaymentID [PaymentIDLen]byte
+
+// NewPaymentID converts a 16-character hex string into a PaymentID.
+func NewPaymentID(hexStr string) (PaymentID, error) {
+	var paymentID PaymentID
+	b, err := hex.DecodeString(hexStr)
+	if err != nil || len(b) != PaymentIDLen {
+		return paymentID, errInvalidPaymentID
+	}
+	copy(paymentID[:], b)
+	return paymentID, nil
+}
+
+// Hex formats the payment ID as a hex string
+func (p PaymentID) Hex() string {
+	return hex.EncodeToString(p[:])
+}
+
+// String formats the payment ID as a hex string. Unlike keys, payment IDs do
+// not use a 0x prefix, as that is not the format used by Monero wallets.
+func (p PaymentID) String() string {
+	return p.Hex()
+}
+
+// EncryptPaymentID encrypts the payment ID of an integrated address for the
+// transaction's extra field. The derivation is the sender's derivation with
+// the recipient's view key, using the main transaction key. Encryption is an
+// XOR with a key stream, so it also decrypts.
+func (d *KeyDerivation) EncryptPaymentID(paymentID PaymentID) PaymentID {
+	const tail = 0x8d // ENCRYPTED_PAYMENT_ID_TAIL
+	key := ethcrypto.Keccak256(d.Bytes(), []byte{tail})
+	for i := range paymentID {
+		paymentID[i] ^= key[i]
+	}
+	return paymentID
+}
+
+// DecryptPaymentID decrypts a payment ID encrypted with EncryptPaymentID
+func (d *KeyDerivation) DecryptPaymentID(encrypted PaymentID) PaymentID {
+	return d.EncryptPaymentID(encrypted)
+}
@@ -11,6 +11,31 @@ type PublicKey struct {
 	key *ed25519.Point
 }
 
+// NewPublicKey returns a new PublicKey from the given 32-byte encoding of a
+// curve point.
+func NewPublicKey(b []byte) (*PublicKey, error) {
+	if len(b) != KeySize {
+		return nil, errInvalidInput
+	}
+
+	pk, err := new(ed25519.Point).SetBytes(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PublicKey{
+		key: pk,
+	}, nil
+}
+
+// Equal returns true if the public keys are identical, otherwise false.
+func (k *PublicKey) Equal(other *PublicKey) bool {
+	if other == nil {
+		return false
+	}
+	return k.key.Equal(other.key) == 1
+}
+
 // Bytes returns the canonical 32-byte, little-endian encoding of PublicKey.
 func (k *PublicKey) Bytes() []byte {
 	return k.key.Bytes()
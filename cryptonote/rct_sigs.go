@@ -0,0 +1,190 @@
+package cryptonote
+
+import (
+	"errors"
+	"fmt"
+
+	ed25519 "filippo.io/edwards25519"
+
+	"github.com/dimalinux/gopherphis/bpplus"
+	"github.com/dimalinux/gopherphis/mcrypto"
+	"github.com/dimalinux/gopherphis/serialization"
+)
+
+var (
+	errCLSAGInvalidScalar   = errors.New("CLSAG has a non-canonical scalar")
+	errCLSAGInvalidPoint    = errors.New("CLSAG has an invalid point")
+	errMLSAGInvalidScalar   = errors.New("MLSAG has a non-canonical scalar")
+	errBPPlusInvalidScalar  = errors.New("Bulletproofs+ proof has a non-canonical scalar")
+	errBPPlusInvalidPoint   = errors.New("Bulletproofs+ proof has an invalid point")
+	errNotBulletproofPlusTx = errors.New("transaction does not use Bulletproofs+")
+	errTxProofCount         = errors.New("Bulletproofs+ transactions must have exactly one range proof")
+)
+
+// inv8 is the inverse of the cofactor, 8, modulo the curve order
+var inv8 = new(ed25519.Scalar).Invert(mcrypto.ScalarFromUint64(8))
+
+// NewCLSAG converts a serialized CLSAG, decoded from a transaction
+func NewCLSAG(sig *serialization.CLSAG) (*mcrypto.CLSAG, error) {
+	c := &mcrypto.CLSAG{S: make([]*ed25519.Scalar, len(sig.S))}
+	var err error
+	for i := range sig.S {
+		if c.S[i], err = ed25519.NewScalar().SetCanonicalBytes(sig.S[i][:]); err != nil {
+			return nil, errCLSAGInvalidScalar
+		}
+	}
+	if c.C1, err = ed25519.NewScalar().SetCanonicalBytes(sig.C1[:]); err != nil {
+		return nil, errCLSAGInvalidScalar
+	}
+	if c.D, err = new(ed25519.Point).SetBytes(sig.D[:]); err != nil {
+		return nil, errCLSAGInvalidPoint
+	}
+	return c, nil
+}
+
+// SerializeCLSAG converts the signature to the form stored in transactions
+func SerializeCLSAG(c *mcrypto.CLSAG) *serialization.CLSAG {
+	sig := &serialization.CLSAG{
+		S:  make([][32]byte, len(c.S)),
+		C1: [32]byte(c.C1.Bytes()),
+		D:  [32]byte(c.D.Bytes()),
+	}
+	for i, s := range c.S {
+		sig.S[i] = [32]byte(s.Bytes())
+	}
+	return sig
+}
+
+// NewMLSAG converts a serialized MLSAG, decoded from a transaction
+func NewMLSAG(sig *serialization.MLSAG) (*mcrypto.MLSAG, error) {
+	m := &mcrypto.MLSAG{SS: make([][]*ed25519.Scalar, len(sig.SS))}
+	var err error
+	for i := range sig.SS {
+		m.SS[i] = make([]*ed25519.Scalar, len(sig.SS[i]))
+		for j := range sig.SS[i] {
+			if m.SS[i][j], err = ed25519.NewScalar().SetCanonicalBytes(sig.SS[i][j][:]); err != nil {
+				return nil, errMLSAGInvalidScalar
+			}
+		}
+	}
+	if m.CC, err = ed25519.NewScalar().SetCanonicalBytes(sig.CC[:]); err != nil {
+		return nil, errMLSAGInvalidScalar
+	}
+	return m, nil
+}
+
+// SerializeMLSAG converts the signature to the form stored in transactions
+func SerializeMLSAG(m *mcrypto.MLSAG) *serialization.MLSAG {
+	sig := &serialization.MLSAG{
+		SS: make([][][32]byte, len(m.SS)),
+		CC: [32]byte(m.CC.Bytes()),
+	}
+	for i := range m.SS {
+		sig.SS[i] = make([][32]byte, len(m.SS[i]))
+		for j, s := range m.SS[i] {
+			sig.SS[i][j] = [32]byte(s.Bytes())
+		}
+	}
+	return sig
+}
+
+// NewRangeSig converts a serialized Borromean range signature, decoded from a
+// transaction. Both types have the same layout, so no copy is made.
+func NewRangeSig(sig *serialization.RangeSig) *mcrypto.RangeSig {
+	return (*mcrypto.RangeSig)(sig)
+}
+
+// NewBulletproofPlus converts a serialized Bulletproofs+ proof, decoded from a
+// transaction, whose outputs have the passed amount commitments.
+func NewBulletproofPlus(sig *serialization.BulletproofPlus, commitments []*ed25519.Point) (*bpplus.Proof, error) {
+	p := &bpplus.Proof{V: make([]*ed25519.Point, len(commitments))}
+	for i, c := range commitments {
+		p.V[i] = new(ed25519.Point).ScalarMult(inv8, c)
+	}
+
+	var err error
+	for _, pair := range []struct {
+		dst **ed25519.Point
+		src [32]byte
+	}{{&p.A, sig.A}, {&p.A1, sig.A1}, {&p.B, sig.B}} {
+		if *pair.dst, err = new(ed25519.Point).SetBytes(pair.src[:]); err != nil {
+			return nil, errBPPlusInvalidPoint
+		}
+	}
+	for _, pair := range []struct {
+		dst **ed25519.Scalar
+		src [32]byte
+	}{{&p.R1, sig.R1}, {&p.S1, sig.S1}, {&p.D1, sig.D1}} {
+		if *pair.dst, err = ed25519.NewScalar().SetCanonicalBytes(pair.src[:]); err != nil {
+			return nil, errBPPlusInvalidScalar
+		}
+	}
+	if p.L, err = decodePoints(sig.L); err != nil {
+		return nil, err
+	}
+	if p.R, err = decodePoints(sig.R); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func decodePoints(keys [][32]byte) ([]*ed25519.Point, error) {
+	points := make([]*ed25519.Point, len(keys))
+	for i := range keys {
+		var err error
+		if points[i], err = new(ed25519.Point).SetBytes(keys[i][:]); err != nil {
+			return nil, errBPPlusInvalidPoint
+		}
+	}
+	return points, nil
+}
+
+func encodePoints(points []*ed25519.Point) [][32]byte {
+	keys := make([][32]byte, len(points))
+	for i, p := range points {
+		keys[i] = [32]byte(p.Bytes())
+	}
+	return keys
+}
+
+// SerializeBulletproofPlus converts the proof to the form stored in
+// transactions
+func SerializeBulletproofPlus(p *bpplus.Proof) *serialization.BulletproofPlus {
+	return &serialization.BulletproofPlus{
+		A:  [32]byte(p.A.Bytes()),
+		A1: [32]byte(p.A1.Bytes()),
+		B:  [32]byte(p.B.Bytes()),
+		R1: [32]byte(p.R1.Bytes()),
+		S1: [32]byte(p.S1.Bytes()),
+		D1: [32]byte(p.D1.Bytes()),
+		L:  encodePoints(p.L),
+		R:  encodePoints(p.R),
+	}
+}
+
+// VerifyBulletproofPlusTxs batch verifies the range proofs of Bulletproofs+
+// transactions. Each transaction's single proof covers all of its output
+// commitments. The transactions must not be pruned.
+func VerifyBulletproofPlusTxs(txs []*serialization.Transaction) error {
+	proofs := make([]*bpplus.Proof, 0, len(txs))
+	for i, tx := range txs {
+		if tx.Rct == nil || tx.Rct.Type != serialization.RctTypeBulletproofPlus || tx.Rct.Prunable == nil {
+			return fmt.Errorf("transaction %d: %w", i, errNotBulletproofPlusTx)
+		}
+		if len(tx.Rct.Prunable.BulletproofsPlus) != 1 {
+			return fmt.Errorf("transaction %d: %w", i, errTxProofCount)
+		}
+
+		commitments, err := decodePoints(tx.Rct.OutPk)
+		if err != nil {
+			return fmt.Errorf("transaction %d: %w", i, err)
+		}
+		proof, err := NewBulletproofPlus(tx.Rct.Prunable.BulletproofsPlus[0], commitments)
+		if err != nil {
+			return fmt.Errorf("transaction %d: %w", i, err)
+		}
+		proofs = append(proofs, proof)
+	}
+
+	return bpplus.BatchVerify(proofs)
+}
@@ -0,0 +1,227 @@
+package cryptonote
+
+import (
+	"testing"
+
+	ed25519 "filippo.io/edwards25519"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/bpplus"
+	"github.com/dimalinux/gopherphis/mcrypto"
+	"github.com/dimalinux/gopherphis/serialization"
+)
+
+// randomKeys returns n random canonical keys
+func randomKeys(n int) [][32]byte {
+	keys := make([][32]byte, n)
+	for i := range keys {
+		keys[i] = [32]byte(mcrypto.RandomScalar().Bytes())
+	}
+	return keys
+}
+
+func TestNewCLSAG(t *testing.T) {
+	message := ethcrypto.Keccak256([]byte("pre-mlsag hash"))
+	secretKey, secretMask, pseudoMask := mcrypto.RandomScalar(), mcrypto.RandomScalar(), mcrypto.RandomScalar()
+	ring := make([]*mcrypto.RingMember, 11)
+	for i := range ring {
+		ring[i] = &mcrypto.RingMember{
+			Dest:       new(ed25519.Point).ScalarBaseMult(mcrypto.RandomScalar()),
+			Commitment: mcrypto.Commit(mcrypto.RandomScalar(), 5),
+		}
+	}
+	const secretIndex = 4
+	ring[secretIndex] = &mcrypto.RingMember{
+		Dest:       new(ed25519.Point).ScalarBaseMult(secretKey),
+		Commitment: mcrypto.Commit(secretMask, 5),
+	}
+	pseudoOut := mcrypto.Commit(pseudoMask, 5)
+	keyImage := mcrypto.HashToEC(ring[secretIndex].Dest.Bytes())
+	keyImage.ScalarMult(secretKey, keyImage)
+	sig := mcrypto.SignCLSAG(message, ring, pseudoOut, secretKey, secretMask, pseudoMask, secretIndex)
+
+	decoded, err := NewCLSAG(SerializeCLSAG(sig))
+	require.NoError(t, err)
+	require.True(t, mcrypto.VerifyCLSAG(message, ring, pseudoOut, keyImage, decoded))
+
+	bad := SerializeCLSAG(sig)
+	bad.C1 = [32]byte{0: 0xff, 31: 0xff}
+	_, err = NewCLSAG(bad)
+	require.ErrorIs(t, err, errCLSAGInvalidScalar)
+
+	bad = SerializeCLSAG(sig)
+	bad.D = [32]byte{2} // not a valid point
+	_, err = NewCLSAG(bad)
+	require.ErrorIs(t, err, errCLSAGInvalidPoint)
+}
+
+func TestNewMLSAG(t *testing.T) {
+	sig := &serialization.MLSAG{SS: [][][32]byte{randomKeys(2), randomKeys(2), randomKeys(2)}, CC: randomKeys(1)[0]}
+	decoded, err := NewMLSAG(sig)
+	require.NoError(t, err)
+	require.Len(t, decoded.SS, 3)
+	require.Equal(t, sig, SerializeMLSAG(decoded))
+
+	sig.SS[1][1] = [32]byte{0: 0xff, 31: 0xff}
+	_, err = NewMLSAG(sig)
+	require.ErrorIs(t, err, errMLSAGInvalidScalar)
+}
+
+// proveRange returns a Bulletproofs+ proof of the amounts with random masks
+func proveRange(t *testing.T, amounts ...uint64) *bpplus.Proof {
+	masks := make([]*ed25519.Scalar, len(amounts))
+	for i := range masks {
+		masks[i] = mcrypto.RandomScalar()
+	}
+	proof, err := bpplus.Prove(amounts, masks)
+	require.NoError(t, err)
+	return proof
+}
+
+func TestNewBulletproofPlus(t *testing.T) {
+	proof := proveRange(t, 10, 20)
+	decoded, err := NewBulletproofPlus(SerializeBulletproofPlus(proof), proof.Commitments())
+	require.NoError(t, err)
+	require.True(t, bpplus.Verify(decoded))
+
+	sig := SerializeBulletproofPlus(proof)
+	sig.S1 = [32]byte{0: 0xff, 31: 0xff}
+	_, err = NewBulletproofPlus(sig, proof.Commitments())
+	require.ErrorIs(t, err, errBPPlusInvalidScalar)
+
+	sig = SerializeBulletproofPlus(proof)
+	sig.L[0] = [32]byte{2}
+	_, err = NewBulletproofPlus(sig, proof.Commitments())
+	require.ErrorIs(t, err, errBPPlusInvalidPoint)
+}
+
+func TestVerifyBulletproofPlusTxs(t *testing.T) {
+	proof := proveRange(t, 5, 6)
+	tx := &serialization.Transaction{
+		Prefix: serialization.TxPrefix{Version: serialization.TxVersion2},
+		Rct: &serialization.RctSignatures{
+			RctSigBase: serialization.RctSigBase{Type: serialization.RctTypeBulletproofPlus},
+			Prunable: &serialization.RctSigPrunable{
+				BulletproofsPlus: []*serialization.BulletproofPlus{SerializeBulletproofPlus(proof)},
+			},
+		},
+	}
+	for _, c := range proof.Commitments() {
+		tx.Rct.OutPk = append(tx.Rct.OutPk, [32]byte(c.Bytes()))
+	}
+	require.NoError(t, VerifyBulletproofPlusTxs([]*serialization.Transaction{tx}))
+
+	tx.Rct.OutPk[0], tx.Rct.OutPk[1] = tx.Rct.OutPk[1], tx.Rct.OutPk[0]
+	require.Error(t, VerifyBulletproofPlusTxs([]*serialization.Transaction{tx}))
+
+	tx.Rct.Prunable.BulletproofsPlus = nil
+	require.ErrorIs(t, VerifyBulletproofPlusTxs([]*serialization.Transaction{tx}), errTxProofCount)
+
+	tx.Rct.Type = serialization.RctTypeCLSAG
+	require.ErrorIs(t, VerifyBulletproofPlusTxs([]*serialization.Transaction{tx}), errNotBulletproofPlusTx)
+}
+
+// TestLegacyTransactions checks that the signatures of an RctTypeSimple
+// transaction and of a v1 transaction survive serialization round trips,
+// and that the messages they sign don't depend on them, as a chain auditor
+// needs.
+func TestLegacyTransactions(t *testing.T) {
+	const ringSize = 5
+
+	t.Run("RctTypeSimple", func(t *testing.T) {
+		rangeSig := new(serialization.RangeSig)
+		copy(rangeSig.S0[:], randomKeys(len(rangeSig.S0)))
+		copy(rangeSig.S1[:], randomKeys(len(rangeSig.S1)))
+		copy(rangeSig.Ci[:], randomKeys(len(rangeSig.Ci)))
+		rangeSig.EE = randomKeys(1)[0]
+		mlsag := &serialization.MLSAG{SS: make([][][32]byte, ringSize), CC: randomKeys(1)[0]}
+		for i := range mlsag.SS {
+			mlsag.SS[i] = randomKeys(2)
+		}
+
+		tx := &serialization.Transaction{
+			Prefix: serialization.TxPrefix{
+				Version: serialization.TxVersion2,
+				Inputs: []*serialization.TxInput{{
+					Type:       serialization.InputTypeToKey,
+					KeyOffsets: make([]uint64, ringSize),
+					KeyImage:   randomKeys(1)[0],
+				}},
+				Outputs: []*serialization.TxOutput{{Type: serialization.OutputTypeToKey}},
+			},
+			Rct: &serialization.RctSignatures{
+				RctSigBase: serialization.RctSigBase{
+					Type:       serialization.RctTypeSimple,
+					TxnFee:     10,
+					PseudoOuts: randomKeys(1),
+					EcdhInfo:   []*serialization.EcdhInfo{{}},
+					OutPk:      randomKeys(1),
+				},
+				Prunable: &serialization.RctSigPrunable{
+					RangeSigs: []*serialization.RangeSig{rangeSig},
+				},
+			},
+		}
+		message, err := tx.SignatureHash()
+		require.NoError(t, err)
+		tx.Rct.Prunable.MLSAGs = []*serialization.MLSAG{mlsag}
+
+		raw, err := tx.MarshalBinary()
+		require.NoError(t, err)
+		decoded, err := serialization.DecodeTransaction(raw, false)
+		require.NoError(t, err)
+		decodedMessage, err := decoded.SignatureHash()
+		require.NoError(t, err)
+		require.Equal(t, message, decodedMessage)
+
+		sig, err := NewMLSAG(decoded.Rct.Prunable.MLSAGs[0])
+		require.NoError(t, err)
+		require.Equal(t, mlsag, SerializeMLSAG(sig))
+		require.Equal(t, (*mcrypto.RangeSig)(rangeSig), NewRangeSig(decoded.Rct.Prunable.RangeSigs[0]))
+	})
+
+	t.Run("v1", func(t *testing.T) {
+		ring := make([]*ed25519.Point, ringSize)
+		for i := range ring {
+			ring[i] = new(ed25519.Point).ScalarBaseMult(mcrypto.RandomScalar())
+		}
+		secret := mcrypto.RandomScalar()
+		ring[0] = new(ed25519.Point).ScalarBaseMult(secret)
+		keyImage := mcrypto.HashToEC(ring[0].Bytes())
+		keyImage.ScalarMult(secret, keyImage)
+
+		tx := &serialization.Transaction{
+			Prefix: serialization.TxPrefix{
+				Version: serialization.TxVersion1,
+				Inputs: []*serialization.TxInput{{
+					Type:       serialization.InputTypeToKey,
+					Amount:     1000,
+					KeyOffsets: make([]uint64, ringSize),
+					KeyImage:   [32]byte(keyImage.Bytes()),
+				}},
+				Outputs: []*serialization.TxOutput{{Amount: 1000, Type: serialization.OutputTypeToKey}},
+			},
+		}
+		prefixHash, err := tx.PrefixHash()
+		require.NoError(t, err)
+		sig := mcrypto.GenerateRingSignature(prefixHash[:], keyImage, ring, secret, 0)
+		tx.Signatures = [][][serialization.V1SignatureLen]byte{make([][serialization.V1SignatureLen]byte, ringSize)}
+		for i := range tx.Signatures[0] {
+			copy(tx.Signatures[0][i][:], sig[i*mcrypto.RingSignatureElemLen:])
+		}
+
+		raw, err := tx.MarshalBinary()
+		require.NoError(t, err)
+		decoded, err := serialization.DecodeTransaction(raw, false)
+		require.NoError(t, err)
+		prefixHash, err = decoded.PrefixHash()
+		require.NoError(t, err)
+
+		var decodedSig []byte
+		for _, elem := range decoded.Signatures[0] {
+			decodedSig = append(decodedSig, elem[:]...)
+		}
+		require.True(t, mcrypto.CheckRingSignature(prefixHash[:], keyImage, ring, decodedSig))
+	})
+}
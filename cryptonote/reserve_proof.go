@@ -0,0 +1,484 @@
+package cryptonote
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	ed25519 "filippo.io/edwards25519"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/dimalinux/gopherphis/base58"
+	"github.com/dimalinux/gopherphis/mcrypto"
+)
+
+// Reserve proof headers. V1 proofs did not sign the address or key images and
+// are not supported.
+const (
+	reserveProofV1Header = "ReserveProofV1"
+	reserveProofV2Header = "ReserveProofV2"
+
+	// reserveProofEntryVersion is the version of wallet2's reserve_proof_entry
+	reserveProofEntryVersion = 0
+	// reserveProofPairSize prefixes each spend key and signature pair, the
+	// number of members that binary_archive writes for a std::pair
+	reserveProofPairSize = 2
+)
+
+var (
+	errInvalidReserveProofFormat = errors.New("invalid reserve proof format")
+	errUnsupportedReserveProof   = errors.New("V1 reserve proofs are not supported")
+	errInvalidReserveProof       = errors.New("invalid reserve proof")
+	errReserveProofSubaddress    = errors.New("reserve proofs must be checked against the primary address")
+	errReserveProofNoPrimary     = errors.New("reserve proof does not include the primary address")
+	errOutputNotInTx             = errors.New("output index not found in transaction")
+	errOutputNotOwned            = errors.New("output was not received by the subaddress")
+	errTxNotFound                = errors.New("reserve proof transaction not provided")
+)
+
+// OwnedOutput is an output received by the wallet
+type OwnedOutput struct {
+	// Tx is the transaction that created the output
+	Tx *TxInfo
+	// Index is the index of the output in the transaction
+	Index uint64
+	// AccountIndex and SubAddrIndex identify the subaddress that received the
+	// output. Both are zero for the primary address.
+	AccountIndex uint32
+	SubAddrIndex uint32
+}
+
+// ownedOutputKeys holds the keys of an output received by the wallet
+type ownedOutputKeys struct {
+	out *TxOutput
+	// txPubKey is the transaction public key that the output was created
+	// with, either the main key or the output's additional key.
+	txPubKey   *PublicKey
+	derivation *KeyDerivation
+	// spendKey is the private spend key of the receiving subaddress
+	spendKey   *PrivateSpendKey
+	oneTimeKey *OneTimePrivateKey
+}
+
+// ownedOutputKeys finds the transaction public key that the output was
+// created with, and derives the output's one-time private key.
+func (kp *PrivateKeyPair) ownedOutputKeys(o *OwnedOutput) (*ownedOutputKeys, error) {
+	out := o.Tx.output(o.Index)
+	if out == nil {
+		return nil, errOutputNotInTx
+	}
+	subAddrSpendKey := kp.SubAddrPubKeyPair(o.AccountIndex, o.SubAddrIndex).SpendKey()
+
+	for _, R := range o.Tx.txPubKeys(o.Index) {
+		d := kp.vk.KeyDerivation(R)
+		outSpendKey, ok := d.OutputSpendKey(out)
+		if ok && outSpendKey.Equal(subAddrSpendKey) {
+			spendKey := kp.subAddrSpendKey(o.AccountIndex, o.SubAddrIndex)
+			return &ownedOutputKeys{
+				out:        out,
+				txPubKey:   R,
+				derivation: d,
+				spendKey:   spendKey,
+				oneTimeKey: d.DeriveSecretKey(o.Index, spendKey),
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("output %d of tx %x: %w", o.Index, o.Tx.ID, errOutputNotOwned)
+}
+
+// ReserveProofOutput is an output whose ownership was proven by a reserve
+// proof. The proof does not show that the output is unspent; the caller has to
+// check that the key image is not spent on-chain.
+type ReserveProofOutput struct {
+	TxID     [32]byte
+	Index    uint64
+	KeyImage *KeyImage
+	Amount   uint64
+}
+
+// ReserveProof is a decoded ReserveProofV2
+type ReserveProof struct {
+	entries      []*reserveProofEntry
+	spendKeySigs []*reserveProofSpendKeySig
+}
+
+// reserveProofEntry is wallet2's reserve_proof_entry
+type reserveProofEntry struct {
+	txID            [32]byte
+	index           uint64
+	sharedSecret    *ed25519.Point // D = a*R, where R is the tx public key
+	keyImage        *KeyImage
+	sharedSecretSig []byte // tx proof that D = a*R, for the primary view key a*G
+	keyImageSig     []byte // ring signature with a ring size of 1
+}
+
+// reserveProofSpendKeySig is a signature by the spend key of a subaddress
+// that received one of the proof's outputs.
+type reserveProofSpendKeySig struct {
+	spendKey *PublicKey
+	sig      []byte
+}
+
+// output returns the output at index, or nil if the transaction has no such
+// output.
+func (tx *TxInfo) output(index uint64) *TxOutput {
+	for _, out := range tx.Outputs {
+		if out.Index == index {
+			return out
+		}
+	}
+	return nil
+}
+
+// txPubKeys returns the public keys that could have been used to create the
+// output at index: the main transaction public key and, if present, the
+// output's additional public key.
+func (tx *TxInfo) txPubKeys(index uint64) []*PublicKey {
+	pubKeys := []*PublicKey{tx.PubKey}
+	if index < uint64(len(tx.AdditionalPubKeys)) {
+		pubKeys = append(pubKeys, tx.AdditionalPubKeys[index])
+	}
+	return pubKeys
+}
+
+// reserveProofPrefixHash returns the hash signed by a reserve proof. It
+// commits to the message, the primary address and the key images of all the
+// outputs in the proof.
+func reserveProofPrefixHash(message []byte, primary *PublicKeyPair, keyImages []*KeyImage) []byte {
+	data := [][]byte{message, primary.sk.Bytes(), primary.vk.Bytes()}
+	for _, ki := range keyImages {
+		data = append(data, ki.Bytes())
+	}
+	return ethcrypto.Keccak256(data...)
+}
+
+// GenerateReserveProof returns a ReserveProofV2 proving that the wallet owns
+// the passed outputs. The proof does not reveal the wallet's secret keys, but
+// it does reveal the key images of the outputs, so anyone with the proof can
+// see when the outputs are spent. The optional message is signed with the
+// proof. The result is compatible with monero-wallet-cli's
+// check_reserve_proof command.
+func (kp *PrivateKeyPair) GenerateReserveProof(outputs []*OwnedOutput, message []byte) (string, error) {
+	primary := kp.PublicKeyPair()
+
+	// The prefix hash commits to all the key images, so the one-time keys are
+	// derived before signing.
+	entries := make([]*reserveProofEntry, 0, len(outputs))
+	oneTimeKeys := make([]*OneTimePrivateKey, 0, len(outputs))
+	txPubKeys := make([]*PublicKey, 0, len(outputs))
+	keyImages := make([]*KeyImage, 0, len(outputs))
+	// The primary address's spend key is always signed. Each subaddress that
+	// received an output is signed once.
+	spendKeys := []*PrivateSpendKey{kp.sk}
+
+	for _, o := range outputs {
+		keys, err := kp.ownedOutputKeys(o)
+		if err != nil {
+			return "", err
+		}
+		keyImage := keys.oneTimeKey.KeyImage()
+
+		entries = append(entries, &reserveProofEntry{
+			txID:         o.Tx.ID,
+			index:        o.Index,
+			sharedSecret: new(ed25519.Point).ScalarMult(kp.vk.key, keys.txPubKey.key),
+			keyImage:     keyImage,
+		})
+		oneTimeKeys = append(oneTimeKeys, keys.oneTimeKey)
+		txPubKeys = append(txPubKeys, keys.txPubKey)
+		keyImages = append(keyImages, keyImage)
+		spendKeys = appendUniqueSpendKey(spendKeys, keys.spendKey)
+	}
+
+	prefixHash := reserveProofPrefixHash(message, primary, keyImages)
+
+	for i, e := range entries {
+		e.sharedSecretSig = generateTxProof(prefixHash, primary.vk.key, txPubKeys[i].key, nil, e.sharedSecret, kp.vk.key)
+		ring := []*ed25519.Point{oneTimeKeys[i].Public().key}
+		e.keyImageSig = mcrypto.GenerateRingSignature(prefixHash, e.keyImage.key, ring, oneTimeKeys[i].key, 0)
+	}
+
+	proof := &ReserveProof{entries: entries}
+	for _, sk := range spendKeys {
+		pub := sk.Public()
+		proof.spendKeySigs = append(proof.spendKeySigs, &reserveProofSpendKeySig{
+			spendKey: pub,
+			sig:      generateSignature(prefixHash, pub.key, sk.key),
+		})
+	}
+
+	return proof.String(), nil
+}
+
+func appendUniqueSpendKey(keys []*PrivateSpendKey, key *PrivateSpendKey) []*PrivateSpendKey {
+	for _, k := range keys {
+		if k.key.Equal(key.key) == 1 {
+			return keys
+		}
+	}
+	return append(keys, key)
+}
+
+// String returns the proof as a ReserveProofV2 string. The binary layout is
+// wallet2's binary_archive serialization of the proof entries, each starting
+// with its VERSION_FIELD, followed by the map of subaddress spend keys to their
+// signatures. Like any std::pair, each key and signature pair of the map is
+// prefixed with its number of members, 2.
+func (p *ReserveProof) String() string {
+	var b []byte
+	b = binary.AppendUvarint(b, uint64(len(p.entries)))
+	for _, e := range p.entries {
+		b = binary.AppendUvarint(b, reserveProofEntryVersion)
+		b = append(b, e.txID[:]...)
+		b = binary.AppendUvarint(b, e.index)
+		b = append(b, e.sharedSecret.Bytes()...)
+		b = append(b, e.keyImage.Bytes()...)
+		b = append(b, e.sharedSecretSig...)
+		b = append(b, e.keyImageSig...)
+	}
+
+	b = binary.AppendUvarint(b, uint64(len(p.spendKeySigs)))
+	for _, s := range p.spendKeySigs {
+		b = binary.AppendUvarint(b, reserveProofPairSize)
+		b = append(b, s.spendKey.Bytes()...)
+		b = append(b, s.sig...)
+	}
+
+	return reserveProofV2Header + base58.Encode(b)
+}
+
+// ParseReserveProof decodes a ReserveProofV2 without verifying it. Use TxIDs
+// to find the transactions needed by Verify.
+func ParseReserveProof(proof string) (*ReserveProof, error) {
+	switch {
+	case strings.HasPrefix(proof, reserveProofV2Header):
+	case strings.HasPrefix(proof, reserveProofV1Header):
+		return nil, errUnsupportedReserveProof
+	default:
+		return nil, errInvalidReserveProofFormat
+	}
+
+	b, err := base58.Decode(proof[len(reserveProofV2Header):])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidReserveProofFormat, err)
+	}
+	r := &proofReader{b: b}
+	p := new(ReserveProof)
+
+	numEntries := r.readUvarint()
+	for i := uint64(0); i < numEntries && r.err == nil; i++ {
+		if version := r.readUvarint(); r.err == nil && version != reserveProofEntryVersion {
+			r.err = fmt.Errorf("unsupported entry version %d", version)
+			break
+		}
+		e := new(reserveProofEntry)
+		copy(e.txID[:], r.readBytes(32))
+		e.index = r.readUvarint()
+		e.sharedSecret = r.readPoint()
+		if ki := r.readPoint(); ki != nil {
+			e.keyImage = &KeyImage{key: ki}
+		}
+		e.sharedSecretSig = r.readBytes(SignatureLen)
+		e.keyImageSig = r.readBytes(SignatureLen)
+		p.entries = append(p.entries, e)
+	}
+
+	numSpendKeys := r.readUvarint()
+	for i := uint64(0); i < numSpendKeys && r.err == nil; i++ {
+		if size := r.readUvarint(); r.err == nil && size != reserveProofPairSize {
+			r.err = fmt.Errorf("spend key signature pair has %d members", size)
+			break
+		}
+		s := new(reserveProofSpendKeySig)
+		if pub := r.readPoint(); pub != nil {
+			s.spendKey = &PublicKey{key: pub}
+		}
+		s.sig = r.readBytes(SignatureLen)
+		p.spendKeySigs = append(p.spendKeySigs, s)
+	}
+
+	if r.err == nil && len(r.b) != 0 {
+		r.err = errors.New("trailing data")
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidReserveProofFormat, r.err)
+	}
+
+	return p, nil
+}
+
+// TxIDs returns the IDs of the transactions that created the proof's outputs
+func (p *ReserveProof) TxIDs() [][32]byte {
+	txIDs := make([][32]byte, 0, len(p.entries))
+	for _, e := range p.entries {
+		txIDs = append(txIDs, e.txID)
+	}
+	return txIDs
+}
+
+// Verify checks the reserve proof against the primary address of the wallet
+// that created it and the transactions listed by TxIDs. On success, the
+// proven outputs are returned. Check their key images to determine which
+// outputs are still unspent.
+func (p *ReserveProof) Verify(addr *Address, message []byte, txs []*TxInfo) ([]*ReserveProofOutput, error) {
+	if addr.Type() == Subaddress {
+		return nil, errReserveProofSubaddress
+	}
+	primary, err := addr.PublicKeyPair()
+	if err != nil {
+		return nil, err
+	}
+
+	keyImages := make([]*KeyImage, 0, len(p.entries))
+	for _, e := range p.entries {
+		keyImages = append(keyImages, e.keyImage)
+	}
+	prefixHash := reserveProofPrefixHash(message, primary, keyImages)
+
+	var hasPrimary bool
+	for _, s := range p.spendKeySigs {
+		if !checkSignature(prefixHash, s.spendKey.key, s.sig) {
+			return nil, errInvalidReserveProof
+		}
+		hasPrimary = hasPrimary || s.spendKey.Equal(primary.sk)
+	}
+	if !hasPrimary {
+		return nil, errReserveProofNoPrimary
+	}
+
+	results := make([]*ReserveProofOutput, 0, len(p.entries))
+	for _, e := range p.entries {
+		amount, err := p.verifyEntry(e, primary, prefixHash, txs)
+		if err != nil {
+			return nil, fmt.Errorf("output %d of tx %x: %w", e.index, e.txID, err)
+		}
+		results = append(results, &ReserveProofOutput{
+			TxID:     e.txID,
+			Index:    e.index,
+			KeyImage: e.keyImage,
+			Amount:   amount,
+		})
+	}
+
+	return results, nil
+}
+
+// verifyEntry checks a single proof entry and returns the amount of the output
+func (p *ReserveProof) verifyEntry(
+	e *reserveProofEntry,
+	primary *PublicKeyPair,
+	prefixHash []byte,
+	txs []*TxInfo,
+) (uint64, error) {
+	var tx *TxInfo
+	for _, t := range txs {
+		if t.ID == e.txID {
+			tx = t
+			break
+		}
+	}
+	if tx == nil || tx.PubKey == nil {
+		return 0, errTxNotFound
+	}
+	out := tx.output(e.index)
+	if out == nil {
+		return 0, errOutputNotInTx
+	}
+
+	// the shared secret is a*R, for the tx public key or the output's
+	// additional public key
+	validSharedSecret := false
+	for _, R := range tx.txPubKeys(e.index) {
+		if checkTxProof(prefixHash, primary.vk.key, R.key, nil, e.sharedSecret, e.sharedSecretSig) {
+			validSharedSecret = true
+			break
+		}
+	}
+	if !validSharedSecret {
+		return 0, errInvalidReserveProof
+	}
+
+	ring := []*ed25519.Point{out.Key.key}
+	if !mcrypto.CheckRingSignature(prefixHash, e.keyImage.key, ring, e.keyImageSig) {
+		return 0, errInvalidReserveProof
+	}
+
+	// the output must belong to one of the signed subaddress spend keys
+	derivation := &KeyDerivation{key: new(ed25519.Point).MultByCofactor(e.sharedSecret)}
+	outSpendKey, ok := derivation.OutputSpendKey(out)
+	if !ok {
+		return 0, errOutputNotOwned
+	}
+	signedSpendKey := false
+	for _, s := range p.spendKeySigs {
+		if s.spendKey.Equal(outSpendKey) {
+			signedSpendKey = true
+			break
+		}
+	}
+	if !signedSpendKey {
+		return 0, errOutputNotOwned
+	}
+
+	amount, _, err := derivation.OutputAmount(out)
+	return amount, err
+}
+
+// CheckReserveProof parses and verifies a ReserveProofV2, created by
+// monero-wallet-cli's get_reserve_proof command or by GenerateReserveProof.
+// See ReserveProof.Verify.
+func CheckReserveProof(addr *Address, message []byte, proof string, txs []*TxInfo) ([]*ReserveProofOutput, error) {
+	p, err := ParseReserveProof(proof)
+	if err != nil {
+		return nil, err
+	}
+	return p.Verify(addr, message, txs)
+}
+
+// proofReader reads the binary fields of a proof. After the first error, all
+// reads return zero values and the error is kept in err.
+type proofReader struct {
+	b   []byte
+	err error
+}
+
+func (r *proofReader) readBytes(n int) []byte {
+	if r.err != nil {
+		return make([]byte, n)
+	}
+	if len(r.b) < n {
+		r.err = errors.New("unexpected end of data")
+		return make([]byte, n)
+	}
+	data := r.b[:n]
+	r.b = r.b[n:]
+	return data
+}
+
+func (r *proofReader) readUvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.err = errors.New("invalid varint")
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *proofReader) readPoint() *ed25519.Point {
+	b := r.readBytes(KeySize)
+	if r.err != nil {
+		return nil
+	}
+	p, err := new(ed25519.Point).SetBytes(b)
+	if err != nil {
+		r.err = err
+		return nil
+	}
+	return p
+}
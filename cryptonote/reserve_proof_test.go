@@ -0,0 +1,103 @@
+package cryptonote
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/base58"
+)
+
+func TestReserveProof(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	tx, _, _ := newTestTx(t, kp)
+	msg := []byte("proof of reserves 2026-Q3")
+	outputs := []*OwnedOutput{
+		{Tx: tx, Index: 0},
+		{Tx: tx, Index: 1, AccountIndex: 1, SubAddrIndex: 2},
+	}
+
+	proof, err := kp.GenerateReserveProof(outputs, msg)
+	require.NoError(t, err)
+
+	parsed, err := ParseReserveProof(proof)
+	require.NoError(t, err)
+	require.Equal(t, proof, parsed.String())
+	require.Equal(t, [][32]byte{tx.ID, tx.ID}, parsed.TxIDs())
+
+	addr := kp.PublicKeyPair().Address(Mainnet)
+	results, err := CheckReserveProof(addr, msg, proof, []*TxInfo{tx})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, uint64(1000), results[0].Amount)
+	require.Equal(t, uint64(2000), results[1].Amount)
+	require.Equal(t, uint64(1), results[1].Index)
+
+	// the key images let the verifier check whether the outputs are spent
+	oneTimeKey, ok := kp.ScanOutput(tx.PubKey, tx.Outputs[0])
+	require.True(t, ok)
+	require.True(t, oneTimeKey.KeyImage().Equal(results[0].KeyImage))
+
+	_, err = CheckReserveProof(addr, []byte("other message"), proof, []*TxInfo{tx})
+	require.ErrorIs(t, err, errInvalidReserveProof)
+
+	otherKeys, err := GenerateKeys()
+	require.NoError(t, err)
+	_, err = CheckReserveProof(otherKeys.PublicKeyPair().Address(Mainnet), msg, proof, []*TxInfo{tx})
+	require.Error(t, err)
+
+	subAddr := kp.SubAddrPubKeyPair(1, 2).Address(Mainnet)
+	_, err = CheckReserveProof(subAddr, msg, proof, []*TxInfo{tx})
+	require.ErrorIs(t, err, errReserveProofSubaddress)
+
+	_, err = CheckReserveProof(addr, msg, proof, nil)
+	require.ErrorIs(t, err, errTxNotFound)
+}
+
+func TestGenerateReserveProof_notOwned(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	tx, _, _ := newTestTx(t, kp)
+
+	// output 1 was sent to subaddress (1, 2), not (1, 3)
+	_, err = kp.GenerateReserveProof([]*OwnedOutput{{Tx: tx, Index: 1, AccountIndex: 1, SubAddrIndex: 3}}, nil)
+	require.ErrorIs(t, err, errOutputNotOwned)
+
+	_, err = kp.GenerateReserveProof([]*OwnedOutput{{Tx: tx, Index: 2}}, nil)
+	require.ErrorIs(t, err, errOutputNotInTx)
+}
+
+func TestParseReserveProof_invalid(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	tx, _, _ := newTestTx(t, kp)
+	proof, err := kp.GenerateReserveProof([]*OwnedOutput{{Tx: tx, Index: 0}}, nil)
+	require.NoError(t, err)
+
+	_, err = ParseReserveProof("ReserveProofV1" + proof[len(reserveProofV2Header):])
+	require.ErrorIs(t, err, errUnsupportedReserveProof)
+
+	for _, badProof := range []string{"", "ReserveProofV3", proof[:len(proof)-11], proof + "11"} {
+		_, err = ParseReserveProof(badProof)
+		require.ErrorIs(t, err, errInvalidReserveProofFormat)
+	}
+
+	// the entry, after the number of entries, starts with its version
+	b, err := base58.Decode(proof[len(reserveProofV2Header):])
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, reserveProofEntryVersion}, b[:2])
+	badVersion := append([]byte(nil), b...)
+	badVersion[1] = reserveProofEntryVersion + 1
+	_, err = ParseReserveProof(reserveProofV2Header + base58.Encode(badVersion))
+	require.ErrorIs(t, err, errInvalidReserveProofFormat)
+
+	// the spend key signature pair, after the entry and the number of pairs,
+	// starts with its size
+	const pairPos = 2 + 32 + 1 + 32 + 32 + 2*SignatureLen + 1
+	require.Equal(t, []byte{1, reserveProofPairSize}, b[pairPos-1:pairPos+1])
+	require.Len(t, b, pairPos+1+KeySize+SignatureLen)
+	b[pairPos] = reserveProofPairSize + 1
+	_, err = ParseReserveProof(reserveProofV2Header + base58.Encode(b))
+	require.ErrorIs(t, err, errInvalidReserveProofFormat)
+}
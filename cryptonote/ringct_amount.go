@@ -0,0 +1,216 @@
+package cryptonote
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+
+	ed25519 "filippo.io/edwards25519"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/dimalinux/gopherphis/mcrypto"
+)
+
+// EncryptedAmountLen is the length, in bytes, of the compact encrypted amount
+// used by RingCT outputs since Bulletproofs v2.
+const EncryptedAmountLen = 8
+
+var (
+	errInvalidEncAmountLen = errors.New("encrypted amount is not 8 or 64 bytes")
+	errAmountOverflow      = errors.New("decrypted amount does not fit in 64 bits")
+	errCommitmentMismatch  = errors.New("decrypted amount does not match the output commitment")
+)
+
+// Commitment is the Pedersen commitment, mask*G + amount*H, to the amount of a
+// RingCT output.
+type Commitment struct {
+	key *ed25519.Point
+}
+
+// NewCommitment returns a new Commitment from its 32-byte encoding.
+func NewCommitment(b []byte) (*Commitment, error) {
+	if len(b) != KeySize {
+		return nil, errInvalidInput
+	}
+
+	c, err := new(ed25519.Point).SetBytes(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Commitment{
+		key: c,
+	}, nil
+}
+
+// Commit returns the commitment, mask*G + amount*H, to the amount.
+func Commit(mask *ed25519.Scalar, amount uint64) *Commitment {
+	return &Commitment{key: mcrypto.Commit(mask, amount)}
+}
+
+// Verify returns true if the commitment opens to the passed mask and amount.
+func (c *Commitment) Verify(mask *ed25519.Scalar, amount uint64) bool {
+	return c.key.Equal(mcrypto.Commit(mask, amount)) == 1
+}
+
+// Equal returns true if the commitments are identical, otherwise false.
+func (c *Commitment) Equal(other *Commitment) bool {
+	if other == nil {
+		return false
+	}
+	return c.key.Equal(other.key) == 1
+}
+
+// Bytes returns the canonical 32-byte encoding of the commitment.
+func (c *Commitment) Bytes() []byte {
+	return c.key.Bytes()
+}
+
+// Hex formats the commitment as a hex string
+func (c *Commitment) Hex() string {
+	return hex.EncodeToString(c.key.Bytes())
+}
+
+// String formats the commitment as a 0x-prefixed hex string
+func (c *Commitment) String() string {
+	return "0x" + c.Hex()
+}
+
+// CommitmentMask returns the commitment mask of the output at outputIndex, as
+// used by outputs with compact encrypted amounts. This is Monero's
+// genCommitmentMask.
+func (d *KeyDerivation) CommitmentMask(outputIndex uint64) *ed25519.Scalar {
+	const salt = "commitment_mask"
+	return mcrypto.HashToScalar([]byte(salt), d.scalar(outputIndex).Bytes())
+}
+
+// amountKey returns the key stream that the compact amount of the output at
+// outputIndex is XOR'd with.
+func (d *KeyDerivation) amountKey(outputIndex uint64) []byte {
+	const salt = "amount"
+	return ethcrypto.Keccak256([]byte(salt), d.scalar(outputIndex).Bytes())[:EncryptedAmountLen]
+}
+
+// EncryptAmount returns the compact encrypted amount of the output at
+// outputIndex. The commitment mask for the output is given by CommitmentMask.
+func (d *KeyDerivation) EncryptAmount(outputIndex uint64, amount uint64) [EncryptedAmountLen]byte {
+	var encAmount [EncryptedAmountLen]byte
+	binary.LittleEndian.PutUint64(encAmount[:], amount)
+	for i, k := range d.amountKey(outputIndex) {
+		encAmount[i] ^= k
+	}
+	return encAmount
+}
+
+// DecryptAmount decrypts the compact encrypted amount of the output at
+// outputIndex and verifies that it matches the output's on-chain
+// commitment. On success, the amount and the commitment mask are returned.
+// The compact scheme is used by all RingCT outputs since Bulletproofs v2.
+func (d *KeyDerivation) DecryptAmount(
+	outputIndex uint64,
+	encAmount [EncryptedAmountLen]byte,
+	commitment *Commitment,
+) (uint64, *ed25519.Scalar, error) {
+	for i, k := range d.amountKey(outputIndex) {
+		encAmount[i] ^= k
+	}
+	amount := binary.LittleEndian.Uint64(encAmount[:])
+	mask := d.CommitmentMask(outputIndex)
+
+	if !commitment.Verify(mask, amount) {
+		return 0, nil, errCommitmentMismatch
+	}
+
+	return amount, mask, nil
+}
+
+// v1AmountKeys returns the two scalars that the mask and amount of the output
+// at outputIndex are offset by in the original RingCT ECDH scheme.
+func (d *KeyDerivation) v1AmountKeys(outputIndex uint64) (*ed25519.Scalar, *ed25519.Scalar) {
+	maskKey := mcrypto.HashToScalar(d.scalar(outputIndex).Bytes())
+	amountKey := mcrypto.HashToScalar(maskKey.Bytes())
+	return maskKey, amountKey
+}
+
+// EncryptAmountV1 returns the encrypted mask and amount of the output at
+// outputIndex using the original RingCT ECDH scheme. The scheme has not
+// been used for new transactions since Bulletproofs v2.
+func (d *KeyDerivation) EncryptAmountV1(
+	outputIndex uint64,
+	mask *ed25519.Scalar,
+	amount uint64,
+) (encMask [KeySize]byte, encAmount [KeySize]byte) {
+	maskKey, amountKey := d.v1AmountKeys(outputIndex)
+	copy(encMask[:], new(ed25519.Scalar).Add(mask, maskKey).Bytes())
+	copy(encAmount[:], amountKey.Add(mcrypto.ScalarFromUint64(amount), amountKey).Bytes())
+	return encMask, encAmount
+}
+
+// DecryptAmountV1 decrypts the mask and amount of the output at outputIndex
+// that were encrypted with the original RingCT ECDH scheme, and verifies that
+// they match the output's on-chain commitment. On success, the amount and the
+// commitment mask are returned.
+func (d *KeyDerivation) DecryptAmountV1(
+	outputIndex uint64,
+	encMask [KeySize]byte,
+	encAmount [KeySize]byte,
+	commitment *Commitment,
+) (uint64, *ed25519.Scalar, error) {
+	maskKey, amountKey := d.v1AmountKeys(outputIndex)
+
+	// Like Monero, we reduce the encrypted values instead of rejecting
+	// non-canonical encodings.
+	mask := reduceScalar(encMask[:])
+	mask.Subtract(mask, maskKey)
+	amountScalar := reduceScalar(encAmount[:])
+	amountBytes := amountScalar.Subtract(amountScalar, amountKey).Bytes()
+
+	for _, b := range amountBytes[8:] {
+		if b != 0 {
+			return 0, nil, errAmountOverflow
+		}
+	}
+	amount := binary.LittleEndian.Uint64(amountBytes)
+
+	if !commitment.Verify(mask, amount) {
+		return 0, nil, errCommitmentMismatch
+	}
+
+	return amount, mask, nil
+}
+
+// OutputAmount returns the amount and commitment mask of the output at
+// out.Index. RingCT amounts are decrypted and verified against the output's
+// commitment. For outputs with a cleartext amount, the mask is one, as used
+// by Monero's zeroCommit.
+func (d *KeyDerivation) OutputAmount(out *TxOutput) (uint64, *ed25519.Scalar, error) {
+	if out.Commitment == nil {
+		return out.Amount, mcrypto.ScalarFromUint64(1), nil
+	}
+
+	switch len(out.EncryptedAmount) {
+	case EncryptedAmountLen:
+		return d.DecryptAmount(out.Index, [EncryptedAmountLen]byte(out.EncryptedAmount), out.Commitment)
+	case 2 * KeySize:
+		return d.DecryptAmountV1(
+			out.Index,
+			[KeySize]byte(out.EncryptedAmount[:KeySize]),
+			[KeySize]byte(out.EncryptedAmount[KeySize:]),
+			out.Commitment,
+		)
+	default:
+		return 0, nil, errInvalidEncAmountLen
+	}
+}
+
+// reduceScalar returns the 32 little-endian bytes reduced modulo the curve
+// order.
+func reduceScalar(b []byte) *ed25519.Scalar {
+	var wide [64]byte
+	copy(wide[:], b)
+	s, err := ed25519.NewScalar().SetUniformBytes(wide[:])
+	if err != nil {
+		panic(err) // only possible if the input length is not 64
+	}
+	return s
+}
@@ -0,0 +1,87 @@
+package cryptonote
+
+import (
+	"math"
+	"testing"
+
+	ed25519 "filippo.io/edwards25519"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/mcrypto"
+)
+
+// newTestDerivations returns the sender's and recipient's view of the same
+// derivation
+func newTestDerivations(t *testing.T) (*KeyDerivation, *KeyDerivation) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	r, txPubKey := newTestTxKeys(t)
+	return newKeyDerivation(kp.vk.Public().key, r), kp.PrivateViewKey().KeyDerivation(txPubKey)
+}
+
+func TestKeyDerivation_DecryptAmount(t *testing.T) {
+	senderDerivation, recipientDerivation := newTestDerivations(t)
+
+	for _, amount := range []uint64{0, 1, 1e12, math.MaxUint64} {
+		const outputIndex = 1
+		encAmount := senderDerivation.EncryptAmount(outputIndex, amount)
+		commitment := Commit(senderDerivation.CommitmentMask(outputIndex), amount)
+
+		decAmount, mask, err := recipientDerivation.DecryptAmount(outputIndex, encAmount, commitment)
+		require.NoError(t, err)
+		require.Equal(t, amount, decAmount)
+		require.True(t, commitment.Verify(mask, amount))
+
+		// wrong output index
+		_, _, err = recipientDerivation.DecryptAmount(outputIndex+1, encAmount, commitment)
+		require.ErrorIs(t, err, errCommitmentMismatch)
+	}
+}
+
+func TestKeyDerivation_DecryptAmount_commitmentMismatch(t *testing.T) {
+	senderDerivation, recipientDerivation := newTestDerivations(t)
+
+	// The sender commits to a different amount than the one encrypted
+	encAmount := senderDerivation.EncryptAmount(0, 100)
+	commitment := Commit(senderDerivation.CommitmentMask(0), 1000)
+	_, _, err := recipientDerivation.DecryptAmount(0, encAmount, commitment)
+	require.ErrorIs(t, err, errCommitmentMismatch)
+}
+
+func TestKeyDerivation_DecryptAmountV1(t *testing.T) {
+	senderDerivation, recipientDerivation := newTestDerivations(t)
+
+	const outputIndex = 0
+	const amount = 123456789
+	mask := mcrypto.HashToScalar([]byte("random mask"))
+	encMask, encAmount := senderDerivation.EncryptAmountV1(outputIndex, mask, amount)
+	commitment := Commit(mask, amount)
+
+	decAmount, decMask, err := recipientDerivation.DecryptAmountV1(outputIndex, encMask, encAmount, commitment)
+	require.NoError(t, err)
+	require.Equal(t, uint64(amount), decAmount)
+	require.Equal(t, 1, mask.Equal(decMask))
+
+	_, _, err = recipientDerivation.DecryptAmountV1(outputIndex+1, encMask, encAmount, commitment)
+	require.ErrorIs(t, err, errAmountOverflow)
+
+	_, _, err = recipientDerivation.DecryptAmountV1(outputIndex, encMask, encAmount, Commit(mask, amount+1))
+	require.ErrorIs(t, err, errCommitmentMismatch)
+}
+
+func TestCommitment(t *testing.T) {
+	mask := mcrypto.HashToScalar([]byte("mask"))
+	c := Commit(mask, 5)
+	require.True(t, c.Verify(mask, 5))
+	require.False(t, c.Verify(mask, 6))
+	require.False(t, c.Verify(ed25519.NewScalar(), 5))
+
+	c2, err := NewCommitment(c.Bytes())
+	require.NoError(t, err)
+	require.True(t, c.Equal(c2))
+	require.False(t, c.Equal(nil))
+	require.Equal(t, "0x"+c.Hex(), c2.String())
+
+	_, err = NewCommitment(c.Bytes()[1:])
+	require.ErrorIs(t, err, errInvalidInput)
+}
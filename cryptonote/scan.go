@@ -0,0 +1,62 @@
+package cryptonote
+
+// ReceivedOutput is an output found by SubaddressTable.ScanTx, with its
+// decrypted amount
+type ReceivedOutput struct {
+	OwnedOutput
+	Amount uint64
+}
+
+// ScanTx returns the outputs of the transaction received by the subaddresses
+// in the table, in output order. Each output is checked against the main
+// transaction public key and, if present, its additional public key. Outputs
+// whose amount doesn't match their commitment can't be spent, so they are
+// skipped.
+func (t *SubaddressTable) ScanTx(tx *TxInfo) []*ReceivedOutput {
+	// the main derivation is shared by all the outputs
+	var mainDerivation *KeyDerivation
+	if tx.PubKey != nil {
+		mainDerivation = t.viewKey.KeyDerivation(tx.PubKey)
+	}
+
+	var received []*ReceivedOutput
+	for _, out := range tx.Outputs {
+		derivations := []*KeyDerivation{mainDerivation}
+		if out.Index < uint64(len(tx.AdditionalPubKeys)) && tx.AdditionalPubKeys[out.Index] != nil {
+			derivations = append(derivations, t.viewKey.KeyDerivation(tx.AdditionalPubKeys[out.Index]))
+		}
+		for _, derivation := range derivations {
+			if derivation == nil {
+				continue
+			}
+			spendKey, ok := derivation.OutputSpendKey(out)
+			if !ok {
+				continue
+			}
+			account, index, ok := t.Lookup(spendKey)
+			if !ok {
+				continue
+			}
+			amount, _, err := derivation.OutputAmount(out)
+			if err != nil {
+				continue
+			}
+			received = append(received, &ReceivedOutput{
+				OwnedOutput: OwnedOutput{Tx: tx, Index: out.Index, AccountIndex: account, SubAddrIndex: index},
+				Amount:      amount,
+			})
+			break
+		}
+	}
+	return received
+}
+
+// OutputKeyImage returns the key image of an output received by the wallet,
+// which identifies the output when it is spent
+func (kp *PrivateKeyPair) OutputKeyImage(o *OwnedOutput) (*KeyImage, error) {
+	keys, err := kp.ownedOutputKeys(o)
+	if err != nil {
+		return nil, err
+	}
+	return keys.oneTimeKey.KeyImage(), nil
+}
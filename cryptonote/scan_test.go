@@ -0,0 +1,43 @@
+package cryptonote
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubaddressTable_ScanTx(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	tx, _, _ := newTestTx(t, kp)
+
+	received := kp.SubaddressTable().ScanTx(tx)
+	require.Len(t, received, 2)
+	require.Equal(t, uint64(0), received[0].Index)
+	require.Equal(t, uint64(1000), received[0].Amount)
+	require.Equal(t, uint32(0), received[0].AccountIndex)
+	require.Equal(t, uint64(1), received[1].Index)
+	require.Equal(t, uint64(2000), received[1].Amount)
+	require.Equal(t, uint32(1), received[1].AccountIndex)
+	require.Equal(t, uint32(2), received[1].SubAddrIndex)
+
+	// the key images match those of the one-time keys of the outputs
+	keyImage, err := kp.OutputKeyImage(&received[0].OwnedOutput)
+	require.NoError(t, err)
+	oneTimeKey := kp.PrivateViewKey().KeyDerivation(tx.PubKey).DeriveSecretKey(0, kp.SpendKey())
+	require.True(t, keyImage.Equal(oneTimeKey.KeyImage()))
+	_, err = kp.OutputKeyImage(&received[1].OwnedOutput)
+	require.NoError(t, err)
+
+	// a tampered amount is skipped
+	tx.Outputs[0].EncryptedAmount[0]++
+	received = kp.SubaddressTable().ScanTx(tx)
+	require.Len(t, received, 1)
+	require.Equal(t, uint64(1), received[0].Index)
+
+	other, err := GenerateKeys()
+	require.NoError(t, err)
+	require.Empty(t, other.SubaddressTable().ScanTx(tx))
+	_, err = other.OutputKeyImage(&received[0].OwnedOutput)
+	require.ErrorIs(t, err, errOutputNotOwned)
+}
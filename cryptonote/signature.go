@@ -0,0 +1,50 @@
+package cryptonote
+
+import (
+	ed25519 "filippo.io/edwards25519"
+
+	"github.com/dimalinux/gopherphis/mcrypto"
+)
+
+// SignatureLen is the length, in bytes, of a Schnorr signature (c || r)
+const SignatureLen = 64
+
+// generateSignature is Monero's generate_signature. It returns the Schnorr
+// signature, c || r, of the 32-byte hash with the secret key of pub, where
+// c = Hs(hash || pub || k*G) and r = k - c*secret.
+func generateSignature(hash []byte, pub *ed25519.Point, secret *ed25519.Scalar) []byte {
+	k := mcrypto.RandomScalar()
+	kG := new(ed25519.Point).ScalarBaseMult(k)
+	c := mcrypto.HashToScalar(hash, pub.Bytes(), kG.Bytes())
+	r := new(ed25519.Scalar).Multiply(c, secret)
+	r.Subtract(k, r)
+
+	sig := make([]byte, 0, SignatureLen)
+	sig = append(sig, c.Bytes()...)
+	return append(sig, r.Bytes()...)
+}
+
+// checkSignature is Monero's check_signature. It returns true if sig is a
+// valid signature of the 32-byte hash for the public key pub.
+func checkSignature(hash []byte, pub *ed25519.Point, sig []byte) bool {
+	if len(sig) != SignatureLen {
+		return false
+	}
+
+	c, err := ed25519.NewScalar().SetCanonicalBytes(sig[:32])
+	if err != nil || c.Equal(ed25519.NewScalar()) == 1 {
+		return false
+	}
+	r, err := ed25519.NewScalar().SetCanonicalBytes(sig[32:])
+	if err != nil {
+		return false
+	}
+
+	// k*G = c*pub + r*G
+	kG := new(ed25519.Point).VarTimeDoubleScalarBaseMult(c, pub, r)
+	if kG.Equal(ed25519.NewIdentityPoint()) == 1 {
+		return false
+	}
+
+	return mcrypto.HashToScalar(hash, pub.Bytes(), kG.Bytes()).Equal(c) == 1
+}
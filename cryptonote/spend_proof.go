@@ -0,0 +1,122 @@
+package cryptonote
+
+import (
+	"errors"
+	"strings"
+
+	ed25519 "filippo.io/edwards25519"
+
+	"github.com/dimalinux/gopherphis/base58"
+	"github.com/dimalinux/gopherphis/mcrypto"
+)
+
+const spendProofV1Header = "SpendProofV1"
+
+var (
+	errInvalidSpendProofFormat = errors.New("invalid spend proof format")
+	errInvalidSpendProof       = errors.New("invalid spend proof")
+	errSpendProofKeyCount      = errors.New("number of one-time keys does not match the number of inputs")
+	errKeyImageMismatch        = errors.New("one-time key does not match the input's key image")
+	errKeyNotInRing            = errors.New("one-time key is not a member of the input's ring")
+)
+
+// SpendProofInput is a transaction input covered by a spend proof
+type SpendProofInput struct {
+	// KeyImage is the key image of the input
+	KeyImage *KeyImage
+	// Ring holds the one-time public keys of the input's ring members, in the
+	// same order as the input's key offsets.
+	Ring []*PublicKey
+}
+
+func (in *SpendProofInput) ringPoints() []*ed25519.Point {
+	ring := make([]*ed25519.Point, 0, len(in.Ring))
+	for _, pub := range in.Ring {
+		ring = append(ring, pub.key)
+	}
+	return ring
+}
+
+// GenerateSpendProof returns a SpendProofV1 proving that we created the
+// transaction with the passed inputs, by signing a ring signature with the
+// one-time private key of each input's real output. The optional message is
+// signed with the proof. The result is compatible with monero-wallet-cli's
+// check_spend_proof command.
+func GenerateSpendProof(
+	txID [32]byte,
+	message []byte,
+	inputs []*SpendProofInput,
+	keys []*OneTimePrivateKey,
+) (string, error) {
+	if len(keys) != len(inputs) {
+		return "", errSpendProofKeyCount
+	}
+
+	prefixHash := txProofPrefixHash(txID, message)
+
+	var sb strings.Builder
+	sb.WriteString(spendProofV1Header)
+	for i, in := range inputs {
+		if !keys[i].KeyImage().Equal(in.KeyImage) {
+			return "", errKeyImageMismatch
+		}
+
+		pub := keys[i].Public()
+		secretIndex := -1
+		for j, member := range in.Ring {
+			if member.Equal(pub) {
+				secretIndex = j
+				break
+			}
+		}
+		if secretIndex < 0 {
+			return "", errKeyNotInRing
+		}
+
+		sig := mcrypto.GenerateRingSignature(prefixHash, in.KeyImage.key, in.ringPoints(), keys[i].key, secretIndex)
+		for j := 0; j < len(in.Ring); j++ {
+			sb.WriteString(base58.Encode(sig[j*SignatureLen : (j+1)*SignatureLen]))
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// CheckSpendProof verifies a SpendProofV1, created by monero-wallet-cli's
+// get_spend_proof command or by GenerateSpendProof, for the transaction with
+// the passed ID and inputs.
+func CheckSpendProof(txID [32]byte, message []byte, inputs []*SpendProofInput, proof string) error {
+	if !strings.HasPrefix(proof, spendProofV1Header) {
+		return errInvalidSpendProofFormat
+	}
+	encoded := proof[len(spendProofV1Header):]
+
+	numSigs := 0
+	for _, in := range inputs {
+		numSigs += len(in.Ring)
+	}
+	encodedSigLen := base58.EncodedLen(SignatureLen)
+	if numSigs == 0 || len(encoded) != numSigs*encodedSigLen {
+		return errInvalidSpendProofFormat
+	}
+
+	prefixHash := txProofPrefixHash(txID, message)
+
+	for _, in := range inputs {
+		sig := make([]byte, 0, len(in.Ring)*SignatureLen)
+		for range in.Ring {
+			elem, err := base58.Decode(encoded[:encodedSigLen])
+			if err != nil || len(elem) != SignatureLen {
+				return errInvalidSpendProofFormat
+			}
+			sig = append(sig, elem...)
+			encoded = encoded[encodedSigLen:]
+		}
+
+		if !mcrypto.CheckRingSignature(prefixHash, in.KeyImage.key, in.ringPoints(), sig) {
+			return errInvalidSpendProof
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package cryptonote
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSpendProofInput returns an input spending the output with the passed
+// one-time key, hidden in a ring of the given size.
+func newTestSpendProofInput(t *testing.T, oneTimeKey *OneTimePrivateKey, ringSize int, realIndex int) *SpendProofInput {
+	in := &SpendProofInput{KeyImage: oneTimeKey.KeyImage()}
+	for i := 0; i < ringSize; i++ {
+		if i == realIndex {
+			in.Ring = append(in.Ring, oneTimeKey.Public())
+			continue
+		}
+		decoy, err := GenerateKeys()
+		require.NoError(t, err)
+		in.Ring = append(in.Ring, decoy.sk.Public())
+	}
+	return in
+}
+
+func newTestOneTimeKey(t *testing.T) *OneTimePrivateKey {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	_, txPubKey := newTestTxKeys(t)
+	return kp.PrivateViewKey().KeyDerivation(txPubKey).DeriveSecretKey(0, kp.SpendKey())
+}
+
+func TestSpendProof(t *testing.T) {
+	txID := [32]byte{0xaa}
+	msg := []byte("spent by me")
+	keys := []*OneTimePrivateKey{newTestOneTimeKey(t), newTestOneTimeKey(t)}
+	inputs := []*SpendProofInput{
+		newTestSpendProofInput(t, keys[0], 16, 3),
+		newTestSpendProofInput(t, keys[1], 16, 15),
+	}
+
+	proof, err := GenerateSpendProof(txID, msg, inputs, keys)
+	require.NoError(t, err)
+	require.NoError(t, CheckSpendProof(txID, msg, inputs, proof))
+
+	err = CheckSpendProof(txID, []byte("spent by you"), inputs, proof)
+	require.ErrorIs(t, err, errInvalidSpendProof)
+
+	otherTxID := txID
+	otherTxID[31] = 1
+	err = CheckSpendProof(otherTxID, msg, inputs, proof)
+	require.ErrorIs(t, err, errInvalidSpendProof)
+
+	// the inputs must be in the same order as the transaction
+	reordered := []*SpendProofInput{inputs[1], inputs[0]}
+	err = CheckSpendProof(txID, msg, reordered, proof)
+	require.ErrorIs(t, err, errInvalidSpendProof)
+
+	for _, badProof := range []string{"", "SpendProofV2", proof[:len(proof)-1]} {
+		err = CheckSpendProof(txID, msg, inputs, badProof)
+		require.ErrorIs(t, err, errInvalidSpendProofFormat)
+	}
+	err = CheckSpendProof(txID, msg, inputs[:1], proof)
+	require.ErrorIs(t, err, errInvalidSpendProofFormat)
+}
+
+func TestGenerateSpendProof_wrongKeys(t *testing.T) {
+	key := newTestOneTimeKey(t)
+	in := newTestSpendProofInput(t, key, 11, 0)
+
+	_, err := GenerateSpendProof([32]byte{}, nil, []*SpendProofInput{in}, nil)
+	require.ErrorIs(t, err, errSpendProofKeyCount)
+
+	_, err = GenerateSpendProof([32]byte{}, nil, []*SpendProofInput{in}, []*OneTimePrivateKey{newTestOneTimeKey(t)})
+	require.ErrorIs(t, err, errKeyImageMismatch)
+
+	in.Ring = in.Ring[1:]
+	_, err = GenerateSpendProof([32]byte{}, nil, []*SpendProofInput{in}, []*OneTimePrivateKey{key})
+	require.ErrorIs(t, err, errKeyNotInRing)
+}
@@ -0,0 +1,149 @@
+package cryptonote
+
+import (
+	"errors"
+	"sync"
+)
+
+// Default lookahead window used by wallet2. Outputs sent to subaddresses
+// outside the window are not detected until the window grows.
+const (
+	DefaultSubaddressLookaheadAccounts = 50
+	DefaultSubaddressLookaheadIndices  = 200
+)
+
+// MaxSubaddressAccount and MaxSubaddressIndex bound the subaddresses that a
+// SubaddressTable holds, so that an account or index read from an untrusted
+// source can't make it derive billions of keys.
+const (
+	MaxSubaddressAccount = 1<<10 - 1
+	MaxSubaddressIndex   = 1<<20 - 1
+)
+
+var errSubaddressLimit = errors.New("subaddress account or index is too large")
+
+// SubaddressTable maps the public spend keys of a wallet's subaddresses back to
+// their account and subaddress indices. It is the receive side counterpart of
+// PrivateKeyPair.SubAddrPubKeyPair: given the spend key an output was sent to,
+// as returned by KeyDerivation.OutputSpendKey, Lookup tells us which of our
+// subaddresses received it.
+//
+// Like wallet2, the table holds lookaheadIndices subaddresses for each of
+// lookaheadAccounts accounts. When a subaddress receives funds, call Expand so
+// the window always extends past the highest used account and index.
+//
+// The table only needs the private view key, so it works for view-only
+// wallets. It is safe for concurrent use.
+type SubaddressTable struct {
+	viewKey          *PrivateViewKey
+	spendKey         *PublicKey
+	lookaheadAccts   uint32
+	lookaheadIndices uint32
+
+	mu sync.RWMutex
+	// numIndices holds the number of subaddress indices in the table for each
+	// account. The length is the number of accounts in the table.
+	numIndices []uint32
+	table      map[[KeySize]byte]subaddressIndex
+}
+
+type subaddressIndex struct {
+	account uint32
+	index   uint32
+}
+
+// NewSubaddressTable returns a SubaddressTable for the wallet with the passed
+// private view key and public spend key. The table initially holds indices
+// [0, lookaheadIndices) for accounts [0, lookaheadAccounts), including the
+// primary address at account 0, index 0. Lookahead values of zero are
+// treated as one. The window never extends past MaxSubaddressAccount and
+// MaxSubaddressIndex.
+func NewSubaddressTable(
+	viewKey *PrivateViewKey,
+	spendKey *PublicKey,
+	lookaheadAccounts uint32,
+	lookaheadIndices uint32,
+) *SubaddressTable {
+	t := &SubaddressTable{
+		viewKey:          viewKey,
+		spendKey:         spendKey,
+		lookaheadAccts:   min(max(lookaheadAccounts, 1), MaxSubaddressAccount+1),
+		lookaheadIndices: min(max(lookaheadIndices, 1), MaxSubaddressIndex+1),
+		table:            make(map[[KeySize]byte]subaddressIndex),
+	}
+	t.expand(0, 0)
+	return t
+}
+
+// SubaddressTable returns a SubaddressTable for the key pair using wallet2's
+// default lookahead window.
+func (kp *PrivateKeyPair) SubaddressTable() *SubaddressTable {
+	return kp.ViewKeyPair().SubaddressTable()
+}
+
+// Lookup returns the account and subaddress index of the passed public spend
+// key. The primary address is account 0, index 0. If the spend key is not in
+// the table, ok is false.
+func (t *SubaddressTable) Lookup(spendKey *PublicKey) (account uint32, index uint32, ok bool) {
+	var key [KeySize]byte
+	copy(key[:], spendKey.Bytes())
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	idx, ok := t.table[key]
+	return idx.account, idx.index, ok
+}
+
+// Expand grows the table, if needed, so that it holds accounts
+// [0, account+lookaheadAccounts) and, for the passed account, subaddress
+// indices [0, index+lookaheadIndices). Call it after an output is received
+// by a subaddress, the same way wallet2 calls expand_subaddresses. Accounts
+// above MaxSubaddressAccount and indices above MaxSubaddressIndex are
+// rejected.
+func (t *SubaddressTable) Expand(account uint32, index uint32) error {
+	if account > MaxSubaddressAccount || index > MaxSubaddressIndex {
+		return errSubaddressLimit
+	}
+	t.expand(account, index)
+	return nil
+}
+
+// expand is Expand without the bounds check. The lookahead values are capped
+// so the sums can't overflow.
+func (t *SubaddressTable) expand(account uint32, index uint32) {
+	lastAccount := min(account+t.lookaheadAccts-1, MaxSubaddressAccount)
+	lastIndex := min(index+t.lookaheadIndices-1, MaxSubaddressIndex)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// New accounts get indices [0, lookaheadIndices)
+	for a := uint32(len(t.numIndices)); a <= lastAccount; a++ {
+		t.numIndices = append(t.numIndices, 0)
+		t.expandAccount(a, t.lookaheadIndices-1)
+	}
+	t.expandAccount(account, lastIndex)
+}
+
+// Len returns the number of subaddresses, including the primary address, in
+// the table.
+func (t *SubaddressTable) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.table)
+}
+
+// expandAccount adds subaddress indices [numIndices[account], lastIndex] of
+// the account to the table. The caller must hold the write lock.
+func (t *SubaddressTable) expandAccount(account uint32, lastIndex uint32) {
+	for index := t.numIndices[account]; index <= lastIndex; index++ {
+		var key [KeySize]byte
+		if account == 0 && index == 0 {
+			copy(key[:], t.spendKey.Bytes())
+		} else {
+			copy(key[:], t.viewKey.subAddressSpendKey(t.spendKey, account, index).Bytes())
+		}
+		t.table[key] = subaddressIndex{account: account, index: index}
+		t.numIndices[account] = index + 1
+	}
+}
@@ -0,0 +1,140 @@
+package cryptonote
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	ed25519 "filippo.io/edwards25519"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubaddressTable_Lookup(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	table := NewSubaddressTable(kp.PrivateViewKey(), kp.PublicKeyPair().SpendKey(), 2, 3)
+	require.Equal(t, 2*3, table.Len())
+
+	for account := uint32(0); account < 2; account++ {
+		for index := uint32(0); index < 3; index++ {
+			spendKey := kp.SubAddrPubKeyPair(account, index).SpendKey()
+			a, i, ok := table.Lookup(spendKey)
+			require.True(t, ok)
+			require.Equal(t, account, a)
+			require.Equal(t, index, i)
+		}
+	}
+
+	// outside the lookahead window
+	_, _, ok := table.Lookup(kp.SubAddrPubKeyPair(0, 3).SpendKey())
+	require.False(t, ok)
+	_, _, ok = table.Lookup(kp.SubAddrPubKeyPair(2, 0).SpendKey())
+	require.False(t, ok)
+	_, _, ok = table.Lookup(kp.PublicKeyPair().ViewKey())
+	require.False(t, ok)
+}
+
+func TestSubaddressTable_Expand(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	table := NewSubaddressTable(kp.PrivateViewKey(), kp.PublicKeyPair().SpendKey(), 2, 3)
+
+	// within the existing window, so nothing to add
+	require.NoError(t, table.Expand(0, 0))
+	require.Equal(t, 2*3, table.Len())
+
+	// Receiving funds on (1, 2) extends account 1 to index 4 and adds
+	// account 2 with the default window.
+	require.NoError(t, table.Expand(1, 2))
+	require.Equal(t, 3+5+3, table.Len())
+
+	a, i, ok := table.Lookup(kp.SubAddrPubKeyPair(1, 4).SpendKey())
+	require.True(t, ok)
+	require.Equal(t, uint32(1), a)
+	require.Equal(t, uint32(4), i)
+
+	a, i, ok = table.Lookup(kp.SubAddrPubKeyPair(2, 2).SpendKey())
+	require.True(t, ok)
+	require.Equal(t, uint32(2), a)
+	require.Equal(t, uint32(2), i)
+
+	_, _, ok = table.Lookup(kp.SubAddrPubKeyPair(1, 5).SpendKey())
+	require.False(t, ok)
+	_, _, ok = table.Lookup(kp.SubAddrPubKeyPair(3, 0).SpendKey())
+	require.False(t, ok)
+	_, _, ok = table.Lookup(kp.SubAddrPubKeyPair(0, 3).SpendKey())
+	require.False(t, ok)
+}
+
+func TestSubaddressTable_Expand_limits(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	table := NewSubaddressTable(kp.PrivateViewKey(), kp.PublicKeyPair().SpendKey(), 2, 3)
+
+	for _, idx := range [][2]uint32{
+		{MaxSubaddressAccount + 1, 0},
+		{0, MaxSubaddressIndex + 1},
+		{math.MaxUint32, 0},
+		{0, math.MaxUint32},
+		{math.MaxUint32, math.MaxUint32},
+	} {
+		require.ErrorIs(t, table.Expand(idx[0], idx[1]), errSubaddressLimit)
+	}
+	require.Equal(t, 2*3, table.Len())
+
+	// the window stops at the last account
+	require.NoError(t, table.Expand(MaxSubaddressAccount, 0))
+	require.Equal(t, (MaxSubaddressAccount+1)*3, table.Len())
+
+	// and huge lookahead values are capped
+	table = NewSubaddressTable(kp.PrivateViewKey(), kp.PublicKeyPair().SpendKey(), math.MaxUint32, 1)
+	require.Equal(t, MaxSubaddressAccount+1, table.Len())
+}
+
+func TestSubaddressTable_scanOutput(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	table := NewSubaddressTable(kp.PrivateViewKey(), kp.PublicKeyPair().SpendKey(), 3, 10)
+
+	// Sender side. The transaction public key for a subaddress recipient is
+	// r*D, where D is the subaddress spend key.
+	subAddr := kp.SubAddrPubKeyPair(2, 7)
+	r, _ := newTestTxKeys(t)
+	txPubKey := &PublicKey{key: new(ed25519.Point).ScalarMult(r, subAddr.SpendKey().key)}
+	derivation := newKeyDerivation(subAddr.ViewKey().key, r)
+	out := &TxOutput{
+		Index: 0,
+		Key:   derivation.DerivePublicKey(0, subAddr.SpendKey()),
+	}
+
+	// recipient side
+	outSpendKey, ok := kp.PrivateViewKey().KeyDerivation(txPubKey).OutputSpendKey(out)
+	require.True(t, ok)
+	account, index, ok := table.Lookup(outSpendKey)
+	require.True(t, ok)
+	require.Equal(t, uint32(2), account)
+	require.Equal(t, uint32(7), index)
+}
+
+func TestSubaddressTable_concurrentReaders(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	table := NewSubaddressTable(kp.PrivateViewKey(), kp.PublicKeyPair().SpendKey(), 1, 5)
+	spendKey := kp.SubAddrPubKeyPair(0, 4).SpendKey()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, index, ok := table.Lookup(spendKey)
+				require.True(t, ok)
+				require.Equal(t, uint32(4), index)
+			}
+		}()
+	}
+	require.NoError(t, table.Expand(0, 10))
+	wg.Wait()
+	require.Equal(t, 15, table.Len())
+}
@@ -0,0 +1,586 @@
+package cryptonote
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+
+	ed25519 "filippo.io/edwards25519"
+
+	"github.com/dimalinux/gopherphis/bpplus"
+	"github.com/dimalinux/gopherphis/mcrypto"
+	"github.com/dimalinux/gopherphis/serialization"
+)
+
+// DefaultRingSize is the ring size, the real output plus 15 decoys, required
+// by Monero since the v15 hard fork.
+const DefaultRingSize = 16
+
+var (
+	errNoTxSources          = errors.New("transaction has no outputs to spend")
+	errNoTxDestinations     = errors.New("transaction has no destinations")
+	errTooManyDestinations  = errors.New("too many transaction destinations")
+	errZeroDestination      = errors.New("transaction destination amount is zero")
+	errMultiplePaymentIDs   = errors.New("transaction has more than one integrated address destination")
+	errInvalidRingSize      = errors.New("ring size must be at least 1")
+	errNoDecoyProvider      = errors.New("a decoy provider is required for ring sizes above 1")
+	errDecoyCount           = errors.New("decoy provider returned the wrong number of decoys")
+	errDuplicateRingMember  = errors.New("decoy provider returned a duplicate ring member")
+	errInvalidDecoy         = errors.New("decoy provider returned an output without a key or commitment")
+	errInsufficientFunds    = errors.New("spent outputs do not cover the destination amounts and fee")
+	errDuplicateSpentOutput = errors.New("transaction spends the same output twice")
+)
+
+// TxDestination is a recipient of a transaction
+type TxDestination struct {
+	Address *Address
+	Amount  Amount
+}
+
+// TxSource is an output received by the wallet, to be spent by a transaction
+type TxSource struct {
+	Output *OwnedOutput
+	// GlobalIndex is the index of the output among all of the chain's
+	// outputs with the same amount, which is zero for RingCT outputs. Inputs
+	// reference their ring members by global index.
+	GlobalIndex uint64
+}
+
+// RingOutput is an output on the chain that can be a member of an input's
+// ring
+type RingOutput struct {
+	GlobalIndex uint64
+	// Key is the output's one-time public key
+	Key *PublicKey
+	// Commitment is the output's amount commitment. For outputs with a
+	// cleartext amount, it is the commitment with a mask of one that Monero
+	// stores for them, Commit(1, amount).
+	Commitment *Commitment
+}
+
+// DecoyProvider picks the decoys of a transaction's inputs. Decoys are other
+// outputs on the chain which, together with the real output, form an input's
+// ring, so that observers can't tell which output is being spent. The
+// selection of decoys is critical for privacy: they must be
+// indistinguishable from real spends, as with GammaDecoyProvider.
+type DecoyProvider interface {
+	// Decoys returns count outputs, to use as decoys for the real output at
+	// globalIndex. The decoys must be unique and must not include the real
+	// output.
+	Decoys(ctx context.Context, globalIndex uint64, count int) ([]*RingOutput, error)
+}
+
+// TxParams are the parameters of a new transaction
+type TxParams struct {
+	// Sources are the outputs spent by the transaction
+	Sources []*TxSource
+	// Destinations are the recipients of the transaction. At most one can be
+	// an integrated address.
+	Destinations []*TxDestination
+	// Change is the address that receives the change. If nil, the primary
+	// address is used.
+	Change *PublicKeyPair
+	// FeePerByte and FeeQuantizationMask are the fee values returned by the
+	// daemon's get_fee_estimate RPC method.
+	FeePerByte          Amount
+	FeeQuantizationMask uint64
+	// RingSize is the number of members of each input's ring, which is
+	// DefaultRingSize on the current Monero network.
+	RingSize int
+	// Decoys picks the other members of each input's ring
+	Decoys DecoyProvider
+}
+
+// SignedTx is a complete, signed transaction
+type SignedTx struct {
+	Tx *serialization.Transaction
+	// Blob is the serialized transaction
+	Blob []byte
+	ID   [32]byte
+	// TxKey and AdditionalTxKeys are the transaction private keys, which the
+	// sender can use to prove the payment with CheckTxKey.
+	TxKey            *TxPrivateKey
+	AdditionalTxKeys []*TxPrivateKey
+	Fee              Amount
+	Weight           uint64
+	// Change is the amount sent back to the change address
+	Change Amount
+}
+
+// Hex returns the hex-encoded transaction, as sent to the daemon's
+// send_raw_transaction RPC method.
+func (tx *SignedTx) Hex() string {
+	return hex.EncodeToString(tx.Blob)
+}
+
+// txBuilderInput is an input of a transaction being built
+type txBuilderInput struct {
+	keys     *ownedOutputKeys
+	keyImage *KeyImage
+	amount   uint64
+	mask     *ed25519.Scalar
+	// ring is sorted by global index, with the real output at realIndex
+	ring       []*RingOutput
+	realIndex  int
+	pseudoMask *ed25519.Scalar
+}
+
+// txBuilderOutput is an output of a transaction being built
+type txBuilderOutput struct {
+	keys      *PublicKeyPair
+	amount    uint64
+	isChange  bool
+	paymentID *PaymentID
+	// derivation is the sender's side of the derivation for the output
+	derivation *KeyDerivation
+}
+
+// BuildTransaction is wallet2's construct_tx_and_get_tx_key for
+// RctTypeBulletproofPlus transactions. It spends the source outputs, paying
+// the destinations and sending the remaining amount, minus the fee, to the
+// change address. The fee is computed from the transaction's weight. Outputs
+// have view tags, the amounts are proven to be in range with a Bulletproofs+
+// proof and each input is signed with a CLSAG. The returned transaction is
+// ready to be sent to the daemon.
+func (kp *PrivateKeyPair) BuildTransaction(ctx context.Context, params *TxParams) (*SignedTx, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	inputs, err := kp.txBuilderInputs(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	var inputSum Amount
+	for _, in := range inputs {
+		if inputSum, err = inputSum.Add(Amount(in.amount)); err != nil {
+			return nil, err
+		}
+	}
+
+	outputs, err := kp.txBuilderOutputs(params)
+	if err != nil {
+		return nil, err
+	}
+	var destSum Amount
+	for _, dest := range params.Destinations {
+		if destSum, err = destSum.Add(dest.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	txKey := GenerateTxPrivateKey()
+	additionalTxKeys, extra := kp.deriveTxBuilderOutputs(txKey, outputs)
+	tx := newUnsignedTx(inputs, outputs, extra, params.RingSize)
+
+	// The fee depends on the weight, which depends on the size of the fee's
+	// varint, so the fee is increased until the weight stops changing. The
+	// signatures are empty, but have their final sizes.
+	var fee Amount
+	for {
+		tx.Rct.TxnFee = uint64(fee)
+		weight, err := tx.Weight()
+		if err != nil {
+			return nil, err
+		}
+		needed, err := FeeForWeight(params.FeePerByte, weight, params.FeeQuantizationMask)
+		if err != nil {
+			return nil, err
+		}
+		if needed <= fee {
+			break
+		}
+		fee = needed
+	}
+
+	spent, err := destSum.Add(fee)
+	if err != nil {
+		return nil, err
+	}
+	change, err := inputSum.Sub(spent)
+	if err != nil {
+		return nil, errInsufficientFunds
+	}
+	for _, out := range outputs {
+		if out.isChange {
+			out.amount = uint64(change)
+		}
+	}
+
+	if err := signTx(tx, inputs, outputs); err != nil {
+		return nil, err
+	}
+
+	signed := &SignedTx{
+		Tx:               tx,
+		TxKey:            txKey,
+		AdditionalTxKeys: additionalTxKeys,
+		Fee:              fee,
+		Change:           change,
+	}
+	if signed.Blob, err = tx.MarshalBinary(); err != nil {
+		return nil, err
+	}
+	if signed.ID, err = tx.Hash(); err != nil {
+		return nil, err
+	}
+	if signed.Weight, err = tx.Weight(); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
+func (p *TxParams) validate() error {
+	if len(p.Sources) == 0 {
+		return errNoTxSources
+	}
+	if len(p.Destinations) == 0 {
+		return errNoTxDestinations
+	}
+	// one output is needed for the change
+	if len(p.Destinations)+1 > bpplus.MaxOutputs {
+		return errTooManyDestinations
+	}
+	if p.RingSize < 1 {
+		return errInvalidRingSize
+	}
+	if p.RingSize > 1 && p.Decoys == nil {
+		return errNoDecoyProvider
+	}
+
+	numPaymentIDs := 0
+	for _, dest := range p.Destinations {
+		if dest.Address == nil {
+			return errAddressNotInitialized
+		}
+		if dest.Amount == 0 {
+			return errZeroDestination
+		}
+		if dest.Address.Type() == Integrated {
+			numPaymentIDs++
+		}
+	}
+	if numPaymentIDs > 1 {
+		return errMultiplePaymentIDs
+	}
+
+	return nil
+}
+
+// txBuilderInputs derives the keys and amounts of the spent outputs and gets
+// their rings. Like wallet2, the inputs are sorted by key image, in
+// descending order.
+func (kp *PrivateKeyPair) txBuilderInputs(ctx context.Context, params *TxParams) ([]*txBuilderInput, error) {
+	inputs := make([]*txBuilderInput, 0, len(params.Sources))
+	for i, src := range params.Sources {
+		keys, err := kp.ownedOutputKeys(src.Output)
+		if err != nil {
+			return nil, err
+		}
+		amount, mask, err := keys.derivation.OutputAmount(keys.out)
+		if err != nil {
+			return nil, fmt.Errorf("source %d: %w", i, err)
+		}
+
+		commitment := keys.out.Commitment
+		if commitment == nil {
+			commitment = Commit(mask, amount)
+		}
+		in := &txBuilderInput{
+			keys:     keys,
+			keyImage: keys.oneTimeKey.KeyImage(),
+			amount:   amount,
+			mask:     mask,
+		}
+		for _, other := range inputs {
+			if other.keyImage.Equal(in.keyImage) {
+				return nil, errDuplicateSpentOutput
+			}
+		}
+
+		realOut := &RingOutput{GlobalIndex: src.GlobalIndex, Key: keys.out.Key, Commitment: commitment}
+		if err := in.setRing(ctx, params.Decoys, realOut, params.RingSize); err != nil {
+			return nil, fmt.Errorf("source %d: %w", i, err)
+		}
+		inputs = append(inputs, in)
+	}
+
+	slices.SortFunc(inputs, func(a, b *txBuilderInput) int {
+		return bytes.Compare(b.keyImage.Bytes(), a.keyImage.Bytes())
+	})
+	return inputs, nil
+}
+
+// setRing gets the decoys of the real output and sorts the ring by global
+// index.
+func (in *txBuilderInput) setRing(ctx context.Context, decoys DecoyProvider, realOut *RingOutput, ringSize int) error {
+	in.ring = []*RingOutput{realOut}
+	if ringSize > 1 {
+		others, err := decoys.Decoys(ctx, realOut.GlobalIndex, ringSize-1)
+		if err != nil {
+			return err
+		}
+		if len(others) != ringSize-1 {
+			return errDecoyCount
+		}
+		in.ring = append(in.ring, others...)
+	}
+
+	slices.SortFunc(in.ring, func(a, b *RingOutput) int {
+		switch {
+		case a.GlobalIndex < b.GlobalIndex:
+			return -1
+		case a.GlobalIndex > b.GlobalIndex:
+			return 1
+		default:
+			return 0
+		}
+	})
+	for i, member := range in.ring {
+		if member.Key == nil || member.Commitment == nil {
+			return errInvalidDecoy
+		}
+		if i > 0 && member.GlobalIndex == in.ring[i-1].GlobalIndex {
+			return errDuplicateRingMember
+		}
+		if member == realOut {
+			in.realIndex = i
+		}
+	}
+	return nil
+}
+
+// txBuilderOutputs returns the outputs paying the destinations and the
+// change, in a random order. The order is shuffled with crypto/rand, so it
+// doesn't leak which output is the change.
+func (kp *PrivateKeyPair) txBuilderOutputs(params *TxParams) ([]*txBuilderOutput, error) {
+	outputs := make([]*txBuilderOutput, 0, len(params.Destinations)+1)
+	for _, dest := range params.Destinations {
+		keys, err := dest.Address.PublicKeyPair()
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, &txBuilderOutput{
+			keys:      keys,
+			amount:    uint64(dest.Amount),
+			paymentID: dest.Address.PaymentID(),
+		})
+	}
+
+	change := params.Change
+	if change == nil {
+		change = kp.PublicKeyPair()
+	}
+	outputs = append(outputs, &txBuilderOutput{keys: change, isChange: true})
+
+	newCryptoRand().Shuffle(len(outputs), func(i, j int) {
+		outputs[i], outputs[j] = outputs[j], outputs[i]
+	})
+	return outputs, nil
+}
+
+// deriveTxBuilderOutputs sets the derivation of each output and returns the
+// additional transaction keys, if needed, and the extra field.
+//
+// Like wallet2, a transaction whose only recipient, other than the change, is
+// a subaddress uses the transaction public key r*D, where D is the
+// subaddress's spend key. Transactions with a subaddress and any other
+// recipient have an additional transaction key for each output. The change
+// output's derivation is computed with our private view key, which lets the
+// change address be any of our subaddresses.
+func (kp *PrivateKeyPair) deriveTxBuilderOutputs(
+	txKey *TxPrivateKey,
+	outputs []*txBuilderOutput,
+) ([]*TxPrivateKey, *serialization.TxExtra) {
+	var numStandard, numSubaddress int
+	var singleDest *txBuilderOutput
+	for _, out := range outputs {
+		if out.isChange {
+			continue
+		}
+		singleDest = out
+		if out.keys.isSubAddress {
+			numSubaddress++
+		} else {
+			numStandard++
+		}
+	}
+	needAdditional := numSubaddress > 0 && (numStandard > 0 || numSubaddress > 1)
+
+	txPubKey := txKey.Public()
+	if numSubaddress == 1 && numStandard == 0 {
+		txPubKey = &PublicKey{key: new(ed25519.Point).ScalarMult(txKey.key, singleDest.keys.sk.key)}
+	}
+
+	var additionalTxKeys []*TxPrivateKey
+	var additionalPubKeys [][32]byte
+	var paymentID *PaymentID
+	var paymentIDKeys *PublicKeyPair
+	for _, out := range outputs {
+		// Like wallet2, every output gets an additional key, but only those
+		// to subaddresses are derived from it. The others use the main key.
+		outTxKey := txKey
+		if needAdditional {
+			additionalKey := GenerateTxPrivateKey()
+			additionalTxKeys = append(additionalTxKeys, additionalKey)
+			pub := additionalKey.Public()
+			if out.keys.isSubAddress {
+				pub = &PublicKey{key: new(ed25519.Point).ScalarMult(additionalKey.key, out.keys.sk.key)}
+				outTxKey = additionalKey
+			}
+			additionalPubKeys = append(additionalPubKeys, [32]byte(pub.Bytes()))
+		}
+
+		if out.isChange {
+			out.derivation = kp.vk.KeyDerivation(txPubKey)
+		} else {
+			out.derivation = outTxKey.KeyDerivation(out.keys.vk)
+		}
+
+		if out.paymentID != nil {
+			paymentID, paymentIDKeys = out.paymentID, out.keys
+		}
+	}
+
+	// Like wallet2, transactions to a single recipient without a payment ID
+	// get an encrypted dummy payment ID, so that they look like those with
+	// one.
+	if paymentID == nil && numStandard+numSubaddress == 1 {
+		paymentID, paymentIDKeys = new(PaymentID), singleDest.keys
+	}
+
+	extra := new(serialization.TxExtra)
+	extra.AddTxPubKey([32]byte(txPubKey.Bytes()))
+	if needAdditional {
+		extra.AddAdditionalPubKeys(additionalPubKeys)
+	}
+	if paymentID != nil {
+		// payment IDs are encrypted with the main transaction key
+		encrypted := txKey.KeyDerivation(paymentIDKeys.vk).EncryptPaymentID(*paymentID)
+		extra.AddEncryptedPaymentID(encrypted)
+	}
+	extra.Sort()
+
+	return additionalTxKeys, extra
+}
+
+// newUnsignedTx returns the transaction with empty signatures and amounts
+func newUnsignedTx(
+	inputs []*txBuilderInput,
+	outputs []*txBuilderOutput,
+	extra *serialization.TxExtra,
+	ringSize int,
+) *serialization.Transaction {
+	tx := &serialization.Transaction{
+		Prefix: serialization.TxPrefix{
+			Version: serialization.TxVersion2,
+			Extra:   extra.Bytes(),
+		},
+		Rct: &serialization.RctSignatures{
+			RctSigBase: serialization.RctSigBase{Type: serialization.RctTypeBulletproofPlus},
+			Prunable:   new(serialization.RctSigPrunable),
+		},
+	}
+
+	for _, in := range inputs {
+		indices := make([]uint64, len(in.ring))
+		for i, member := range in.ring {
+			indices[i] = member.GlobalIndex
+		}
+		tx.Prefix.Inputs = append(tx.Prefix.Inputs, &serialization.TxInput{
+			Type:       serialization.InputTypeToKey,
+			KeyOffsets: serialization.RelativeOffsets(indices),
+			KeyImage:   [32]byte(in.keyImage.Bytes()),
+		})
+		tx.Rct.Prunable.PseudoOuts = append(tx.Rct.Prunable.PseudoOuts, [32]byte{})
+		tx.Rct.Prunable.CLSAGs = append(tx.Rct.Prunable.CLSAGs, &serialization.CLSAG{
+			S: make([][32]byte, ringSize),
+		})
+	}
+
+	for i, out := range outputs {
+		outKey := out.derivation.DerivePublicKey(uint64(i), out.keys.sk)
+		tx.Prefix.Outputs = append(tx.Prefix.Outputs, &serialization.TxOutput{
+			Type:    serialization.OutputTypeToTaggedKey,
+			Key:     [32]byte(outKey.Bytes()),
+			ViewTag: out.derivation.ViewTag(uint64(i)),
+		})
+		tx.Rct.EcdhInfo = append(tx.Rct.EcdhInfo, new(serialization.EcdhInfo))
+		tx.Rct.OutPk = append(tx.Rct.OutPk, [32]byte{})
+	}
+
+	// The proof has 6 rounds for one amount, and one more for each doubling
+	numLR := 6
+	for 1<<(numLR-6) < len(outputs) {
+		numLR++
+	}
+	tx.Rct.Prunable.BulletproofsPlus = []*serialization.BulletproofPlus{{
+		L: make([][32]byte, numLR),
+		R: make([][32]byte, numLR),
+	}}
+
+	return tx
+}
+
+// signTx sets the output amounts and commitments, proves that the amounts are
+// in range, and signs the inputs. The pseudo output masks sum to the output
+// masks, so that the pseudo outputs commit to the output amounts plus the fee.
+func signTx(tx *serialization.Transaction, inputs []*txBuilderInput, outputs []*txBuilderOutput) error {
+	amounts := make([]uint64, len(outputs))
+	masks := make([]*ed25519.Scalar, len(outputs))
+	maskSum := ed25519.NewScalar()
+	for i, out := range outputs {
+		amounts[i] = out.amount
+		masks[i] = out.derivation.CommitmentMask(uint64(i))
+		maskSum.Add(maskSum, masks[i])
+		encAmount := out.derivation.EncryptAmount(uint64(i), out.amount)
+		copy(tx.Rct.EcdhInfo[i].Amount[:], encAmount[:])
+	}
+
+	proof, err := bpplus.Prove(amounts, masks)
+	if err != nil {
+		return err
+	}
+	for i, c := range proof.Commitments() {
+		tx.Rct.OutPk[i] = [32]byte(c.Bytes())
+	}
+	tx.Rct.Prunable.BulletproofsPlus = []*serialization.BulletproofPlus{SerializeBulletproofPlus(proof)}
+
+	pseudoOuts := make([]*ed25519.Point, len(inputs))
+	for i, in := range inputs {
+		if i == len(inputs)-1 {
+			in.pseudoMask = maskSum
+		} else {
+			in.pseudoMask = mcrypto.RandomScalar()
+			maskSum.Subtract(maskSum, in.pseudoMask)
+		}
+		pseudoOuts[i] = mcrypto.Commit(in.pseudoMask, in.amount)
+		tx.Rct.Prunable.PseudoOuts[i] = [32]byte(pseudoOuts[i].Bytes())
+	}
+
+	message, err := tx.SignatureHash()
+	if err != nil {
+		return err
+	}
+	for i, in := range inputs {
+		ring := make([]*mcrypto.RingMember, len(in.ring))
+		for j, member := range in.ring {
+			ring[j] = &mcrypto.RingMember{Dest: member.Key.key, Commitment: member.Commitment.key}
+		}
+		sig := mcrypto.SignCLSAG(
+			message[:],
+			ring,
+			pseudoOuts[i],
+			in.keys.oneTimeKey.key,
+			in.mask,
+			in.pseudoMask,
+			in.realIndex,
+		)
+		tx.Rct.Prunable.CLSAGs[i] = SerializeCLSAG(sig)
+	}
+
+	return nil
+}
@@ -0,0 +1,352 @@
+package cryptonote
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	ed25519 "filippo.io/edwards25519"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/mcrypto"
+	"github.com/dimalinux/gopherphis/serialization"
+)
+
+// testDecoys returns random decoys, remembering every output it returned so
+// that the rings of a transaction can be looked up by global index.
+type testDecoys struct {
+	outputs   map[uint64]*RingOutput
+	nextIndex uint64
+	// extra is added to the number of returned decoys
+	extra int
+}
+
+func newTestDecoys() *testDecoys {
+	return &testDecoys{outputs: make(map[uint64]*RingOutput), nextIndex: 5000}
+}
+
+func (d *testDecoys) Decoys(_ context.Context, globalIndex uint64, count int) ([]*RingOutput, error) {
+	decoys := make([]*RingOutput, count+d.extra)
+	for i := range decoys {
+		// the decoys are both older and newer than the real output
+		d.nextIndex += 7
+		idx := d.nextIndex
+		if i%2 == 0 {
+			idx = globalIndex - d.nextIndex%globalIndex
+		}
+		if _, ok := d.outputs[idx]; ok || idx == globalIndex {
+			idx = d.nextIndex * 1000
+		}
+		decoys[i] = &RingOutput{
+			GlobalIndex: idx,
+			Key:         &PublicKey{key: new(ed25519.Point).ScalarBaseMult(mcrypto.RandomScalar())},
+			Commitment:  Commit(mcrypto.RandomScalar(), uint64(i)),
+		}
+		d.outputs[idx] = decoys[i]
+	}
+	return decoys, nil
+}
+
+// newTestSources returns the outputs of a transaction paying 1000 to the
+// primary address and 2000 to the subaddress (1, 2) of kp.
+func newTestSources(t *testing.T, kp *PrivateKeyPair, decoys *testDecoys) []*TxSource {
+	tx, _, _ := newTestTx(t, kp)
+	sources := []*TxSource{
+		{Output: &OwnedOutput{Tx: tx, Index: 0}, GlobalIndex: 4000},
+		{Output: &OwnedOutput{Tx: tx, Index: 1, AccountIndex: 1, SubAddrIndex: 2}, GlobalIndex: 4001},
+	}
+	for _, src := range sources {
+		out := tx.output(src.Output.Index)
+		decoys.outputs[src.GlobalIndex] = &RingOutput{GlobalIndex: src.GlobalIndex, Key: out.Key, Commitment: out.Commitment}
+	}
+	return sources
+}
+
+// verifyTestTx decodes the transaction's blob and verifies its ID, range
+// proof, ring signatures and balance.
+func verifyTestTx(t *testing.T, signed *SignedTx, decoys *testDecoys) *serialization.Transaction {
+	tx, err := serialization.DecodeTransaction(signed.Blob, false)
+	require.NoError(t, err)
+	id, err := tx.Hash()
+	require.NoError(t, err)
+	require.Equal(t, signed.ID, id)
+	require.Equal(t, uint64(signed.Fee), tx.Rct.TxnFee)
+	require.NoError(t, VerifyBulletproofPlusTxs([]*serialization.Transaction{tx}))
+
+	message, err := tx.SignatureHash()
+	require.NoError(t, err)
+	pseudoSum := ed25519.NewIdentityPoint()
+	for i, in := range tx.Prefix.Inputs {
+		offsets, err := in.AbsoluteOffsets()
+		require.NoError(t, err)
+		ring := make([]*mcrypto.RingMember, len(offsets))
+		for j, idx := range offsets {
+			member := decoys.outputs[idx]
+			require.NotNil(t, member, idx)
+			ring[j] = &mcrypto.RingMember{Dest: member.Key.key, Commitment: member.Commitment.key}
+		}
+		pseudoOut, err := new(ed25519.Point).SetBytes(tx.Rct.Prunable.PseudoOuts[i][:])
+		require.NoError(t, err)
+		keyImage, err := new(ed25519.Point).SetBytes(in.KeyImage[:])
+		require.NoError(t, err)
+		sig, err := NewCLSAG(tx.Rct.Prunable.CLSAGs[i])
+		require.NoError(t, err)
+		require.True(t, mcrypto.VerifyCLSAG(message[:], ring, pseudoOut, keyImage, sig))
+		pseudoSum.Add(pseudoSum, pseudoOut)
+	}
+
+	// the pseudo outputs commit to the output amounts plus the fee
+	outSum := mcrypto.Commit(ed25519.NewScalar(), tx.Rct.TxnFee)
+	for _, c := range tx.Rct.OutPk {
+		commitment, err := new(ed25519.Point).SetBytes(c[:])
+		require.NoError(t, err)
+		outSum.Add(outSum, commitment)
+	}
+	require.Equal(t, 1, pseudoSum.Equal(outSum))
+
+	return tx
+}
+
+func TestPrivateKeyPair_BuildTransaction(t *testing.T) {
+	sender, err := GenerateKeys()
+	require.NoError(t, err)
+	recipient, err := GenerateKeys()
+	require.NoError(t, err)
+	decoys := newTestDecoys()
+
+	recipientAddr := recipient.PublicKeyPair().Address(Mainnet)
+	recipientSubAddr := recipient.SubAddrPubKeyPair(0, 1).Address(Mainnet)
+	params := &TxParams{
+		Sources: newTestSources(t, sender, decoys),
+		Destinations: []*TxDestination{
+			{Address: recipientAddr, Amount: 50},
+			{Address: recipientSubAddr, Amount: 70},
+		},
+		FeePerByte:          1,
+		FeeQuantizationMask: 1,
+		RingSize:            DefaultRingSize,
+		Decoys:              decoys,
+	}
+	signed, err := sender.BuildTransaction(context.Background(), params)
+	require.NoError(t, err)
+	tx := verifyTestTx(t, signed, decoys)
+
+	require.Len(t, tx.Prefix.Inputs, 2)
+	require.Len(t, tx.Prefix.Outputs, 3)
+	require.Len(t, signed.AdditionalTxKeys, 3)
+	// the fee per byte is one, so the fee is the weight
+	require.Equal(t, Amount(signed.Weight), signed.Fee)
+	require.Equal(t, 3000-120-signed.Fee, signed.Change)
+
+	// the inputs are sorted by key image, in descending order
+	require.Positive(t, bytes.Compare(tx.Prefix.Inputs[0].KeyImage[:], tx.Prefix.Inputs[1].KeyImage[:]))
+
+	info, err := NewTxInfo(tx)
+	require.NoError(t, err)
+	received, err := CheckTxKey(info, signed.TxKey, signed.AdditionalTxKeys, recipientAddr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(50), received)
+	received, err = CheckTxKey(info, signed.TxKey, signed.AdditionalTxKeys, recipientSubAddr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(70), received)
+
+	// like wallet2, the output to the standard address is derived from the
+	// main transaction key, not its additional key
+	received, err = CheckTxKey(info, signed.TxKey, nil, recipientAddr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(50), received)
+
+	// the sender finds the change with the main transaction public key
+	var change uint64
+	for _, out := range info.Outputs {
+		if _, ok := sender.ScanOutput(info.PubKey, out); ok {
+			change, _, err = sender.vk.KeyDerivation(info.PubKey).OutputAmount(out)
+			require.NoError(t, err)
+		}
+	}
+	require.Equal(t, uint64(signed.Change), change)
+
+	// the change can be spent in a new transaction
+	_, err = sender.BuildTransaction(context.Background(), &TxParams{
+		Sources:             []*TxSource{{Output: &OwnedOutput{Tx: info, Index: findOutput(t, sender, info)}}},
+		Destinations:        []*TxDestination{{Address: recipientAddr, Amount: 1}},
+		FeeQuantizationMask: 1,
+		RingSize:            1,
+	})
+	require.NoError(t, err)
+}
+
+func TestPrivateKeyPair_BuildTransaction_singleSubaddress(t *testing.T) {
+	sender, err := GenerateKeys()
+	require.NoError(t, err)
+	recipient, err := GenerateKeys()
+	require.NoError(t, err)
+	decoys := newTestDecoys()
+
+	subAddrKeys := recipient.SubAddrPubKeyPair(2, 3)
+	signed, err := sender.BuildTransaction(context.Background(), &TxParams{
+		Sources:      newTestSources(t, sender, decoys),
+		Destinations: []*TxDestination{{Address: subAddrKeys.Address(Mainnet), Amount: 1234}},
+		// no fee
+		FeeQuantizationMask: 1,
+		RingSize:            11,
+		Decoys:              decoys,
+	})
+	require.NoError(t, err)
+	tx := verifyTestTx(t, signed, decoys)
+	require.Empty(t, signed.AdditionalTxKeys)
+	require.Zero(t, signed.Fee)
+
+	// the tx public key is r*D, for the subaddress's spend key D
+	info, err := NewTxInfo(tx)
+	require.NoError(t, err)
+	expected := new(ed25519.Point).ScalarMult(signed.TxKey.key, subAddrKeys.SpendKey().key)
+	require.Equal(t, 1, expected.Equal(info.PubKey.key))
+
+	// the recipient finds the payment with their subaddress table
+	table := recipient.SubaddressTable()
+	require.NoError(t, table.Expand(2, 3))
+	derivation := recipient.vk.KeyDerivation(info.PubKey)
+	var received uint64
+	for _, out := range info.Outputs {
+		spendKey, ok := derivation.OutputSpendKey(out)
+		if !ok {
+			continue
+		}
+		if account, index, found := table.Lookup(spendKey); found {
+			require.Equal(t, [2]uint32{2, 3}, [2]uint32{account, index})
+			received, _, err = derivation.OutputAmount(out)
+			require.NoError(t, err)
+		}
+	}
+	require.Equal(t, uint64(1234), received)
+
+	// a dummy payment ID is added, encrypted for the single recipient
+	extra, err := serialization.ParseTxExtra(tx.Prefix.Extra)
+	require.NoError(t, err)
+	encrypted, ok := extra.EncryptedPaymentID()
+	require.True(t, ok)
+	decrypted := derivation.DecryptPaymentID(encrypted)
+	require.Equal(t, PaymentID{}, decrypted)
+}
+
+func TestPrivateKeyPair_BuildTransaction_integratedAddress(t *testing.T) {
+	sender, err := GenerateKeys()
+	require.NoError(t, err)
+	recipient, err := GenerateKeys()
+	require.NoError(t, err)
+	decoys := newTestDecoys()
+
+	paymentID := PaymentID{1, 2, 3, 4, 5, 6, 7, 8}
+	integrated, err := recipient.PublicKeyPair().IntegratedAddress(Mainnet, paymentID)
+	require.NoError(t, err)
+	change := sender.SubAddrPubKeyPair(0, 1)
+
+	signed, err := sender.BuildTransaction(context.Background(), &TxParams{
+		Sources:             newTestSources(t, sender, decoys)[1:],
+		Destinations:        []*TxDestination{{Address: integrated, Amount: 1500}},
+		Change:              change,
+		FeeQuantizationMask: 1,
+		RingSize:            2,
+		Decoys:              decoys,
+	})
+	require.NoError(t, err)
+	tx := verifyTestTx(t, signed, decoys)
+
+	info, err := NewTxInfo(tx)
+	require.NoError(t, err)
+	received, err := CheckTxKey(info, signed.TxKey, nil, integrated)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1500), received)
+
+	extra, err := serialization.ParseTxExtra(tx.Prefix.Extra)
+	require.NoError(t, err)
+	encrypted, ok := extra.EncryptedPaymentID()
+	require.True(t, ok)
+	require.Equal(t, paymentID, recipient.vk.KeyDerivation(info.PubKey).DecryptPaymentID(encrypted))
+
+	// the change went to our subaddress
+	received, _, err = sender.vk.KeyDerivation(info.PubKey).OutputAmount(info.Outputs[findOutputTo(t, sender, info, change)])
+	require.NoError(t, err)
+	require.Equal(t, uint64(500), received)
+}
+
+func TestPrivateKeyPair_BuildTransaction_errors(t *testing.T) {
+	sender, err := GenerateKeys()
+	require.NoError(t, err)
+	dest := []*TxDestination{{Address: sender.PublicKeyPair().Address(Mainnet), Amount: 100}}
+	ctx := context.Background()
+
+	newParams := func() *TxParams {
+		decoys := newTestDecoys()
+		return &TxParams{
+			Sources:             newTestSources(t, sender, decoys),
+			Destinations:        dest,
+			FeePerByte:          1,
+			FeeQuantizationMask: 1,
+			RingSize:            4,
+			Decoys:              decoys,
+		}
+	}
+
+	params := newParams()
+	params.Destinations = []*TxDestination{{Address: dest[0].Address, Amount: 2500}}
+	_, err = sender.BuildTransaction(ctx, params)
+	require.ErrorIs(t, err, errInsufficientFunds)
+
+	params = newParams()
+	params.Sources = nil
+	_, err = sender.BuildTransaction(ctx, params)
+	require.ErrorIs(t, err, errNoTxSources)
+
+	params = newParams()
+	params.Destinations = []*TxDestination{{Address: dest[0].Address}}
+	_, err = sender.BuildTransaction(ctx, params)
+	require.ErrorIs(t, err, errZeroDestination)
+
+	params = newParams()
+	params.Decoys = nil
+	_, err = sender.BuildTransaction(ctx, params)
+	require.ErrorIs(t, err, errNoDecoyProvider)
+
+	params = newParams()
+	params.Decoys.(*testDecoys).extra = 1
+	_, err = sender.BuildTransaction(ctx, params)
+	require.ErrorIs(t, err, errDecoyCount)
+
+	params = newParams()
+	params.Sources = append(params.Sources, params.Sources[0])
+	_, err = sender.BuildTransaction(ctx, params)
+	require.ErrorIs(t, err, errDuplicateSpentOutput)
+
+	integrated, err := sender.PublicKeyPair().IntegratedAddress(Mainnet, PaymentID{1})
+	require.NoError(t, err)
+	params = newParams()
+	params.Destinations = []*TxDestination{{Address: integrated, Amount: 1}, {Address: integrated, Amount: 2}}
+	_, err = sender.BuildTransaction(ctx, params)
+	require.ErrorIs(t, err, errMultiplePaymentIDs)
+
+	// an output of someone else
+	params = newParams()
+	params.Sources[0].Output.AccountIndex = 5
+	_, err = sender.BuildTransaction(ctx, params)
+	require.ErrorIs(t, err, errOutputNotOwned)
+}
+
+// findOutput returns the index of the output received by the primary address
+// of kp.
+func findOutput(t *testing.T, kp *PrivateKeyPair, tx *TxInfo) uint64 {
+	return findOutputTo(t, kp, tx, kp.PublicKeyPair())
+}
+
+// findOutputTo returns the index of the output, derived with the main
+// transaction public key, received by the address of keys.
+func findOutputTo(t *testing.T, kp *PrivateKeyPair, tx *TxInfo, keys *PublicKeyPair) uint64 {
+	for _, out := range tx.Outputs {
+		if kp.vk.OwnsOutput(keys.SpendKey(), tx.PubKey, out) {
+			return out.Index
+		}
+	}
+	t.Fatal("output not found")
+	return 0
+}
@@ -0,0 +1,74 @@
+package cryptonote
+
+import (
+	"fmt"
+
+	"github.com/dimalinux/gopherphis/serialization"
+)
+
+// NewTxInfo extracts the data needed to scan a transaction's outputs, and to
+// check transaction keys and proofs, from a decoded transaction. Pruned
+// transactions are supported, as scanning does not use the prunable data, but
+// a pruned transaction needs its PrunableHash set to compute the ID.
+func NewTxInfo(tx *serialization.Transaction) (*TxInfo, error) {
+	// Hash also validates that the RingCT signatures match the outputs
+	id, err := tx.Hash()
+	if err != nil {
+		return nil, err
+	}
+	info := &TxInfo{ID: id, UnlockTime: tx.Prefix.UnlockTime}
+	for _, in := range tx.Prefix.Inputs {
+		if in.Type == serialization.InputTypeToKey {
+			info.KeyImages = append(info.KeyImages, in.KeyImage)
+		}
+	}
+
+	// Like wallet2, use whatever fields precede an unparsable part of the
+	// extra field.
+	extra, _ := serialization.ParseTxExtra(tx.Prefix.Extra)
+	if txPubKey, ok := extra.TxPubKey(); ok {
+		if info.PubKey, err = NewPublicKey(txPubKey[:]); err != nil {
+			return nil, fmt.Errorf("tx public key: %w", err)
+		}
+	}
+	for i, key := range extra.AdditionalPubKeys() {
+		pubKey, err := NewPublicKey(key[:])
+		if err != nil {
+			return nil, fmt.Errorf("additional tx public key %d: %w", i, err)
+		}
+		info.AdditionalPubKeys = append(info.AdditionalPubKeys, pubKey)
+	}
+
+	hasCommitments := tx.Rct != nil && tx.Rct.Type != serialization.RctTypeNull
+	for i, out := range tx.Prefix.Outputs {
+		outKey, err := NewPublicKey(out.Key[:])
+		if err != nil {
+			return nil, fmt.Errorf("output %d key: %w", i, err)
+		}
+		txOut := &TxOutput{
+			Index:  uint64(i),
+			Key:    outKey,
+			Amount: out.Amount,
+		}
+		if out.Type == serialization.OutputTypeToTaggedKey {
+			viewTag := out.ViewTag
+			txOut.ViewTag = &viewTag
+		}
+
+		if hasCommitments {
+			if txOut.Commitment, err = NewCommitment(tx.Rct.OutPk[i][:]); err != nil {
+				return nil, fmt.Errorf("output %d commitment: %w", i, err)
+			}
+			ecdh := tx.Rct.EcdhInfo[i]
+			if tx.Rct.Type.CompactEcdhInfo() {
+				txOut.EncryptedAmount = append([]byte{}, ecdh.Amount[:EncryptedAmountLen]...)
+			} else {
+				txOut.EncryptedAmount = append(append([]byte{}, ecdh.Mask[:]...), ecdh.Amount[:]...)
+			}
+		}
+
+		info.Outputs = append(info.Outputs, txOut)
+	}
+
+	return info, nil
+}
@@ -0,0 +1,103 @@
+package cryptonote
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/serialization"
+)
+
+// serializeTestTx returns a pruned CLSAG transaction with the outputs and
+// public keys of info.
+func serializeTestTx(info *TxInfo) *serialization.Transaction {
+	extra := new(serialization.TxExtra)
+	extra.AddTxPubKey([32]byte(info.PubKey.Bytes()))
+	var additional [][32]byte
+	for _, key := range info.AdditionalPubKeys {
+		additional = append(additional, [32]byte(key.Bytes()))
+	}
+	extra.AddAdditionalPubKeys(additional)
+
+	tx := &serialization.Transaction{
+		Prefix: serialization.TxPrefix{
+			Version: serialization.TxVersion2,
+			Inputs: []*serialization.TxInput{{
+				Type:       serialization.InputTypeToKey,
+				KeyOffsets: make([]uint64, 16),
+			}},
+			Extra: extra.Bytes(),
+		},
+		Rct:          &serialization.RctSignatures{RctSigBase: serialization.RctSigBase{Type: serialization.RctTypeCLSAG}},
+		Pruned:       true,
+		PrunableHash: [32]byte{0xff},
+	}
+	for _, out := range info.Outputs {
+		tx.Prefix.Outputs = append(tx.Prefix.Outputs, &serialization.TxOutput{
+			Type:    serialization.OutputTypeToTaggedKey,
+			Key:     [32]byte(out.Key.Bytes()),
+			ViewTag: *out.ViewTag,
+		})
+		ecdh := new(serialization.EcdhInfo)
+		copy(ecdh.Amount[:], out.EncryptedAmount)
+		tx.Rct.EcdhInfo = append(tx.Rct.EcdhInfo, ecdh)
+		tx.Rct.OutPk = append(tx.Rct.OutPk, [32]byte(out.Commitment.Bytes()))
+	}
+	return tx
+}
+
+func TestNewTxInfo(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	expected, txKey, additionalTxKeys := newTestTx(t, kp)
+
+	// round trip through the binary format, as if received from a daemon
+	serialized := serializeTestTx(expected)
+	serialized.Prefix.UnlockTime = 1234
+	serialized.Prefix.Inputs[0].KeyImage = [32]byte{7}
+	raw, err := serialized.MarshalBinary()
+	require.NoError(t, err)
+	tx, err := serialization.DecodeTransaction(raw, true)
+	require.NoError(t, err)
+	tx.PrunableHash = [32]byte{0xff}
+
+	info, err := NewTxInfo(tx)
+	require.NoError(t, err)
+	id, err := tx.Hash()
+	require.NoError(t, err)
+	require.Equal(t, id, info.ID)
+	require.Equal(t, uint64(1234), info.UnlockTime)
+	require.Equal(t, [][KeySize]byte{{7}}, info.KeyImages)
+	require.True(t, expected.PubKey.Equal(info.PubKey))
+	require.Len(t, info.AdditionalPubKeys, len(expected.AdditionalPubKeys))
+	for i, key := range expected.AdditionalPubKeys {
+		require.True(t, key.Equal(info.AdditionalPubKeys[i]))
+	}
+	require.Len(t, info.Outputs, len(expected.Outputs))
+	for i, out := range expected.Outputs {
+		require.Equal(t, out.Index, info.Outputs[i].Index)
+		require.True(t, out.Key.Equal(info.Outputs[i].Key))
+		require.Equal(t, out.ViewTag, info.Outputs[i].ViewTag)
+		require.True(t, out.Commitment.Equal(info.Outputs[i].Commitment))
+		require.Equal(t, out.EncryptedAmount, info.Outputs[i].EncryptedAmount)
+	}
+
+	received, err := CheckTxKey(info, txKey, additionalTxKeys, kp.SubAddrPubKeyPair(1, 2).Address(Mainnet))
+	require.NoError(t, err)
+	require.Equal(t, uint64(2000), received)
+}
+
+func TestNewTxInfo_invalidKey(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	info, _, _ := newTestTx(t, kp)
+	tx := serializeTestTx(info)
+
+	tx.Prefix.Outputs[1].Key = [32]byte{2} // not a valid point
+	_, err = NewTxInfo(tx)
+	require.ErrorContains(t, err, "output 1 key")
+
+	tx.Rct.OutPk = tx.Rct.OutPk[1:]
+	_, err = NewTxInfo(tx)
+	require.ErrorContains(t, err, "do not match the number of outputs")
+}
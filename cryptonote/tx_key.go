@@ -0,0 +1,65 @@
+package cryptonote
+
+import (
+	"encoding/hex"
+
+	ed25519 "filippo.io/edwards25519"
+
+	"github.com/dimalinux/gopherphis/mcrypto"
+)
+
+// TxPrivateKey is the private key, r, of a transaction. The matching public
+// key, R = r*G, is published in the transaction's extra field. Transactions
+// with subaddress recipients may also have additional transaction keys, one
+// per output. The sender keeps the private keys to prove payments.
+type TxPrivateKey struct {
+	key *ed25519.Scalar
+}
+
+// NewTxPrivateKey returns a new TxPrivateKey from its canonical 32-byte
+// little-endian encoding.
+func NewTxPrivateKey(b []byte) (*TxPrivateKey, error) {
+	if len(b) != KeySize {
+		return nil, errInvalidInput
+	}
+
+	r, err := ed25519.NewScalar().SetCanonicalBytes(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TxPrivateKey{
+		key: r,
+	}, nil
+}
+
+// GenerateTxPrivateKey returns a new random TxPrivateKey
+func GenerateTxPrivateKey() *TxPrivateKey {
+	return &TxPrivateKey{key: mcrypto.RandomScalar()}
+}
+
+// Public returns the transaction public key, R = r*G.
+func (k *TxPrivateKey) Public() *PublicKey {
+	return &PublicKey{key: new(ed25519.Point).ScalarBaseMult(k.key)}
+}
+
+// KeyDerivation returns the sender's side of the derivation, 8*r*A, shared
+// with the recipient whose public view key is viewKey.
+func (k *TxPrivateKey) KeyDerivation(viewKey *PublicKey) *KeyDerivation {
+	return newKeyDerivation(viewKey.key, k.key)
+}
+
+// Bytes returns the canonical 32-byte little-endian encoding of the key.
+func (k *TxPrivateKey) Bytes() []byte {
+	return k.key.Bytes()
+}
+
+// Hex formats the key as a hex string
+func (k *TxPrivateKey) Hex() string {
+	return hex.EncodeToString(k.key.Bytes())
+}
+
+// String formats the key as a 0x-prefixed hex string
+func (k *TxPrivateKey) String() string {
+	return "0x" + k.Hex()
+}
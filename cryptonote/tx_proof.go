@@ -0,0 +1,354 @@
+package cryptonote
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	ed25519 "filippo.io/edwards25519"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/dimalinux/gopherphis/base58"
+	"github.com/dimalinux/gopherphis/mcrypto"
+)
+
+// Transaction proof headers. V1 proofs have been superseded by V2 proofs,
+// which also sign the public keys involved.
+const (
+	outProofV1Header = "OutProofV1"
+	outProofV2Header = "OutProofV2"
+	inProofV1Header  = "InProofV1"
+	inProofV2Header  = "InProofV2"
+)
+
+var (
+	errInvalidTxProofFormat  = errors.New("invalid tx proof format")
+	errUnsupportedTxProof    = errors.New("V1 tx proofs are not supported")
+	errInvalidTxProof        = errors.New("invalid tx proof")
+	errAdditionalTxKeysCount = errors.New("number of additional tx keys does not match the number of outputs")
+	errAddressNotOwned       = errors.New("address does not belong to the view key")
+	errTxMissingPubKey       = errors.New("transaction has no public key")
+)
+
+// TxInfo holds the data of a transaction needed to check transaction keys and
+// transaction proofs. It can be filled in from a daemon or a block explorer.
+type TxInfo struct {
+	// ID is the transaction hash
+	ID [32]byte
+	// PubKey is the transaction public key, R, from the extra field
+	PubKey *PublicKey
+	// AdditionalPubKeys holds one public key per output when the transaction
+	// has subaddress recipients, otherwise it is empty.
+	AdditionalPubKeys []*PublicKey
+	// Outputs holds the transaction's outputs, including their amount fields
+	Outputs []*TxOutput
+	// UnlockTime is the block height or unix timestamp before which the
+	// outputs can't be spent
+	UnlockTime uint64
+	// KeyImages holds the key images of the spent inputs, which a wallet uses
+	// to find its spent outputs
+	KeyImages [][KeySize]byte
+}
+
+// receivedAmount is wallet2's check_tx_key_helper. It returns the total amount
+// of the transaction's outputs that were sent to spendKey, using derivation or,
+// when present, the additional derivation of each output. Nil derivations are
+// skipped.
+func (tx *TxInfo) receivedAmount(
+	spendKey *PublicKey,
+	derivation *KeyDerivation,
+	additionalDerivations []*KeyDerivation,
+) (uint64, error) {
+	var received uint64
+
+	for _, out := range tx.Outputs {
+		derivations := []*KeyDerivation{derivation}
+		if out.Index < uint64(len(additionalDerivations)) {
+			derivations = append(derivations, additionalDerivations[out.Index])
+		}
+
+		for _, d := range derivations {
+			if d == nil {
+				continue
+			}
+			outSpendKey, ok := d.OutputSpendKey(out)
+			if !ok || !outSpendKey.Equal(spendKey) {
+				continue
+			}
+
+			amount, _, err := d.OutputAmount(out)
+			if err != nil {
+				return 0, fmt.Errorf("output %d: %w", out.Index, err)
+			}
+			received += amount
+			break
+		}
+	}
+
+	return received, nil
+}
+
+// CheckTxKey verifies the transaction private key, and any additional
+// transaction private keys, given to us by a sender, and returns the amount
+// that the transaction sent to addr. A zero amount means that the keys do not
+// belong to the transaction, or that nothing was sent to the address.
+func CheckTxKey(tx *TxInfo, txKey *TxPrivateKey, additionalTxKeys []*TxPrivateKey, addr *Address) (uint64, error) {
+	pubKeys, err := addr.PublicKeyPair()
+	if err != nil {
+		return 0, err
+	}
+	if len(additionalTxKeys) != 0 && len(additionalTxKeys) != len(tx.Outputs) {
+		return 0, errAdditionalTxKeysCount
+	}
+
+	additionalDerivations := make([]*KeyDerivation, 0, len(additionalTxKeys))
+	for _, k := range additionalTxKeys {
+		additionalDerivations = append(additionalDerivations, k.KeyDerivation(pubKeys.vk))
+	}
+
+	return tx.receivedAmount(pubKeys.sk, txKey.KeyDerivation(pubKeys.vk), additionalDerivations)
+}
+
+// txProofPrefixHash returns the message hash signed by transaction proofs
+func txProofPrefixHash(txID [32]byte, message []byte) []byte {
+	return ethcrypto.Keccak256(txID[:], message)
+}
+
+// txProofChallenge returns the challenge scalar of a V2 transaction proof,
+// Hs(msg || D || X || Y || sep || R || A || B), where B is all zeros when not
+// present.
+func txProofChallenge(prefixHash []byte, R, A, B, D, X, Y *ed25519.Point) *ed25519.Scalar {
+	const domainSep = "TXPROOF_V2"
+	bBytes := make([]byte, KeySize)
+	if B != nil {
+		bBytes = B.Bytes()
+	}
+	return mcrypto.HashToScalar(
+		prefixHash,
+		D.Bytes(),
+		X.Bytes(),
+		Y.Bytes(),
+		ethcrypto.Keccak256([]byte(domainSep)),
+		R.Bytes(),
+		A.Bytes(),
+		bBytes,
+	)
+}
+
+// generateTxProof is Monero's generate_tx_proof (V2). It proves knowledge of r
+// such that R = r*G (or r*B when B is not nil) and D = r*A.
+func generateTxProof(prefixHash []byte, R, A, B, D *ed25519.Point, r *ed25519.Scalar) []byte {
+	k := mcrypto.RandomScalar()
+	var X *ed25519.Point
+	if B != nil {
+		X = new(ed25519.Point).ScalarMult(k, B)
+	} else {
+		X = new(ed25519.Point).ScalarBaseMult(k)
+	}
+	Y := new(ed25519.Point).ScalarMult(k, A)
+
+	c := txProofChallenge(prefixHash, R, A, B, D, X, Y)
+	sigR := new(ed25519.Scalar).Multiply(c, r)
+	sigR.Subtract(k, sigR)
+
+	sig := make([]byte, 0, SignatureLen)
+	sig = append(sig, c.Bytes()...)
+	return append(sig, sigR.Bytes()...)
+}
+
+// checkTxProof is Monero's check_tx_proof (V2)
+func checkTxProof(prefixHash []byte, R, A, B, D *ed25519.Point, sig []byte) bool {
+	c, err := ed25519.NewScalar().SetCanonicalBytes(sig[:32])
+	if err != nil {
+		return false
+	}
+	r, err := ed25519.NewScalar().SetCanonicalBytes(sig[32:])
+	if err != nil {
+		return false
+	}
+
+	// X = c*R + r*G (or r*B), Y = c*D + r*A
+	var X *ed25519.Point
+	if B != nil {
+		X = new(ed25519.Point).VarTimeMultiScalarMult([]*ed25519.Scalar{c, r}, []*ed25519.Point{R, B})
+	} else {
+		X = new(ed25519.Point).VarTimeDoubleScalarBaseMult(c, R, r)
+	}
+	Y := new(ed25519.Point).VarTimeMultiScalarMult([]*ed25519.Scalar{c, r}, []*ed25519.Point{D, A})
+
+	return txProofChallenge(prefixHash, R, A, B, D, X, Y).Equal(c) == 1
+}
+
+// GenerateOutProof returns an OutProofV2 proving that the transaction with
+// the private key txKey, and the additional private keys if any, sent funds
+// to addr. The optional message is signed with the proof. The result is
+// compatible with monero-wallet-cli's check_tx_proof command.
+func GenerateOutProof(
+	txID [32]byte,
+	txKey *TxPrivateKey,
+	additionalTxKeys []*TxPrivateKey,
+	addr *Address,
+	message []byte,
+) (string, error) {
+	pubKeys, err := addr.PublicKeyPair()
+	if err != nil {
+		return "", err
+	}
+
+	// Transactions to a subaddress use R = r*B, where B is the subaddress
+	// spend key, instead of R = r*G.
+	var B *ed25519.Point
+	if addr.Type() == Subaddress {
+		B = pubKeys.sk.key
+	}
+
+	prefixHash := txProofPrefixHash(txID, message)
+	A := pubKeys.vk.key
+
+	var sb strings.Builder
+	sb.WriteString(outProofV2Header)
+	for _, k := range append([]*TxPrivateKey{txKey}, additionalTxKeys...) {
+		var R *ed25519.Point
+		if B != nil {
+			R = new(ed25519.Point).ScalarMult(k.key, B)
+		} else {
+			R = new(ed25519.Point).ScalarBaseMult(k.key)
+		}
+		D := new(ed25519.Point).ScalarMult(k.key, A)
+
+		sb.WriteString(base58.Encode(D.Bytes()))
+		sb.WriteString(base58.Encode(generateTxProof(prefixHash, R, A, B, D, k.key)))
+	}
+
+	return sb.String(), nil
+}
+
+// GenerateInProof returns an InProofV2 proving that the transaction sent funds
+// to addr, which must be the primary address or a subaddress of the view key.
+// The optional message is signed with the proof. The result is compatible with
+// monero-wallet-cli's check_tx_proof command.
+func (k *PrivateViewKey) GenerateInProof(tx *TxInfo, addr *Address, message []byte) (string, error) {
+	if tx.PubKey == nil {
+		return "", errTxMissingPubKey
+	}
+
+	pubKeys, err := addr.PublicKeyPair()
+	if err != nil {
+		return "", err
+	}
+
+	// The view key of a subaddress is a*B, where B is the subaddress spend
+	// key. For the primary address, it is a*G.
+	var B *ed25519.Point
+	var expectedViewKey *ed25519.Point
+	if addr.Type() == Subaddress {
+		B = pubKeys.sk.key
+		expectedViewKey = new(ed25519.Point).ScalarMult(k.key, B)
+	} else {
+		expectedViewKey = new(ed25519.Point).ScalarBaseMult(k.key)
+	}
+	if pubKeys.vk.key.Equal(expectedViewKey) != 1 {
+		return "", errAddressNotOwned
+	}
+
+	prefixHash := txProofPrefixHash(tx.ID, message)
+	R := pubKeys.vk.key
+
+	var sb strings.Builder
+	sb.WriteString(inProofV2Header)
+	for _, txPubKey := range append([]*PublicKey{tx.PubKey}, tx.AdditionalPubKeys...) {
+		A := txPubKey.key
+		D := new(ed25519.Point).ScalarMult(k.key, A)
+
+		sb.WriteString(base58.Encode(D.Bytes()))
+		sb.WriteString(base58.Encode(generateTxProof(prefixHash, R, A, B, D, k.key)))
+	}
+
+	return sb.String(), nil
+}
+
+// CheckTxProof verifies an OutProofV2 or InProofV2, created by
+// monero-wallet-cli's get_tx_proof command or by this package, that the
+// transaction sent funds to addr. On success, the amount received by addr is
+// returned.
+//
+// The proof holds one signature per transaction public key. Like wallet2, we
+// accept the proof if any signature is valid, as the additional keys of
+// outputs to other recipients can't be proven for addr. Only outputs found
+// with the shared secrets of valid signatures count towards the amount.
+func CheckTxProof(tx *TxInfo, addr *Address, message []byte, proof string) (uint64, error) {
+	if tx.PubKey == nil {
+		return 0, errTxMissingPubKey
+	}
+
+	pubKeys, err := addr.PublicKeyPair()
+	if err != nil {
+		return 0, err
+	}
+
+	var isOutProof bool
+	var header string
+	switch {
+	case strings.HasPrefix(proof, outProofV2Header):
+		isOutProof, header = true, outProofV2Header
+	case strings.HasPrefix(proof, inProofV2Header):
+		isOutProof, header = false, inProofV2Header
+	case strings.HasPrefix(proof, outProofV1Header), strings.HasPrefix(proof, inProofV1Header):
+		return 0, errUnsupportedTxProof
+	default:
+		return 0, errInvalidTxProofFormat
+	}
+
+	txPubKeys := append([]*PublicKey{tx.PubKey}, tx.AdditionalPubKeys...)
+	encodedSecretLen := base58.EncodedLen(KeySize)
+	encodedSigLen := base58.EncodedLen(SignatureLen)
+	encoded := proof[len(header):]
+	if len(encoded) != len(txPubKeys)*(encodedSecretLen+encodedSigLen) {
+		return 0, errInvalidTxProofFormat
+	}
+
+	var B *ed25519.Point
+	if addr.Type() == Subaddress {
+		B = pubKeys.sk.key
+	}
+	prefixHash := txProofPrefixHash(tx.ID, message)
+
+	var anyValid bool
+	derivations := make([]*KeyDerivation, 0, len(txPubKeys))
+	for _, txPubKey := range txPubKeys {
+		secretBytes, err := base58.Decode(encoded[:encodedSecretLen])
+		if err != nil {
+			return 0, errInvalidTxProofFormat
+		}
+		sig, err := base58.Decode(encoded[encodedSecretLen : encodedSecretLen+encodedSigLen])
+		if err != nil {
+			return 0, errInvalidTxProofFormat
+		}
+		encoded = encoded[encodedSecretLen+encodedSigLen:]
+
+		D, err := new(ed25519.Point).SetBytes(secretBytes)
+		if err != nil {
+			return 0, errInvalidTxProofFormat
+		}
+
+		R, A := txPubKey.key, pubKeys.vk.key
+		if !isOutProof {
+			R, A = A, R
+		}
+		if !checkTxProof(prefixHash, R, A, B, D, sig) {
+			derivations = append(derivations, nil)
+			continue
+		}
+		anyValid = true
+
+		// the proof's shared secret, D, is the derivation before the
+		// cofactor is cleared
+		derivations = append(derivations, &KeyDerivation{key: new(ed25519.Point).MultByCofactor(D)})
+	}
+
+	if !anyValid {
+		return 0, errInvalidTxProof
+	}
+
+	return tx.receivedAmount(pubKeys.sk, derivations[0], derivations[1:])
+}
@@ -0,0 +1,165 @@
+package cryptonote
+
+import (
+	"testing"
+
+	ed25519 "filippo.io/edwards25519"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestOutput returns a RingCT output at outputIndex paying amount to the
+// recipient using the sender's derivation.
+func newTestOutput(derivation *KeyDerivation, outputIndex uint64, recipient *PublicKeyPair, amount uint64) *TxOutput {
+	viewTag := derivation.ViewTag(outputIndex)
+	encAmount := derivation.EncryptAmount(outputIndex, amount)
+	return &TxOutput{
+		Index:           outputIndex,
+		Key:             derivation.DerivePublicKey(outputIndex, recipient.SpendKey()),
+		ViewTag:         &viewTag,
+		Commitment:      Commit(derivation.CommitmentMask(outputIndex), amount),
+		EncryptedAmount: encAmount[:],
+	}
+}
+
+// newTestTx returns a transaction with 2 outputs, paying 1000 to the primary
+// address of recipientKeys and 2000 to its subaddress (1, 2). The transaction
+// has additional tx keys, as it has a subaddress recipient.
+func newTestTx(t *testing.T, recipientKeys *PrivateKeyPair) (*TxInfo, *TxPrivateKey, []*TxPrivateKey) {
+	primary := recipientKeys.PublicKeyPair()
+	subAddr := recipientKeys.SubAddrPubKeyPair(1, 2)
+
+	txKey := GenerateTxPrivateKey()
+	additionalTxKeys := []*TxPrivateKey{GenerateTxPrivateKey(), GenerateTxPrivateKey()}
+
+	tx := &TxInfo{
+		ID:     [32]byte{1, 2, 3},
+		PubKey: txKey.Public(),
+		AdditionalPubKeys: []*PublicKey{
+			additionalTxKeys[0].Public(),
+			{key: new(ed25519.Point).ScalarMult(additionalTxKeys[1].key, subAddr.SpendKey().key)},
+		},
+		Outputs: []*TxOutput{
+			newTestOutput(txKey.KeyDerivation(primary.ViewKey()), 0, primary, 1000),
+			newTestOutput(additionalTxKeys[1].KeyDerivation(subAddr.ViewKey()), 1, subAddr, 2000),
+		},
+	}
+
+	return tx, txKey, additionalTxKeys
+}
+
+func TestCheckTxKey(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	tx, txKey, additionalTxKeys := newTestTx(t, kp)
+
+	primaryAddr := kp.PublicKeyPair().Address(Mainnet)
+	received, err := CheckTxKey(tx, txKey, additionalTxKeys, primaryAddr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000), received)
+
+	subAddr := kp.SubAddrPubKeyPair(1, 2).Address(Mainnet)
+	received, err = CheckTxKey(tx, txKey, additionalTxKeys, subAddr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2000), received)
+
+	// the wrong tx key finds nothing
+	received, err = CheckTxKey(tx, GenerateTxPrivateKey(), nil, primaryAddr)
+	require.NoError(t, err)
+	require.Zero(t, received)
+
+	_, err = CheckTxKey(tx, txKey, additionalTxKeys[:1], primaryAddr)
+	require.ErrorIs(t, err, errAdditionalTxKeysCount)
+}
+
+func TestOutProof(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	tx, txKey, additionalTxKeys := newTestTx(t, kp)
+	msg := []byte("invoice 42")
+
+	testCases := []struct {
+		addr     *Address
+		received uint64
+	}{
+		{kp.PublicKeyPair().Address(Mainnet), 1000},
+		{kp.SubAddrPubKeyPair(1, 2).Address(Mainnet), 2000},
+	}
+
+	for _, tc := range testCases {
+		proof, err := GenerateOutProof(tx.ID, txKey, additionalTxKeys, tc.addr, msg)
+		require.NoError(t, err)
+		require.Equal(t, outProofV2Header, proof[:len(outProofV2Header)])
+
+		received, err := CheckTxProof(tx, tc.addr, msg, proof)
+		require.NoError(t, err)
+		require.Equal(t, tc.received, received)
+
+		_, err = CheckTxProof(tx, tc.addr, []byte("invoice 43"), proof)
+		require.ErrorIs(t, err, errInvalidTxProof)
+
+		otherTx := *tx
+		otherTx.ID[0]++
+		_, err = CheckTxProof(&otherTx, tc.addr, msg, proof)
+		require.ErrorIs(t, err, errInvalidTxProof)
+	}
+}
+
+func TestInProof(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	tx, _, _ := newTestTx(t, kp)
+
+	testCases := []struct {
+		addr     *Address
+		received uint64
+	}{
+		{kp.PublicKeyPair().Address(Stagenet), 1000},
+		{kp.SubAddrPubKeyPair(1, 2).Address(Stagenet), 2000},
+		{kp.SubAddrPubKeyPair(0, 1).Address(Stagenet), 0},
+	}
+
+	for _, tc := range testCases {
+		proof, err := kp.PrivateViewKey().GenerateInProof(tx, tc.addr, nil)
+		require.NoError(t, err)
+		require.Equal(t, inProofV2Header, proof[:len(inProofV2Header)])
+
+		received, err := CheckTxProof(tx, tc.addr, nil, proof)
+		require.NoError(t, err)
+		require.Equal(t, tc.received, received)
+
+		// the proof is bound to the address
+		otherAddr := kp.SubAddrPubKeyPair(5, 5).Address(Stagenet)
+		_, err = CheckTxProof(tx, otherAddr, nil, proof)
+		require.ErrorIs(t, err, errInvalidTxProof)
+	}
+
+	// addresses of other wallets can't be proven
+	otherKeys, err := GenerateKeys()
+	require.NoError(t, err)
+	_, err = kp.PrivateViewKey().GenerateInProof(tx, otherKeys.PublicKeyPair().Address(Stagenet), nil)
+	require.ErrorIs(t, err, errAddressNotOwned)
+}
+
+func TestCheckTxProof_invalidFormat(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	tx, txKey, additionalTxKeys := newTestTx(t, kp)
+	addr := kp.PublicKeyPair().Address(Mainnet)
+
+	proof, err := GenerateOutProof(tx.ID, txKey, additionalTxKeys, addr, nil)
+	require.NoError(t, err)
+
+	_, err = CheckTxProof(tx, addr, nil, "OutProofV1"+proof[len(outProofV2Header):])
+	require.ErrorIs(t, err, errUnsupportedTxProof)
+
+	for _, badProof := range []string{"", "OutProofV3", proof[:len(proof)-1], proof + "1"} {
+		_, err = CheckTxProof(tx, addr, nil, badProof)
+		require.ErrorIs(t, err, errInvalidTxProofFormat)
+	}
+
+	// the proof has one signature per tx public key
+	noAdditional, err := GenerateOutProof(tx.ID, txKey, nil, addr, nil)
+	require.NoError(t, err)
+	_, err = CheckTxProof(tx, addr, nil, noAdditional)
+	require.ErrorIs(t, err, errInvalidTxProofFormat)
+}
@@ -0,0 +1,279 @@
+package cryptonote
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// URIScheme is the scheme of Monero payment request URIs
+const URIScheme = "monero"
+
+// Query parameters of a Monero URI
+const (
+	uriParamAmount      = "tx_amount"
+	uriParamPaymentID   = "tx_payment_id"
+	uriParamName        = "recipient_name"
+	uriParamDescription = "tx_description"
+)
+
+// uriListSep separates the per-recipient values of multi-recipient URIs
+const uriListSep = ";"
+
+var (
+	errURIScheme           = errors.New(`monero URI must start with "monero:"`)
+	errURINoRecipients     = errors.New("monero URI has no recipient addresses")
+	errURIDuplicateParam   = errors.New("monero URI has a duplicate parameter")
+	errURIInvalidParam     = errors.New("monero URI has an invalid parameter")
+	errURIValueCount       = errors.New("monero URI parameter value count does not match the number of addresses")
+	errURIPaymentID        = errors.New("monero URI payment ID is not 8 or 32 hex-encoded bytes")
+	errURIPaymentIDAndAddr = errors.New("monero URI has both an integrated address and a payment ID")
+)
+
+// URIRecipient is a single payment destination of a MoneroURI
+type URIRecipient struct {
+	Address *Address
+	// Amount is the requested amount, with zero meaning that no amount was
+	// requested.
+	Amount Amount
+	// Name is the optional name of the recipient
+	Name string
+}
+
+// MoneroURI is a payment request, like those encoded in QR codes and deep
+// links, with the format:
+//
+//	monero:<address>?tx_amount=<xmr>&tx_payment_id=<hex>&recipient_name=<name>&tx_description=<text>
+//
+// URIs requesting payment to multiple recipients separate the addresses, and
+// the values of tx_amount and recipient_name, with semicolons. Amounts in the
+// URI are decimal XMR, not piconero.
+type MoneroURI struct {
+	Recipients []*URIRecipient
+	// PaymentID is the optional, legacy, hex-encoded payment ID. Integrated
+	// addresses should be used instead.
+	PaymentID string
+	// Description is the optional description of the transaction
+	Description string
+	// UnknownParams holds any query parameters that are not part of the
+	// format, preserved so that they are not silently dropped.
+	UnknownParams url.Values
+}
+
+// ParseMoneroURI parses a Monero payment request URI. Each recipient address
+// is validated against the expected network.
+func ParseMoneroURI(uri string, net Network) (*MoneroURI, error) {
+	scheme, rest, found := strings.Cut(uri, ":")
+	if !found || !strings.EqualFold(scheme, URIScheme) {
+		return nil, errURIScheme
+	}
+	// Some encoders use the "monero://" form
+	rest = strings.TrimPrefix(rest, "//")
+
+	addrsPart, query, _ := strings.Cut(rest, "?")
+	if addrsPart == "" {
+		return nil, errURINoRecipients
+	}
+
+	u := new(MoneroURI)
+	for _, addrStr := range strings.Split(addrsPart, uriListSep) {
+		addr, err := NewAddress(addrStr, net)
+		if err != nil {
+			return nil, err
+		}
+		u.Recipients = append(u.Recipients, &URIRecipient{Address: addr})
+	}
+
+	if err := u.parseQuery(query); err != nil {
+		return nil, err
+	}
+
+	if err := u.validatePaymentID(); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func (u *MoneroURI) parseQuery(query string) error {
+	seen := make(map[string]bool)
+	for _, param := range strings.Split(query, "&") {
+		if param == "" {
+			continue
+		}
+		key, encodedValue, _ := strings.Cut(param, "=")
+		value, err := url.PathUnescape(encodedValue)
+		if err != nil {
+			return fmt.Errorf("%w %q: %w", errURIInvalidParam, key, err)
+		}
+		if seen[key] {
+			return fmt.Errorf("%w: %s", errURIDuplicateParam, key)
+		}
+		seen[key] = true
+
+		switch key {
+		case uriParamAmount:
+			// The semicolon separator is not escaped, so split the raw value
+			amounts, err := u.splitValues(encodedValue)
+			if err != nil {
+				return err
+			}
+			for i, amtStr := range amounts {
+				u.Recipients[i].Amount, err = ParseAmount(amtStr)
+				if err != nil {
+					return fmt.Errorf("%w %q: %w", errURIInvalidParam, key, err)
+				}
+			}
+		case uriParamName:
+			names, err := u.splitValues(encodedValue)
+			if err != nil {
+				return err
+			}
+			for i, name := range names {
+				u.Recipients[i].Name, err = url.PathUnescape(name)
+				if err != nil {
+					return fmt.Errorf("%w %q: %w", errURIInvalidParam, key, err)
+				}
+			}
+		case uriParamPaymentID:
+			u.PaymentID = value
+		case uriParamDescription:
+			u.Description = value
+		default:
+			if u.UnknownParams == nil {
+				u.UnknownParams = make(url.Values)
+			}
+			u.UnknownParams.Add(key, value)
+		}
+	}
+
+	return nil
+}
+
+// splitValues splits a per-recipient parameter value, requiring one value for
+// each recipient.
+func (u *MoneroURI) splitValues(encodedValue string) ([]string, error) {
+	values := strings.Split(encodedValue, uriListSep)
+	if len(values) != len(u.Recipients) {
+		return nil, errURIValueCount
+	}
+	return values, nil
+}
+
+func (u *MoneroURI) validatePaymentID() error {
+	if u.PaymentID == "" {
+		return nil
+	}
+
+	b, err := hex.DecodeString(u.PaymentID)
+	if err != nil || (len(b) != PaymentIDLen && len(b) != 32) {
+		return errURIPaymentID
+	}
+
+	for _, r := range u.Recipients {
+		if r.Address.Type() == Integrated {
+			return errURIPaymentIDAndAddr
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that the URI has at least one recipient and a valid payment
+// ID, if any. Each recipient address is validated against the passed network.
+func (u *MoneroURI) Validate(net Network) error {
+	if len(u.Recipients) == 0 {
+		return errURINoRecipients
+	}
+	for _, r := range u.Recipients {
+		if r.Address == nil {
+			return errAddressNotInitialized
+		}
+		if err := r.Address.ValidateNet(net); err != nil {
+			return err
+		}
+	}
+	return u.validatePaymentID()
+}
+
+// String formats the URI. Parameters are written in the same order as
+// monero-wallet-cli's make_uri, with any unknown parameters last. Amounts and
+// names are only included if at least one recipient has them.
+func (u *MoneroURI) String() string {
+	var sb strings.Builder
+	sb.WriteString(URIScheme + ":")
+
+	var hasAmount, hasName bool
+	addrs := make([]string, 0, len(u.Recipients))
+	amounts := make([]string, 0, len(u.Recipients))
+	names := make([]string, 0, len(u.Recipients))
+	for _, r := range u.Recipients {
+		addrs = append(addrs, r.Address.String())
+		amounts = append(amounts, r.Amount.String())
+		names = append(names, uriEscape(r.Name))
+		hasAmount = hasAmount || r.Amount > 0
+		hasName = hasName || r.Name != ""
+	}
+	sb.WriteString(strings.Join(addrs, uriListSep))
+
+	sep := "?"
+	writeParam := func(key string, encodedValue string) {
+		sb.WriteString(sep + key + "=" + encodedValue)
+		sep = "&"
+	}
+
+	if u.PaymentID != "" {
+		writeParam(uriParamPaymentID, uriEscape(u.PaymentID))
+	}
+	if hasAmount {
+		writeParam(uriParamAmount, strings.Join(amounts, uriListSep))
+	}
+	if hasName {
+		writeParam(uriParamName, strings.Join(names, uriListSep))
+	}
+	if u.Description != "" {
+		writeParam(uriParamDescription, uriEscape(u.Description))
+	}
+
+	// url.Values.Encode is not used, as it encodes spaces as "+"
+	for _, key := range sortedKeys(u.UnknownParams) {
+		for _, value := range u.UnknownParams[key] {
+			writeParam(uriEscape(key), uriEscape(value))
+		}
+	}
+
+	return sb.String()
+}
+
+func sortedKeys(values url.Values) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// uriEscape percent-encodes every byte of s other than the RFC 3986
+// unreserved characters. Unlike url.QueryEscape, spaces become "%20" and not
+// "+", which Monero wallets would decode as a literal plus sign.
+func uriEscape(s string) string {
+	const upperHex = "0123456789ABCDEF"
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(upperHex[c>>4])
+			sb.WriteByte(upperHex[c&0x0f])
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,106 @@
+package cryptonote
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testURIAddress = "42ey1afDFnn4886T7196doS9GPMzexD9gXpsZJDwVjeRVdFCSoHnv7KPbBeGpzJBzHRCAs9UxqeoyFQMYbqSWYTfJJQAWDm"
+
+func TestParseMoneroURI(t *testing.T) {
+	uri := "monero:" + testURIAddress +
+		"?tx_amount=1.5&recipient_name=Jane%20Doe&tx_description=Coffee%20%26%20cake"
+	u, err := ParseMoneroURI(uri, Mainnet)
+	require.NoError(t, err)
+	require.Len(t, u.Recipients, 1)
+	require.Equal(t, testURIAddress, u.Recipients[0].Address.String())
+	require.Equal(t, 1_500_000_000_000*Piconero, u.Recipients[0].Amount)
+	require.Equal(t, "Jane Doe", u.Recipients[0].Name)
+	require.Equal(t, "Coffee & cake", u.Description)
+	require.Empty(t, u.PaymentID)
+	require.Nil(t, u.UnknownParams)
+	require.Equal(t, uri, u.String())
+}
+
+func TestParseMoneroURI_addressOnly(t *testing.T) {
+	u, err := ParseMoneroURI("monero:"+testURIAddress, Mainnet)
+	require.NoError(t, err)
+	require.Len(t, u.Recipients, 1)
+	require.Zero(t, u.Recipients[0].Amount)
+	require.Equal(t, "monero:"+testURIAddress, u.String())
+}
+
+func TestMoneroURI_multiRecipient(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	subAddr := kp.SubAddrPubKeyPair(0, 1).Address(Mainnet)
+	addr1, err := NewAddress(testURIAddress, Mainnet)
+	require.NoError(t, err)
+
+	u := &MoneroURI{
+		Recipients: []*URIRecipient{
+			{Address: addr1, Amount: 1, Name: "a;b"},
+			{Address: subAddr, Amount: 2 * XMR},
+		},
+		Description: "split",
+	}
+	require.NoError(t, u.Validate(Mainnet))
+
+	uri := u.String()
+	require.Equal(t, "monero:"+addr1.String()+";"+subAddr.String()+
+		"?tx_amount=0.000000000001;2&recipient_name=a%3Bb;&tx_description=split", uri)
+
+	parsed, err := ParseMoneroURI(uri, Mainnet)
+	require.NoError(t, err)
+	require.Equal(t, u, parsed)
+}
+
+func TestParseMoneroURI_unknownParams(t *testing.T) {
+	uri := "monero:" + testURIAddress + "?tx_amount=2&x_label=a%20b"
+	u, err := ParseMoneroURI(uri, Mainnet)
+	require.NoError(t, err)
+	require.Equal(t, url.Values{"x_label": {"a b"}}, u.UnknownParams)
+	require.Equal(t, uri, u.String())
+}
+
+func TestParseMoneroURI_paymentID(t *testing.T) {
+	const paymentID = "0102030405060708"
+	u, err := ParseMoneroURI("monero:"+testURIAddress+"?tx_payment_id="+paymentID, Mainnet)
+	require.NoError(t, err)
+	require.Equal(t, paymentID, u.PaymentID)
+
+	_, err = ParseMoneroURI("monero:"+testURIAddress+"?tx_payment_id=0102", Mainnet)
+	require.ErrorIs(t, err, errURIPaymentID)
+
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	intAddr, err := kp.PublicKeyPair().IntegratedAddress(Mainnet, PaymentID{1})
+	require.NoError(t, err)
+	_, err = ParseMoneroURI("monero:"+intAddr.String()+"?tx_payment_id="+paymentID, Mainnet)
+	require.ErrorIs(t, err, errURIPaymentIDAndAddr)
+}
+
+func TestParseMoneroURI_fail(t *testing.T) {
+	tests := []struct {
+		uri string
+		err error
+	}{
+		{uri: testURIAddress, err: errURIScheme},
+		{uri: "bitcoin:" + testURIAddress, err: errURIScheme},
+		{uri: "monero:", err: errURINoRecipients},
+		{uri: "monero:" + testURIAddress + "?tx_amount=1;2", err: errURIValueCount},
+		{uri: "monero:" + testURIAddress + "?tx_amount=1&tx_amount=2", err: errURIDuplicateParam},
+		{uri: "monero:" + testURIAddress + "?tx_amount=1e3", err: errInvalidAmount},
+		{uri: "monero:" + testURIAddress + "?tx_description=%zz", err: errURIInvalidParam},
+	}
+	for _, tt := range tests {
+		_, err := ParseMoneroURI(tt.uri, Mainnet)
+		require.ErrorIs(t, err, tt.err, tt.uri)
+	}
+
+	var netErr *NetworkMismatchError
+	_, err := ParseMoneroURI("monero:"+testURIAddress, Stagenet)
+	require.ErrorAs(t, err, &netErr)
+}
@@ -0,0 +1,96 @@
+package cryptonote
+
+import (
+	"errors"
+
+	ed25519 "filippo.io/edwards25519"
+)
+
+var (
+	errViewKeySubaddress = errors.New("view-only keys need the primary address, not a subaddress")
+	errViewKeyMismatch   = errors.New("private view key does not match the address")
+)
+
+// ViewKeyPair holds a wallet's private view key and public spend key, which
+// are the keys of a view-only wallet. They find the outputs received by the
+// wallet's addresses and decrypt their amounts, but they can't spend them or
+// compute their key images.
+type ViewKeyPair struct {
+	vk *PrivateViewKey
+	sk *PublicKey
+}
+
+// NewViewKeyPair returns the ViewKeyPair with the passed private view key and
+// public spend key
+func NewViewKeyPair(viewKey *PrivateViewKey, spendKey *PublicKey) *ViewKeyPair {
+	return &ViewKeyPair{vk: viewKey, sk: spendKey}
+}
+
+// NewViewKeyPairFromAddress returns the ViewKeyPair of the wallet with the
+// passed primary address, or integrated address, and private view key. The
+// view key must match the address.
+func NewViewKeyPairFromAddress(addr *Address, viewKey *PrivateViewKey) (*ViewKeyPair, error) {
+	if addr.Type() == Subaddress {
+		return nil, errViewKeySubaddress
+	}
+	pubKeys, err := addr.PublicKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	if !viewKey.Public().Equal(pubKeys.vk) {
+		return nil, errViewKeyMismatch
+	}
+	return NewViewKeyPair(viewKey, pubKeys.sk), nil
+}
+
+// ViewKeyPair returns the key pair's private view key and public spend key
+func (kp *PrivateKeyPair) ViewKeyPair() *ViewKeyPair {
+	return NewViewKeyPair(kp.vk, kp.sk.Public())
+}
+
+// PrivateViewKey returns the key pair's private view key
+func (kp *ViewKeyPair) PrivateViewKey() *PrivateViewKey {
+	return kp.vk
+}
+
+// SpendKey returns the key pair's public spend key
+func (kp *ViewKeyPair) SpendKey() *PublicKey {
+	return kp.sk
+}
+
+// PublicKeyPair returns the public keys of the primary address
+func (kp *ViewKeyPair) PublicKeyPair() *PublicKeyPair {
+	return &PublicKeyPair{
+		isSubAddress: false,
+		sk:           kp.sk,
+		vk:           kp.vk.Public(),
+	}
+}
+
+// SubAddrPubKeyPair returns the PublicKeyPair of the requested subaddress, or
+// of the primary address for account 0, index 0.
+func (kp *ViewKeyPair) SubAddrPubKeyPair(accountIndex uint32, subAddrIndex uint32) *PublicKeyPair {
+	if accountIndex == 0 && subAddrIndex == 0 {
+		return kp.PublicKeyPair()
+	}
+
+	spendKeyPub := kp.vk.subAddressSpendKey(kp.sk, accountIndex, subAddrIndex)
+	viewKeyPub := new(ed25519.Point).ScalarMult(kp.vk.key, spendKeyPub)
+
+	return &PublicKeyPair{
+		isSubAddress: true,
+		sk:           &PublicKey{key: spendKeyPub},
+		vk:           &PublicKey{key: viewKeyPub},
+	}
+}
+
+// SubaddressTable returns a SubaddressTable for the key pair using wallet2's
+// default lookahead window.
+func (kp *ViewKeyPair) SubaddressTable() *SubaddressTable {
+	return NewSubaddressTable(
+		kp.vk,
+		kp.sk,
+		DefaultSubaddressLookaheadAccounts,
+		DefaultSubaddressLookaheadIndices,
+	)
+}
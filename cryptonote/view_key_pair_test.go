@@ -0,0 +1,38 @@
+package cryptonote
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewViewKeyPairFromAddress(t *testing.T) {
+	kp, err := GenerateKeys()
+	require.NoError(t, err)
+	addr := kp.PublicKeyPair().Address(Stagenet)
+
+	viewKeys, err := NewViewKeyPairFromAddress(addr, kp.PrivateViewKey())
+	require.NoError(t, err)
+	require.True(t, kp.PublicKeyPair().SpendKey().Equal(viewKeys.SpendKey()))
+	require.Equal(t, kp.PrivateViewKey().Hex(), viewKeys.PrivateViewKey().Hex())
+
+	// the view-only keys derive the same addresses
+	for _, idx := range [][2]uint32{{0, 0}, {0, 1}, {1, 0}, {3, 7}} {
+		expected := kp.SubAddrPubKeyPair(idx[0], idx[1]).Address(Stagenet)
+		require.True(t, expected.Equal(viewKeys.SubAddrPubKeyPair(idx[0], idx[1]).Address(Stagenet)))
+	}
+
+	// an integrated address has the keys of the primary address
+	integrated, err := kp.PublicKeyPair().IntegratedAddress(Stagenet, PaymentID{1})
+	require.NoError(t, err)
+	_, err = NewViewKeyPairFromAddress(integrated, kp.PrivateViewKey())
+	require.NoError(t, err)
+
+	_, err = NewViewKeyPairFromAddress(kp.SubAddrPubKeyPair(0, 1).Address(Stagenet), kp.PrivateViewKey())
+	require.ErrorIs(t, err, errViewKeySubaddress)
+
+	other, err := GenerateKeys()
+	require.NoError(t, err)
+	_, err = NewViewKeyPairFromAddress(addr, other.PrivateViewKey())
+	require.ErrorIs(t, err, errViewKeyMismatch)
+}
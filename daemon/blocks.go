@@ -0,0 +1,228 @@
+package daemon
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/dimalinux/gopherphis/epee"
+	"github.com/dimalinux/gopherphis/serialization"
+)
+
+// BlockHeader is the header of a block, with the values the daemon computes
+// for it
+type BlockHeader struct {
+	MajorVersion uint8  `json:"major_version"`
+	MinorVersion uint8  `json:"minor_version"`
+	Timestamp    uint64 `json:"timestamp"`
+	PrevHash     Hash   `json:"prev_hash"`
+	Nonce        uint32 `json:"nonce"`
+	Height       uint64 `json:"height"`
+	Hash         Hash   `json:"hash"`
+	// Depth is the number of blocks after this one
+	Depth        uint64 `json:"depth"`
+	OrphanStatus bool   `json:"orphan_status"`
+	Difficulty   uint64 `json:"difficulty"`
+	// Reward is the block reward, in piconero, including the fees
+	Reward      uint64 `json:"reward"`
+	BlockWeight uint64 `json:"block_weight"`
+	NumTxes     uint64 `json:"num_txes"`
+	MinerTxHash Hash   `json:"miner_tx_hash"`
+}
+
+// Block is a block returned by GetBlock
+type Block struct {
+	Header *BlockHeader
+	// Blob is the serialized block, which includes the miner transaction,
+	// but only the hashes of the other transactions
+	Blob     []byte
+	TxHashes []Hash
+}
+
+// Decode decodes the block
+func (b *Block) Decode() (*serialization.Block, error) {
+	return serialization.DecodeBlock(b.Blob)
+}
+
+// GetBlock returns the block at a height, with the get_block method
+func (c *Client) GetBlock(ctx context.Context, height uint64) (*Block, error) {
+	params := struct {
+		Height uint64 `json:"height"`
+	}{Height: height}
+	return c.getBlock(ctx, params)
+}
+
+// GetBlockByHash returns a block by its hash, with the get_block method
+func (c *Client) GetBlockByHash(ctx context.Context, hash Hash) (*Block, error) {
+	params := struct {
+		Hash Hash `json:"hash"`
+	}{Hash: hash}
+	return c.getBlock(ctx, params)
+}
+
+func (c *Client) getBlock(ctx context.Context, params any) (*Block, error) {
+	var result struct {
+		Blob        string       `json:"blob"`
+		BlockHeader *BlockHeader `json:"block_header"`
+		TxHashes    []Hash       `json:"tx_hashes"`
+	}
+	if err := c.callJSONRPC(ctx, "get_block", params, &result); err != nil {
+		return nil, err
+	}
+	blob, err := hex.DecodeString(result.Blob)
+	if err != nil {
+		return nil, fmt.Errorf("get_block: %w", err)
+	}
+	return &Block{Header: result.BlockHeader, Blob: blob, TxHashes: result.TxHashes}, nil
+}
+
+// BlockEntry is a block returned by GetBlocks, with its transactions
+type BlockEntry struct {
+	// Blob is the serialized block, which includes the miner transaction
+	Blob        []byte
+	BlockWeight uint64
+	// Txs are the block's transactions, excluding the miner transaction, in
+	// the order of the block's transaction hashes
+	Txs []*TxBlob
+	// OutputIndices holds the global output indices of the outputs of each
+	// of the block's transactions, starting with the miner transaction
+	OutputIndices [][]uint64
+}
+
+// Decode decodes the block
+func (b *BlockEntry) Decode() (*serialization.Block, error) {
+	return serialization.DecodeBlock(b.Blob)
+}
+
+// TxBlob is a serialized transaction returned by GetBlocks
+type TxBlob struct {
+	Blob []byte
+	// Pruned is true if the prunable data of the transaction was removed,
+	// and PrunableHash is then its hash
+	Pruned       bool
+	PrunableHash Hash
+}
+
+// Decode decodes the transaction
+func (t *TxBlob) Decode() (*serialization.Transaction, error) {
+	tx, err := serialization.DecodeTransaction(t.Blob, t.Pruned)
+	if err != nil {
+		return nil, err
+	}
+	if t.Pruned {
+		tx.PrunableHash = t.PrunableHash
+	}
+	return tx, nil
+}
+
+// Blocks are the blocks returned by GetBlocks
+type Blocks struct {
+	Blocks []*BlockEntry
+	// StartHeight is the height of the first returned block
+	StartHeight uint64
+	// CurrentHeight is the number of blocks in the daemon's chain
+	CurrentHeight uint64
+}
+
+// getBlocksRequest is COMMAND_RPC_GET_BLOCKS_FAST::request
+type getBlocksRequest struct {
+	// RequestedInfo is 0 for blocks only
+	RequestedInfo uint8  `epee:"requested_info"`
+	BlockIDs      []Hash `epee:"block_ids,blob"`
+	StartHeight   uint64 `epee:"start_height"`
+	Prune         bool   `epee:"prune"`
+	NoMinerTx     bool   `epee:"no_miner_tx"`
+}
+
+type getBlocksResponse struct {
+	Blocks        []blockCompleteEntry `epee:"blocks"`
+	StartHeight   uint64               `epee:"start_height"`
+	CurrentHeight uint64               `epee:"current_height"`
+	OutputIndices []blockOutputIndices `epee:"output_indices"`
+}
+
+type blockCompleteEntry struct {
+	Pruned      bool          `epee:"pruned"`
+	Block       []byte        `epee:"block"`
+	BlockWeight uint64        `epee:"block_weight"`
+	Txs         txBlobEntries `epee:"txs"`
+}
+
+type txBlobEntry struct {
+	Blob         []byte `epee:"blob"`
+	PrunableHash Hash   `epee:"prunable_hash"`
+}
+
+// txBlobEntries are the transactions of a block_complete_entry, which are
+// sections with the pruned blob and the prunable hash for pruned blocks, and
+// just the blobs otherwise
+type txBlobEntries []txBlobEntry
+
+// UnmarshalEpee implements epee.Unmarshaler
+func (e *txBlobEntries) UnmarshalEpee(value any) error {
+	if values, ok := value.([]any); !ok || len(values) == 0 {
+		return epee.UnmarshalValue(value, (*[]txBlobEntry)(e))
+	} else if _, isBlob := values[0].([]byte); !isBlob {
+		return epee.UnmarshalValue(value, (*[]txBlobEntry)(e))
+	}
+
+	var blobs [][]byte
+	if err := epee.UnmarshalValue(value, &blobs); err != nil {
+		return err
+	}
+	*e = make(txBlobEntries, len(blobs))
+	for i, blob := range blobs {
+		(*e)[i].Blob = blob
+	}
+	return nil
+}
+
+type blockOutputIndices struct {
+	Indices []txOutputIndices `epee:"indices"`
+}
+
+type txOutputIndices struct {
+	Indices []uint64 `epee:"indices"`
+}
+
+// GetBlocks returns blocks with their transactions, with the binary
+// get_blocks.bin endpoint, which is how wallets scan the chain. If
+// startHeight is zero, the blocks start at the newest of blockIDs that is in
+// the daemon's chain. blockIDs is a list of block hashes, newest first, which
+// must end with the genesis block, so that the daemon can find where the
+// wallet's chain and its own diverge. The daemon limits the number of
+// returned blocks. If prune is set, the transactions are returned without
+// their prunable data, which wallets don't need.
+func (c *Client) GetBlocks(ctx context.Context, startHeight uint64, blockIDs []Hash, prune bool) (*Blocks, error) {
+	req := &getBlocksRequest{BlockIDs: blockIDs, StartHeight: startHeight, Prune: prune}
+	var resp getBlocksResponse
+	if err := c.callBinary(ctx, "/get_blocks.bin", req, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.OutputIndices) != 0 && len(resp.OutputIndices) != len(resp.Blocks) {
+		return nil, fmt.Errorf("/get_blocks.bin: %w", errResultCount)
+	}
+
+	blocks := &Blocks{
+		Blocks:        make([]*BlockEntry, len(resp.Blocks)),
+		StartHeight:   resp.StartHeight,
+		CurrentHeight: resp.CurrentHeight,
+	}
+	for i, entry := range resp.Blocks {
+		block := &BlockEntry{
+			Blob:        entry.Block,
+			BlockWeight: entry.BlockWeight,
+			Txs:         make([]*TxBlob, len(entry.Txs)),
+		}
+		for j, tx := range entry.Txs {
+			block.Txs[j] = &TxBlob{Blob: tx.Blob, Pruned: entry.Pruned, PrunableHash: tx.PrunableHash}
+		}
+		if len(resp.OutputIndices) != 0 {
+			for _, indices := range resp.OutputIndices[i].Indices {
+				block.OutputIndices = append(block.OutputIndices, indices.Indices)
+			}
+		}
+		blocks.Blocks[i] = block
+	}
+	return blocks, nil
+}
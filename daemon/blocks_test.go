@@ -0,0 +1,105 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_GetBlock(t *testing.T) {
+	c, server := newTestClient(t)
+	require.NoError(t, server.AddBlocks(2))
+	ctx := context.Background()
+
+	block, err := c.GetBlock(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), block.Header.Height)
+	require.Equal(t, uint64(1), block.Header.Depth)
+	require.Equal(t, Hash(server.BlockHash(1)), block.Header.Hash)
+	require.Equal(t, Hash(server.BlockHash(0)), block.Header.PrevHash)
+	require.Empty(t, block.TxHashes)
+
+	decoded, err := block.Decode()
+	require.NoError(t, err)
+	hash, err := decoded.Hash()
+	require.NoError(t, err)
+	require.Equal(t, block.Header.Hash, Hash(hash))
+	minerTxHash, err := decoded.MinerTx.Hash()
+	require.NoError(t, err)
+	require.Equal(t, block.Header.MinerTxHash, Hash(minerTxHash))
+	require.Equal(t, decoded.MinerTx.Prefix.Outputs[0].Amount, block.Header.Reward)
+
+	byHash, err := c.GetBlockByHash(ctx, block.Header.Hash)
+	require.NoError(t, err)
+	require.Equal(t, block, byHash)
+
+	_, err = c.GetBlock(ctx, 3)
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, -2, rpcErr.Code)
+
+	_, err = c.GetBlockByHash(ctx, Hash{1})
+	require.ErrorAs(t, err, &rpcErr)
+}
+
+func TestClient_GetBlocks(t *testing.T) {
+	c, server := newTestClient(t)
+	wallet := newTestWallet(t, c, server)
+	tx := newTestTransfer(t, c, wallet)
+	require.NoError(t, c.SendRawTransaction(context.Background(), tx.Blob, false))
+	_, err := server.MinePool()
+	require.NoError(t, err)
+	height := server.Height()
+
+	for _, prune := range []bool{false, true} {
+		blocks, err := c.GetBlocks(context.Background(), height-2, nil, prune)
+		require.NoError(t, err)
+		require.Equal(t, height-2, blocks.StartHeight)
+		require.Equal(t, height, blocks.CurrentHeight)
+		require.Len(t, blocks.Blocks, 2)
+		require.Empty(t, blocks.Blocks[0].Txs)
+		require.Len(t, blocks.Blocks[0].OutputIndices, 1)
+
+		entry := blocks.Blocks[1]
+		block, err := entry.Decode()
+		require.NoError(t, err)
+		hash, err := block.Hash()
+		require.NoError(t, err)
+		require.Equal(t, server.BlockHash(height-1), hash)
+		require.Equal(t, [][32]byte{tx.ID}, block.TxHashes)
+
+		require.Len(t, entry.Txs, 1)
+		require.Equal(t, prune, entry.Txs[0].Pruned)
+		decoded, err := entry.Txs[0].Decode()
+		require.NoError(t, err)
+		txHash, err := decoded.Hash()
+		require.NoError(t, err)
+		require.Equal(t, tx.ID, txHash)
+
+		// the miner transaction and the transfer's two outputs
+		require.Len(t, entry.OutputIndices, 2)
+		require.Len(t, entry.OutputIndices[1], 2)
+		require.Equal(t, entry.OutputIndices[0][0]+1, entry.OutputIndices[1][0])
+	}
+}
+
+func TestClient_GetBlocks_blockIDs(t *testing.T) {
+	c, server := newTestClient(t)
+	require.NoError(t, server.AddBlocks(5))
+	ctx := context.Background()
+
+	// the newest known block is the first returned
+	blockIDs := []Hash{{1}, Hash(server.BlockHash(3)), Hash(server.BlockHash(1)), Hash(server.BlockHash(0))}
+	blocks, err := c.GetBlocks(ctx, 0, blockIDs, true)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), blocks.StartHeight)
+	require.Len(t, blocks.Blocks, 3)
+
+	blocks, err = c.GetBlocks(ctx, 0, nil, false)
+	require.NoError(t, err)
+	require.Len(t, blocks.Blocks, 6)
+
+	_, err = c.GetBlocks(ctx, 0, []Hash{{1}}, false)
+	require.ErrorIs(t, err, errResponseStatus)
+}
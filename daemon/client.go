@@ -0,0 +1,342 @@
+// Package daemon is a client for the RPC interface of monerod, the Monero
+// daemon. It supports the JSON-RPC methods under /json_rpc, the plain JSON
+// endpoints, like /get_transactions, and the binary endpoints, like
+// /get_blocks.bin, which use epee's portable storage format.
+//
+// Every request is bounded by the client's timeout, in addition to the
+// caller's context. Daemons started with --rpc-login are supported using HTTP
+// digest authentication.
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dimalinux/gopherphis/digest"
+	"github.com/dimalinux/gopherphis/epee"
+)
+
+// DefaultTimeout is the timeout of each request, unless changed with
+// WithTimeout
+const DefaultTimeout = time.Minute
+
+// Response statuses. Other values, like "BUSY", are also errors.
+const (
+	statusOK     = "OK"
+	statusFailed = "Failed"
+)
+
+var (
+	errInvalidURL      = errors.New("daemon URL must be an http or https URL")
+	errHTTPStatus      = errors.New("unexpected daemon HTTP status")
+	errUnauthorized    = errors.New("daemon rejected the RPC login")
+	errNoLogin         = errors.New("daemon requires an RPC login")
+	errResponseStatus  = errors.New("daemon response status is not OK")
+	errEmptyResult     = errors.New("daemon JSON-RPC response has no result")
+	errInvalidHashHex  = errors.New("hash must be 64 hex characters")
+	errMissedTxs       = errors.New("daemon does not have some of the requested transactions")
+	errResultCount     = errors.New("daemon returned the wrong number of results")
+	errNoDistributions = errors.New("daemon returned no output distribution")
+)
+
+// Hash is a 32-byte block or transaction hash. Its text form, used in JSON,
+// is hex.
+type Hash [32]byte
+
+// String returns the hash as hex
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Like monerod, an empty
+// string is the zero hash.
+func (h *Hash) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*h = Hash{}
+		return nil
+	}
+	if len(text) != hex.EncodedLen(len(h)) {
+		return errInvalidHashHex
+	}
+	_, err := hex.Decode(h[:], text)
+	return err
+}
+
+// RPCError is an error returned by a JSON-RPC method
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("daemon RPC error %d: %s", e.Code, e.Message)
+}
+
+// responseStatus holds the fields common to all daemon responses
+type responseStatus struct {
+	Status    string `json:"status" epee:"status"`
+	Untrusted bool   `json:"untrusted" epee:"untrusted"`
+}
+
+func (s *responseStatus) check() error {
+	if s.Status != statusOK {
+		return fmt.Errorf("%w: %q", errResponseStatus, s.Status)
+	}
+	return nil
+}
+
+// ClientOption configures a Client
+type ClientOption func(*Client)
+
+// WithDigestAuth sets the username and password of a daemon started with
+// --rpc-login
+func WithDigestAuth(username, password string) ClientOption {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithTimeout sets the timeout of each request. A timeout of zero leaves
+// requests bounded only by their context.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for requests, for example to
+// connect through a proxy
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// Client is a monerod RPC client. It is safe for concurrent use.
+type Client struct {
+	url        string
+	httpClient *http.Client
+	timeout    time.Duration
+	username   string
+	password   string
+
+	mu sync.Mutex
+	// challenge is the daemon's last digest authentication challenge, which
+	// is reused until the daemon sends a new one
+	challenge *digest.Challenge
+}
+
+// NewClient returns a client for the daemon at daemonURL, like
+// http://127.0.0.1:18081
+func NewClient(daemonURL string, opts ...ClientOption) (*Client, error) {
+	u, err := url.Parse(daemonURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errInvalidURL
+	}
+
+	c := &Client{
+		url:        strings.TrimSuffix(u.String(), "/"),
+		httpClient: http.DefaultClient,
+		timeout:    DefaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// callJSONRPC calls a method under the /json_rpc endpoint and decodes its
+// result
+func (c *Client) callJSONRPC(ctx context.Context, method string, params any, result any) error {
+	req := struct {
+		JSONRPC string `json:"jsonrpc"`
+		ID      string `json:"id"`
+		Method  string `json:"method"`
+		Params  any    `json:"params,omitempty"`
+	}{JSONRPC: "2.0", ID: "0", Method: method, Params: params}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	respBody, err := c.post(ctx, "/json_rpc", "application/json", body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("%s: %w", method, resp.Error)
+	}
+	if len(resp.Result) == 0 {
+		return fmt.Errorf("%s: %w", method, errEmptyResult)
+	}
+	if err := decodeJSON(resp.Result, result); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	return nil
+}
+
+// callJSON calls a plain JSON endpoint, like /get_transactions
+func (c *Client) callJSON(ctx context.Context, path string, req any, result any) error {
+	return c.callJSONWith(ctx, path, req, result, decodeJSON)
+}
+
+// callJSONUnchecked is callJSON without the check of the response status
+func (c *Client) callJSONUnchecked(ctx context.Context, path string, req any, result any) error {
+	return c.callJSONWith(ctx, path, req, result, json.Unmarshal)
+}
+
+func (c *Client) callJSONWith(
+	ctx context.Context,
+	path string,
+	req any,
+	result any,
+	decode func(data []byte, result any) error,
+) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	respBody, err := c.post(ctx, path, "application/json", body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := decode(respBody, result); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// decodeJSON decodes a JSON response into result and checks its status
+func decodeJSON(data []byte, result any) error {
+	var status responseStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return err
+	}
+	if err := status.check(); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
+}
+
+// callBinary calls a binary endpoint, like /get_blocks.bin, whose request
+// and response are encoded with epee's portable storage
+func (c *Client) callBinary(ctx context.Context, path string, req any, result any) error {
+	body, err := epee.Marshal(req)
+	if err != nil {
+		return err
+	}
+	respBody, err := c.post(ctx, path, "application/octet-stream", body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	section, err := epee.Decode(respBody)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	var status responseStatus
+	if err := epee.UnmarshalValue(section, &status); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := status.check(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := epee.UnmarshalValue(section, result); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// post sends a request to the daemon and returns the response body. If the
+// daemon requires digest authentication, the request is retried with the
+// daemon's challenge, which is then reused for later requests.
+func (c *Client) post(ctx context.Context, path string, contentType string, body []byte) ([]byte, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	resp, err := c.send(ctx, path, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge, err := c.newChallenge(resp)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		c.challenge = challenge
+		c.mu.Unlock()
+
+		if resp, err = c.send(ctx, path, contentType, body); err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized {
+			_ = resp.Body.Close()
+			return nil, errUnauthorized
+		}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", errHTTPStatus, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// newChallenge returns the digest authentication challenge of a 401
+// response, and closes its body
+func (c *Client) newChallenge(resp *http.Response) (*digest.Challenge, error) {
+	_ = resp.Body.Close()
+	if c.username == "" && c.password == "" {
+		return nil, errNoLogin
+	}
+	return digest.ParseChallenges(resp.Header.Values("WWW-Authenticate"))
+}
+
+// send sends a single POST request, with an authorization header if the
+// client has a digest challenge
+func (c *Client) send(ctx context.Context, path string, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	c.mu.Lock()
+	if c.challenge != nil {
+		req.Header.Set("Authorization", c.challenge.Authorization(c.username, c.password, http.MethodPost, path))
+	}
+	c.mu.Unlock()
+
+	return c.httpClient.Do(req)
+}
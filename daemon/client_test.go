@@ -0,0 +1,144 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/daemon/fakedaemon"
+)
+
+// newTestClient starts a fake daemon and returns a client for it
+func newTestClient(t *testing.T, opts ...ClientOption) (*Client, *fakedaemon.Server) {
+	server := fakedaemon.New()
+	t.Cleanup(server.Close)
+	c, err := NewClient(server.URL, opts...)
+	require.NoError(t, err)
+	return c, server
+}
+
+func TestNewClient_invalidURL(t *testing.T) {
+	for _, u := range []string{"", "127.0.0.1:18081", "ftp://127.0.0.1", "http://", "http://[::1"} {
+		_, err := NewClient(u)
+		require.ErrorIs(t, err, errInvalidURL, u)
+	}
+}
+
+func TestHash_text(t *testing.T) {
+	h := Hash{1, 2, 3}
+	data, err := json.Marshal(h)
+	require.NoError(t, err)
+	require.Equal(t, `"0102030000000000000000000000000000000000000000000000000000000000"`, string(data))
+
+	var decoded Hash
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, h, decoded)
+
+	require.NoError(t, json.Unmarshal([]byte(`""`), &decoded))
+	require.Equal(t, Hash{}, decoded)
+
+	require.ErrorIs(t, json.Unmarshal([]byte(`"0102"`), &decoded), errInvalidHashHex)
+}
+
+func TestClient_GetInfo(t *testing.T) {
+	c, server := newTestClient(t)
+	require.NoError(t, server.AddBlocks(4))
+
+	info, err := c.GetInfo(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), info.Height)
+	require.Equal(t, Hash(server.BlockHash(4)), info.TopBlockHash)
+	require.Equal(t, "fakechain", info.Nettype)
+	require.True(t, info.Synchronized)
+}
+
+func TestClient_GetFeeEstimate(t *testing.T) {
+	c, server := newTestClient(t)
+	server.SetFeeEstimate(20_000, 10_000)
+
+	estimate, err := c.GetFeeEstimate(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 20_000, estimate.FeePerByte)
+	require.Len(t, estimate.Fees, 4)
+	require.EqualValues(t, 20_000, estimate.Fees[0])
+	require.Equal(t, uint64(10_000), estimate.QuantizationMask)
+}
+
+func TestClient_digestAuth(t *testing.T) {
+	server := fakedaemon.NewWithLogin("user", "pass")
+	t.Cleanup(server.Close)
+
+	c, err := NewClient(server.URL, WithDigestAuth("user", "pass"))
+	require.NoError(t, err)
+	// the first request gets the challenge, and later ones reuse it with an
+	// incremented nonce count
+	for i := 0; i < 3; i++ {
+		_, err = c.GetInfo(context.Background())
+		require.NoError(t, err)
+	}
+	_, err = c.GetBlocks(context.Background(), 0, nil, false)
+	require.NoError(t, err)
+
+	c, err = NewClient(server.URL, WithDigestAuth("user", "wrong"))
+	require.NoError(t, err)
+	_, err = c.GetInfo(context.Background())
+	require.ErrorIs(t, err, errUnauthorized)
+
+	c, err = NewClient(server.URL)
+	require.NoError(t, err)
+	_, err = c.GetInfo(context.Background())
+	require.ErrorIs(t, err, errNoLogin)
+}
+
+func TestClient_timeout(t *testing.T) {
+	c, server := newTestClient(t, WithTimeout(50*time.Millisecond))
+	server.SetResponseDelay(time.Second)
+
+	_, err := c.GetInfo(context.Background())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the caller's context also bounds the request
+	c, err = NewClient(server.URL, WithTimeout(0))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.GetInfo(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestClient_errors(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	c, err := NewClient(server.URL + "/")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	body = `{"jsonrpc":"2.0","id":"0","error":{"code":-2,"message":"too big"}}`
+	_, err = c.GetInfo(ctx)
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, -2, rpcErr.Code)
+
+	body = `{"jsonrpc":"2.0","id":"0","result":{"status":"BUSY"}}`
+	_, err = c.GetInfo(ctx)
+	require.ErrorIs(t, err, errResponseStatus)
+
+	body = `{"jsonrpc":"2.0","id":"0"}`
+	_, err = c.GetInfo(ctx)
+	require.ErrorIs(t, err, errEmptyResult)
+
+	err = c.callJSON(ctx, "/missing", struct{}{}, new(responseStatus))
+	require.ErrorIs(t, err, errHTTPStatus)
+}
@@ -0,0 +1,436 @@
+// Package fakedaemon is an in-memory fake of monerod's RPC interface, for
+// testing code that uses the daemon package without a running daemon. It
+// serves the methods supported by the daemon package from a chain built with
+// AddBlock, and checks transactions sent to it for parse errors, double
+// spends and low fees before adding them to its pool.
+package fakedaemon
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	ed25519 "filippo.io/edwards25519"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+	"github.com/dimalinux/gopherphis/digest"
+	"github.com/dimalinux/gopherphis/mcrypto"
+	"github.com/dimalinux/gopherphis/serialization"
+)
+
+const (
+	// blockTime is the spacing of the fake chain's block timestamps
+	blockTime = 120
+	// genesisTimestamp is the timestamp of the fake chain's first block
+	genesisTimestamp = 1_700_000_000
+	// spendableAge is the number of blocks before an output is unlocked
+	spendableAge = 10
+	// minerUnlockWindow is the number of blocks before a miner transaction's
+	// outputs are unlocked
+	minerUnlockWindow = 60
+	// minerReward is the amount paid by the default miner transactions
+	minerReward = 600_000_000_000
+	// maxBlocksPerRequest is the maximum number of blocks returned by
+	// get_blocks.bin
+	maxBlocksPerRequest = 1000
+	// blockVersion is the major and minor version of the fake chain's blocks
+	blockVersion = 16
+)
+
+var errCoinbaseInput = errors.New("transaction has a coinbase input")
+
+// tx is a transaction in the chain or the pool
+type tx struct {
+	tx   *serialization.Transaction
+	blob []byte
+	hash [32]byte
+	// height is the height of the block containing the transaction
+	height uint64
+	inPool bool
+	// outputIndices are the global indices of the transaction's outputs
+	outputIndices []uint64
+}
+
+// block is a block of the chain
+type block struct {
+	block *serialization.Block
+	blob  []byte
+	hash  [32]byte
+	// txs holds the miner transaction followed by the other transactions
+	txs []*tx
+}
+
+// output is a RingCT output of the chain
+type output struct {
+	key        [32]byte
+	commitment [32]byte
+	height     uint64
+	unlockTime uint64
+	txHash     [32]byte
+}
+
+// Server is a fake monerod listening on a local port. Use its URL with
+// daemon.NewClient.
+type Server struct {
+	*httptest.Server
+	// auth checks the requests' digest authorization, if the server has a
+	// login
+	auth *digest.Server
+
+	mu        sync.Mutex
+	blocks    []*block
+	txs       map[[32]byte]*tx
+	pool      []*tx
+	outputs   []*output
+	keyImages map[[32]byte]bool
+	// feePerByte is the fee returned by get_fee_estimate, and the minimum
+	// fee per byte of accepted transactions
+	feePerByte       uint64
+	quantizationMask uint64
+	delay            time.Duration
+	// miner receives the rewards of new blocks, if set
+	miner *cryptonote.PublicKeyPair
+}
+
+// New starts a fake daemon, without authentication, whose chain has a
+// genesis block
+func New() *Server {
+	return NewWithLogin("", "")
+}
+
+// NewWithLogin starts a fake daemon, like one started with
+// --rpc-login username:password, which requires digest authentication
+func NewWithLogin(username, password string) *Server {
+	s := &Server{
+		txs:              make(map[[32]byte]*tx),
+		keyImages:        make(map[[32]byte]bool),
+		feePerByte:       20_000,
+		quantizationMask: 10_000,
+	}
+	if username != "" || password != "" {
+		s.auth = digest.NewServer(username, password)
+	}
+	if _, err := s.AddBlock(); err != nil {
+		panic(err)
+	}
+	s.Server = httptest.NewServer(s.handler())
+	return s
+}
+
+// SetFeeEstimate sets the fee per byte and quantization mask returned by
+// get_fee_estimate. Transactions paying less than the fee per byte are
+// rejected.
+func (s *Server) SetFeeEstimate(feePerByte uint64, quantizationMask uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.feePerByte = feePerByte
+	s.quantizationMask = quantizationMask
+}
+
+// SetResponseDelay delays every response, to test timeouts
+func (s *Server) SetResponseDelay(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = delay
+}
+
+// SetMiner sets the keys that receive the rewards of the blocks added after.
+// If miner is nil, the rewards go to random keys.
+func (s *Server) SetMiner(miner *cryptonote.PublicKeyPair) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.miner = miner
+}
+
+// Height returns the number of blocks in the chain
+func (s *Server) Height() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return uint64(len(s.blocks))
+}
+
+// BlockHash returns the hash of the block at height
+func (s *Server) BlockHash(height uint64) [32]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.blocks[height].hash
+}
+
+// PoolTxs returns the hashes of the transactions in the pool
+func (s *Server) PoolTxs() [][32]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hashes := make([][32]byte, len(s.pool))
+	for i, t := range s.pool {
+		hashes[i] = t.hash
+	}
+	return hashes
+}
+
+// AddBlock adds a block with the passed transactions to the chain, and
+// returns it. The block's miner transaction pays a fixed reward, which is
+// unlocked after 60 blocks, to the keys set with SetMiner. Transactions in the pool are removed from it.
+func (s *Server) AddBlock(txs ...*serialization.Transaction) (*serialization.Block, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addBlock(txs)
+}
+
+// AddBlocks adds n blocks without transactions to the chain
+func (s *Server) AddBlocks(n int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		if _, err := s.addBlock(nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MinePool adds a block with all the transactions of the pool to the chain
+func (s *Server) MinePool() (*serialization.Block, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	txs := make([]*serialization.Transaction, len(s.pool))
+	for i, t := range s.pool {
+		txs[i] = t.tx
+	}
+	return s.addBlock(txs)
+}
+
+// PopBlocks removes the last n blocks from the chain, like a reorganization,
+// and returns their transactions, other than the miner transactions, to the
+// pool. The genesis block can't be removed. Blocks added after are different
+// from the removed ones, as miner transactions have random keys.
+func (s *Server) PopBlocks(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n && len(s.blocks) > 1; i++ {
+		b := s.blocks[len(s.blocks)-1]
+		s.blocks = s.blocks[:len(s.blocks)-1]
+		for j := len(b.txs) - 1; j >= 0; j-- {
+			t := b.txs[j]
+			// outputs are indexed in chain order, so the block's outputs
+			// are the last ones
+			s.outputs = s.outputs[:len(s.outputs)-len(t.outputIndices)]
+			t.outputIndices = nil
+			if j == 0 {
+				delete(s.txs, t.hash)
+				continue
+			}
+			// the key images stay spent by the pool
+			t.inPool = true
+			s.pool = append(s.pool, t)
+		}
+	}
+}
+
+func (s *Server) addBlock(txs []*serialization.Transaction) (*serialization.Block, error) {
+	height := uint64(len(s.blocks))
+	b := &serialization.Block{
+		Header: serialization.BlockHeader{
+			MajorVersion: blockVersion,
+			MinorVersion: blockVersion,
+			Timestamp:    genesisTimestamp + height*blockTime,
+		},
+		MinerTx: newMinerTx(height, s.miner),
+	}
+	if height > 0 {
+		b.Header.PrevID = s.blocks[height-1].hash
+	}
+
+	blockTxs := make([]*tx, 0, len(txs)+1)
+	for _, t := range append([]*serialization.Transaction{b.MinerTx}, txs...) {
+		newTx, err := newChainTx(t)
+		if err != nil {
+			return nil, err
+		}
+		if len(blockTxs) > 0 {
+			b.TxHashes = append(b.TxHashes, newTx.hash)
+		}
+		blockTxs = append(blockTxs, newTx)
+	}
+
+	blob, err := b.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := b.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range blockTxs {
+		t.height = height
+		s.indexOutputs(t)
+		s.spendKeyImages(t.tx)
+		s.txs[t.hash] = t
+		s.removeFromPool(t.hash)
+	}
+	s.blocks = append(s.blocks, &block{block: b, blob: blob, hash: hash, txs: blockTxs})
+	return b, nil
+}
+
+// newMinerTx returns a miner transaction paying minerReward to miner, or to
+// a random key if miner is nil
+func newMinerTx(height uint64, miner *cryptonote.PublicKeyPair) *serialization.Transaction {
+	txKey := cryptonote.GenerateTxPrivateKey()
+	out := &serialization.TxOutput{Amount: minerReward, Type: serialization.OutputTypeToTaggedKey}
+	if miner != nil {
+		derivation := txKey.KeyDerivation(miner.ViewKey())
+		out.Key = [32]byte(derivation.DerivePublicKey(0, miner.SpendKey()).Bytes())
+		out.ViewTag = derivation.ViewTag(0)
+	} else {
+		out.Key = [32]byte(new(ed25519.Point).ScalarBaseMult(mcrypto.RandomScalar()).Bytes())
+	}
+
+	extra := new(serialization.TxExtra)
+	extra.AddTxPubKey([32]byte(txKey.Public().Bytes()))
+	return &serialization.Transaction{
+		Prefix: serialization.TxPrefix{
+			Version:    serialization.TxVersion2,
+			UnlockTime: height + minerUnlockWindow,
+			Inputs:     []*serialization.TxInput{{Type: serialization.InputTypeGen, Height: height}},
+			Outputs:    []*serialization.TxOutput{out},
+			Extra:      extra.Bytes(),
+		},
+		Rct: &serialization.RctSignatures{RctSigBase: serialization.RctSigBase{Type: serialization.RctTypeNull}},
+	}
+}
+
+func newChainTx(t *serialization.Transaction) (*tx, error) {
+	blob, err := t.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := t.Hash()
+	if err != nil {
+		return nil, err
+	}
+	return &tx{tx: t, blob: blob, hash: hash}, nil
+}
+
+// indexOutputs assigns global indices to the outputs of a RingCT
+// transaction. The outputs of version 1 transactions are indexed by amount
+// on the real chain, which the fake chain doesn't support.
+func (s *Server) indexOutputs(t *tx) {
+	if t.tx.Prefix.Version != serialization.TxVersion2 {
+		return
+	}
+	for i, out := range t.tx.Prefix.Outputs {
+		o := &output{key: out.Key, height: t.height, unlockTime: t.tx.Prefix.UnlockTime, txHash: t.hash}
+		if t.tx.Rct.Type == serialization.RctTypeNull {
+			// outputs with a cleartext amount have a commitment with a mask
+			// of one
+			o.commitment = [32]byte(mcrypto.Commit(mcrypto.ScalarFromUint64(1), out.Amount).Bytes())
+		} else {
+			o.commitment = t.tx.Rct.OutPk[i]
+		}
+		t.outputIndices = append(t.outputIndices, uint64(len(s.outputs)))
+		s.outputs = append(s.outputs, o)
+	}
+}
+
+func (s *Server) spendKeyImages(t *serialization.Transaction) {
+	for _, in := range t.Prefix.Inputs {
+		if in.Type == serialization.InputTypeToKey {
+			s.keyImages[in.KeyImage] = true
+		}
+	}
+}
+
+func (s *Server) removeFromPool(hash [32]byte) {
+	for i, t := range s.pool {
+		if t.hash == hash {
+			s.pool = append(s.pool[:i], s.pool[i+1:]...)
+			return
+		}
+	}
+}
+
+// isUnlocked returns true if the output can be spent in the next block, like
+// monerod's is_tx_spendtime_unlocked with the spendable age
+func (s *Server) isUnlocked(o *output) bool {
+	height := uint64(len(s.blocks))
+	if o.height+spendableAge > height {
+		return false
+	}
+	// unlock times below this are block heights, and above are timestamps
+	const maxBlockNumber = 500_000_000
+	if o.unlockTime < maxBlockNumber {
+		return o.unlockTime <= height
+	}
+	return o.unlockTime <= uint64(time.Now().Unix())+blockTime
+}
+
+// acceptTx checks a transaction sent by send_raw_transaction, and adds it to
+// the pool. It returns the response flags of a rejected transaction.
+func (s *Server) acceptTx(blob []byte) map[string]any {
+	t, err := serialization.DecodeTransaction(blob, false)
+	if err != nil {
+		return map[string]any{"reason": fmt.Sprintf("failed to parse transaction: %s", err)}
+	}
+	poolTx, err := newChainTx(t)
+	if err != nil {
+		return map[string]any{"sanity_check_failed": true, "reason": err.Error()}
+	}
+
+	for _, in := range t.Prefix.Inputs {
+		if in.Type != serialization.InputTypeToKey {
+			return map[string]any{"invalid_input": true, "reason": errCoinbaseInput.Error()}
+		}
+		if s.keyImages[in.KeyImage] {
+			return map[string]any{"double_spend": true}
+		}
+	}
+	if t.Rct != nil && t.Rct.Type != serialization.RctTypeNull {
+		weight, err := t.Weight()
+		if err != nil {
+			return map[string]any{"sanity_check_failed": true, "reason": err.Error()}
+		}
+		if t.Rct.TxnFee < weight*s.feePerByte {
+			return map[string]any{"fee_too_low": true}
+		}
+	}
+
+	poolTx.inPool = true
+	s.spendKeyImages(t)
+	s.txs[poolTx.hash] = poolTx
+	s.pool = append(s.pool, poolTx)
+	return nil
+}
+
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json_rpc", s.handleJSONRPC)
+	mux.HandleFunc("/get_transactions", jsonHandler(s, s.getTransactions))
+	mux.HandleFunc("/get_outs", jsonHandler(s, s.getOuts))
+	mux.HandleFunc("/send_raw_transaction", jsonHandler(s, s.sendRawTransaction))
+	mux.HandleFunc("/get_blocks.bin", s.handleGetBlocksBin)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		delay := s.delay
+		s.mu.Unlock()
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if s.auth != nil && !s.auth.Check(w, r) {
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
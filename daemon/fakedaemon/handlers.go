@@ -0,0 +1,520 @@
+package fakedaemon
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/dimalinux/gopherphis/epee"
+)
+
+const (
+	statusOK     = "OK"
+	statusFailed = "Failed"
+)
+
+// JSON-RPC error codes of monerod
+const (
+	errCodeMethodNotFound = -32601
+	errCodeInvalidParams  = -32602
+	errCodeTooBigHeight   = -2
+	errCodeInternal       = -5
+)
+
+var errHashHex = errors.New("hash must be 64 hex characters")
+
+// hash is a block or transaction hash, which is hex in JSON
+type hash [32]byte
+
+func (h hash) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(h[:])), nil
+}
+
+func (h *hash) UnmarshalText(text []byte) error {
+	if len(text) != hex.EncodedLen(len(h)) {
+		return errHashHex
+	}
+	_, err := hex.Decode(h[:], text)
+	return err
+}
+
+// rpcError is a JSON-RPC error
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// jsonHandler serves a plain JSON endpoint, whose request is decoded into a
+// new value of type T
+func jsonHandler[T any](s *Server, handle func(req *T) any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := new(T)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		resp := handle(req)
+		s.mu.Unlock()
+		writeJSON(w, resp)
+	}
+}
+
+func (s *Server) handleJSONRPC(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Params) == 0 {
+		req.Params = json.RawMessage("{}")
+	}
+
+	methods := map[string]func(params json.RawMessage) (any, *rpcError){
+		"get_info":                s.getInfo,
+		"get_block":               s.getBlock,
+		"get_fee_estimate":        s.getFeeEstimate,
+		"get_output_distribution": s.getOutputDistribution,
+	}
+	resp := struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Result  any             `json:"result,omitempty"`
+		Error   *rpcError       `json:"error,omitempty"`
+	}{JSONRPC: "2.0", ID: req.ID}
+
+	if method, ok := methods[req.Method]; ok {
+		s.mu.Lock()
+		resp.Result, resp.Error = method(req.Params)
+		s.mu.Unlock()
+	} else {
+		resp.Error = &rpcError{Code: errCodeMethodNotFound, Message: "Method not found"}
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) getInfo(json.RawMessage) (any, *rpcError) {
+	top := s.blocks[len(s.blocks)-1]
+	return map[string]any{
+		"height":              len(s.blocks),
+		"target_height":       0,
+		"top_block_hash":      hash(top.hash),
+		"difficulty":          1,
+		"nettype":             "fakechain",
+		"synchronized":        true,
+		"busy_syncing":        false,
+		"offline":             false,
+		"tx_pool_size":        len(s.pool),
+		"block_weight_limit":  600_000,
+		"block_weight_median": 300_000,
+		"version":             "0.18.3.4-fake",
+		"status":              statusOK,
+		"untrusted":           false,
+	}, nil
+}
+
+func (s *Server) getBlock(rawParams json.RawMessage) (any, *rpcError) {
+	var params struct {
+		Height uint64 `json:"height"`
+		Hash   string `json:"hash"`
+	}
+	if err := json.Unmarshal(rawParams, &params); err != nil {
+		return nil, &rpcError{Code: errCodeInvalidParams, Message: err.Error()}
+	}
+
+	height := params.Height
+	if params.Hash != "" {
+		var h hash
+		if err := h.UnmarshalText([]byte(params.Hash)); err != nil {
+			return nil, &rpcError{Code: errCodeInvalidParams, Message: err.Error()}
+		}
+		found := false
+		for i, b := range s.blocks {
+			if b.hash == h {
+				height, found = uint64(i), true
+				break
+			}
+		}
+		if !found {
+			return nil, &rpcError{
+				Code:    errCodeInternal,
+				Message: "Internal error: can't get block by hash. Hash = " + params.Hash + ".",
+			}
+		}
+	} else if height >= uint64(len(s.blocks)) {
+		return nil, &rpcError{Code: errCodeTooBigHeight, Message: fmt.Sprintf(
+			"Requested block height: %d greater than current top block height: %d", height, len(s.blocks)-1,
+		)}
+	}
+
+	b := s.blocks[height]
+	var reward, weight uint64
+	for _, out := range b.block.MinerTx.Prefix.Outputs {
+		reward += out.Amount
+	}
+	for _, t := range b.txs {
+		w, err := t.tx.Weight()
+		if err != nil {
+			return nil, &rpcError{Code: errCodeInternal, Message: err.Error()}
+		}
+		weight += w
+	}
+	txHashes := make([]hash, len(b.block.TxHashes))
+	for i, h := range b.block.TxHashes {
+		txHashes[i] = h
+	}
+
+	return map[string]any{
+		"blob": hex.EncodeToString(b.blob),
+		"block_header": map[string]any{
+			"major_version": b.block.Header.MajorVersion,
+			"minor_version": b.block.Header.MinorVersion,
+			"timestamp":     b.block.Header.Timestamp,
+			"prev_hash":     hash(b.block.Header.PrevID),
+			"nonce":         b.block.Header.Nonce,
+			"height":        height,
+			"hash":          hash(b.hash),
+			"depth":         uint64(len(s.blocks)) - height - 1,
+			"orphan_status": false,
+			"difficulty":    1,
+			"reward":        reward,
+			"block_weight":  weight,
+			"num_txes":      len(b.block.TxHashes),
+			"miner_tx_hash": hash(b.txs[0].hash),
+		},
+		"tx_hashes": txHashes,
+		"status":    statusOK,
+		"untrusted": false,
+	}, nil
+}
+
+func (s *Server) getFeeEstimate(json.RawMessage) (any, *rpcError) {
+	return map[string]any{
+		"fee":               s.feePerByte,
+		"fees":              []uint64{s.feePerByte, s.feePerByte * 5, s.feePerByte * 25, s.feePerByte * 1000},
+		"quantization_mask": s.quantizationMask,
+		"status":            statusOK,
+		"untrusted":         false,
+	}, nil
+}
+
+func (s *Server) getOutputDistribution(rawParams json.RawMessage) (any, *rpcError) {
+	var params struct {
+		Amounts    []uint64 `json:"amounts"`
+		FromHeight uint64   `json:"from_height"`
+		ToHeight   uint64   `json:"to_height"`
+		Cumulative bool     `json:"cumulative"`
+	}
+	if err := json.Unmarshal(rawParams, &params); err != nil {
+		return nil, &rpcError{Code: errCodeInvalidParams, Message: err.Error()}
+	}
+	if len(params.Amounts) != 1 || params.Amounts[0] != 0 {
+		return nil, &rpcError{Code: errCodeInternal, Message: "only RingCT outputs are supported"}
+	}
+
+	toHeight := params.ToHeight
+	if toHeight == 0 || toHeight >= uint64(len(s.blocks)) {
+		toHeight = uint64(len(s.blocks)) - 1
+	}
+	if params.FromHeight > toHeight {
+		return nil, &rpcError{Code: errCodeInternal, Message: "Failed to get output distribution"}
+	}
+
+	// counts holds the number of RingCT outputs of each block
+	counts := make([]uint64, len(s.blocks))
+	for _, o := range s.outputs {
+		counts[o.height]++
+	}
+	var base uint64
+	for _, c := range counts[:params.FromHeight] {
+		base += c
+	}
+	distribution := counts[params.FromHeight : toHeight+1]
+	if params.Cumulative {
+		sum := base
+		for i, c := range distribution {
+			sum += c
+			distribution[i] = sum
+		}
+	}
+
+	return map[string]any{
+		"distributions": []map[string]any{{
+			"amount":       0,
+			"start_height": params.FromHeight,
+			"distribution": distribution,
+			"base":         base,
+		}},
+		"status":    statusOK,
+		"untrusted": false,
+	}, nil
+}
+
+type getTransactionsRequest struct {
+	TxsHashes []hash `json:"txs_hashes"`
+	Prune     bool   `json:"prune"`
+}
+
+func (s *Server) getTransactions(req *getTransactionsRequest) any {
+	type txEntry struct {
+		AsHex           string   `json:"as_hex"`
+		PrunedAsHex     string   `json:"pruned_as_hex"`
+		PrunableHash    hash     `json:"prunable_hash"`
+		TxHash          hash     `json:"tx_hash"`
+		BlockHeight     uint64   `json:"block_height"`
+		BlockTimestamp  uint64   `json:"block_timestamp"`
+		InPool          bool     `json:"in_pool"`
+		DoubleSpendSeen bool     `json:"double_spend_seen"`
+		OutputIndices   []uint64 `json:"output_indices"`
+	}
+	var txs []*txEntry
+	var missed []hash
+	for _, h := range req.TxsHashes {
+		t, ok := s.txs[h]
+		if !ok {
+			missed = append(missed, h)
+			continue
+		}
+		entry := &txEntry{TxHash: h, InPool: t.inPool, OutputIndices: t.outputIndices}
+		if !t.inPool {
+			entry.BlockHeight = t.height
+			entry.BlockTimestamp = s.blocks[t.height].block.Header.Timestamp
+		}
+		if req.Prune {
+			pruned, err := t.tx.Prune()
+			if err != nil {
+				return map[string]any{"status": err.Error()}
+			}
+			blob, err := pruned.MarshalBinary()
+			if err != nil {
+				return map[string]any{"status": err.Error()}
+			}
+			entry.PrunedAsHex = hex.EncodeToString(blob)
+			entry.PrunableHash = pruned.PrunableHash
+		} else {
+			entry.AsHex = hex.EncodeToString(t.blob)
+		}
+		txs = append(txs, entry)
+	}
+	return map[string]any{"txs": txs, "missed_tx": missed, "status": statusOK, "untrusted": false}
+}
+
+type getOutsRequest struct {
+	Outputs []struct {
+		Amount uint64 `json:"amount"`
+		Index  uint64 `json:"index"`
+	} `json:"outputs"`
+	GetTxID bool `json:"get_txid"`
+}
+
+func (s *Server) getOuts(req *getOutsRequest) any {
+	type outEntry struct {
+		Height   uint64 `json:"height"`
+		Key      hash   `json:"key"`
+		Mask     hash   `json:"mask"`
+		TxID     hash   `json:"txid"`
+		Unlocked bool   `json:"unlocked"`
+	}
+	outs := make([]*outEntry, len(req.Outputs))
+	for i, ref := range req.Outputs {
+		if ref.Amount != 0 || ref.Index >= uint64(len(s.outputs)) {
+			return map[string]any{"status": statusFailed}
+		}
+		o := s.outputs[ref.Index]
+		outs[i] = &outEntry{Height: o.height, Key: o.key, Mask: o.commitment, Unlocked: s.isUnlocked(o)}
+		if req.GetTxID {
+			outs[i].TxID = o.txHash
+		}
+	}
+	return map[string]any{"outs": outs, "status": statusOK, "untrusted": false}
+}
+
+type sendRawTransactionRequest struct {
+	TxAsHex    string `json:"tx_as_hex"`
+	DoNotRelay bool   `json:"do_not_relay"`
+}
+
+func (s *Server) sendRawTransaction(req *sendRawTransactionRequest) any {
+	resp := map[string]any{"status": statusFailed, "not_relayed": req.DoNotRelay, "untrusted": false}
+	blob, err := hex.DecodeString(req.TxAsHex)
+	if err != nil {
+		resp["reason"] = "Failed to parse hex representation of transaction"
+		return resp
+	}
+	rejected := s.acceptTx(blob)
+	if rejected == nil {
+		resp["status"] = statusOK
+		return resp
+	}
+	for k, v := range rejected {
+		resp[k] = v
+	}
+	return resp
+}
+
+// getBlocksRequest is the request of get_blocks.bin
+type getBlocksRequest struct {
+	BlockIDs    [][32]byte `epee:"block_ids,blob"`
+	StartHeight uint64     `epee:"start_height"`
+	Prune       bool       `epee:"prune"`
+}
+
+type getBlocksResponse struct {
+	// Blocks holds blockEntry values, or prunedBlockEntry values for pruned
+	// requests
+	Blocks        any                  `epee:"blocks"`
+	StartHeight   uint64               `epee:"start_height"`
+	CurrentHeight uint64               `epee:"current_height"`
+	OutputIndices []blockOutputIndices `epee:"output_indices"`
+	Status        string               `epee:"status"`
+	Untrusted     bool                 `epee:"untrusted"`
+}
+
+// blockEntry is a block_complete_entry, whose transactions are blobs
+type blockEntry struct {
+	Block       []byte   `epee:"block"`
+	BlockWeight uint64   `epee:"block_weight"`
+	Txs         [][]byte `epee:"txs"`
+}
+
+// prunedBlockEntry is a pruned block_complete_entry, whose transactions are
+// sections with the pruned blob and the prunable hash
+type prunedBlockEntry struct {
+	Pruned      bool           `epee:"pruned"`
+	Block       []byte         `epee:"block"`
+	BlockWeight uint64         `epee:"block_weight"`
+	Txs         []prunedTxBlob `epee:"txs"`
+}
+
+type prunedTxBlob struct {
+	Blob         []byte   `epee:"blob"`
+	PrunableHash [32]byte `epee:"prunable_hash"`
+}
+
+type blockOutputIndices struct {
+	Indices []txOutputIndices `epee:"indices"`
+}
+
+type txOutputIndices struct {
+	Indices []uint64 `epee:"indices"`
+}
+
+func (s *Server) handleGetBlocksBin(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req getBlocksRequest
+	if err := epee.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	resp, err := s.getBlocks(&req)
+	s.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	respBody, err := epee.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(respBody)
+}
+
+// getBlocks returns the blocks from the request's start height or, if it is
+// zero, from the newest of its block IDs that is in the chain
+func (s *Server) getBlocks(req *getBlocksRequest) (*getBlocksResponse, error) {
+	resp := &getBlocksResponse{CurrentHeight: uint64(len(s.blocks)), Status: statusOK}
+	start := req.StartHeight
+	if start == 0 && len(req.BlockIDs) > 0 {
+		found := false
+		for _, id := range req.BlockIDs {
+			for i, b := range s.blocks {
+				if b.hash == id {
+					start, found = uint64(i), true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			resp.Status = statusFailed
+			return resp, nil
+		}
+	}
+	if start > uint64(len(s.blocks)) {
+		resp.Status = statusFailed
+		return resp, nil
+	}
+	resp.StartHeight = start
+
+	end := min(uint64(len(s.blocks)), start+maxBlocksPerRequest)
+	var entries []blockEntry
+	var prunedEntries []prunedBlockEntry
+	for _, b := range s.blocks[start:end] {
+		var weight uint64
+		var indices blockOutputIndices
+		var blobs [][]byte
+		var prunedBlobs []prunedTxBlob
+		for i, t := range b.txs {
+			w, err := t.tx.Weight()
+			if err != nil {
+				return nil, err
+			}
+			weight += w
+			indices.Indices = append(indices.Indices, txOutputIndices{Indices: t.outputIndices})
+			if i == 0 {
+				continue
+			}
+			if !req.Prune {
+				blobs = append(blobs, t.blob)
+				continue
+			}
+			pruned, err := t.tx.Prune()
+			if err != nil {
+				return nil, err
+			}
+			blob, err := pruned.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			prunedBlobs = append(prunedBlobs, prunedTxBlob{Blob: blob, PrunableHash: pruned.PrunableHash})
+		}
+		resp.OutputIndices = append(resp.OutputIndices, indices)
+		if req.Prune {
+			prunedEntries = append(prunedEntries, prunedBlockEntry{
+				Pruned: true, Block: b.blob, BlockWeight: weight, Txs: prunedBlobs,
+			})
+		} else {
+			entries = append(entries, blockEntry{Block: b.blob, BlockWeight: weight, Txs: blobs})
+		}
+	}
+	if req.Prune {
+		resp.Blocks = prunedEntries
+	} else {
+		resp.Blocks = entries
+	}
+	return resp, nil
+}
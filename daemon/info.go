@@ -0,0 +1,75 @@
+package daemon
+
+import (
+	"context"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+)
+
+// Info is the daemon's state, returned by GetInfo
+type Info struct {
+	// Height is the number of blocks in the daemon's chain, one more than
+	// the height of the top block
+	Height       uint64 `json:"height"`
+	TargetHeight uint64 `json:"target_height"`
+	TopBlockHash Hash   `json:"top_block_hash"`
+	Difficulty   uint64 `json:"difficulty"`
+	// Nettype is mainnet, stagenet, testnet or fakechain
+	Nettype           string `json:"nettype"`
+	Synchronized      bool   `json:"synchronized"`
+	BusySyncing       bool   `json:"busy_syncing"`
+	Offline           bool   `json:"offline"`
+	TxPoolSize        uint64 `json:"tx_pool_size"`
+	BlockWeightLimit  uint64 `json:"block_weight_limit"`
+	BlockWeightMedian uint64 `json:"block_weight_median"`
+	Version           string `json:"version"`
+	// Untrusted is true if the daemon is bootstrapping from another daemon,
+	// whose responses could be false
+	Untrusted bool `json:"untrusted"`
+}
+
+// GetInfo returns the daemon's state, with the get_info method
+func (c *Client) GetInfo(ctx context.Context) (*Info, error) {
+	info := new(Info)
+	if err := c.callJSONRPC(ctx, "get_info", nil, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// FeeEstimate holds the fee values used to build transactions, in the form
+// expected by cryptonote.TxParams
+type FeeEstimate struct {
+	// FeePerByte is the fee per byte of transaction weight for the default
+	// priority
+	FeePerByte cryptonote.Amount
+	// Fees are the fees per byte for each of the 4 priorities, from lowest
+	// to highest
+	Fees []cryptonote.Amount
+	// QuantizationMask is the value that fees are rounded up to a multiple
+	// of
+	QuantizationMask uint64
+}
+
+// GetFeeEstimate returns the current fee per byte, with the get_fee_estimate
+// method
+func (c *Client) GetFeeEstimate(ctx context.Context) (*FeeEstimate, error) {
+	var result struct {
+		Fee              uint64   `json:"fee"`
+		Fees             []uint64 `json:"fees"`
+		QuantizationMask uint64   `json:"quantization_mask"`
+	}
+	if err := c.callJSONRPC(ctx, "get_fee_estimate", nil, &result); err != nil {
+		return nil, err
+	}
+
+	estimate := &FeeEstimate{
+		FeePerByte:       cryptonote.Amount(result.Fee),
+		Fees:             make([]cryptonote.Amount, len(result.Fees)),
+		QuantizationMask: result.QuantizationMask,
+	}
+	for i, fee := range result.Fees {
+		estimate.Fees[i] = cryptonote.Amount(fee)
+	}
+	return estimate, nil
+}
@@ -0,0 +1,114 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+)
+
+// Output is a RingCT output returned by GetOuts
+type Output struct {
+	Height uint64 `json:"height"`
+	// Key is the output's one-time public key
+	Key Hash `json:"key"`
+	// Mask is the output's amount commitment
+	Mask Hash `json:"mask"`
+	TxID Hash `json:"txid"`
+	// Unlocked is true if the output's unlock time has passed
+	Unlocked bool `json:"unlocked"`
+}
+
+// GetOuts returns RingCT outputs by global index, with the /get_outs
+// endpoint, in the order of indices
+func (c *Client) GetOuts(ctx context.Context, indices []uint64) ([]*Output, error) {
+	type outputRef struct {
+		Amount uint64 `json:"amount"`
+		Index  uint64 `json:"index"`
+	}
+	req := struct {
+		Outputs []outputRef `json:"outputs"`
+		GetTxID bool        `json:"get_txid"`
+	}{Outputs: make([]outputRef, len(indices)), GetTxID: true}
+	for i, idx := range indices {
+		req.Outputs[i] = outputRef{Index: idx}
+	}
+
+	var result struct {
+		Outs []*Output `json:"outs"`
+	}
+	if err := c.callJSON(ctx, "/get_outs", req, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Outs) != len(indices) {
+		return nil, fmt.Errorf("/get_outs: %w", errResultCount)
+	}
+	return result.Outs, nil
+}
+
+var _ cryptonote.OutputFetcher = (*Client)(nil)
+
+// Outputs implements cryptonote.OutputFetcher, so that the client can fetch
+// the decoys picked by cryptonote.GammaDecoyProvider
+func (c *Client) Outputs(ctx context.Context, indices []uint64) ([]*cryptonote.ChainOutput, error) {
+	outs, err := c.GetOuts(ctx, indices)
+	if err != nil {
+		return nil, err
+	}
+
+	chainOuts := make([]*cryptonote.ChainOutput, len(outs))
+	for i, out := range outs {
+		key, err := cryptonote.NewPublicKey(out.Key[:])
+		if err != nil {
+			return nil, fmt.Errorf("output %d key: %w", indices[i], err)
+		}
+		commitment, err := cryptonote.NewCommitment(out.Mask[:])
+		if err != nil {
+			return nil, fmt.Errorf("output %d commitment: %w", indices[i], err)
+		}
+		chainOuts[i] = &cryptonote.ChainOutput{
+			RingOutput: cryptonote.RingOutput{GlobalIndex: indices[i], Key: key, Commitment: commitment},
+			Unlocked:   out.Unlocked,
+		}
+	}
+	return chainOuts, nil
+}
+
+// OutputDistribution is the number of RingCT outputs in each block of a
+// range of heights
+type OutputDistribution struct {
+	StartHeight uint64
+	// Distribution holds, for each height from StartHeight, the cumulative
+	// number of RingCT outputs up to and including that block. Requested
+	// from height zero, it can be passed to
+	// cryptonote.NewGammaDecoyProvider.
+	Distribution []uint64
+}
+
+// GetOutputDistribution returns the cumulative distribution of RingCT
+// outputs between two heights, inclusive, with the get_output_distribution
+// method. A toHeight of zero is the top of the chain.
+func (c *Client) GetOutputDistribution(ctx context.Context, fromHeight, toHeight uint64) (*OutputDistribution, error) {
+	params := struct {
+		Amounts    []uint64 `json:"amounts"`
+		FromHeight uint64   `json:"from_height"`
+		ToHeight   uint64   `json:"to_height"`
+		Cumulative bool     `json:"cumulative"`
+		Binary     bool     `json:"binary"`
+	}{Amounts: []uint64{0}, FromHeight: fromHeight, ToHeight: toHeight, Cumulative: true}
+	var result struct {
+		Distributions []struct {
+			Amount       uint64   `json:"amount"`
+			StartHeight  uint64   `json:"start_height"`
+			Distribution []uint64 `json:"distribution"`
+		} `json:"distributions"`
+	}
+	if err := c.callJSONRPC(ctx, "get_output_distribution", params, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Distributions) == 0 {
+		return nil, fmt.Errorf("get_output_distribution: %w", errNoDistributions)
+	}
+	d := result.Distributions[0]
+	return &OutputDistribution{StartHeight: d.StartHeight, Distribution: d.Distribution}, nil
+}
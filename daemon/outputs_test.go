@@ -0,0 +1,66 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/mcrypto"
+)
+
+func TestClient_GetOuts(t *testing.T) {
+	c, server := newTestClient(t)
+	require.NoError(t, server.AddBlocks(69))
+	ctx := context.Background()
+
+	// miner outputs unlock 60 blocks after their block, and each block of
+	// the fake chain has one output
+	outs, err := c.GetOuts(ctx, []uint64{10, 11, 0})
+	require.NoError(t, err)
+	require.Len(t, outs, 3)
+	require.True(t, outs[0].Unlocked)
+	require.False(t, outs[1].Unlocked)
+	require.True(t, outs[2].Unlocked)
+	require.Equal(t, uint64(10), outs[0].Height)
+
+	block, err := c.GetBlock(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, block.Header.MinerTxHash, outs[0].TxID)
+	decoded, err := block.Decode()
+	require.NoError(t, err)
+	require.Equal(t, Hash(decoded.MinerTx.Prefix.Outputs[0].Key), outs[0].Key)
+
+	chainOuts, err := c.Outputs(ctx, []uint64{10})
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), chainOuts[0].GlobalIndex)
+	require.Equal(t, outs[0].Key[:], chainOuts[0].Key.Bytes())
+	// outputs with a cleartext amount have a commitment with a mask of one
+	expected := mcrypto.Commit(mcrypto.ScalarFromUint64(1), decoded.MinerTx.Prefix.Outputs[0].Amount)
+	require.Equal(t, expected.Bytes(), chainOuts[0].Commitment.Bytes())
+	require.True(t, chainOuts[0].Unlocked)
+
+	_, err = c.GetOuts(ctx, []uint64{70})
+	require.ErrorIs(t, err, errResponseStatus)
+}
+
+func TestClient_GetOutputDistribution(t *testing.T) {
+	c, server := newTestClient(t)
+	require.NoError(t, server.AddBlocks(9))
+	ctx := context.Background()
+
+	dist, err := c.GetOutputDistribution(ctx, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), dist.StartHeight)
+	require.Equal(t, []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, dist.Distribution)
+
+	// the counts are cumulative from height zero
+	dist, err = c.GetOutputDistribution(ctx, 5, 7)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), dist.StartHeight)
+	require.Equal(t, []uint64{6, 7, 8}, dist.Distribution)
+
+	_, err = c.GetOutputDistribution(ctx, 11, 12)
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+}
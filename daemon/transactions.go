@@ -0,0 +1,178 @@
+package daemon
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/dimalinux/gopherphis/serialization"
+)
+
+// Transaction is a transaction returned by GetTransactions
+type Transaction struct {
+	Hash Hash
+	// Blob is the serialized transaction, which is pruned if Pruned is set
+	Blob   []byte
+	Pruned bool
+	// PrunableHash is the hash of the removed prunable data of a pruned
+	// transaction
+	PrunableHash Hash
+	// BlockHeight and BlockTimestamp are those of the block containing the
+	// transaction, and are zero while it is in the pool
+	BlockHeight     uint64
+	BlockTimestamp  uint64
+	InPool          bool
+	DoubleSpendSeen bool
+	// OutputIndices are the global indices of the transaction's outputs
+	OutputIndices []uint64
+}
+
+// Decode decodes the transaction
+func (t *Transaction) Decode() (*serialization.Transaction, error) {
+	tx, err := serialization.DecodeTransaction(t.Blob, t.Pruned)
+	if err != nil {
+		return nil, err
+	}
+	if t.Pruned {
+		tx.PrunableHash = t.PrunableHash
+	}
+	return tx, nil
+}
+
+// GetTransactions returns transactions from the chain or the pool, with the
+// /get_transactions endpoint. If prune is set, the transactions are returned
+// without their prunable data. It is an error if the daemon does not have
+// all the transactions.
+func (c *Client) GetTransactions(ctx context.Context, hashes []Hash, prune bool) ([]*Transaction, error) {
+	req := struct {
+		TxsHashes    []Hash `json:"txs_hashes"`
+		DecodeAsJSON bool   `json:"decode_as_json"`
+		Prune        bool   `json:"prune"`
+	}{TxsHashes: hashes, Prune: prune}
+	var result struct {
+		Txs []struct {
+			AsHex           string   `json:"as_hex"`
+			PrunedAsHex     string   `json:"pruned_as_hex"`
+			PrunableHash    Hash     `json:"prunable_hash"`
+			TxHash          Hash     `json:"tx_hash"`
+			BlockHeight     uint64   `json:"block_height"`
+			BlockTimestamp  uint64   `json:"block_timestamp"`
+			InPool          bool     `json:"in_pool"`
+			DoubleSpendSeen bool     `json:"double_spend_seen"`
+			OutputIndices   []uint64 `json:"output_indices"`
+		} `json:"txs"`
+		MissedTx []Hash `json:"missed_tx"`
+	}
+	if err := c.callJSON(ctx, "/get_transactions", req, &result); err != nil {
+		return nil, err
+	}
+	if len(result.MissedTx) > 0 {
+		return nil, fmt.Errorf("/get_transactions: %w: %s", errMissedTxs, result.MissedTx[0])
+	}
+	if len(result.Txs) != len(hashes) {
+		return nil, fmt.Errorf("/get_transactions: %w", errResultCount)
+	}
+
+	txs := make([]*Transaction, len(result.Txs))
+	for i, r := range result.Txs {
+		tx := &Transaction{
+			Hash:            r.TxHash,
+			PrunableHash:    r.PrunableHash,
+			BlockHeight:     r.BlockHeight,
+			BlockTimestamp:  r.BlockTimestamp,
+			InPool:          r.InPool,
+			DoubleSpendSeen: r.DoubleSpendSeen,
+			OutputIndices:   r.OutputIndices,
+		}
+		// the daemon returns the full blob unless the transaction is pruned
+		blobHex := r.AsHex
+		if blobHex == "" {
+			blobHex = r.PrunedAsHex
+			tx.Pruned = true
+		}
+		var err error
+		if tx.Blob, err = hex.DecodeString(blobHex); err != nil {
+			return nil, fmt.Errorf("/get_transactions: %w", err)
+		}
+		txs[i] = tx
+	}
+	return txs, nil
+}
+
+// TxRejectedError is returned by SendRawTransaction when the daemon rejects a
+// transaction. The flags give the reasons.
+type TxRejectedError struct {
+	Reason            string `json:"reason"`
+	DoubleSpend       bool   `json:"double_spend"`
+	FeeTooLow         bool   `json:"fee_too_low"`
+	InvalidInput      bool   `json:"invalid_input"`
+	InvalidOutput     bool   `json:"invalid_output"`
+	LowMixin          bool   `json:"low_mixin"`
+	Overspend         bool   `json:"overspend"`
+	TooBig            bool   `json:"too_big"`
+	TooFewOutputs     bool   `json:"too_few_outputs"`
+	SanityCheckFailed bool   `json:"sanity_check_failed"`
+	TxExtraTooBig     bool   `json:"tx_extra_too_big"`
+	NonzeroUnlockTime bool   `json:"nonzero_unlock_time"`
+}
+
+// Error implements the error interface
+func (e *TxRejectedError) Error() string {
+	var reasons []string
+	for _, flag := range []struct {
+		set    bool
+		reason string
+	}{
+		{e.DoubleSpend, "double spend"},
+		{e.FeeTooLow, "fee too low"},
+		{e.InvalidInput, "invalid input"},
+		{e.InvalidOutput, "invalid output"},
+		{e.LowMixin, "ring size too small"},
+		{e.Overspend, "overspend"},
+		{e.TooBig, "too big"},
+		{e.TooFewOutputs, "too few outputs"},
+		{e.SanityCheckFailed, "sanity check failed"},
+		{e.TxExtraTooBig, "tx_extra too big"},
+		{e.NonzeroUnlockTime, "nonzero unlock time"},
+	} {
+		if flag.set {
+			reasons = append(reasons, flag.reason)
+		}
+	}
+	if e.Reason != "" {
+		reasons = append(reasons, e.Reason)
+	}
+	if len(reasons) == 0 {
+		return "daemon rejected the transaction"
+	}
+	return "daemon rejected the transaction: " + strings.Join(reasons, ", ")
+}
+
+// SendRawTransaction submits a serialized transaction, like
+// cryptonote.SignedTx.Blob, with the /send_raw_transaction endpoint. If
+// doNotRelay is set, the daemon adds the transaction to its pool without
+// relaying it to other nodes. A rejected transaction returns a
+// *TxRejectedError.
+func (c *Client) SendRawTransaction(ctx context.Context, blob []byte, doNotRelay bool) error {
+	req := struct {
+		TxAsHex    string `json:"tx_as_hex"`
+		DoNotRelay bool   `json:"do_not_relay"`
+	}{TxAsHex: hex.EncodeToString(blob), DoNotRelay: doNotRelay}
+	var result struct {
+		responseStatus
+		TxRejectedError
+	}
+	// a rejected transaction has the status "Failed", so the status is
+	// checked here, to return the reasons
+	if err := c.callJSONUnchecked(ctx, "/send_raw_transaction", req, &result); err != nil {
+		return err
+	}
+	if result.Status == statusOK {
+		return nil
+	}
+	if result.Status != statusFailed {
+		return fmt.Errorf("/send_raw_transaction: %w", result.check())
+	}
+	return &result.TxRejectedError
+}
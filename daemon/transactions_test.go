@@ -0,0 +1,154 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+	"github.com/dimalinux/gopherphis/daemon/fakedaemon"
+)
+
+// testWallet holds the keys of a wallet and an unlocked output it received
+type testWallet struct {
+	keys   *cryptonote.PrivateKeyPair
+	source *cryptonote.TxSource
+}
+
+// newTestWallet mines a block to new wallet keys, followed by enough blocks
+// with other outputs to unlock the block reward and to pick decoys from
+func newTestWallet(t *testing.T, c *Client, server *fakedaemon.Server) *testWallet {
+	ctx := context.Background()
+	keys, err := cryptonote.GenerateKeys()
+	require.NoError(t, err)
+	require.NoError(t, server.AddBlocks(200))
+	server.SetMiner(keys.PublicKeyPair())
+	_, err = server.AddBlock()
+	require.NoError(t, err)
+	server.SetMiner(nil)
+	require.NoError(t, server.AddBlocks(100))
+
+	block, err := c.GetBlock(ctx, 201)
+	require.NoError(t, err)
+	txs, err := c.GetTransactions(ctx, []Hash{block.Header.MinerTxHash}, false)
+	require.NoError(t, err)
+	tx, err := txs[0].Decode()
+	require.NoError(t, err)
+	info, err := cryptonote.NewTxInfo(tx)
+	require.NoError(t, err)
+
+	return &testWallet{
+		keys: keys,
+		source: &cryptonote.TxSource{
+			Output:      &cryptonote.OwnedOutput{Tx: info, Index: 0},
+			GlobalIndex: txs[0].OutputIndices[0],
+		},
+	}
+}
+
+// newTestTransfer builds a transaction spending the wallet's output, with
+// decoys and fees from the daemon
+func newTestTransfer(t *testing.T, c *Client, wallet *testWallet) *cryptonote.SignedTx {
+	ctx := context.Background()
+	dist, err := c.GetOutputDistribution(ctx, 0, 0)
+	require.NoError(t, err)
+	decoys, err := cryptonote.NewGammaDecoyProvider(dist.Distribution, c, nil)
+	require.NoError(t, err)
+	fees, err := c.GetFeeEstimate(ctx)
+	require.NoError(t, err)
+	recipient, err := cryptonote.GenerateKeys()
+	require.NoError(t, err)
+
+	tx, err := wallet.keys.BuildTransaction(ctx, &cryptonote.TxParams{
+		Sources: []*cryptonote.TxSource{wallet.source},
+		Destinations: []*cryptonote.TxDestination{{
+			Address: recipient.PublicKeyPair().Address(cryptonote.Mainnet),
+			Amount:  cryptonote.XMR / 10,
+		}},
+		FeePerByte:          fees.FeePerByte,
+		FeeQuantizationMask: fees.QuantizationMask,
+		RingSize:            cryptonote.DefaultRingSize,
+		Decoys:              decoys,
+	})
+	require.NoError(t, err)
+	return tx
+}
+
+func TestClient_SendRawTransaction(t *testing.T) {
+	c, server := newTestClient(t)
+	wallet := newTestWallet(t, c, server)
+	tx := newTestTransfer(t, c, wallet)
+	ctx := context.Background()
+
+	require.NoError(t, c.SendRawTransaction(ctx, tx.Blob, false))
+	require.Equal(t, [][32]byte{tx.ID}, server.PoolTxs())
+
+	txs, err := c.GetTransactions(ctx, []Hash{tx.ID}, false)
+	require.NoError(t, err)
+	require.True(t, txs[0].InPool)
+	require.Equal(t, tx.Blob, txs[0].Blob)
+
+	// spending the output again is a double spend
+	err = c.SendRawTransaction(ctx, newTestTransfer(t, c, wallet).Blob, false)
+	var rejected *TxRejectedError
+	require.ErrorAs(t, err, &rejected)
+	require.True(t, rejected.DoubleSpend)
+	require.Equal(t, "daemon rejected the transaction: double spend", err.Error())
+
+	err = c.SendRawTransaction(ctx, []byte{1, 2, 3}, false)
+	require.ErrorAs(t, err, &rejected)
+	require.NotEmpty(t, rejected.Reason)
+
+	block, err := server.MinePool()
+	require.NoError(t, err)
+	require.Empty(t, server.PoolTxs())
+	require.Equal(t, [][32]byte{tx.ID}, block.TxHashes)
+
+	txs, err = c.GetTransactions(ctx, []Hash{tx.ID}, false)
+	require.NoError(t, err)
+	require.False(t, txs[0].InPool)
+	require.Equal(t, server.Height()-1, txs[0].BlockHeight)
+	require.Equal(t, block.Header.Timestamp, txs[0].BlockTimestamp)
+	require.Len(t, txs[0].OutputIndices, 2)
+}
+
+func TestClient_SendRawTransaction_feeTooLow(t *testing.T) {
+	c, server := newTestClient(t)
+	wallet := newTestWallet(t, c, server)
+	tx := newTestTransfer(t, c, wallet)
+
+	server.SetFeeEstimate(1_000_000, 10_000)
+	err := c.SendRawTransaction(context.Background(), tx.Blob, false)
+	var rejected *TxRejectedError
+	require.ErrorAs(t, err, &rejected)
+	require.True(t, rejected.FeeTooLow)
+	require.Empty(t, server.PoolTxs())
+}
+
+func TestClient_GetTransactions(t *testing.T) {
+	c, server := newTestClient(t)
+	wallet := newTestWallet(t, c, server)
+	tx := newTestTransfer(t, c, wallet)
+	ctx := context.Background()
+	_, err := server.AddBlock(tx.Tx)
+	require.NoError(t, err)
+
+	txs, err := c.GetTransactions(ctx, []Hash{tx.ID, wallet.source.Output.Tx.ID}, true)
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+	for i, id := range []Hash{tx.ID, wallet.source.Output.Tx.ID} {
+		require.Equal(t, id, txs[i].Hash)
+		require.True(t, txs[i].Pruned)
+		decoded, err := txs[i].Decode()
+		require.NoError(t, err)
+		hash, err := decoded.Hash()
+		require.NoError(t, err)
+		require.Equal(t, id, Hash(hash))
+	}
+	require.Equal(t, uint64(201), txs[1].BlockHeight)
+	require.Equal(t, []uint64{wallet.source.GlobalIndex}, txs[1].OutputIndices)
+
+	_, err = c.GetTransactions(ctx, []Hash{tx.ID, {1}}, false)
+	require.ErrorIs(t, err, errMissedTxs)
+}
@@ -0,0 +1,81 @@
+package digest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Challenge is a WWW-Authenticate challenge, used to authorize the client's
+// requests until the server sends a new one. It is not safe for concurrent
+// use.
+type Challenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qopAuth   bool
+	// nc is the count of requests sent with the nonce
+	nc uint32
+}
+
+// ParseChallenges returns the first supported challenge of the passed
+// WWW-Authenticate header values. Servers send a challenge for each
+// algorithm they support.
+func ParseChallenges(headers []string) (*Challenge, error) {
+	for _, header := range headers {
+		s, ok := cutScheme(header)
+		if !ok {
+			continue
+		}
+		params, err := parseParams(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidChallenge, err)
+		}
+		if params["nonce"] == "" {
+			return nil, fmt.Errorf("%w: no nonce", errInvalidChallenge)
+		}
+		ch := &Challenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: params["algorithm"],
+		}
+		for _, qop := range strings.Split(params["qop"], ",") {
+			if strings.TrimSpace(qop) == "auth" {
+				ch.qopAuth = true
+			}
+		}
+		switch strings.ToUpper(ch.algorithm) {
+		case "", "MD5", "MD5-SESS":
+			return ch, nil
+		}
+	}
+	return nil, errNoChallenge
+}
+
+// Authorization returns the Authorization header of the next request, which
+// increments the nonce count
+func (ch *Challenge) Authorization(username, password, method, uri string) string {
+	ch.nc++
+	nc := fmt.Sprintf("%08x", ch.nc)
+	cnonce := randomHex()
+
+	h1 := ha1(ch.algorithm, username, ch.realm, password, ch.nonce, cnonce)
+	h2 := md5Hex(method, uri)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `Digest username="%s", realm="%s", nonce="%s", uri="%s"`, username, ch.realm, ch.nonce, uri)
+	if ch.algorithm != "" {
+		fmt.Fprintf(&b, `, algorithm=%s`, ch.algorithm)
+	}
+	if ch.qopAuth {
+		response := md5Hex(h1, ch.nonce, nc, cnonce, "auth", h2)
+		fmt.Fprintf(&b, `, response="%s", qop=auth, nc=%s, cnonce="%s"`, response, nc, cnonce)
+	} else {
+		fmt.Fprintf(&b, `, response="%s"`, md5Hex(h1, ch.nonce, h2))
+	}
+	if ch.opaque != "" {
+		fmt.Fprintf(&b, `, opaque="%s"`, ch.opaque)
+	}
+	return b.String()
+}
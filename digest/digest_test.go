@@ -0,0 +1,165 @@
+package digest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseParams(t *testing.T) {
+	params, err := parseParams(`Username="user", nc=00000001 ,qop=auth, uri="/a,b", empty=""`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"username": "user",
+		"nc":       "00000001",
+		"qop":      "auth",
+		"uri":      "/a,b",
+		"empty":    "",
+	}, params)
+
+	_, err = parseParams(`realm="monero-rpc`)
+	require.ErrorIs(t, err, errInvalidParams)
+	_, err = parseParams(`realm`)
+	require.ErrorIs(t, err, errInvalidParams)
+}
+
+func TestParseChallenges(t *testing.T) {
+	ch, err := ParseChallenges([]string{
+		`Basic realm="x"`,
+		`Digest qop="auth",algorithm=SHA-256,realm="monero-rpc",nonce="n1"`,
+		`Digest qop="auth",algorithm=MD5,realm="monero-rpc",nonce="n2",stale=false`,
+	})
+	require.NoError(t, err)
+	require.Equal(t, &Challenge{realm: Realm, nonce: "n2", algorithm: "MD5", qopAuth: true}, ch)
+
+	_, err = ParseChallenges([]string{`Digest qop="auth",realm="monero-rpc"`})
+	require.ErrorIs(t, err, errInvalidChallenge)
+	_, err = ParseChallenges([]string{`Basic realm="x"`})
+	require.ErrorIs(t, err, errNoChallenge)
+}
+
+// testServer is a Server with a clock that can be moved forward
+type testServer struct {
+	*Server
+	now time.Time
+}
+
+func newTestServer() *testServer {
+	ts := &testServer{Server: NewServer("user", "pass"), now: time.Unix(1_700_000_000, 0)}
+	ts.Server.now = func() time.Time { return ts.now }
+	return ts
+}
+
+// send sends a request, with the authorization if it isn't empty, and returns
+// the response
+func (ts *testServer) send(authorization string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/json_rpc", nil)
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	if ts.Check(w, r) {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w
+}
+
+// challenge returns the challenge of an unauthorized request, for the
+// algorithm
+func (ts *testServer) challenge(t *testing.T, algorithm string) *Challenge {
+	w := ts.send("")
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	headers := w.Result().Header.Values("WWW-Authenticate")
+	require.Len(t, headers, 2)
+	for _, header := range headers {
+		if strings.Contains(header, "algorithm="+algorithm+",") {
+			ch, err := ParseChallenges([]string{header})
+			require.NoError(t, err)
+			return ch
+		}
+	}
+	require.FailNow(t, "no challenge", algorithm)
+	return nil
+}
+
+func TestServer_Check(t *testing.T) {
+	for _, algorithm := range []string{"MD5", "MD5-sess"} {
+		t.Run(algorithm, func(t *testing.T) {
+			ts := newTestServer()
+			ch := ts.challenge(t, algorithm)
+
+			auth1 := ch.Authorization("user", "pass", http.MethodPost, "/json_rpc")
+			require.Equal(t, http.StatusOK, ts.send(auth1).Code)
+			require.Equal(t, http.StatusOK, ts.send(ch.Authorization("user", "pass", http.MethodPost, "/json_rpc")).Code)
+
+			// replays are rejected, without marking the nonce as stale
+			w := ts.send(auth1)
+			require.Equal(t, http.StatusUnauthorized, w.Code)
+			require.Contains(t, w.Header().Get("WWW-Authenticate"), "stale=false")
+
+			// a wrong password, user or URI is rejected
+			for _, login := range [][3]string{
+				{"user", "wrong", "/json_rpc"},
+				{"other", "pass", "/json_rpc"},
+				{"user", "pass", "/other"},
+			} {
+				w = ts.send(ch.Authorization(login[0], login[1], http.MethodPost, login[2]))
+				require.Equal(t, http.StatusUnauthorized, w.Code, login)
+			}
+		})
+	}
+}
+
+func TestServer_Check_nonces(t *testing.T) {
+	ts := newTestServer()
+
+	// each challenge has its own nonce
+	ch1, ch2 := ts.challenge(t, "MD5"), ts.challenge(t, "MD5")
+	require.NotEqual(t, ch1.nonce, ch2.nonce)
+	require.Equal(t, http.StatusOK, ts.send(ch1.Authorization("user", "pass", http.MethodPost, "/json_rpc")).Code)
+	require.Equal(t, http.StatusOK, ts.send(ch2.Authorization("user", "pass", http.MethodPost, "/json_rpc")).Code)
+
+	// unknown and expired nonces get a challenge with stale=true
+	unknown := *ch1
+	unknown.nonce = "00"
+	w := ts.send(unknown.Authorization("user", "pass", http.MethodPost, "/json_rpc"))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Contains(t, w.Header().Get("WWW-Authenticate"), "stale=true")
+
+	ts.now = ts.now.Add(NonceLifetime + time.Second)
+	w = ts.send(ch1.Authorization("user", "pass", http.MethodPost, "/json_rpc"))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Contains(t, w.Header().Get("WWW-Authenticate"), "stale=true")
+
+	// a wrong password never gets stale=true
+	w = ts.send(ch2.Authorization("user", "wrong", http.MethodPost, "/json_rpc"))
+	require.Contains(t, w.Header().Get("WWW-Authenticate"), "stale=false")
+
+	// the number of nonces is bounded
+	for i := 0; i < maxNonces+10; i++ {
+		ts.send("")
+	}
+	require.Len(t, ts.nonces, maxNonces)
+}
+
+func TestNonceState_use(t *testing.T) {
+	var n nonceState
+	require.True(t, n.use(1))
+	require.False(t, n.use(1))
+
+	// counts can arrive out of order, within the window
+	require.True(t, n.use(5))
+	require.True(t, n.use(3))
+	require.False(t, n.use(3))
+	require.True(t, n.use(2))
+	require.False(t, n.use(5))
+
+	require.True(t, n.use(100))
+	require.False(t, n.use(100-ncWindow))
+	require.True(t, n.use(100-ncWindow+1))
+	require.False(t, n.use(100-ncWindow+1))
+}
@@ -0,0 +1,171 @@
+package digest
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// NonceLifetime is how long a nonce can be used after it is issued. Later
+	// requests with it get a challenge with stale=true, so clients retry with
+	// the new nonce without asking for the password again.
+	NonceLifetime = 10 * time.Minute
+
+	// maxNonces bounds the number of nonces the server tracks, as any client
+	// can make it issue one. When it is reached, the oldest nonce is dropped.
+	maxNonces = 1024
+
+	// ncWindow is the number of nonce counts below the highest one seen that
+	// are still accepted, once each, for requests that arrive out of order
+	ncWindow = 64
+)
+
+// nonceState tracks the nonce counts used with a nonce, so that a captured
+// Authorization header can't be replayed
+type nonceState struct {
+	issued time.Time
+	// maxNC is the highest nonce count seen
+	maxNC uint64
+	// seen has bit i set if the nonce count maxNC-i was seen
+	seen uint64
+}
+
+// use records the nonce count, and returns false if it was already seen or
+// is too far below the highest one
+func (n *nonceState) use(nc uint64) bool {
+	switch {
+	case nc > n.maxNC:
+		if shift := nc - n.maxNC; shift < ncWindow {
+			n.seen = n.seen<<shift | 1
+		} else {
+			n.seen = 1
+		}
+		n.maxNC = nc
+		return true
+	case n.maxNC-nc >= ncWindow:
+		return false
+	default:
+		bit := uint64(1) << (n.maxNC - nc)
+		if n.seen&bit != 0 {
+			return false
+		}
+		n.seen |= bit
+		return true
+	}
+}
+
+// Server checks the digest authorization of requests to a server with a
+// single login. Each challenge issues a new nonce, and the nonce count of
+// each request must not have been used before with its nonce. It is safe for
+// concurrent use.
+type Server struct {
+	username string
+	password string
+	now      func() time.Time
+
+	mu     sync.Mutex
+	nonces map[string]*nonceState
+}
+
+// NewServer returns a Server for the login
+func NewServer(username, password string) *Server {
+	return &Server{
+		username: username,
+		password: password,
+		now:      time.Now,
+		nonces:   make(map[string]*nonceState),
+	}
+}
+
+// Check returns true if the request has a valid authorization. Otherwise it
+// responds with a 401 status and a new challenge, and returns false.
+func (s *Server) Check(w http.ResponseWriter, r *http.Request) bool {
+	ok, stale := s.authorized(r)
+	if !ok {
+		s.challenge(w, stale)
+	}
+	return ok
+}
+
+// authorized checks the request's authorization. If the credentials are
+// valid but the nonce is unknown or expired, stale is true.
+func (s *Server) authorized(r *http.Request) (ok bool, stale bool) {
+	header, ok := cutScheme(r.Header.Get("Authorization"))
+	if !ok {
+		return false, false
+	}
+	p, err := parseParams(header)
+	if err != nil {
+		return false, false
+	}
+	if subtle.ConstantTimeCompare([]byte(p["username"]), []byte(s.username)) != 1 ||
+		p["realm"] != Realm || p["uri"] != r.URL.RequestURI() || p["qop"] != "auth" {
+		return false, false
+	}
+	switch strings.ToUpper(p["algorithm"]) {
+	case "", "MD5", "MD5-SESS":
+	default:
+		return false, false
+	}
+	nc, err := strconv.ParseUint(p["nc"], 16, 32)
+	if err != nil || nc == 0 {
+		return false, false
+	}
+
+	nonce := p["nonce"]
+	h1 := ha1(p["algorithm"], s.username, Realm, s.password, nonce, p["cnonce"])
+	expected := md5Hex(h1, nonce, p["nc"], p["cnonce"], "auth", md5Hex(r.Method, p["uri"]))
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(p["response"])) != 1 {
+		return false, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, found := s.nonces[nonce]
+	if !found || s.now().Sub(state.issued) > NonceLifetime {
+		delete(s.nonces, nonce)
+		return false, true
+	}
+	return state.use(nc), false
+}
+
+// challenge responds with a challenge for each supported algorithm, with a
+// new nonce
+func (s *Server) challenge(w http.ResponseWriter, stale bool) {
+	nonce := s.newNonce()
+	for _, algorithm := range []string{"MD5-sess", "MD5"} {
+		w.Header().Add("WWW-Authenticate", fmt.Sprintf(
+			`Digest qop="auth",algorithm=%s,realm="%s",nonce="%s",stale=%t`,
+			algorithm, Realm, nonce, stale,
+		))
+	}
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+// newNonce issues a nonce, dropping expired ones and, if there are too many,
+// the oldest one
+func (s *Server) newNonce() string {
+	nonce := randomHex()
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var oldest string
+	for n, state := range s.nonces {
+		if now.Sub(state.issued) > NonceLifetime {
+			delete(s.nonces, n)
+		} else if oldest == "" || state.issued.Before(s.nonces[oldest].issued) {
+			oldest = n
+		}
+	}
+	if len(s.nonces) >= maxNonces {
+		delete(s.nonces, oldest)
+	}
+	s.nonces[nonce] = &nonceState{issued: now}
+	return nonce
+}
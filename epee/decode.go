@@ -0,0 +1,312 @@
+package epee
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Unmarshaler is implemented by types that decode themselves from a value
+// returned by Decode. It is needed for entries whose type depends on other
+// entries, like the transactions of get_blocks.bin's blocks, which are
+// strings for unpruned blocks and sections for pruned ones.
+type Unmarshaler interface {
+	UnmarshalEpee(value any) error
+}
+
+var unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+
+// Decode decodes a portable storage blob into its root section. Sections are
+// returned as map[string]any and arrays as []any. Strings are returned as
+// []byte, as they hold arbitrary bytes, and the other values use the Go type
+// of the same size: int64, int32, int16, int8, uint64, uint32, uint16, uint8,
+// float64 and bool.
+func Decode(b []byte) (map[string]any, error) {
+	if !bytes.HasPrefix(b, Signature) {
+		return nil, errInvalidSignature
+	}
+	d := &decoder{b: b[len(Signature):]}
+	section := d.readSection(0)
+	if d.err != nil {
+		return nil, d.err
+	}
+	if len(d.b) != 0 {
+		return nil, errTrailingBytes
+	}
+	return section, nil
+}
+
+// Unmarshal decodes a portable storage blob into the struct pointed to by v.
+// Entries without a matching field are ignored.
+func Unmarshal(b []byte, v any) error {
+	section, err := Decode(b)
+	if err != nil {
+		return err
+	}
+	return UnmarshalValue(section, v)
+}
+
+// UnmarshalValue stores a value returned by Decode, or a part of one, in the
+// value pointed to by v
+func UnmarshalValue(value any, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errNotPointer
+	}
+	return assign(value, rv.Elem(), false)
+}
+
+func (d *decoder) readSection(depth int) map[string]any {
+	if depth > maxDepth {
+		d.err = errMaxDepth
+		return nil
+	}
+	// an entry takes at least a name length, type and 1 byte value
+	count := d.readCount(3)
+	section := make(map[string]any, count)
+	for i := 0; i < count && d.err == nil; i++ {
+		name := string(d.readBytes(int(d.readByte())))
+		section[name] = d.readValue(Type(d.readByte()), depth)
+	}
+	return section
+}
+
+func (d *decoder) readValue(typ Type, depth int) any {
+	if d.err != nil {
+		return nil
+	}
+	if typ&TypeArrayFlag == 0 {
+		return d.readScalar(typ, depth)
+	}
+
+	elemType := typ &^ TypeArrayFlag
+	size, ok := elemType.minSize()
+	if !ok {
+		d.err = fmt.Errorf("%w %d", errUnknownType, typ)
+		return nil
+	}
+	count := d.readCount(size)
+	values := make([]any, count)
+	for i := range values {
+		values[i] = d.readScalar(elemType, depth+1)
+	}
+	return values
+}
+
+func (d *decoder) readScalar(typ Type, depth int) any {
+	switch typ {
+	case TypeInt64:
+		return int64(binary.LittleEndian.Uint64(d.readFixed(8)))
+	case TypeInt32:
+		return int32(binary.LittleEndian.Uint32(d.readFixed(4)))
+	case TypeInt16:
+		return int16(binary.LittleEndian.Uint16(d.readFixed(2)))
+	case TypeInt8:
+		return int8(d.readByte())
+	case TypeUint64:
+		return binary.LittleEndian.Uint64(d.readFixed(8))
+	case TypeUint32:
+		return binary.LittleEndian.Uint32(d.readFixed(4))
+	case TypeUint16:
+		return binary.LittleEndian.Uint16(d.readFixed(2))
+	case TypeUint8:
+		return d.readByte()
+	case TypeDouble:
+		return math.Float64frombits(binary.LittleEndian.Uint64(d.readFixed(8)))
+	case TypeString:
+		return bytes.Clone(d.readBytes(d.readCount(1)))
+	case TypeBool:
+		return d.readByte() != 0
+	case TypeObject:
+		return d.readSection(depth + 1)
+	case typeArray:
+		d.err = errNestedArray
+	default:
+		d.err = fmt.Errorf("%w %d", errUnknownType, typ)
+	}
+	return nil
+}
+
+// minSize returns the minimum encoded size of a value of type t, and false
+// if t is not a known element type
+func (t Type) minSize() (int, bool) {
+	switch t {
+	case TypeInt64, TypeUint64, TypeDouble:
+		return 8, true
+	case TypeInt32, TypeUint32:
+		return 4, true
+	case TypeInt16, TypeUint16:
+		return 2, true
+	case TypeInt8, TypeUint8, TypeString, TypeBool, TypeObject, typeArray:
+		return 1, true
+	default:
+		return 0, false
+	}
+}
+
+// assign stores the decoded value in rv. If blob is set, value is a string
+// holding the packed elements of the slice rv.
+func assign(value any, rv reflect.Value, blob bool) error {
+	if rv.CanAddr() && rv.Addr().Type().Implements(unmarshalerType) {
+		return rv.Addr().Interface().(Unmarshaler).UnmarshalEpee(value)
+	}
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return assign(value, rv.Elem(), blob)
+	}
+	if blob {
+		return assignBlob(value, rv)
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := toInt64(value)
+		if !ok {
+			return typeMismatch(value, rv)
+		}
+		if rv.OverflowInt(n) {
+			return fmt.Errorf("%w: %d does not fit in %s", errIntegerOverflow, n, rv.Type())
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := toUint64(value)
+		if !ok {
+			return typeMismatch(value, rv)
+		}
+		if rv.OverflowUint(n) {
+			return fmt.Errorf("%w: %d does not fit in %s", errIntegerOverflow, n, rv.Type())
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, ok := value.(float64)
+		if !ok {
+			return typeMismatch(value, rv)
+		}
+		rv.SetFloat(f)
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return typeMismatch(value, rv)
+		}
+		rv.SetBool(b)
+	case reflect.String:
+		s, ok := value.([]byte)
+		if !ok {
+			return typeMismatch(value, rv)
+		}
+		rv.SetString(string(s))
+	case reflect.Slice:
+		if s, ok := value.([]byte); ok && rv.Type().Elem().Kind() == reflect.Uint8 {
+			rv.SetBytes(bytes.Clone(s))
+			return nil
+		}
+		values, ok := value.([]any)
+		if !ok {
+			return typeMismatch(value, rv)
+		}
+		slice := reflect.MakeSlice(rv.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := assign(v, slice.Index(i), false); err != nil {
+				return err
+			}
+		}
+		rv.Set(slice)
+	case reflect.Array:
+		s, ok := value.([]byte)
+		if !ok || rv.Type().Elem().Kind() != reflect.Uint8 {
+			return typeMismatch(value, rv)
+		}
+		if len(s) != rv.Len() {
+			return fmt.Errorf("%w: %d bytes for %s", errBlobSize, len(s), rv.Type())
+		}
+		reflect.Copy(rv, reflect.ValueOf(s))
+	case reflect.Struct:
+		section, ok := value.(map[string]any)
+		if !ok {
+			return typeMismatch(value, rv)
+		}
+		for _, f := range structFields(rv.Type()) {
+			v, ok := section[f.name]
+			if !ok {
+				continue
+			}
+			if err := assign(v, rv.Field(f.index), f.blob); err != nil {
+				return fmt.Errorf("%s: %w", f.name, err)
+			}
+		}
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return typeMismatch(value, rv)
+		}
+		rv.Set(reflect.ValueOf(value))
+	default:
+		return typeMismatch(value, rv)
+	}
+	return nil
+}
+
+// assignBlob unpacks the elements of a slice of fixed size values from a
+// string
+func assignBlob(value any, rv reflect.Value) error {
+	s, ok := value.([]byte)
+	if !ok || rv.Kind() != reflect.Slice {
+		return typeMismatch(value, rv)
+	}
+	elemSize := binary.Size(reflect.Zero(rv.Type().Elem()).Interface())
+	if elemSize <= 0 || len(s)%elemSize != 0 {
+		return fmt.Errorf("%w: %d bytes for %s", errBlobSize, len(s), rv.Type())
+	}
+
+	count := len(s) / elemSize
+	rv.Set(reflect.MakeSlice(rv.Type(), count, count))
+	return binary.Read(bytes.NewReader(s), binary.LittleEndian, rv.Interface())
+}
+
+func toInt64(value any) (int64, bool) {
+	switch n := value.(type) {
+	case int64:
+		return n, true
+	case int32:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	}
+	u, ok := toUint64(value)
+	if !ok || u > math.MaxInt64 {
+		return 0, false
+	}
+	return int64(u), true
+}
+
+func toUint64(value any) (uint64, bool) {
+	switch n := value.(type) {
+	case uint64:
+		return n, true
+	case uint32:
+		return uint64(n), true
+	case uint16:
+		return uint64(n), true
+	case uint8:
+		return uint64(n), true
+	}
+	// monerod encodes some unsigned values with signed types
+	switch n := value.(type) {
+	case int64, int32, int16, int8:
+		i := reflect.ValueOf(n).Int()
+		if i < 0 {
+			return 0, false
+		}
+		return uint64(i), true
+	}
+	return 0, false
+}
+
+func typeMismatch(value any, rv reflect.Value) error {
+	return fmt.Errorf("%w: cannot decode %T into %s", errTypeMismatch, value, rv.Type())
+}
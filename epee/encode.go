@@ -0,0 +1,234 @@
+package epee
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// field is a tagged field of a struct
+type field struct {
+	name      string
+	index     int
+	omitEmpty bool
+	blob      bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+// structFields returns the tagged fields of the struct type t
+func structFields(t reflect.Type) []field {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.([]field)
+	}
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := t.Field(i).Tag.Lookup("epee")
+		if !ok || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		f := field{name: name, index: i}
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "omitempty":
+				f.omitEmpty = true
+			case "blob":
+				f.blob = true
+			}
+		}
+		fields = append(fields, f)
+	}
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// Marshal encodes the struct v, or a pointer to it, as a portable storage
+// blob
+func Marshal(v any) ([]byte, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %T", errUnsupportedType, v)
+	}
+	return appendSection(bytes.Clone(Signature), rv)
+}
+
+func appendSection(b []byte, rv reflect.Value) ([]byte, error) {
+	type entry struct {
+		field
+		value reflect.Value
+	}
+	var entries []entry
+	for _, f := range structFields(rv.Type()) {
+		v := rv.Field(f.index)
+		for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		if !v.IsValid() || (f.omitEmpty && v.IsZero()) {
+			continue
+		}
+		entries = append(entries, entry{field: f, value: v})
+	}
+
+	b, err := appendVarint(b, uint64(len(entries)))
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if len(e.name) > math.MaxUint8 {
+			return nil, errNameTooLong
+		}
+		b = append(b, byte(len(e.name)))
+		b = append(b, e.name...)
+		if b, err = appendEntryValue(b, e.value, e.blob); err != nil {
+			return nil, fmt.Errorf("%s: %w", e.name, err)
+		}
+	}
+	return b, nil
+}
+
+// appendEntryValue appends the type and value of a section entry
+func appendEntryValue(b []byte, rv reflect.Value, blob bool) ([]byte, error) {
+	if blob {
+		if rv.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("%w: blob of %s", errUnsupportedType, rv.Type())
+		}
+		var buf bytes.Buffer
+		if err := binary.Write(&buf, binary.LittleEndian, rv.Interface()); err != nil {
+			return nil, err
+		}
+		return appendString(append(b, byte(TypeString)), buf.Bytes())
+	}
+
+	if rv.Kind() != reflect.Slice || isString(rv.Type()) {
+		typ, err := typeOf(rv.Type())
+		if err != nil {
+			return nil, err
+		}
+		return appendValue(append(b, byte(typ)), rv)
+	}
+
+	elemType := rv.Type().Elem()
+	for elemType.Kind() == reflect.Pointer {
+		elemType = elemType.Elem()
+	}
+	typ, err := typeOf(elemType)
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, byte(typ|TypeArrayFlag))
+	if b, err = appendVarint(b, uint64(rv.Len())); err != nil {
+		return nil, err
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if b, err = appendValue(b, reflect.Indirect(rv.Index(i))); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// appendValue appends a value without its type
+func appendValue(b []byte, rv reflect.Value) ([]byte, error) {
+	if !rv.IsValid() {
+		return nil, fmt.Errorf("%w: nil array element", errUnsupportedType)
+	}
+	switch rv.Kind() {
+	case reflect.Int64, reflect.Int:
+		return binary.LittleEndian.AppendUint64(b, uint64(rv.Int())), nil
+	case reflect.Int32:
+		return binary.LittleEndian.AppendUint32(b, uint32(rv.Int())), nil
+	case reflect.Int16:
+		return binary.LittleEndian.AppendUint16(b, uint16(rv.Int())), nil
+	case reflect.Int8:
+		return append(b, byte(rv.Int())), nil
+	case reflect.Uint64, reflect.Uint:
+		return binary.LittleEndian.AppendUint64(b, rv.Uint()), nil
+	case reflect.Uint32:
+		return binary.LittleEndian.AppendUint32(b, uint32(rv.Uint())), nil
+	case reflect.Uint16:
+		return binary.LittleEndian.AppendUint16(b, uint16(rv.Uint())), nil
+	case reflect.Uint8:
+		return append(b, byte(rv.Uint())), nil
+	case reflect.Float64:
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(rv.Float())), nil
+	case reflect.Bool:
+		if rv.Bool() {
+			return append(b, 1), nil
+		}
+		return append(b, 0), nil
+	case reflect.String:
+		return appendString(b, []byte(rv.String()))
+	case reflect.Slice:
+		return appendString(b, rv.Bytes())
+	case reflect.Array:
+		s := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(s), rv)
+		return appendString(b, s)
+	case reflect.Struct:
+		return appendSection(b, rv)
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedType, rv.Type())
+	}
+}
+
+func appendString(b []byte, s []byte) ([]byte, error) {
+	b, err := appendVarint(b, uint64(len(s)))
+	if err != nil {
+		return nil, err
+	}
+	return append(b, s...), nil
+}
+
+// isString returns true for the types encoded as strings: strings, byte
+// slices and byte arrays
+func isString(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String:
+		return true
+	case reflect.Slice, reflect.Array:
+		return t.Elem().Kind() == reflect.Uint8
+	default:
+		return false
+	}
+}
+
+// typeOf returns the portable storage type of values of type t, which can't
+// be an array
+func typeOf(t reflect.Type) (Type, error) {
+	if isString(t) {
+		return TypeString, nil
+	}
+	switch t.Kind() {
+	case reflect.Int64, reflect.Int:
+		return TypeInt64, nil
+	case reflect.Int32:
+		return TypeInt32, nil
+	case reflect.Int16:
+		return TypeInt16, nil
+	case reflect.Int8:
+		return TypeInt8, nil
+	case reflect.Uint64, reflect.Uint:
+		return TypeUint64, nil
+	case reflect.Uint32:
+		return TypeUint32, nil
+	case reflect.Uint16:
+		return TypeUint16, nil
+	case reflect.Uint8:
+		return TypeUint8, nil
+	case reflect.Float64:
+		return TypeDouble, nil
+	case reflect.Bool:
+		return TypeBool, nil
+	case reflect.Struct:
+		return TypeObject, nil
+	case reflect.Slice:
+		return 0, errNestedArray
+	default:
+		return 0, fmt.Errorf("%w: %s", errUnsupportedType, t)
+	}
+}
@@ -0,0 +1,165 @@
+// Package epee implements the portable storage format of Monero's epee
+// library, the binary encoding used by the daemon's .bin RPC endpoints, like
+// get_blocks.bin, and by its peer-to-peer protocol.
+//
+// A portable storage blob is a signature followed by a section, a list of
+// named entries. Each entry holds a typed value, which can be an integer, a
+// double, a string of arbitrary bytes, a bool, a nested section, or an array
+// of values of the same type.
+//
+// Marshal and Unmarshal map Go structs to sections using field tags of the
+// form `epee:"name,opts"`, similar to encoding/json. Fields without a tag are
+// ignored. The supported options are:
+//
+//	omitempty  skip the field when encoding if it has its zero value
+//	blob       encode a slice of fixed size values, like [32]byte hashes or
+//	           uint64s, as a single string of their little-endian bytes,
+//	           like epee's KV_SERIALIZE_CONTAINER_POD_AS_BLOB
+package epee
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Signature is the header of every portable storage blob: two little-endian
+// 32-bit signatures followed by the format version.
+var Signature = []byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01}
+
+// Type is the type tag of a portable storage value
+type Type byte
+
+// Value types. An array's type is its element type with TypeArrayFlag set.
+const (
+	TypeInt64  Type = 1
+	TypeInt32  Type = 2
+	TypeInt16  Type = 3
+	TypeInt8   Type = 4
+	TypeUint64 Type = 5
+	TypeUint32 Type = 6
+	TypeUint16 Type = 7
+	TypeUint8  Type = 8
+	TypeDouble Type = 9
+	TypeString Type = 10
+	TypeBool   Type = 11
+	TypeObject Type = 12
+
+	TypeArrayFlag Type = 0x80
+
+	// typeArray is the element type of arrays of arrays, which Monero
+	// doesn't use
+	typeArray Type = 13
+)
+
+// maxVarint is the largest value of epee's varints, which use the low 2 bits
+// of the first byte for the encoded length
+const maxVarint = 1<<62 - 1
+
+// maxDepth limits the nesting of sections and arrays when decoding, like
+// epee's EPEE_PORTABLE_STORAGE_RECURSION_LIMIT_INTERNAL
+const maxDepth = 100
+
+var (
+	errInvalidSignature = errors.New("invalid portable storage signature")
+	errUnexpectedEOF    = errors.New("unexpected end of portable storage data")
+	errTrailingBytes    = errors.New("unexpected trailing bytes after portable storage data")
+	errVarintTooLarge   = errors.New("value is too large for a portable storage varint")
+	errUnknownType      = errors.New("unknown portable storage type")
+	errNestedArray      = errors.New("arrays of arrays are not supported")
+	errMaxDepth         = errors.New("portable storage data is nested too deeply")
+	errNameTooLong      = errors.New("portable storage entry name is longer than 255 bytes")
+	errNotPointer       = errors.New("portable storage values can only be decoded into a non-nil pointer")
+	errTypeMismatch     = errors.New("portable storage type mismatch")
+	errIntegerOverflow  = errors.New("portable storage integer overflow")
+	errBlobSize         = errors.New("portable storage blob has the wrong size")
+	errUnsupportedType  = errors.New("unsupported type for portable storage")
+)
+
+// appendVarint appends epee's varint encoding of v, where the low 2 bits of
+// the first byte give the length of the little-endian value: 1, 2, 4 or 8
+// bytes.
+func appendVarint(b []byte, v uint64) ([]byte, error) {
+	switch {
+	case v <= 1<<6-1:
+		return append(b, byte(v<<2)), nil
+	case v <= 1<<14-1:
+		return binary.LittleEndian.AppendUint16(b, uint16(v<<2|1)), nil
+	case v <= 1<<30-1:
+		return binary.LittleEndian.AppendUint32(b, uint32(v<<2|2)), nil
+	case v <= maxVarint:
+		return binary.LittleEndian.AppendUint64(b, v<<2|3), nil
+	default:
+		return nil, errVarintTooLarge
+	}
+}
+
+// decoder reads portable storage values from a byte slice. After the first
+// error, all reads return zero values and the error is kept, so that callers
+// can check it once after a group of reads.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) readBytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.b) < n {
+		d.err = errUnexpectedEOF
+		return nil
+	}
+	b := d.b[:n:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *decoder) readByte() byte {
+	b := d.readBytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) readVarint() uint64 {
+	if d.err != nil || len(d.b) == 0 {
+		d.readBytes(1) // sets the error
+		return 0
+	}
+	switch d.b[0] & 3 {
+	case 0:
+		return uint64(d.readByte()) >> 2
+	case 1:
+		return uint64(binary.LittleEndian.Uint16(d.readFixed(2))) >> 2
+	case 2:
+		return uint64(binary.LittleEndian.Uint32(d.readFixed(4))) >> 2
+	default:
+		return binary.LittleEndian.Uint64(d.readFixed(8)) >> 2
+	}
+}
+
+// readFixed reads n bytes, returning zeros on error so that they can be
+// passed to binary.LittleEndian
+func (d *decoder) readFixed(n int) []byte {
+	b := d.readBytes(n)
+	if b == nil {
+		return make([]byte, n)
+	}
+	return b
+}
+
+// readCount reads the varint length of a string or array whose elements each
+// take at least minElemSize bytes. Counts that could not fit in the remaining
+// data are rejected before anything is allocated.
+func (d *decoder) readCount(minElemSize int) int {
+	count := d.readVarint()
+	if d.err != nil {
+		return 0
+	}
+	if count > uint64(len(d.b)/minElemSize) {
+		d.err = errUnexpectedEOF
+		return 0
+	}
+	return int(count)
+}
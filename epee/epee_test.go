@@ -0,0 +1,211 @@
+package epee
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testEntry struct {
+	Blob    []byte   `epee:"blob"`
+	Indices []uint64 `epee:"indices"`
+}
+
+type testStruct struct {
+	Status    string      `epee:"status"`
+	Height    uint64      `epee:"height"`
+	Count     uint32      `epee:"count,omitempty"`
+	Signed    int16       `epee:"signed"`
+	Small     uint8       `epee:"small"`
+	Ratio     float64     `epee:"ratio"`
+	Untrusted bool        `epee:"untrusted"`
+	Hash      [32]byte    `epee:"hash"`
+	BlockIDs  [][32]byte  `epee:"block_ids,blob"`
+	Entries   []testEntry `epee:"entries"`
+	Nested    *testEntry  `epee:"nested,omitempty"`
+	Strings   [][]byte    `epee:"strings"`
+	Ignored   string
+}
+
+func TestAppendVarint(t *testing.T) {
+	for _, test := range []struct {
+		v       uint64
+		encoded string
+	}{
+		{0, "00"},
+		{63, "fc"},
+		{64, "0101"},
+		{16383, "fdff"},
+		{16384, "02000100"},
+		{1<<30 - 1, "feffffff"},
+		{1 << 30, "0300000001000000"},
+		{maxVarint, "ffffffffffffffff"},
+	} {
+		b, err := appendVarint(nil, test.v)
+		require.NoError(t, err)
+		require.Equal(t, test.encoded, hex.EncodeToString(b), test.v)
+
+		d := &decoder{b: b}
+		require.Equal(t, test.v, d.readVarint())
+		require.NoError(t, d.err)
+		require.Empty(t, d.b)
+	}
+
+	_, err := appendVarint(nil, maxVarint+1)
+	require.ErrorIs(t, err, errVarintTooLarge)
+}
+
+func TestMarshal(t *testing.T) {
+	type status struct {
+		Status string `epee:"status"`
+	}
+	b, err := Marshal(&status{Status: "OK"})
+	require.NoError(t, err)
+	require.Equal(t, "01110101010102010104067374617475730a084f4b", hex.EncodeToString(b))
+
+	var decoded status
+	require.NoError(t, Unmarshal(b, &decoded))
+	require.Equal(t, "OK", decoded.Status)
+}
+
+func TestMarshal_roundTrip(t *testing.T) {
+	v := &testStruct{
+		Status:    "OK",
+		Height:    math.MaxUint64,
+		Signed:    -5,
+		Small:     200,
+		Ratio:     1.5,
+		Untrusted: true,
+		Hash:      [32]byte{1, 2, 3},
+		BlockIDs:  [][32]byte{{4}, {5}},
+		Entries: []testEntry{
+			{Blob: []byte{6, 7}, Indices: []uint64{8, 9}},
+			{Indices: []uint64{}},
+		},
+		Strings: [][]byte{{10}, {}},
+		Ignored: "ignored",
+	}
+	b, err := Marshal(v)
+	require.NoError(t, err)
+
+	var decoded testStruct
+	require.NoError(t, Unmarshal(b, &decoded))
+	v.Ignored = ""
+	v.Entries[1].Blob = []byte{}
+	require.Equal(t, v, &decoded)
+
+	section, err := Decode(b)
+	require.NoError(t, err)
+	require.NotContains(t, section, "count")
+	require.NotContains(t, section, "nested")
+	// the hashes are packed in a single string
+	require.Equal(t, append(v.BlockIDs[0][:], v.BlockIDs[1][:]...), section["block_ids"])
+	require.Equal(t, []any{[]byte{10}, []byte{}}, section["strings"])
+}
+
+type testUnmarshaler struct {
+	strings int
+	objects int
+}
+
+func (u *testUnmarshaler) UnmarshalEpee(value any) error {
+	for _, v := range value.([]any) {
+		switch v.(type) {
+		case []byte:
+			u.strings++
+		case map[string]any:
+			u.objects++
+		}
+	}
+	return nil
+}
+
+func TestUnmarshal_unmarshaler(t *testing.T) {
+	b, err := Marshal(&struct {
+		Txs [][]byte `epee:"txs"`
+	}{Txs: [][]byte{{1}, {2}}})
+	require.NoError(t, err)
+
+	var decoded struct {
+		Txs testUnmarshaler `epee:"txs"`
+	}
+	require.NoError(t, Unmarshal(b, &decoded))
+	require.Equal(t, testUnmarshaler{strings: 2}, decoded.Txs)
+}
+
+func TestUnmarshal_integerTypes(t *testing.T) {
+	b, err := Marshal(&struct {
+		A int32  `epee:"a"`
+		B int64  `epee:"b"`
+		C uint64 `epee:"c"`
+	}{A: 5, B: -1, C: 300})
+	require.NoError(t, err)
+
+	// signed values that are not negative can be decoded as unsigned
+	var unsigned struct {
+		A uint8 `epee:"a"`
+	}
+	require.NoError(t, Unmarshal(b, &unsigned))
+	require.Equal(t, uint8(5), unsigned.A)
+
+	var negative struct {
+		B uint64 `epee:"b"`
+	}
+	require.ErrorIs(t, Unmarshal(b, &negative), errTypeMismatch)
+
+	var overflow struct {
+		C uint8 `epee:"c"`
+	}
+	require.ErrorIs(t, Unmarshal(b, &overflow), errIntegerOverflow)
+
+	var mismatch struct {
+		C string `epee:"c"`
+	}
+	require.ErrorIs(t, Unmarshal(b, &mismatch), errTypeMismatch)
+}
+
+func TestDecode_errors(t *testing.T) {
+	b, err := Marshal(&testStruct{Entries: []testEntry{{Blob: []byte{1}}}})
+	require.NoError(t, err)
+
+	_, err = Decode(b[1:])
+	require.ErrorIs(t, err, errInvalidSignature)
+
+	for i := len(Signature); i < len(b); i++ {
+		_, err = Decode(b[:i])
+		require.ErrorIs(t, err, errUnexpectedEOF, i)
+	}
+
+	_, err = Decode(append(b, 0))
+	require.ErrorIs(t, err, errTrailingBytes)
+
+	// a section with an entry of an unknown type
+	_, err = Decode(append(bytes.Clone(Signature), 0x04, 0x01, 'a', 0x0e, 0x00))
+	require.ErrorIs(t, err, errUnknownType)
+
+	// an array of arrays
+	_, err = Decode(append(bytes.Clone(Signature), 0x04, 0x01, 'a', 0x8d, 0x04, 0x00))
+	require.ErrorIs(t, err, errNestedArray)
+
+	// sections nested too deeply
+	deep := bytes.Clone(Signature)
+	for i := 0; i <= maxDepth+1; i++ {
+		deep = append(deep, 0x04, 0x01, 'a', byte(TypeObject))
+	}
+	deep = append(deep, 0x00)
+	_, err = Decode(deep)
+	require.ErrorIs(t, err, errMaxDepth)
+
+	var blob struct {
+		BlockIDs [][32]byte `epee:"block_ids,blob"`
+	}
+	b, err = Marshal(&struct {
+		BlockIDs []byte `epee:"block_ids"`
+	}{BlockIDs: make([]byte, 33)})
+	require.NoError(t, err)
+	require.ErrorIs(t, Unmarshal(b, &blob), errBlobSize)
+	require.ErrorIs(t, Unmarshal(b, blob), errNotPointer)
+}
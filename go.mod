@@ -5,7 +5,6 @@ go 1.21
 require (
 	ekyu.moe/cryptonight v0.3.0
 	filippo.io/edwards25519 v1.0.0
-	github.com/btcsuite/btcd/btcutil v1.1.3
 	github.com/ethereum/go-ethereum v1.12.2
 	github.com/stretchr/testify v1.8.4
 	golang.org/x/crypto v0.12.0
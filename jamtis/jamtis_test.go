@@ -81,3 +81,24 @@ func TestJamtisKeys(t *testing.T) {
 	require.Equal(t, tc.addressK3, hex.EncodeToString(address.K3[:]))
 	require.Equal(t, tc.addressTag, hex.EncodeToString(address.Tag))
 }
+
+func TestDeriveKeys(t *testing.T) {
+	masterKey, err := hex.DecodeString(tc.masterKey)
+	require.NoError(t, err)
+
+	keys, err := DeriveKeys(masterKey)
+	require.NoError(t, err)
+	require.Equal(t, tc.viewBalanceKey, hex.EncodeToString(keys.ViewBalanceKey))
+	require.Equal(t, tc.unlockAmountsKey, hex.EncodeToString(keys.UnlockAmountsKey))
+	require.Equal(t, tc.findReceivedKey, hex.EncodeToString(keys.FindReceivedKey))
+	require.Equal(t, tc.generateAddressSecret, hex.EncodeToString(keys.GenerateAddressSecret))
+	require.Equal(t, tc.jamtisSpendKeyBase, hex.EncodeToString(keys.SpendKeyBase))
+	require.Equal(t, tc.unlockAmountsPubKey, hex.EncodeToString(keys.UnlockAmountsPubKey))
+	require.Equal(t, tc.findReceivedPubKey, hex.EncodeToString(keys.FindReceivedPubKey))
+
+	j := [AddressIndexLen]byte{1}
+	address, err := keys.Address(j[:])
+	require.NoError(t, err)
+	require.Equal(t, tc.addressK1, hex.EncodeToString(address.K1))
+	require.Equal(t, tc.addressTag, hex.EncodeToString(address.Tag))
+}
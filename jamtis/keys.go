@@ -0,0 +1,58 @@
+package jamtis
+
+// Keys holds the keys of a Jamtis wallet, all derived from its master key
+type Keys struct {
+	ViewBalanceKey        []byte // k_vb
+	UnlockAmountsKey      []byte // xk_ua
+	FindReceivedKey       []byte // xk_fr
+	GenerateAddressSecret []byte // s_ga
+	SpendKeyBase          []byte // K_s
+	UnlockAmountsPubKey   []byte // xK_ua
+	FindReceivedPubKey    []byte // xK_fr
+}
+
+// DeriveKeys derives the keys of a Jamtis wallet from its master key.
+func DeriveKeys(masterKey []byte) (*Keys, error) {
+	viewBalanceKey, err := GenViewBalancePrivKey(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	unlockAmountsKey, err := GenUnlockAmountsPrivKey(viewBalanceKey)
+	if err != nil {
+		return nil, err
+	}
+	findReceivedKey, err := GenFindReceivedPrivKey(viewBalanceKey)
+	if err != nil {
+		return nil, err
+	}
+	genAddressSecret, err := GenGenAddressSecret(viewBalanceKey)
+	if err != nil {
+		return nil, err
+	}
+	spendKeyBase, err := GenSeraphisSpendKey(viewBalanceKey, masterKey)
+	if err != nil {
+		return nil, err
+	}
+	unlockAmountsPubKey := GenUnlockAmountsPubKey(unlockAmountsKey)
+
+	return &Keys{
+		ViewBalanceKey:        viewBalanceKey,
+		UnlockAmountsKey:      unlockAmountsKey,
+		FindReceivedKey:       findReceivedKey,
+		GenerateAddressSecret: genAddressSecret,
+		SpendKeyBase:          spendKeyBase,
+		UnlockAmountsPubKey:   unlockAmountsPubKey,
+		FindReceivedPubKey:    GenFindReceivedPubKey(findReceivedKey, unlockAmountsPubKey),
+	}, nil
+}
+
+// Address generates the address with the passed AddressIndexLen-byte index.
+func (k *Keys) Address(addressIndex []byte) (*Address, error) {
+	return GenJamtisAddressV1(
+		k.SpendKeyBase,
+		k.UnlockAmountsPubKey,
+		k.FindReceivedPubKey,
+		k.GenerateAddressSecret,
+		addressIndex,
+	)
+}
@@ -0,0 +1,84 @@
+package mcrypto
+
+import (
+	ed25519 "filippo.io/edwards25519"
+)
+
+// rangeSigBits is the number of bits, each with its own commitment, proven by
+// a Borromean range signature.
+const rangeSigBits = 64
+
+// RangeSig is a Borromean range signature, Monero's rangeSig. Its keys are
+// kept as bytes, as the s values don't have to be canonical scalars and the
+// bit commitments are only decoded when verifying.
+type RangeSig struct {
+	S0 [rangeSigBits][32]byte
+	S1 [rangeSigBits][32]byte
+	EE [32]byte
+	Ci [rangeSigBits][32]byte
+}
+
+// h2 holds 2^i*H, the commitment generator for bit i of an amount
+var h2 = func() [rangeSigBits]*ed25519.Point {
+	var powers [rangeSigBits]*ed25519.Point
+	powers[0] = H()
+	for i := 1; i < rangeSigBits; i++ {
+		powers[i] = new(ed25519.Point).Add(powers[i-1], powers[i-1])
+	}
+	return powers
+}()
+
+// borromeanScalar converts a scalar of a Borromean signature. Monero does not
+// check that they are canonical, and they only multiply the base point,
+// so they are reduced instead of rejected.
+func borromeanScalar(b [32]byte) *ed25519.Scalar {
+	var wide [64]byte
+	copy(wide[:], b[:])
+	s, err := ed25519.NewScalar().SetUniformBytes(wide[:])
+	if err != nil {
+		panic(err) // only possible if the input length is not 64
+	}
+	return s
+}
+
+// VerifyRangeSig is Monero's verRange. It returns true if the Borromean range
+// signature proves that the commitment is to an amount in [0, 2^64), used by
+// RctTypeFull and RctTypeSimple transactions. The signature's bit
+// commitments, Ci, must sum to the commitment, and each must commit to
+// either 0 or 2^i, which is shown by a ring signature over Ci and Ci - 2^i*H.
+func VerifyRangeSig(commitment *ed25519.Point, sig *RangeSig) bool {
+	var p1, p2 [rangeSigBits]*ed25519.Point
+	sum := ed25519.NewIdentityPoint()
+	for i := range sig.Ci {
+		ci, err := new(ed25519.Point).SetBytes(sig.Ci[i][:])
+		if err != nil {
+			return false
+		}
+		p1[i] = ci
+		p2[i] = new(ed25519.Point).Subtract(ci, h2[i])
+		sum.Add(sum, ci)
+	}
+	if sum.Equal(commitment) != 1 {
+		return false
+	}
+
+	ee, err := ed25519.NewScalar().SetCanonicalBytes(sig.EE[:])
+	if err != nil {
+		return false
+	}
+	return verifyBorromean(sig, ee, p1, p2)
+}
+
+// verifyBorromean is Monero's verifyBorromean. For each bit, the challenge
+// from the first key's L, s0*G + ee*P1, links to the second key's L,
+// s1*G + c*P2, and the hash of the second keys' L values must be ee.
+func verifyBorromean(sig *RangeSig, ee *ed25519.Scalar, p1, p2 [rangeSigBits]*ed25519.Point) bool {
+	lv := make([][]byte, 0, rangeSigBits)
+	for i := 0; i < rangeSigBits; i++ {
+		ll := new(ed25519.Point).VarTimeDoubleScalarBaseMult(ee, p1[i], borromeanScalar(sig.S0[i]))
+		c := HashToScalar(ll.Bytes())
+		l := new(ed25519.Point).VarTimeDoubleScalarBaseMult(c, p2[i], borromeanScalar(sig.S1[i]))
+		lv = append(lv, l.Bytes())
+	}
+	return HashToScalar(lv...).Equal(ee) == 1
+}
@@ -0,0 +1,124 @@
+package mcrypto
+
+import (
+	"math"
+	"testing"
+
+	ed25519 "filippo.io/edwards25519"
+	"github.com/stretchr/testify/require"
+)
+
+// proveRange is Monero's proveRange, which we only need to create test
+// vectors, as new transactions must use Bulletproofs+. The mask of the
+// returned commitment is the passed mask.
+func proveRange(amount uint64, mask *ed25519.Scalar) (*RangeSig, *ed25519.Point) {
+	sig := new(RangeSig)
+	var x [rangeSigBits]*ed25519.Scalar
+	var p1, p2 [rangeSigBits]*ed25519.Point
+	remaining := new(ed25519.Scalar).Set(mask)
+	for i := 0; i < rangeSigBits; i++ {
+		// the bit masks sum to the mask
+		x[i] = RandomScalar()
+		if i == rangeSigBits-1 {
+			x[i] = remaining
+		}
+		remaining = new(ed25519.Scalar).Subtract(remaining, x[i])
+
+		p1[i] = new(ed25519.Point).ScalarBaseMult(x[i])
+		if amount>>i&1 == 1 {
+			p1[i].Add(p1[i], h2[i])
+		}
+		p2[i] = new(ed25519.Point).Subtract(p1[i], h2[i])
+		sig.Ci[i] = [32]byte(p1[i].Bytes())
+	}
+
+	// genBorromean, where x[i] is the private key of p1[i] if bit i is 0,
+	// and of p2[i] if it is 1
+	alpha := make([]*ed25519.Scalar, rangeSigBits)
+	l1 := make([][]byte, rangeSigBits)
+	for i := range alpha {
+		alpha[i] = RandomScalar()
+		l := new(ed25519.Point).ScalarBaseMult(alpha[i])
+		if amount>>i&1 == 0 {
+			s1 := RandomScalar()
+			sig.S1[i] = [32]byte(s1.Bytes())
+			l = new(ed25519.Point).VarTimeDoubleScalarBaseMult(HashToScalar(l.Bytes()), p2[i], s1)
+		}
+		l1[i] = l.Bytes()
+	}
+	ee := HashToScalar(l1...)
+	sig.EE = [32]byte(ee.Bytes())
+
+	for i := range alpha {
+		if amount>>i&1 == 0 {
+			s0 := new(ed25519.Scalar).Multiply(ee, x[i])
+			sig.S0[i] = [32]byte(s0.Subtract(alpha[i], s0).Bytes())
+			continue
+		}
+		s0 := RandomScalar()
+		sig.S0[i] = [32]byte(s0.Bytes())
+		ll := new(ed25519.Point).VarTimeDoubleScalarBaseMult(ee, p1[i], s0)
+		s1 := new(ed25519.Scalar).Multiply(HashToScalar(ll.Bytes()), x[i])
+		sig.S1[i] = [32]byte(s1.Subtract(alpha[i], s1).Bytes())
+	}
+
+	return sig, Commit(mask, amount)
+}
+
+func TestVerifyRangeSig(t *testing.T) {
+	for _, amount := range []uint64{0, 1, 990, math.MaxUint64} {
+		mask := RandomScalar()
+		sig, c := proveRange(amount, mask)
+		require.True(t, VerifyRangeSig(c, sig), amount)
+
+		// a commitment to a different amount
+		require.False(t, VerifyRangeSig(Commit(mask, amount+1), sig))
+
+		tampered := *sig
+		tampered.S0[17] = [32]byte(RandomScalar().Bytes())
+		require.False(t, VerifyRangeSig(c, &tampered))
+
+		tampered = *sig
+		tampered.EE = [32]byte(RandomScalar().Bytes())
+		require.False(t, VerifyRangeSig(c, &tampered))
+	}
+}
+
+// TestVerifyRangeSig_nonCanonical checks that, like Monero, non-canonical s
+// values are accepted, as they only multiply the base point.
+func TestVerifyRangeSig_nonCanonical(t *testing.T) {
+	mask := RandomScalar()
+	sig, c := proveRange(5, mask)
+
+	// s + l, which has the same value modulo l, if it fits in 32 bytes
+	for i := range sig.S0 {
+		plusL := addCurveOrder(sig.S0[i])
+		if plusL == nil {
+			continue
+		}
+		_, err := ed25519.NewScalar().SetCanonicalBytes(plusL[:])
+		require.Error(t, err)
+		sig.S0[i] = *plusL
+		require.True(t, VerifyRangeSig(c, sig))
+		return
+	}
+	t.Fatal("no s0 value could be made non-canonical")
+}
+
+// addCurveOrder returns s + l as 32 little endian bytes, or nil if it
+// overflows 32 bytes.
+func addCurveOrder(s [32]byte) *[32]byte {
+	l := lMinusOne.Bytes()
+	l[0]++ // l-1 has a low byte of 0xec, so this can't carry
+	var sum [32]byte
+	carry := 0
+	for i := range sum {
+		v := int(s[i]) + int(l[i]) + carry
+		sum[i] = byte(v)
+		carry = v >> 8
+	}
+	if carry != 0 {
+		return nil
+	}
+	return &sum
+}
@@ -0,0 +1,196 @@
+package mcrypto
+
+import (
+	ed25519 "filippo.io/edwards25519"
+)
+
+// CLSAG domain separators. Each is zero-padded to 32 bytes before hashing.
+const (
+	clsagAgg0Domain  = "CLSAG_agg_0"
+	clsagAgg1Domain  = "CLSAG_agg_1"
+	clsagRoundDomain = "CLSAG_round"
+)
+
+// inv8 is the inverse of the cofactor, 8, modulo the curve order
+var inv8 = func() *ed25519.Scalar {
+	return new(ed25519.Scalar).Invert(ScalarFromUint64(8))
+}()
+
+// RingMember is a member of a RingCT ring: the one-time public key of an
+// output and the output's amount commitment.
+type RingMember struct {
+	Dest       *ed25519.Point
+	Commitment *ed25519.Point
+}
+
+// CLSAG is a concise linkable spontaneous anonymous group signature. It
+// proves that the signer owns the one-time key of one ring member, linked to
+// a key image, and that the pseudo output commitment commits to the same
+// amount as that ring member's commitment.
+type CLSAG struct {
+	S  []*ed25519.Scalar
+	C1 *ed25519.Scalar
+	// D is the commitment key image divided by 8, as stored on-chain
+	D *ed25519.Point
+}
+
+// domainKey returns the domain separator zero-padded to 32 bytes
+func domainKey(domain string) []byte {
+	key := make([]byte, 32)
+	copy(key, domain)
+	return key
+}
+
+// clsagHashes holds the aggregation coefficients and the round hash prefix,
+// which only depend on the public inputs of the signature.
+type clsagHashes struct {
+	muP, muC    *ed25519.Scalar
+	roundPrefix [][]byte
+}
+
+func newCLSAGHashes(
+	message []byte,
+	ring []*RingMember,
+	pseudoOut *ed25519.Point,
+	keyImage *ed25519.Point,
+	dInv8 *ed25519.Point,
+) *clsagHashes {
+	keys := make([][]byte, 0, 2*len(ring))
+	for _, m := range ring {
+		keys = append(keys, m.Dest.Bytes())
+	}
+	for _, m := range ring {
+		keys = append(keys, m.Commitment.Bytes())
+	}
+
+	agg := append(keys, keyImage.Bytes(), dInv8.Bytes(), pseudoOut.Bytes())
+	hashes := &clsagHashes{
+		muP: HashToScalar(append([][]byte{domainKey(clsagAgg0Domain)}, agg...)...),
+		muC: HashToScalar(append([][]byte{domainKey(clsagAgg1Domain)}, agg...)...),
+	}
+
+	hashes.roundPrefix = append([][]byte{domainKey(clsagRoundDomain)}, keys...)
+	hashes.roundPrefix = append(hashes.roundPrefix, pseudoOut.Bytes(), message)
+	return hashes
+}
+
+// roundHash returns the challenge Hs(prefix || L || R) of the next ring
+// member.
+func (h *clsagHashes) roundHash(l, r *ed25519.Point) *ed25519.Scalar {
+	data := append(h.roundPrefix[:len(h.roundPrefix):len(h.roundPrefix)], l.Bytes(), r.Bytes())
+	return HashToScalar(data...)
+}
+
+// round computes L = s*G + c_p*P + c_c*C and R = s*Hp(P) + c_p*I + c_c*D for
+// a ring member, with c_p = mu_P*c and c_c = mu_C*c, and returns the hash of
+// the next round.
+func (h *clsagHashes) round(
+	c, s *ed25519.Scalar,
+	member *RingMember,
+	pseudoOut *ed25519.Point,
+	keyImage *ed25519.Point,
+	d *ed25519.Point,
+) *ed25519.Scalar {
+	cP := new(ed25519.Scalar).Multiply(h.muP, c)
+	cC := new(ed25519.Scalar).Multiply(h.muC, c)
+	commitment := new(ed25519.Point).Subtract(member.Commitment, pseudoOut)
+	hp := HashToEC(member.Dest.Bytes())
+
+	l := new(ed25519.Point).VarTimeMultiScalarMult(
+		[]*ed25519.Scalar{s, cP, cC},
+		[]*ed25519.Point{ed25519.NewGeneratorPoint(), member.Dest, commitment},
+	)
+	r := new(ed25519.Point).VarTimeMultiScalarMult(
+		[]*ed25519.Scalar{s, cP, cC},
+		[]*ed25519.Point{hp, keyImage, d},
+	)
+	return h.roundHash(l, r)
+}
+
+// SignCLSAG is Monero's proveRctCLSAGSimple. It signs the message, the
+// transaction's pre-MLSAG hash, proving ownership of ring[secretIndex]. The
+// secret key is the one-time private key of the real output, secretMask is
+// the mask of its amount commitment and pseudoMask is the mask of the pseudo
+// output commitment, which must commit to the same amount. The key image of
+// the real output is secretKey*Hp(P).
+func SignCLSAG(
+	message []byte,
+	ring []*RingMember,
+	pseudoOut *ed25519.Point,
+	secretKey *ed25519.Scalar,
+	secretMask *ed25519.Scalar,
+	pseudoMask *ed25519.Scalar,
+	secretIndex int,
+) *CLSAG {
+	if secretIndex < 0 || secretIndex >= len(ring) {
+		panic("CLSAG secret index is out of range")
+	}
+	n := len(ring)
+	hp := HashToEC(ring[secretIndex].Dest.Bytes())
+
+	// z is the discrete log, with respect to G, of C - C_offset
+	z := new(ed25519.Scalar).Subtract(secretMask, pseudoMask)
+	keyImage := new(ed25519.Point).ScalarMult(secretKey, hp)
+	d := new(ed25519.Point).ScalarMult(z, hp)
+	dInv8 := new(ed25519.Point).ScalarMult(inv8, d)
+
+	hashes := newCLSAGHashes(message, ring, pseudoOut, keyImage, dInv8)
+
+	sig := &CLSAG{S: make([]*ed25519.Scalar, n), D: dInv8}
+	a := RandomScalar()
+	c := hashes.roundHash(
+		new(ed25519.Point).ScalarBaseMult(a),
+		new(ed25519.Point).ScalarMult(a, hp),
+	)
+
+	for i := (secretIndex + 1) % n; i != secretIndex; i = (i + 1) % n {
+		if i == 0 {
+			sig.C1 = c
+		}
+		sig.S[i] = RandomScalar()
+		c = hashes.round(c, sig.S[i], ring[i], pseudoOut, keyImage, d)
+	}
+	if secretIndex == 0 {
+		sig.C1 = c
+	}
+
+	// s_l = a - c*(mu_P*p + mu_C*z)
+	sl := new(ed25519.Scalar).Multiply(hashes.muP, secretKey)
+	sl.MultiplyAdd(hashes.muC, z, sl)
+	sl.Multiply(c, sl)
+	sig.S[secretIndex] = sl.Subtract(a, sl)
+
+	return sig
+}
+
+// VerifyCLSAG is Monero's verRctCLSAGSimple. It returns true if sig is a
+// valid signature of the message by one of the ring members, linked to
+// keyImage, with the pseudo output commitment committing to the same amount
+// as the signer's ring member.
+func VerifyCLSAG(
+	message []byte,
+	ring []*RingMember,
+	pseudoOut *ed25519.Point,
+	keyImage *ed25519.Point,
+	sig *CLSAG,
+) bool {
+	if len(ring) == 0 || len(sig.S) != len(ring) {
+		return false
+	}
+	identity := ed25519.NewIdentityPoint()
+	if keyImage.Equal(identity) == 1 || !InPrimeSubgroup(keyImage) {
+		return false
+	}
+	d := new(ed25519.Point).MultByCofactor(sig.D)
+	if d.Equal(identity) == 1 {
+		return false
+	}
+
+	hashes := newCLSAGHashes(message, ring, pseudoOut, keyImage, sig.D)
+	c := sig.C1
+	for i := range ring {
+		c = hashes.round(c, sig.S[i], ring[i], pseudoOut, keyImage, d)
+	}
+
+	return c.Equal(sig.C1) == 1
+}
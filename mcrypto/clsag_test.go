@@ -0,0 +1,110 @@
+package mcrypto
+
+import (
+	"testing"
+
+	ed25519 "filippo.io/edwards25519"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+type testCLSAGInput struct {
+	ring        []*RingMember
+	secretIndex int
+	secretKey   *ed25519.Scalar
+	secretMask  *ed25519.Scalar
+	pseudoMask  *ed25519.Scalar
+	pseudoOut   *ed25519.Point
+	keyImage    *ed25519.Point
+}
+
+// newTestCLSAGInput returns a ring of random members, where the member at
+// secretIndex commits to amount, with a pseudo output committing to the same
+// amount.
+func newTestCLSAGInput(size int, secretIndex int, amount uint64) *testCLSAGInput {
+	in := &testCLSAGInput{
+		ring:        make([]*RingMember, size),
+		secretIndex: secretIndex,
+		secretKey:   RandomScalar(),
+		secretMask:  RandomScalar(),
+		pseudoMask:  RandomScalar(),
+	}
+	for i := range in.ring {
+		in.ring[i] = &RingMember{
+			Dest:       new(ed25519.Point).ScalarBaseMult(RandomScalar()),
+			Commitment: Commit(RandomScalar(), uint64(i)),
+		}
+	}
+	in.ring[secretIndex] = &RingMember{
+		Dest:       new(ed25519.Point).ScalarBaseMult(in.secretKey),
+		Commitment: Commit(in.secretMask, amount),
+	}
+	in.pseudoOut = Commit(in.pseudoMask, amount)
+	in.keyImage = HashToEC(in.ring[secretIndex].Dest.Bytes())
+	in.keyImage.ScalarMult(in.secretKey, in.keyImage)
+	return in
+}
+
+func (in *testCLSAGInput) sign(message []byte) *CLSAG {
+	return SignCLSAG(message, in.ring, in.pseudoOut, in.secretKey, in.secretMask, in.pseudoMask, in.secretIndex)
+}
+
+func TestCLSAG(t *testing.T) {
+	message := ethcrypto.Keccak256([]byte("pre-mlsag hash"))
+
+	for _, size := range []int{1, 2, 11, 16} {
+		for _, secretIndex := range []int{0, size / 2, size - 1} {
+			in := newTestCLSAGInput(size, secretIndex, 123456789)
+			sig := in.sign(message)
+			require.Len(t, sig.S, size)
+			require.True(t, VerifyCLSAG(message, in.ring, in.pseudoOut, in.keyImage, sig))
+
+			otherMessage := ethcrypto.Keccak256([]byte("other"))
+			require.False(t, VerifyCLSAG(otherMessage, in.ring, in.pseudoOut, in.keyImage, sig))
+
+			otherKeyImage := new(ed25519.Point).ScalarBaseMult(RandomScalar())
+			require.False(t, VerifyCLSAG(message, in.ring, in.pseudoOut, otherKeyImage, sig))
+			torsioned := new(ed25519.Point).Add(in.keyImage, order2Point(t))
+			require.False(t, VerifyCLSAG(message, in.ring, in.pseudoOut, torsioned, sig))
+
+			otherPseudoOut := Commit(in.pseudoMask, 123456788)
+			require.False(t, VerifyCLSAG(message, in.ring, otherPseudoOut, in.keyImage, sig))
+
+			require.False(t, VerifyCLSAG(message, in.ring[1:], in.pseudoOut, in.keyImage, sig))
+		}
+	}
+}
+
+func TestCLSAG_amountMismatch(t *testing.T) {
+	message := ethcrypto.Keccak256([]byte("pre-mlsag hash"))
+	in := newTestCLSAGInput(11, 3, 1000)
+
+	// a pseudo output committing to a larger amount can't be signed for
+	in.pseudoOut = Commit(in.pseudoMask, 1001)
+	sig := in.sign(message)
+	require.False(t, VerifyCLSAG(message, in.ring, in.pseudoOut, in.keyImage, sig))
+}
+
+func TestCLSAG_tampered(t *testing.T) {
+	message := ethcrypto.Keccak256([]byte("pre-mlsag hash"))
+	in := newTestCLSAGInput(11, 5, 1000)
+	sig := in.sign(message)
+
+	tampered := *sig
+	tampered.S = append([]*ed25519.Scalar{}, sig.S...)
+	tampered.S[2] = RandomScalar()
+	require.False(t, VerifyCLSAG(message, in.ring, in.pseudoOut, in.keyImage, &tampered))
+
+	tampered = *sig
+	tampered.C1 = RandomScalar()
+	require.False(t, VerifyCLSAG(message, in.ring, in.pseudoOut, in.keyImage, &tampered))
+
+	tampered = *sig
+	tampered.D = new(ed25519.Point).Add(sig.D, ed25519.NewGeneratorPoint())
+	require.False(t, VerifyCLSAG(message, in.ring, in.pseudoOut, in.keyImage, &tampered))
+
+	// D with only a torsion component
+	tampered = *sig
+	tampered.D = order2Point(t)
+	require.False(t, VerifyCLSAG(message, in.ring, in.pseudoOut, in.keyImage, &tampered))
+}
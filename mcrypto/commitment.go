@@ -0,0 +1,42 @@
+package mcrypto
+
+import (
+	"encoding/binary"
+
+	ed25519 "filippo.io/edwards25519"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// h is Monero's second generator for Pedersen commitments. Unlike HashToEC,
+// Monero derived it with hashToPointSimple, which decodes keccak(G) directly
+// as a point before multiplying by the cofactor.
+var h = func() *ed25519.Point {
+	p, err := new(ed25519.Point).SetBytes(ethcrypto.Keccak256(ed25519.NewGeneratorPoint().Bytes()))
+	if err != nil {
+		panic(err)
+	}
+	return p.MultByCofactor(p)
+}()
+
+// H returns a copy of Monero's second Pedersen commitment generator. No one
+// knows its discrete log with respect to the base point G.
+func H() *ed25519.Point {
+	return new(ed25519.Point).Set(h)
+}
+
+// ScalarFromUint64 returns the amount as a scalar. This is Monero's d2h.
+func ScalarFromUint64(amount uint64) *ed25519.Scalar {
+	var b [32]byte
+	binary.LittleEndian.PutUint64(b[:], amount)
+	s, err := ed25519.NewScalar().SetCanonicalBytes(b[:])
+	if err != nil {
+		panic(err) // 64-bit values are always canonical
+	}
+	return s
+}
+
+// Commit returns the Pedersen commitment, mask*G + amount*H, to an amount.
+func Commit(mask *ed25519.Scalar, amount uint64) *ed25519.Point {
+	c := new(ed25519.Point).ScalarMult(ScalarFromUint64(amount), h)
+	return c.Add(c, new(ed25519.Point).ScalarBaseMult(mask))
+}
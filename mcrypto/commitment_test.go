@@ -0,0 +1,37 @@
+package mcrypto
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+
+	ed25519 "filippo.io/edwards25519"
+	"github.com/stretchr/testify/require"
+)
+
+func TestH(t *testing.T) {
+	// H from Monero's rctTypes.h
+	const hHex = "8b655970153799af2aeadc9ff1add0ea6c7251d54154cfa92c173a0dd39c1f94"
+	require.Equal(t, hHex, hex.EncodeToString(H().Bytes()))
+
+	// callers can't modify the package's copy
+	H().Add(H(), H())
+	require.Equal(t, hHex, hex.EncodeToString(H().Bytes()))
+}
+
+func TestCommit(t *testing.T) {
+	// Coinbase outputs use a mask of 1, so the commitment is G + amount*H
+	one := ScalarFromUint64(1)
+	expected := new(ed25519.Point).ScalarMult(ScalarFromUint64(1000), H())
+	expected.Add(expected, ed25519.NewGeneratorPoint())
+	require.Equal(t, 1, Commit(one, 1000).Equal(expected))
+
+	// commitments are additively homomorphic
+	sum := new(ed25519.Point).Add(Commit(one, math.MaxUint64), Commit(one, 1))
+	two := ScalarFromUint64(2)
+	twoToThe64 := ScalarFromUint64(math.MaxUint64)
+	twoToThe64.Add(twoToThe64, one)
+	expected = new(ed25519.Point).ScalarMult(twoToThe64, H())
+	expected.Add(expected, new(ed25519.Point).ScalarBaseMult(two))
+	require.Equal(t, 1, sum.Equal(expected))
+}
@@ -0,0 +1,21 @@
+package mcrypto
+
+import (
+	ed25519 "filippo.io/edwards25519"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// HashToScalar is Monero's hash_to_scalar function. The inputs are concatenated,
+// hashed with keccak-256 and the result is reduced modulo the ed25519 curve
+// order.
+func HashToScalar(data ...[]byte) *ed25519.Scalar {
+	// Setting 64 uniform bytes where the upper 32 are zero is equivalent to
+	// sc_reduce32 on the lower 32 bytes.
+	var wide [64]byte
+	copy(wide[:], ethcrypto.Keccak256(data...))
+	s, err := ed25519.NewScalar().SetUniformBytes(wide[:])
+	if err != nil {
+		panic(err) // only possible if the input length is not 64
+	}
+	return s
+}
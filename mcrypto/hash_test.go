@@ -0,0 +1,29 @@
+package mcrypto
+
+import (
+	"crypto/rand"
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashToScalar(t *testing.T) {
+	// HashToScalar should match the slower big.Int based reduction of the
+	// keccak-256 hash
+	for i := 0; i < 100; i++ {
+		data := make([]byte, i)
+		_, err := rand.Read(data)
+		require.NoError(t, err)
+
+		expected := ScReduce32(ethcrypto.Keccak256(data))
+		require.Equal(t, expected, HashToScalar(data).Bytes())
+	}
+}
+
+func TestHashToScalar_concatenates(t *testing.T) {
+	require.Equal(t,
+		HashToScalar([]byte("hello world")).Bytes(),
+		HashToScalar([]byte("hello"), []byte(" "), []byte("world")).Bytes(),
+	)
+}
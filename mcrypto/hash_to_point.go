@@ -0,0 +1,164 @@
+package mcrypto
+
+import (
+	"encoding/hex"
+
+	ed25519 "filippo.io/edwards25519"
+	"filippo.io/edwards25519/field"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// Field constants used by geFromFeFromBytesVartime. The names match the
+// constants in Monero's crypto-ops-data.c. A is the Montgomery curve
+// parameter 486662.
+var (
+	feOne    = new(field.Element).One()
+	feSqrtM1 = mustFieldElement("b0a00e4a271beec478e42fad0618432fa7d7fb3d99004d2b0bdfc14f8024832b")
+	feMA     = new(field.Element) // -A
+	feMA2    = new(field.Element) // -A^2
+	feFFFB1  = new(field.Element) // sqrt(-2 * A * (A + 2))
+	feFFFB2  = new(field.Element) // sqrt(2 * A * (A + 2))
+	feFFFB3  = new(field.Element) // sqrt(-sqrt(-1) * A * (A + 2))
+	feFFFB4  = new(field.Element) // sqrt(sqrt(-1) * A * (A + 2))
+	fe19     = new(field.Element) // 2^255 mod p
+)
+
+func init() {
+	feA := new(field.Element).Mult32(feOne, 486662)
+	feMA.Negate(feA)
+	feMA2.Square(feA)
+	feMA2.Negate(feMA2)
+	fe19.Mult32(feOne, 19)
+
+	// A * (A + 2)
+	aa2 := new(field.Element).Add(feA, new(field.Element).Mult32(feOne, 2))
+	aa2.Multiply(aa2, feA)
+
+	two := new(field.Element).Mult32(feOne, 2)
+	twoAA2 := new(field.Element).Multiply(two, aa2)
+	sqrtM1AA2 := new(field.Element).Multiply(feSqrtM1, aa2)
+
+	// The sign of each square root is irrelevant, as the sign of the
+	// x-coordinate is fixed at the end of geFromFeFromBytesVartime.
+	mustSqrt(feFFFB1, new(field.Element).Negate(twoAA2))
+	mustSqrt(feFFFB2, twoAA2)
+	mustSqrt(feFFFB3, new(field.Element).Negate(sqrtM1AA2))
+	mustSqrt(feFFFB4, sqrtM1AA2)
+}
+
+func mustFieldElement(hexStr string) *field.Element {
+	b, err := hex.DecodeString(hexStr)
+	if err != nil {
+		panic(err)
+	}
+	fe, err := new(field.Element).SetBytes(b)
+	if err != nil {
+		panic(err)
+	}
+	return fe
+}
+
+func mustSqrt(dst *field.Element, x *field.Element) {
+	if _, wasSquare := dst.SqrtRatio(x, feOne); wasSquare != 1 {
+		panic("field constant is not a square")
+	}
+}
+
+// HashToEC is Monero's hash_to_ec function (called hash_to_p3 in the RingCT
+// code). The data is hashed with keccak-256, mapped to a curve point and
+// multiplied by the cofactor 8, so the result is always in the prime order
+// subgroup. Key images, ring signatures and the Bulletproof generators all
+// depend on this mapping.
+func HashToEC(data ...[]byte) *ed25519.Point {
+	p := geFromFeFromBytesVartime(ethcrypto.Keccak256(data...))
+	return p.MultByCofactor(p)
+}
+
+// geFromFeFromBytesVartime is a port of Monero's ge_fromfe_frombytes_vartime.
+// It maps 32 arbitrary bytes to a point on the curve using a variant of the
+// Elligator 2 map. The input is not required to be a canonical field element
+// and all 256 bits are used. The result is not multiplied by the cofactor.
+func geFromFeFromBytesVartime(s []byte) *ed25519.Point {
+	var u, v, w, x, y, z, rX, rY, rZ field.Element
+
+	// SetBytes ignores the top bit, but Monero reduces the full 256-bit input
+	// modulo p, so we add back 2^255 = 19 (mod p) when the bit is set.
+	if _, err := u.SetBytes(s); err != nil {
+		panic(err) // only possible if len(s) != 32
+	}
+	if s[31]&0x80 != 0 {
+		u.Add(&u, fe19)
+	}
+
+	v.Square(&u)
+	v.Add(&v, &v)         // 2 * u^2
+	w.Add(&v, feOne)      // w = 2 * u^2 + 1
+	x.Square(&w)          // w^2
+	y.Multiply(feMA2, &v) // -2 * A^2 * u^2
+	x.Add(&x, &y)         // x = w^2 - 2 * A^2 * u^2
+	feDivPowM1(&rX, &w, &x)
+	y.Square(&rX)
+	x.Multiply(&y, &x)
+	y.Subtract(&w, &x)
+	z.Set(feMA)
+
+	var sign int
+	switch {
+	case y.Equal(new(field.Element)) == 1:
+		rX.Multiply(&rX, feFFFB2)
+		rX.Multiply(&rX, &u) // u * sqrt(2 * A * (A + 2) * w / x)
+		z.Multiply(&z, &v)   // -2 * A * u^2
+		sign = 0
+	case y.Add(&w, &x).Equal(new(field.Element)) == 1:
+		rX.Multiply(&rX, feFFFB1)
+		rX.Multiply(&rX, &u) // u * sqrt(2 * A * (A + 2) * w / x)
+		z.Multiply(&z, &v)   // -2 * A * u^2
+		sign = 0
+	default:
+		x.Multiply(&x, feSqrtM1)
+		y.Subtract(&w, &x)
+		if y.Equal(new(field.Element)) != 1 {
+			// w + x is zero in this branch
+			rX.Multiply(&rX, feFFFB3)
+		} else {
+			rX.Multiply(&rX, feFFFB4)
+		}
+		// rX = sqrt(A * (A + 2) * w / x), z = -A
+		sign = 1
+	}
+
+	if rX.IsNegative() != sign {
+		rX.Negate(&rX)
+	}
+
+	rZ.Add(&z, &w)
+	rY.Subtract(&z, &w)
+	rX.Multiply(&rX, &rZ)
+
+	// Convert the projective (X:Y:Z) result to extended coordinates
+	// (XZ:YZ:Z^2:XY), which represent the same point.
+	var eX, eY, eZ, eT field.Element
+	eX.Multiply(&rX, &rZ)
+	eY.Multiply(&rY, &rZ)
+	eZ.Square(&rZ)
+	eT.Multiply(&rX, &rY)
+	p, err := new(ed25519.Point).SetExtendedCoordinates(&eX, &eY, &eZ, &eT)
+	if err != nil {
+		panic("hash to point produced an invalid point")
+	}
+	return p
+}
+
+// feDivPowM1 is Monero's fe_divpowm1. It sets r = u * v^3 * (u * v^7)^((p-5)/8),
+// which is a square root of u/v, or of sqrt(-1) * u/v, up to sign.
+func feDivPowM1(r, u, v *field.Element) {
+	var v3, uv7 field.Element
+	v3.Square(v)
+	v3.Multiply(&v3, v) // v^3
+	uv7.Square(&v3)
+	uv7.Multiply(&uv7, v)
+	uv7.Multiply(&uv7, u) // u * v^7
+	uv7.Pow22523(&uv7)    // (u * v^7)^((p-5)/8)
+	uv7.Multiply(&uv7, &v3)
+	r.Multiply(&uv7, u)
+}
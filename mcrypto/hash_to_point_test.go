@@ -0,0 +1,42 @@
+package mcrypto
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	ed25519 "filippo.io/edwards25519"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashToEC(t *testing.T) {
+	// hash_to_ec test vector from Monero's tests/crypto/tests.txt
+	pubKey, err := hex.DecodeString("da66e9ba613919dec28ef367a125bb310d6d83fb9052e71034164b6dc4f392d0")
+	require.NoError(t, err)
+	const expected = "52b3f38753b4e13b74624862e253072cf12f745d43fcfafbe8c217701a6e5875"
+	require.Equal(t, expected, hex.EncodeToString(HashToEC(pubKey).Bytes()))
+}
+
+func TestHashToEC_primeOrder(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		data := make([]byte, 32)
+		_, err := rand.Read(data)
+		require.NoError(t, err)
+
+		p := HashToEC(data)
+		require.Equal(t, 0, p.Equal(ed25519.NewIdentityPoint()))
+		require.True(t, InPrimeSubgroup(p))
+	}
+}
+
+func TestGeFromFeFromBytesVartime_highBit(t *testing.T) {
+	// Inputs that only differ by the top bit are different field elements to
+	// Monero, as all 256 bits are reduced modulo p.
+	hash := ethcrypto.Keccak256([]byte("gopherphis"))
+	hash[31] &= 0x7f
+	p1 := geFromFeFromBytesVartime(hash)
+	hash[31] |= 0x80
+	p2 := geFromFeFromBytesVartime(hash)
+	require.Equal(t, 0, p1.Equal(p2))
+}
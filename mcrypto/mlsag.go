@@ -0,0 +1,135 @@
+package mcrypto
+
+import (
+	ed25519 "filippo.io/edwards25519"
+)
+
+// MLSAG is a multilayered linkable spontaneous anonymous group signature,
+// used by RingCT transactions before CLSAG. SS holds a row of scalars for
+// each ring member (column of the key matrix).
+type MLSAG struct {
+	SS [][]*ed25519.Scalar
+	CC *ed25519.Scalar
+}
+
+// mlsagRoundHash returns the challenge of the next column, the hash of the
+// message and, for each row, the public key and L = s*G + c*P. Rows with a
+// key image, the first len(keyImages) rows, also hash R = s*Hp(P) + c*I.
+func mlsagRoundHash(
+	message []byte,
+	column []*ed25519.Point,
+	keyImages []*ed25519.Point,
+	c *ed25519.Scalar,
+	ss []*ed25519.Scalar,
+) *ed25519.Scalar {
+	data := make([][]byte, 0, 1+3*len(keyImages)+2*(len(column)-len(keyImages)))
+	data = append(data, message)
+	for j, pub := range column {
+		l := new(ed25519.Point).VarTimeDoubleScalarBaseMult(c, pub, ss[j])
+		data = append(data, pub.Bytes(), l.Bytes())
+		if j < len(keyImages) {
+			hp := HashToEC(pub.Bytes())
+			r := new(ed25519.Point).VarTimeMultiScalarMult([]*ed25519.Scalar{ss[j], c}, []*ed25519.Point{hp, keyImages[j]})
+			data = append(data, r.Bytes())
+		}
+	}
+	return HashToScalar(data...)
+}
+
+// VerifyMLSAG is Monero's MLSAG_Ver. The key matrix, pubKeys, has a column
+// for each ring member, holding a public key for each row. The first
+// len(keyImages) rows are linked to the key images. It returns true if sig
+// proves knowledge of the private keys of every row of one column.
+func VerifyMLSAG(message []byte, pubKeys [][]*ed25519.Point, keyImages []*ed25519.Point, sig *MLSAG) bool {
+	if len(pubKeys) == 0 || len(sig.SS) != len(pubKeys) {
+		return false
+	}
+	rows := len(pubKeys[0])
+	if rows == 0 || len(keyImages) > rows {
+		return false
+	}
+	for i := range pubKeys {
+		if len(pubKeys[i]) != rows || len(sig.SS[i]) != rows {
+			return false
+		}
+	}
+	identity := ed25519.NewIdentityPoint()
+	for _, keyImage := range keyImages {
+		if keyImage.Equal(identity) == 1 || !InPrimeSubgroup(keyImage) {
+			return false
+		}
+	}
+
+	zero := ed25519.NewScalar()
+	c := sig.CC
+	for i := range pubKeys {
+		c = mlsagRoundHash(message, pubKeys[i], keyImages, c, sig.SS[i])
+		if c.Equal(zero) == 1 {
+			return false
+		}
+	}
+
+	return c.Equal(sig.CC) == 1
+}
+
+// VerifyMLSAGFull is Monero's verRctMG, which verifies the single MLSAG of an
+// RctTypeFull transaction. The ring holds, for each input, the members of
+// the input's ring, which must all have the same size. The last row of the
+// key matrix is the sum of each column's commitments minus the output
+// commitments and the fee, so the signature also proves that the
+// transaction's amounts balance.
+func VerifyMLSAGFull(
+	message []byte,
+	ring [][]*RingMember,
+	outPk []*ed25519.Point,
+	fee uint64,
+	keyImages []*ed25519.Point,
+	sig *MLSAG,
+) bool {
+	if len(ring) == 0 || len(keyImages) != len(ring) {
+		return false
+	}
+	ringSize := len(ring[0])
+
+	// sum of the output commitments and the fee, committed with a zero mask
+	outSum := Commit(ed25519.NewScalar(), fee)
+	for _, c := range outPk {
+		outSum.Add(outSum, c)
+	}
+
+	pubKeys := make([][]*ed25519.Point, ringSize)
+	for i := range pubKeys {
+		pubKeys[i] = make([]*ed25519.Point, 0, len(ring)+1)
+		inSum := ed25519.NewIdentityPoint()
+		for _, inputRing := range ring {
+			if len(inputRing) != ringSize {
+				return false
+			}
+			pubKeys[i] = append(pubKeys[i], inputRing[i].Dest)
+			inSum.Add(inSum, inputRing[i].Commitment)
+		}
+		pubKeys[i] = append(pubKeys[i], inSum.Subtract(inSum, outSum))
+	}
+
+	return VerifyMLSAG(message, pubKeys, keyImages, sig)
+}
+
+// VerifyMLSAGSimple is Monero's verRctMGSimple, which verifies the MLSAG of
+// one input of an RctTypeSimple, RctTypeBulletproof or RctTypeBulletproof2
+// transaction. The second row of the key matrix is each ring member's
+// commitment minus the pseudo output commitment, so the signature also
+// proves that the pseudo output commits to the same amount as the signer's
+// ring member.
+func VerifyMLSAGSimple(
+	message []byte,
+	ring []*RingMember,
+	pseudoOut *ed25519.Point,
+	keyImage *ed25519.Point,
+	sig *MLSAG,
+) bool {
+	pubKeys := make([][]*ed25519.Point, len(ring))
+	for i, m := range ring {
+		pubKeys[i] = []*ed25519.Point{m.Dest, new(ed25519.Point).Subtract(m.Commitment, pseudoOut)}
+	}
+	return VerifyMLSAG(message, pubKeys, []*ed25519.Point{keyImage}, sig)
+}
@@ -0,0 +1,168 @@
+package mcrypto
+
+import (
+	"testing"
+
+	ed25519 "filippo.io/edwards25519"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// signMLSAG is Monero's MLSAG_Gen, which we only need to create test vectors,
+// as new transactions must use CLSAG. The secret keys are those of the column
+// pubKeys[secretIndex], and the first dsRows rows are linked to the returned
+// key images.
+func signMLSAG(
+	message []byte,
+	pubKeys [][]*ed25519.Point,
+	secretKeys []*ed25519.Scalar,
+	secretIndex int,
+	dsRows int,
+) (*MLSAG, []*ed25519.Point) {
+	cols, rows := len(pubKeys), len(secretKeys)
+	keyImages := make([]*ed25519.Point, dsRows)
+	alpha := make([]*ed25519.Scalar, rows)
+
+	data := [][]byte{message}
+	for j := 0; j < rows; j++ {
+		alpha[j] = RandomScalar()
+		pub := pubKeys[secretIndex][j]
+		data = append(data, pub.Bytes(), new(ed25519.Point).ScalarBaseMult(alpha[j]).Bytes())
+		if j < dsRows {
+			hp := HashToEC(pub.Bytes())
+			keyImages[j] = new(ed25519.Point).ScalarMult(secretKeys[j], hp)
+			data = append(data, new(ed25519.Point).ScalarMult(alpha[j], hp).Bytes())
+		}
+	}
+	c := HashToScalar(data...)
+
+	sig := &MLSAG{SS: make([][]*ed25519.Scalar, cols)}
+	for i := (secretIndex + 1) % cols; i != secretIndex; i = (i + 1) % cols {
+		if i == 0 {
+			sig.CC = c
+		}
+		sig.SS[i] = make([]*ed25519.Scalar, rows)
+		for j := range sig.SS[i] {
+			sig.SS[i][j] = RandomScalar()
+		}
+		c = mlsagRoundHash(message, pubKeys[i], keyImages, c, sig.SS[i])
+	}
+	if secretIndex == 0 {
+		sig.CC = c
+	}
+
+	sig.SS[secretIndex] = make([]*ed25519.Scalar, rows)
+	for j := range alpha {
+		sig.SS[secretIndex][j] = new(ed25519.Scalar).Multiply(c, secretKeys[j])
+		sig.SS[secretIndex][j].Subtract(alpha[j], sig.SS[secretIndex][j])
+	}
+	return sig, keyImages
+}
+
+func TestMLSAG(t *testing.T) {
+	message := ethcrypto.Keccak256([]byte("pre-mlsag hash"))
+
+	for _, rows := range []int{1, 2, 3} {
+		for _, secretIndex := range []int{0, 4, 10} {
+			secretKeys := make([]*ed25519.Scalar, rows)
+			pubKeys := make([][]*ed25519.Point, 11)
+			for i := range pubKeys {
+				for j := 0; j < rows; j++ {
+					pubKeys[i] = append(pubKeys[i], new(ed25519.Point).ScalarBaseMult(RandomScalar()))
+				}
+			}
+			for j := range secretKeys {
+				secretKeys[j] = RandomScalar()
+				pubKeys[secretIndex][j] = new(ed25519.Point).ScalarBaseMult(secretKeys[j])
+			}
+
+			// the last row is not linked to a key image
+			sig, keyImages := signMLSAG(message, pubKeys, secretKeys, secretIndex, rows-1)
+			require.True(t, VerifyMLSAG(message, pubKeys, keyImages, sig))
+
+			otherMessage := ethcrypto.Keccak256([]byte("other"))
+			require.False(t, VerifyMLSAG(otherMessage, pubKeys, keyImages, sig))
+			require.False(t, VerifyMLSAG(message, pubKeys[1:], keyImages, sig))
+
+			tampered := *sig
+			tampered.CC = RandomScalar()
+			require.False(t, VerifyMLSAG(message, pubKeys, keyImages, &tampered))
+
+			if len(keyImages) > 0 {
+				torsioned := append([]*ed25519.Point{}, keyImages...)
+				torsioned[0] = new(ed25519.Point).Add(keyImages[0], order2Point(t))
+				require.False(t, VerifyMLSAG(message, pubKeys, torsioned, sig))
+			}
+		}
+	}
+}
+
+// signMLSAG signs the input with an RctTypeSimple MLSAG
+func (in *testCLSAGInput) signMLSAG(message []byte) *MLSAG {
+	pubKeys := make([][]*ed25519.Point, len(in.ring))
+	for i, m := range in.ring {
+		pubKeys[i] = []*ed25519.Point{m.Dest, new(ed25519.Point).Subtract(m.Commitment, in.pseudoOut)}
+	}
+	z := new(ed25519.Scalar).Subtract(in.secretMask, in.pseudoMask)
+	sig, _ := signMLSAG(message, pubKeys, []*ed25519.Scalar{in.secretKey, z}, in.secretIndex, 1)
+	return sig
+}
+
+func TestVerifyMLSAGSimple(t *testing.T) {
+	message := ethcrypto.Keccak256([]byte("pre-mlsag hash"))
+	in := newTestCLSAGInput(5, 2, 1000)
+	require.True(t, VerifyMLSAGSimple(message, in.ring, in.pseudoOut, in.keyImage, in.signMLSAG(message)))
+
+	// a pseudo output committing to a different amount can't be signed for
+	in.pseudoOut = Commit(in.pseudoMask, 1001)
+	require.False(t, VerifyMLSAGSimple(message, in.ring, in.pseudoOut, in.keyImage, in.signMLSAG(message)))
+}
+
+func TestVerifyMLSAGFull(t *testing.T) {
+	const ringSize = 4
+	const secretIndex = 1
+	message := ethcrypto.Keccak256([]byte("pre-mlsag hash"))
+	inAmounts := []uint64{700, 300}
+	outAmounts := []uint64{950, 20}
+	const fee = 30
+
+	// the secret column holds the inputs' real outputs
+	ring := make([][]*RingMember, len(inAmounts))
+	secretKeys := make([]*ed25519.Scalar, 0, len(inAmounts)+1)
+	maskSum := ed25519.NewScalar()
+	for j, amount := range inAmounts {
+		in := newTestCLSAGInput(ringSize, secretIndex, amount)
+		ring[j] = in.ring
+		secretKeys = append(secretKeys, in.secretKey)
+		maskSum.Add(maskSum, in.secretMask)
+	}
+	outPk := make([]*ed25519.Point, len(outAmounts))
+	for i, amount := range outAmounts {
+		mask := RandomScalar()
+		outPk[i] = Commit(mask, amount)
+		maskSum.Subtract(maskSum, mask)
+	}
+	secretKeys = append(secretKeys, maskSum)
+
+	pubKeys := make([][]*ed25519.Point, ringSize)
+	for i := range pubKeys {
+		sum := ed25519.NewIdentityPoint()
+		for j := range ring {
+			pubKeys[i] = append(pubKeys[i], ring[j][i].Dest)
+			sum.Add(sum, ring[j][i].Commitment)
+		}
+		sum.Subtract(sum, Commit(ed25519.NewScalar(), fee))
+		for _, c := range outPk {
+			sum.Subtract(sum, c)
+		}
+		pubKeys[i] = append(pubKeys[i], sum)
+	}
+
+	sig, keyImages := signMLSAG(message, pubKeys, secretKeys, secretIndex, len(inAmounts))
+	require.True(t, VerifyMLSAGFull(message, ring, outPk, fee, keyImages, sig))
+
+	// the amounts don't balance with a different fee
+	require.False(t, VerifyMLSAGFull(message, ring, outPk, fee+1, keyImages, sig))
+	require.False(t, VerifyMLSAGFull(message, ring, outPk[:1], fee, keyImages, sig))
+	require.False(t, VerifyMLSAGFull(message, ring, outPk, fee, keyImages[:1], sig))
+}
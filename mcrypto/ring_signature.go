@@ -0,0 +1,129 @@
+package mcrypto
+
+import (
+	"encoding/hex"
+
+	ed25519 "filippo.io/edwards25519"
+)
+
+// RingSignatureElemLen is the length, in bytes, of the (c, r) pair stored in
+// a ring signature for each ring member.
+const RingSignatureElemLen = 64
+
+// lMinusOne is the curve order minus one. The curve order itself can't be
+// represented as a Scalar.
+var lMinusOne = func() *ed25519.Scalar {
+	b, err := hex.DecodeString("ecd3f55c1a631258d69cf7a2def9de1400000000000000000000000000000010")
+	if err != nil {
+		panic(err)
+	}
+	s, err := ed25519.NewScalar().SetCanonicalBytes(b)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}()
+
+// InPrimeSubgroup returns true if p is in the prime order subgroup of the
+// curve, meaning it has no small order torsion component. Key images must be
+// checked with this function, otherwise the same output could be spent with
+// up to 8 different key images.
+func InPrimeSubgroup(p *ed25519.Point) bool {
+	// l*P = (l-1)*P + P
+	lp := new(ed25519.Point).VarTimeDoubleScalarBaseMult(lMinusOne, p, ed25519.NewScalar())
+	lp.Add(lp, p)
+	return lp.Equal(ed25519.NewIdentityPoint()) == 1
+}
+
+// ringSignatureChallenge returns Hs(prefixHash || a_0 || b_0 || ... || a_n || b_n)
+func ringSignatureChallenge(prefixHash []byte, ab [][]byte) *ed25519.Scalar {
+	return HashToScalar(append([][]byte{prefixHash}, ab...)...)
+}
+
+// GenerateRingSignature is Monero's generate_ring_signature, used by
+// transactions that predate RingCT and by spend and reserve proofs. It signs
+// the prefix hash with the secret key of ring[secretIndex], linking the
+// signature to the key image, secret*Hp(ring[secretIndex]). The signature
+// holds a (c, r) pair for each ring member.
+func GenerateRingSignature(
+	prefixHash []byte,
+	keyImage *ed25519.Point,
+	ring []*ed25519.Point,
+	secret *ed25519.Scalar,
+	secretIndex int,
+) []byte {
+	if secretIndex < 0 || secretIndex >= len(ring) {
+		panic("ring signature secret index is out of range")
+	}
+
+	cs := make([]*ed25519.Scalar, len(ring))
+	rs := make([]*ed25519.Scalar, len(ring))
+	ab := make([][]byte, 0, 2*len(ring))
+	sum := ed25519.NewScalar()
+	var k *ed25519.Scalar
+
+	for i, pub := range ring {
+		hp := HashToEC(pub.Bytes())
+		var a, b *ed25519.Point
+		if i == secretIndex {
+			k = RandomScalar()
+			a = new(ed25519.Point).ScalarBaseMult(k)
+			b = new(ed25519.Point).ScalarMult(k, hp)
+		} else {
+			cs[i], rs[i] = RandomScalar(), RandomScalar()
+			// a = c*P + r*G, b = r*Hp(P) + c*I
+			a = new(ed25519.Point).VarTimeDoubleScalarBaseMult(cs[i], pub, rs[i])
+			b = new(ed25519.Point).VarTimeMultiScalarMult([]*ed25519.Scalar{rs[i], cs[i]}, []*ed25519.Point{hp, keyImage})
+			sum.Add(sum, cs[i])
+		}
+		ab = append(ab, a.Bytes(), b.Bytes())
+	}
+
+	// the real signer's c closes the ring, so that the c values sum to the
+	// challenge
+	cs[secretIndex] = new(ed25519.Scalar).Subtract(ringSignatureChallenge(prefixHash, ab), sum)
+	rs[secretIndex] = new(ed25519.Scalar).Multiply(cs[secretIndex], secret)
+	rs[secretIndex].Subtract(k, rs[secretIndex])
+
+	sig := make([]byte, 0, RingSignatureElemLen*len(ring))
+	for i := range ring {
+		sig = append(sig, cs[i].Bytes()...)
+		sig = append(sig, rs[i].Bytes()...)
+	}
+	return sig
+}
+
+// CheckRingSignature is Monero's check_ring_signature. It returns true if sig
+// is a valid ring signature of the prefix hash by one of the ring members,
+// linked to keyImage.
+func CheckRingSignature(prefixHash []byte, keyImage *ed25519.Point, ring []*ed25519.Point, sig []byte) bool {
+	if len(ring) == 0 || len(sig) != RingSignatureElemLen*len(ring) {
+		return false
+	}
+	if !InPrimeSubgroup(keyImage) {
+		return false
+	}
+
+	ab := make([][]byte, 0, 2*len(ring))
+	sum := ed25519.NewScalar()
+
+	for i, pub := range ring {
+		elem := sig[i*RingSignatureElemLen : (i+1)*RingSignatureElemLen]
+		c, err := ed25519.NewScalar().SetCanonicalBytes(elem[:32])
+		if err != nil {
+			return false
+		}
+		r, err := ed25519.NewScalar().SetCanonicalBytes(elem[32:])
+		if err != nil {
+			return false
+		}
+
+		hp := HashToEC(pub.Bytes())
+		a := new(ed25519.Point).VarTimeDoubleScalarBaseMult(c, pub, r)
+		b := new(ed25519.Point).VarTimeMultiScalarMult([]*ed25519.Scalar{r, c}, []*ed25519.Point{hp, keyImage})
+		ab = append(ab, a.Bytes(), b.Bytes())
+		sum.Add(sum, c)
+	}
+
+	return ringSignatureChallenge(prefixHash, ab).Equal(sum) == 1
+}
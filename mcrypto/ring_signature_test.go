@@ -0,0 +1,70 @@
+package mcrypto
+
+import (
+	"testing"
+
+	ed25519 "filippo.io/edwards25519"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRing returns a ring of random public keys, where the member at
+// secretIndex has the returned secret key and key image.
+func newTestRing(size int, secretIndex int) ([]*ed25519.Point, *ed25519.Scalar, *ed25519.Point) {
+	ring := make([]*ed25519.Point, size)
+	for i := range ring {
+		ring[i] = new(ed25519.Point).ScalarBaseMult(RandomScalar())
+	}
+	secret := RandomScalar()
+	ring[secretIndex] = new(ed25519.Point).ScalarBaseMult(secret)
+	keyImage := HashToEC(ring[secretIndex].Bytes())
+	keyImage.ScalarMult(secret, keyImage)
+	return ring, secret, keyImage
+}
+
+func TestRingSignature(t *testing.T) {
+	prefixHash := ethcrypto.Keccak256([]byte("prefix"))
+
+	for _, size := range []int{1, 2, 11, 16} {
+		for _, secretIndex := range []int{0, size - 1} {
+			ring, secret, keyImage := newTestRing(size, secretIndex)
+			sig := GenerateRingSignature(prefixHash, keyImage, ring, secret, secretIndex)
+			require.Len(t, sig, size*RingSignatureElemLen)
+			require.True(t, CheckRingSignature(prefixHash, keyImage, ring, sig))
+
+			otherHash := ethcrypto.Keccak256([]byte("other prefix"))
+			require.False(t, CheckRingSignature(otherHash, keyImage, ring, sig))
+
+			// a different key image, the same key image with a torsion
+			// component, and a truncated signature
+			otherKeyImage := new(ed25519.Point).ScalarBaseMult(RandomScalar())
+			require.False(t, CheckRingSignature(prefixHash, otherKeyImage, ring, sig))
+			torsioned := new(ed25519.Point).Add(keyImage, order2Point(t))
+			require.False(t, CheckRingSignature(prefixHash, torsioned, ring, sig))
+			require.False(t, CheckRingSignature(prefixHash, keyImage, ring, sig[RingSignatureElemLen:]))
+		}
+	}
+}
+
+func TestRingSignature_reorderedRing(t *testing.T) {
+	prefixHash := ethcrypto.Keccak256([]byte("prefix"))
+	ring, secret, keyImage := newTestRing(4, 1)
+	sig := GenerateRingSignature(prefixHash, keyImage, ring, secret, 1)
+
+	ring[0], ring[2] = ring[2], ring[0]
+	require.False(t, CheckRingSignature(prefixHash, keyImage, ring, sig))
+}
+
+// order2Point returns the point of order 2, (0, -1)
+func order2Point(t *testing.T) *ed25519.Point {
+	b := make([]byte, 32)
+	b[0] = 0xec
+	for i := 1; i < 31; i++ {
+		b[i] = 0xff
+	}
+	b[31] = 0x7f
+	p, err := new(ed25519.Point).SetBytes(b)
+	require.NoError(t, err)
+	require.False(t, InPrimeSubgroup(p))
+	return p
+}
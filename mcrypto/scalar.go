@@ -0,0 +1,21 @@
+package mcrypto
+
+import (
+	"crypto/rand"
+
+	ed25519 "filippo.io/edwards25519"
+)
+
+// RandomScalar returns a uniformly random scalar. This is Monero's
+// random_scalar.
+func RandomScalar() *ed25519.Scalar {
+	var b [64]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err) // crypto/rand does not fail on supported platforms
+	}
+	s, err := ed25519.NewScalar().SetUniformBytes(b[:])
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
@@ -107,3 +107,14 @@ func getIndexes(words []string) ([]uint16, error) {
 	}
 	return nil, ErrLang
 }
+
+// FindLanguage returns the language of the passed seed words, which is the
+// first language whose word list has all of them.
+func FindLanguage(words []string) (*Lang, error) {
+	for _, l := range Languages() {
+		if l.getIndexes(words) != nil {
+			return l, nil
+		}
+	}
+	return nil, ErrLang
+}
@@ -62,3 +62,15 @@ func TestLang_getIndex(t *testing.T) {
 		require.False(t, found)
 	}
 }
+
+func TestFindLanguage(t *testing.T) {
+	indexes := []uint16{691, 1962, 1644, 430, 1966, 128, 1602, 1632, 540, 1462, 1594, 1467, 1296, 987, 140, 225}
+	for _, expected := range []*Lang{EnglishLang, JapaneseLang, KoreanLang, CzechLang} {
+		lang, err := FindLanguage(expected.getWords(indexes))
+		require.NoError(t, err)
+		require.Equal(t, expected, lang)
+	}
+
+	_, err := FindLanguage([]string{"xxxxxx"})
+	require.ErrorIs(t, err, ErrLang)
+}
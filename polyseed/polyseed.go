@@ -63,10 +63,7 @@ func CreateNewSeedPhrase(lang *Lang) ([]string, error) {
 	}
 	seed.secret[numKeyEntropyBytes-1] &= clearBitMask
 
-	// encode polynomial
-	poly := dataToPoly(seed)
-
-	return lang.getWords(poly.coeff[:]), nil
+	return seed.Encode(lang), nil
 }
 
 // CreateSeedData initializes a SeedData object with the passed seed phrase
@@ -0,0 +1,72 @@
+package polyseed
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	// StorageSize is the size in bytes of the serialized SeedData
+	StorageSize = 32
+
+	storageHeader = "POLYSEED"
+	storageExtra  = 0xFF
+	storageFooter = 0x7000
+	checksumMask  = 0x7FF // the 11 bits of a GF(2048) element
+)
+
+// Store serializes the seed data like polyseed's polyseed_store: a header,
+// the features and birthday, the secret, a constant byte and the checksum. The
+// encryption state of the seed is kept, so an encrypted seed stays encrypted.
+func (sd *SeedData) Store() [StorageSize]byte {
+	var b [StorageSize]byte
+	pos := copy(b[:], storageHeader)
+	binary.LittleEndian.PutUint16(b[pos:], uint16(sd.features<<DateBits|sd.birthday))
+	pos += 2
+	pos += copy(b[pos:], sd.secret[:numKeyEntropyBytes])
+	b[pos] = storageExtra
+	pos++
+	binary.LittleEndian.PutUint16(b[pos:], storageFooter|dataToPoly(sd).coeff[0])
+	return b
+}
+
+// LoadSeedData deserializes seed data serialized by Store, like polyseed's
+// polyseed_load.
+func LoadSeedData(b []byte) (*SeedData, error) {
+	if len(b) != StorageSize || !bytes.HasPrefix(b, []byte(storageHeader)) {
+		return nil, ErrFormat
+	}
+	pos := len(storageHeader)
+
+	sd := new(SeedData)
+	v1 := uint(binary.LittleEndian.Uint16(b[pos:]))
+	pos += 2
+	sd.birthday = v1 & DateMask
+	if sd.features = v1 >> DateBits; sd.features > featureMask {
+		return nil, ErrFormat
+	}
+	pos += copy(sd.secret[:numKeyEntropyBytes], b[pos:])
+	if sd.secret[numKeyEntropyBytes-1]&^clearBitMask != 0 || b[pos] != storageExtra {
+		return nil, ErrFormat
+	}
+	pos++
+	v2 := binary.LittleEndian.Uint16(b[pos:])
+	if v2&^checksumMask != storageFooter {
+		return nil, ErrFormat
+	}
+	sd.checksum = v2 & checksumMask
+
+	// Encrypted is the only feature we support at the current time.
+	if sd.features & ^uint(encryptedMask) != 0 {
+		return nil, ErrUnsupported
+	}
+	if dataToPoly(sd).coeff[0] != sd.checksum {
+		return nil, ErrChecksum
+	}
+	return sd, nil
+}
+
+// Encode returns the seed phrase of the seed data in the passed language.
+func (sd *SeedData) Encode(lang *Lang) []string {
+	return lang.getWords(dataToPoly(sd).coeff[:])
+}
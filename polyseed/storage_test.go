@@ -0,0 +1,46 @@
+package polyseed
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeedData_Store(t *testing.T) {
+	words := strings.Fields(
+		"filter vocal snow cupboard volume avoid sign slot drum replace shrug resist pear kiwi bag bring")
+	sd, err := CreateSeedData(words)
+	require.NoError(t, err)
+
+	stored := sd.Store()
+	require.Equal(t, "POLYSEED", string(stored[:8]))
+	loaded, err := LoadSeedData(stored[:])
+	require.NoError(t, err)
+	require.Equal(t, sd.KeyGen(), loaded.KeyGen())
+	require.Equal(t, sd.BirthDate(), loaded.BirthDate())
+	require.Equal(t, words, loaded.Encode(EnglishLang))
+
+	// encrypted seeds stay encrypted
+	sd.Crypt("password")
+	stored = sd.Store()
+	loaded, err = LoadSeedData(stored[:])
+	require.NoError(t, err)
+	require.True(t, loaded.IsEncrypted())
+	require.Equal(t, sd.KeyGen(), loaded.KeyGen())
+
+	_, err = LoadSeedData(stored[:StorageSize-1])
+	require.ErrorIs(t, err, ErrFormat)
+	corrupted := stored
+	corrupted[StorageSize-3] = 0
+	_, err = LoadSeedData(corrupted[:])
+	require.ErrorIs(t, err, ErrFormat)
+	corrupted = stored
+	corrupted[10] ^= 1
+	_, err = LoadSeedData(corrupted[:])
+	require.ErrorIs(t, err, ErrChecksum)
+	corrupted = stored
+	corrupted[9] |= 0x04 // a reserved feature bit
+	_, err = LoadSeedData(corrupted[:])
+	require.ErrorIs(t, err, ErrUnsupported)
+}
@@ -0,0 +1,176 @@
+package serialization
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	errBlockVersion  = errors.New("block version does not fit in a byte")
+	errNoTreeHashes  = errors.New("tree hash of zero hashes")
+	errMinerTxPruned = errors.New("block miner transaction can't be pruned")
+)
+
+// BlockHeader is the header of a block
+type BlockHeader struct {
+	MajorVersion uint8
+	MinorVersion uint8
+	Timestamp    uint64
+	// PrevID is the hash of the previous block
+	PrevID [32]byte
+	Nonce  uint32
+}
+
+// Block is a block: its header, the miner transaction, which pays the block
+// reward, and the hashes of the other transactions
+type Block struct {
+	Header   BlockHeader
+	MinerTx  *Transaction
+	TxHashes [][32]byte
+}
+
+// DecodeBlock deserializes a block, which must use all of b
+func DecodeBlock(b []byte) (*Block, error) {
+	d := &decoder{b: b}
+	block := new(Block)
+	if err := block.decode(d); err != nil {
+		return nil, err
+	}
+	if len(d.b) != 0 {
+		return nil, errTrailingBytes
+	}
+	return block, nil
+}
+
+// UnmarshalBinary deserializes a block
+func (b *Block) UnmarshalBinary(data []byte) error {
+	block, err := DecodeBlock(data)
+	if err != nil {
+		return err
+	}
+	*b = *block
+	return nil
+}
+
+func (b *Block) decode(d *decoder) error {
+	if err := b.Header.decode(d); err != nil {
+		return err
+	}
+	b.MinerTx = new(Transaction)
+	if err := b.MinerTx.decode(d); err != nil {
+		return err
+	}
+	numHashes := d.readCount(32)
+	b.TxHashes = make([][32]byte, numHashes)
+	for i := range b.TxHashes {
+		b.TxHashes[i] = d.readKey()
+	}
+	return d.err
+}
+
+func (h *BlockHeader) decode(d *decoder) error {
+	major, minor := d.readVarint(), d.readVarint()
+	if major > math.MaxUint8 || minor > math.MaxUint8 {
+		return errBlockVersion
+	}
+	h.MajorVersion, h.MinorVersion = uint8(major), uint8(minor)
+	h.Timestamp = d.readVarint()
+	h.PrevID = d.readKey()
+	nonce := d.readBytes(4)
+	if d.err != nil {
+		return d.err
+	}
+	h.Nonce = binary.LittleEndian.Uint32(nonce)
+	return nil
+}
+
+func (h *BlockHeader) appendBinary(b []byte) []byte {
+	b = AppendVarint(b, uint64(h.MajorVersion))
+	b = AppendVarint(b, uint64(h.MinorVersion))
+	b = AppendVarint(b, h.Timestamp)
+	b = append(b, h.PrevID[:]...)
+	return binary.LittleEndian.AppendUint32(b, h.Nonce)
+}
+
+// MarshalBinary serializes the block
+func (b *Block) MarshalBinary() ([]byte, error) {
+	if b.MinerTx.Pruned {
+		return nil, errMinerTxPruned
+	}
+	minerTx, err := b.MinerTx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	data := append(b.Header.appendBinary(nil), minerTx...)
+	data = AppendVarint(data, uint64(len(b.TxHashes)))
+	for _, hash := range b.TxHashes {
+		data = append(data, hash[:]...)
+	}
+	return data, nil
+}
+
+// HashingBlob is Monero's get_block_hashing_blob, the data hashed for the
+// block's ID and proof of work: the header, the Merkle tree hash of all the
+// block's transactions, including the miner transaction, and their count.
+func (b *Block) HashingBlob() ([]byte, error) {
+	minerTxHash, err := b.MinerTx.Hash()
+	if err != nil {
+		return nil, err
+	}
+	root, err := TreeHash(append([][32]byte{minerTxHash}, b.TxHashes...))
+	if err != nil {
+		return nil, err
+	}
+	data := append(b.Header.appendBinary(nil), root[:]...)
+	return AppendVarint(data, uint64(len(b.TxHashes)+1)), nil
+}
+
+// Hash returns the block's hash, also called the block ID, which is the hash
+// of the length-prefixed hashing blob. Mainnet block 202612, whose ID was
+// changed by a bug in Monero's tree hash at the time, is not special cased.
+func (b *Block) Hash() ([32]byte, error) {
+	blob, err := b.HashingBlob()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	data := AppendVarint(nil, uint64(len(blob)))
+	return [32]byte(ethcrypto.Keccak256(data, blob)), nil
+}
+
+// TreeHash is Monero's tree_hash, the root of the Merkle tree of the hashes.
+// When the number of hashes is not a power of 2, only the last hashes are
+// paired in the first round, so that the number of hashes left is.
+func TreeHash(hashes [][32]byte) ([32]byte, error) {
+	switch len(hashes) {
+	case 0:
+		return [32]byte{}, errNoTreeHashes
+	case 1:
+		return hashes[0], nil
+	}
+
+	// cnt is the largest power of 2 below the number of hashes
+	cnt := 1
+	for cnt*2 < len(hashes) {
+		cnt *= 2
+	}
+
+	level := make([][32]byte, cnt)
+	numCopied := 2*cnt - len(hashes)
+	copy(level, hashes[:numCopied])
+	for i, j := numCopied, numCopied; j < cnt; i, j = i+2, j+1 {
+		level[j] = hashPair(hashes[i], hashes[i+1])
+	}
+	for ; cnt > 1; cnt /= 2 {
+		for i := 0; i < cnt/2; i++ {
+			level[i] = hashPair(level[2*i], level[2*i+1])
+		}
+	}
+	return level[0], nil
+}
+
+func hashPair(a, b [32]byte) [32]byte {
+	return [32]byte(ethcrypto.Keccak256(a[:], b[:]))
+}
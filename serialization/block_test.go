@@ -0,0 +1,102 @@
+package serialization
+
+import (
+	"encoding/hex"
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlock_genesis(t *testing.T) {
+	// version 1.0, timestamp 0, no previous block, nonce 10000
+	raw := fromHex(t, "010000"+
+		"0000000000000000000000000000000000000000000000000000000000000000"+
+		"10270000"+mainnetGenesisTx+"00")
+	block, err := DecodeBlock(raw)
+	require.NoError(t, err)
+	require.Equal(t, BlockHeader{MajorVersion: 1, Nonce: 10000}, block.Header)
+	require.Empty(t, block.TxHashes)
+
+	hash, err := block.Hash()
+	require.NoError(t, err)
+	require.Equal(t, "418015bb9ae982a1975da7d79277c2705727a56894ba0fb246adaabb1f4632e3", hex.EncodeToString(hash[:]))
+
+	encoded, err := block.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, raw, encoded)
+
+	_, err = DecodeBlock(append(raw, 0))
+	require.ErrorIs(t, err, errTrailingBytes)
+	for i := range raw {
+		_, err = DecodeBlock(raw[:i])
+		require.Error(t, err, i)
+	}
+}
+
+func TestBlock_roundTrip(t *testing.T) {
+	genesis, err := DecodeTransaction(fromHex(t, mainnetGenesisTx), false)
+	require.NoError(t, err)
+	newKey := testKeys()
+	block := &Block{
+		Header:   BlockHeader{MajorVersion: 16, MinorVersion: 16, Timestamp: 1700000000, PrevID: newKey(), Nonce: 7},
+		MinerTx:  genesis,
+		TxHashes: [][32]byte{newKey(), newKey()},
+	}
+	raw, err := block.MarshalBinary()
+	require.NoError(t, err)
+	decoded, err := DecodeBlock(raw)
+	require.NoError(t, err)
+	require.Equal(t, block, decoded)
+
+	// the header, the tree hash of the 3 transactions and their count
+	blob, err := block.HashingBlob()
+	require.NoError(t, err)
+	minerTxHash, err := genesis.Hash()
+	require.NoError(t, err)
+	root, err := TreeHash([][32]byte{minerTxHash, block.TxHashes[0], block.TxHashes[1]})
+	require.NoError(t, err)
+	require.Equal(t, append(append(block.Header.appendBinary(nil), root[:]...), 3), blob)
+
+	block.Header.MajorVersion = 0
+	raw, err = block.MarshalBinary()
+	require.NoError(t, err)
+	raw[0] = 0x80 // a major version of 256
+	raw = append(raw[:1], append([]byte{0x02}, raw[1:]...)...)
+	_, err = DecodeBlock(raw)
+	require.ErrorIs(t, err, errBlockVersion)
+}
+
+func TestTreeHash(t *testing.T) {
+	newKey := testKeys()
+	hashes := make([][32]byte, 9)
+	for i := range hashes {
+		hashes[i] = newKey()
+	}
+	h := func(a, b [32]byte) [32]byte {
+		return [32]byte(ethcrypto.Keccak256(a[:], b[:]))
+	}
+
+	_, err := TreeHash(nil)
+	require.ErrorIs(t, err, errNoTreeHashes)
+
+	for _, test := range []struct {
+		count int
+		root  [32]byte
+	}{
+		{1, hashes[0]},
+		{2, h(hashes[0], hashes[1])},
+		{3, h(hashes[0], h(hashes[1], hashes[2]))},
+		{4, h(h(hashes[0], hashes[1]), h(hashes[2], hashes[3]))},
+		// only the last 2 hashes are paired in the first round
+		{5, h(h(hashes[0], hashes[1]), h(hashes[2], h(hashes[3], hashes[4])))},
+		{9, h(
+			h(h(hashes[0], hashes[1]), h(hashes[2], hashes[3])),
+			h(h(hashes[4], hashes[5]), h(hashes[6], h(hashes[7], hashes[8]))),
+		)},
+	} {
+		root, err := TreeHash(hashes[:test.count])
+		require.NoError(t, err)
+		require.Equal(t, test.root, root, test.count)
+	}
+}
@@ -0,0 +1,68 @@
+package serialization
+
+// decoder reads serialized values from a byte slice. After the first error,
+// all reads return zero values and the error is kept, so that callers can
+// check it once after a group of reads.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) readByte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.b) == 0 {
+		d.err = errUnexpectedEOF
+		return 0
+	}
+	c := d.b[0]
+	d.b = d.b[1:]
+	return c
+}
+
+func (d *decoder) readBytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.b) < n {
+		d.err = errUnexpectedEOF
+		return nil
+	}
+	b := d.b[:n:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *decoder) readKey() (key [32]byte) {
+	copy(key[:], d.readBytes(len(key)))
+	return key
+}
+
+func (d *decoder) readVarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n, err := ReadVarint(d.b)
+	if err != nil {
+		d.err = err
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+// readCount reads the varint length of a vector whose elements each take at
+// least minElemSize bytes. Counts that could not fit in the remaining data
+// are rejected before anything is allocated.
+func (d *decoder) readCount(minElemSize int) int {
+	count := d.readVarint()
+	if d.err != nil {
+		return 0
+	}
+	if count > uint64(len(d.b)/minElemSize) {
+		d.err = errUnexpectedEOF
+		return 0
+	}
+	return int(count)
+}
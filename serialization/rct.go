@@ -0,0 +1,511 @@
+package serialization
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// RctType is the type of a transaction's RingCT signatures
+type RctType byte
+
+// RingCT signature types. Each hard fork that changed the range proofs or the
+// ring signatures added a type. Coinbase transactions use RctTypeNull.
+const (
+	RctTypeNull            RctType = 0
+	RctTypeFull            RctType = 1
+	RctTypeSimple          RctType = 2
+	RctTypeBulletproof     RctType = 3
+	RctTypeBulletproof2    RctType = 4
+	RctTypeCLSAG           RctType = 5
+	RctTypeBulletproofPlus RctType = 6
+)
+
+// Sizes of Borromean range signatures, which prove an amount is in [0, 2^64)
+// one bit at a time.
+const (
+	rangeSigBits = 64
+	rangeSigSize = (3*rangeSigBits + 1) * 32
+)
+
+var (
+	errUnknownRctType     = errors.New("unknown RingCT signature type")
+	errRctTypeMismatch    = errors.New("RingCT signature fields do not match the signature type")
+	errRctInputCount      = errors.New("RingCT signatures do not match the number of inputs")
+	errRctOutputCount     = errors.New("RingCT signatures do not match the number of outputs")
+	errTooManyRangeProofs = errors.New("more range proofs than transaction outputs")
+)
+
+// String returns the name used for the type by Monero
+func (t RctType) String() string {
+	switch t {
+	case RctTypeNull:
+		return "Null"
+	case RctTypeFull:
+		return "Full"
+	case RctTypeSimple:
+		return "Simple"
+	case RctTypeBulletproof:
+		return "Bulletproof"
+	case RctTypeBulletproof2:
+		return "Bulletproof2"
+	case RctTypeCLSAG:
+		return "CLSAG"
+	case RctTypeBulletproofPlus:
+		return "BulletproofPlus"
+	default:
+		return fmt.Sprintf("RctType(%d)", byte(t))
+	}
+}
+
+// valid returns true for known RingCT signature types
+func (t RctType) valid() bool {
+	return t <= RctTypeBulletproofPlus
+}
+
+// CompactEcdhInfo returns true if the type only stores the 8-byte encrypted
+// amount of each output, and not the encrypted mask.
+func (t RctType) CompactEcdhInfo() bool {
+	return t == RctTypeBulletproof2 || t == RctTypeCLSAG || t == RctTypeBulletproofPlus
+}
+
+// simple returns true if each input has its own pseudo output commitment and
+// ring signature, instead of a single MLSAG covering all inputs.
+func (t RctType) simple() bool {
+	return t != RctTypeNull && t != RctTypeFull
+}
+
+// prunablePseudoOuts returns true if the pseudo output commitments are stored
+// in the prunable part of the signatures.
+func (t RctType) prunablePseudoOuts() bool {
+	return t.simple() && t != RctTypeSimple
+}
+
+// usesCLSAG returns true if the inputs are signed with CLSAGs, and false if
+// they are signed with MLSAGs.
+func (t RctType) usesCLSAG() bool {
+	return t == RctTypeCLSAG || t == RctTypeBulletproofPlus
+}
+
+// EcdhInfo is the encrypted amount of an output, decryptable by the
+// recipient. Types that use compact ecdhInfo only set the first 8 bytes of
+// Amount, and do not store a mask.
+type EcdhInfo struct {
+	Mask   [32]byte
+	Amount [32]byte
+}
+
+// RctSigBase holds the non-prunable part of a transaction's RingCT signatures,
+// which is kept by all nodes.
+type RctSigBase struct {
+	Type   RctType
+	TxnFee uint64
+	// PseudoOuts are the pseudo output commitments of RctTypeSimple
+	// transactions. Later types store them in RctSigPrunable.
+	PseudoOuts [][32]byte
+	EcdhInfo   []*EcdhInfo
+	// OutPk holds the amount commitment of each output
+	OutPk [][32]byte
+}
+
+// RangeSig is a Borromean range proof, used before Bulletproofs
+type RangeSig struct {
+	S0 [rangeSigBits][32]byte
+	S1 [rangeSigBits][32]byte
+	EE [32]byte
+	Ci [rangeSigBits][32]byte
+}
+
+// Bulletproof is an aggregated Bulletproof range proof. The commitments it
+// proves, V, are not serialized, as they are the output commitments.
+type Bulletproof struct {
+	A, S, T1, T2 [32]byte
+	Taux, Mu     [32]byte
+	L, R         [][32]byte
+	// ScalarA, ScalarB and ScalarT are the final a, b and t scalars
+	ScalarA, ScalarB, ScalarT [32]byte
+}
+
+// BulletproofPlus is an aggregated Bulletproof+ range proof. The commitments
+// it proves, V, are not serialized, as they are the output commitments.
+type BulletproofPlus struct {
+	A, A1, B   [32]byte
+	R1, S1, D1 [32]byte
+	L, R       [][32]byte
+}
+
+// MLSAG is a multilayered linkable spontaneous anonymous group signature. The
+// key images, II, are not serialized, as they are in the transaction inputs.
+type MLSAG struct {
+	SS [][][32]byte
+	CC [32]byte
+}
+
+// CLSAG is a concise linkable spontaneous anonymous group signature. The key
+// image, I, is not serialized, as it is in the transaction input.
+type CLSAG struct {
+	S  [][32]byte
+	C1 [32]byte
+	D  [32]byte
+}
+
+// RctSigPrunable holds the part of a transaction's RingCT signatures that
+// pruned nodes can drop once the transaction is deeply confirmed. Which
+// fields are used depends on the RingCT type.
+type RctSigPrunable struct {
+	RangeSigs        []*RangeSig
+	Bulletproofs     []*Bulletproof
+	BulletproofsPlus []*BulletproofPlus
+	MLSAGs           []*MLSAG
+	CLSAGs           []*CLSAG
+	// PseudoOuts are the pseudo output commitments of types after
+	// RctTypeSimple
+	PseudoOuts [][32]byte
+}
+
+// RctSignatures are the RingCT signatures of a version 2 transaction.
+// Prunable is nil for pruned transactions.
+type RctSignatures struct {
+	RctSigBase
+	Prunable *RctSigPrunable
+}
+
+func appendKeys(b []byte, keys [][32]byte) []byte {
+	for _, key := range keys {
+		b = append(b, key[:]...)
+	}
+	return b
+}
+
+func appendKeyVector(b []byte, keys [][32]byte) []byte {
+	b = AppendVarint(b, uint64(len(keys)))
+	return appendKeys(b, keys)
+}
+
+func (d *decoder) readKeys(n int) [][32]byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > len(d.b)/32 {
+		d.err = errUnexpectedEOF
+		return nil
+	}
+	keys := make([][32]byte, n)
+	for i := range keys {
+		keys[i] = d.readKey()
+	}
+	return keys
+}
+
+func (d *decoder) readKeyVector() [][32]byte {
+	return d.readKeys(d.readCount(32))
+}
+
+// appendBase appends the serialized base of the signatures, as used by the
+// transaction hash. The number of inputs and outputs are not serialized, but
+// the counts are validated.
+func (s *RctSigBase) appendBase(b []byte, numInputs, numOutputs int) ([]byte, error) {
+	if !s.Type.valid() {
+		return nil, fmt.Errorf("%w: %d", errUnknownRctType, byte(s.Type))
+	}
+	b = append(b, byte(s.Type))
+	if s.Type == RctTypeNull {
+		return b, nil
+	}
+
+	if len(s.EcdhInfo) != numOutputs || len(s.OutPk) != numOutputs {
+		return nil, errRctOutputCount
+	}
+	if s.Type == RctTypeSimple && len(s.PseudoOuts) != numInputs {
+		return nil, errRctInputCount
+	}
+	if s.Type != RctTypeSimple && len(s.PseudoOuts) != 0 {
+		return nil, errRctTypeMismatch
+	}
+
+	b = AppendVarint(b, s.TxnFee)
+	b = appendKeys(b, s.PseudoOuts)
+	for _, ecdh := range s.EcdhInfo {
+		if s.Type.CompactEcdhInfo() {
+			b = append(b, ecdh.Amount[:8]...)
+		} else {
+			b = append(b, ecdh.Mask[:]...)
+			b = append(b, ecdh.Amount[:]...)
+		}
+	}
+	return appendKeys(b, s.OutPk), nil
+}
+
+func (s *RctSigBase) decodeBase(d *decoder, numInputs, numOutputs int) error {
+	s.Type = RctType(d.readByte())
+	if d.err != nil {
+		return d.err
+	}
+	if !s.Type.valid() {
+		return fmt.Errorf("%w: %d", errUnknownRctType, byte(s.Type))
+	}
+	if s.Type == RctTypeNull {
+		return nil
+	}
+
+	s.TxnFee = d.readVarint()
+	if s.Type == RctTypeSimple {
+		s.PseudoOuts = d.readKeys(numInputs)
+	}
+
+	ecdhSize := 64
+	if s.Type.CompactEcdhInfo() {
+		ecdhSize = 8
+	}
+	if d.err == nil && numOutputs > len(d.b)/ecdhSize {
+		return errUnexpectedEOF
+	}
+	s.EcdhInfo = make([]*EcdhInfo, numOutputs)
+	for i := range s.EcdhInfo {
+		ecdh := new(EcdhInfo)
+		if s.Type.CompactEcdhInfo() {
+			copy(ecdh.Amount[:], d.readBytes(8))
+		} else {
+			ecdh.Mask = d.readKey()
+			ecdh.Amount = d.readKey()
+		}
+		s.EcdhInfo[i] = ecdh
+	}
+
+	s.OutPk = d.readKeys(numOutputs)
+	return d.err
+}
+
+// appendPrunable appends the serialized prunable signatures. The ring size,
+// which sets the size of each ring signature, is not serialized. It is taken
+// from the key offsets of the first input.
+func (p *RctSigPrunable) appendPrunable(b []byte, rctType RctType, numInputs, numOutputs, ringSize int) ([]byte, error) {
+	if err := p.validate(rctType, numInputs, numOutputs, ringSize); err != nil {
+		return nil, err
+	}
+
+	switch rctType {
+	case RctTypeNull:
+		return b, nil
+	case RctTypeBulletproofPlus:
+		b = AppendVarint(b, uint64(len(p.BulletproofsPlus)))
+		for _, bp := range p.BulletproofsPlus {
+			b = append(b, bp.A[:]...)
+			b = append(b, bp.A1[:]...)
+			b = append(b, bp.B[:]...)
+			b = append(b, bp.R1[:]...)
+			b = append(b, bp.S1[:]...)
+			b = append(b, bp.D1[:]...)
+			b = appendKeyVector(b, bp.L)
+			b = appendKeyVector(b, bp.R)
+		}
+	case RctTypeBulletproof, RctTypeBulletproof2, RctTypeCLSAG:
+		if rctType == RctTypeBulletproof {
+			b = binary.LittleEndian.AppendUint32(b, uint32(len(p.Bulletproofs)))
+		} else {
+			b = AppendVarint(b, uint64(len(p.Bulletproofs)))
+		}
+		for _, bp := range p.Bulletproofs {
+			b = appendKeys(b, [][32]byte{bp.A, bp.S, bp.T1, bp.T2, bp.Taux, bp.Mu})
+			b = appendKeyVector(b, bp.L)
+			b = appendKeyVector(b, bp.R)
+			b = appendKeys(b, [][32]byte{bp.ScalarA, bp.ScalarB, bp.ScalarT})
+		}
+	default:
+		for _, rs := range p.RangeSigs {
+			b = appendKeys(b, rs.S0[:])
+			b = appendKeys(b, rs.S1[:])
+			b = append(b, rs.EE[:]...)
+			b = appendKeys(b, rs.Ci[:])
+		}
+	}
+
+	if rctType.usesCLSAG() {
+		for _, sig := range p.CLSAGs {
+			b = appendKeys(b, sig.S)
+			b = append(b, sig.C1[:]...)
+			b = append(b, sig.D[:]...)
+		}
+	} else {
+		for _, sig := range p.MLSAGs {
+			for _, ss := range sig.SS {
+				b = appendKeys(b, ss)
+			}
+			b = append(b, sig.CC[:]...)
+		}
+	}
+
+	return appendKeys(b, p.PseudoOuts), nil
+}
+
+// mlsagSize returns the number of MLSAGs and the number of keys in each row of
+// their SS matrices. Full transactions sign all inputs with one MLSAG.
+func mlsagSize(rctType RctType, numInputs int) (numMLSAGs int, rowSize int) {
+	if rctType.simple() {
+		return numInputs, 2
+	}
+	return 1, numInputs + 1
+}
+
+// validate checks that the sizes of the fields match what will be decoded, so
+// that serialization round trips.
+func (p *RctSigPrunable) validate(rctType RctType, numInputs, numOutputs, ringSize int) error {
+	if rctType == RctTypeNull {
+		return nil
+	}
+
+	switch rctType {
+	case RctTypeBulletproofPlus:
+		if len(p.BulletproofsPlus) > numOutputs {
+			return errTooManyRangeProofs
+		}
+		if len(p.Bulletproofs) != 0 || len(p.RangeSigs) != 0 {
+			return errRctTypeMismatch
+		}
+	case RctTypeBulletproof, RctTypeBulletproof2, RctTypeCLSAG:
+		if len(p.Bulletproofs) > numOutputs {
+			return errTooManyRangeProofs
+		}
+		if len(p.BulletproofsPlus) != 0 || len(p.RangeSigs) != 0 {
+			return errRctTypeMismatch
+		}
+	default:
+		if len(p.RangeSigs) != numOutputs {
+			return errRctOutputCount
+		}
+		if len(p.Bulletproofs) != 0 || len(p.BulletproofsPlus) != 0 {
+			return errRctTypeMismatch
+		}
+	}
+
+	if rctType.usesCLSAG() {
+		if len(p.CLSAGs) != numInputs {
+			return errRctInputCount
+		}
+		for _, sig := range p.CLSAGs {
+			if len(sig.S) != ringSize {
+				return errRctInputCount
+			}
+		}
+		if len(p.MLSAGs) != 0 {
+			return errRctTypeMismatch
+		}
+	} else {
+		numMLSAGs, rowSize := mlsagSize(rctType, numInputs)
+		if len(p.MLSAGs) != numMLSAGs {
+			return errRctInputCount
+		}
+		for _, sig := range p.MLSAGs {
+			if len(sig.SS) != ringSize {
+				return errRctInputCount
+			}
+			for _, ss := range sig.SS {
+				if len(ss) != rowSize {
+					return errRctInputCount
+				}
+			}
+		}
+		if len(p.CLSAGs) != 0 {
+			return errRctTypeMismatch
+		}
+	}
+
+	if rctType.prunablePseudoOuts() && len(p.PseudoOuts) != numInputs ||
+		!rctType.prunablePseudoOuts() && len(p.PseudoOuts) != 0 {
+		return errRctInputCount
+	}
+
+	return nil
+}
+
+func (p *RctSigPrunable) decodePrunable(d *decoder, rctType RctType, numInputs, numOutputs, ringSize int) error {
+	switch rctType {
+	case RctTypeNull:
+		return nil
+	case RctTypeBulletproofPlus:
+		numProofs := d.readVarint()
+		if numProofs > uint64(numOutputs) {
+			return errTooManyRangeProofs
+		}
+		p.BulletproofsPlus = make([]*BulletproofPlus, numProofs)
+		for i := range p.BulletproofsPlus {
+			bp := new(BulletproofPlus)
+			bp.A, bp.A1, bp.B = d.readKey(), d.readKey(), d.readKey()
+			bp.R1, bp.S1, bp.D1 = d.readKey(), d.readKey(), d.readKey()
+			bp.L = d.readKeyVector()
+			bp.R = d.readKeyVector()
+			p.BulletproofsPlus[i] = bp
+		}
+	case RctTypeBulletproof, RctTypeBulletproof2, RctTypeCLSAG:
+		// The first Bulletproof type stored the count as a 4-byte integer
+		var numProofs uint64
+		if rctType == RctTypeBulletproof {
+			if b := d.readBytes(4); b != nil {
+				numProofs = uint64(binary.LittleEndian.Uint32(b))
+			}
+		} else {
+			numProofs = d.readVarint()
+		}
+		if numProofs > uint64(numOutputs) {
+			return errTooManyRangeProofs
+		}
+		p.Bulletproofs = make([]*Bulletproof, numProofs)
+		for i := range p.Bulletproofs {
+			bp := new(Bulletproof)
+			bp.A, bp.S, bp.T1, bp.T2 = d.readKey(), d.readKey(), d.readKey(), d.readKey()
+			bp.Taux, bp.Mu = d.readKey(), d.readKey()
+			bp.L = d.readKeyVector()
+			bp.R = d.readKeyVector()
+			bp.ScalarA, bp.ScalarB, bp.ScalarT = d.readKey(), d.readKey(), d.readKey()
+			p.Bulletproofs[i] = bp
+		}
+	default:
+		if d.err == nil && numOutputs > len(d.b)/rangeSigSize {
+			return errUnexpectedEOF
+		}
+		p.RangeSigs = make([]*RangeSig, numOutputs)
+		for i := range p.RangeSigs {
+			rs := new(RangeSig)
+			copy(rs.S0[:], d.readKeys(rangeSigBits))
+			copy(rs.S1[:], d.readKeys(rangeSigBits))
+			rs.EE = d.readKey()
+			copy(rs.Ci[:], d.readKeys(rangeSigBits))
+			p.RangeSigs[i] = rs
+		}
+	}
+	if d.err != nil {
+		return d.err
+	}
+
+	if rctType.usesCLSAG() {
+		if numInputs > len(d.b)/((ringSize+2)*32) {
+			return errUnexpectedEOF
+		}
+		p.CLSAGs = make([]*CLSAG, numInputs)
+		for i := range p.CLSAGs {
+			sig := &CLSAG{S: d.readKeys(ringSize)}
+			sig.C1, sig.D = d.readKey(), d.readKey()
+			p.CLSAGs[i] = sig
+		}
+	} else {
+		numMLSAGs, rowSize := mlsagSize(rctType, numInputs)
+		if numMLSAGs > len(d.b)/((ringSize*rowSize+1)*32) {
+			return errUnexpectedEOF
+		}
+		p.MLSAGs = make([]*MLSAG, numMLSAGs)
+		for i := range p.MLSAGs {
+			sig := &MLSAG{SS: make([][][32]byte, ringSize)}
+			for j := range sig.SS {
+				sig.SS[j] = d.readKeys(rowSize)
+			}
+			sig.CC = d.readKey()
+			p.MLSAGs[i] = sig
+		}
+	}
+
+	if rctType.prunablePseudoOuts() {
+		p.PseudoOuts = d.readKeys(numInputs)
+	}
+
+	return d.err
+}
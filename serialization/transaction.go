@@ -0,0 +1,361 @@
+package serialization
+
+import (
+	"errors"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// V1SignatureLen is the length, in bytes, of the (c, r) pair stored for each
+// ring member in the ring signatures of version 1 transactions.
+const V1SignatureLen = 64
+
+var (
+	errMissingRctSignatures = errors.New("version 2 transaction has no RingCT signatures")
+	errUnexpectedRctSigs    = errors.New("version 1 transaction has RingCT signatures")
+	errMissingPrunableData  = errors.New("unpruned transaction has no prunable RingCT data")
+	errSignatureCount       = errors.New("ring signatures do not match the transaction inputs")
+	errPrunedV1Hash         = errors.New("the hash of a pruned version 1 transaction can't be computed")
+	errPrunedWeight         = errors.New("the weight of a pruned transaction can't be computed")
+)
+
+// Transaction is a complete Monero transaction: the prefix and its signatures.
+// Pruned transactions, as returned by pruned daemons, do not have the ring
+// signatures and range proofs. The hash of a pruned version 2 transaction can
+// still be computed if PrunableHash is set.
+type Transaction struct {
+	Prefix TxPrefix
+	// Signatures are the ring signatures of version 1 transactions. Each input
+	// has a signature with one (c, r) pair per ring member.
+	Signatures [][][V1SignatureLen]byte
+	// Rct holds the RingCT signatures of version 2 transactions
+	Rct *RctSignatures
+	// Pruned is true if the transaction's prunable data was removed
+	Pruned bool
+	// PrunableHash is the hash of the removed prunable data of a pruned
+	// version 2 transaction. Daemons return it with pruned transactions.
+	PrunableHash [32]byte
+}
+
+// DecodeTransaction deserializes a transaction, which must use all of b. If
+// pruned is true, b must hold a pruned transaction.
+func DecodeTransaction(b []byte, pruned bool) (*Transaction, error) {
+	d := &decoder{b: b}
+	tx := &Transaction{Pruned: pruned}
+	if err := tx.decode(d); err != nil {
+		return nil, err
+	}
+	if len(d.b) != 0 {
+		return nil, errTrailingBytes
+	}
+	return tx, nil
+}
+
+// UnmarshalBinary deserializes an unpruned transaction
+func (tx *Transaction) UnmarshalBinary(b []byte) error {
+	newTx, err := DecodeTransaction(b, false)
+	if err != nil {
+		return err
+	}
+	*tx = *newTx
+	return nil
+}
+
+// MarshalBinary serializes the transaction. Pruned transactions are serialized
+// without their prunable data.
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	b, err := tx.appendBase(nil)
+	if err != nil {
+		return nil, err
+	}
+	if tx.Pruned {
+		return b, nil
+	}
+	if tx.Prefix.Version == TxVersion1 {
+		return tx.appendSignatures(b)
+	}
+	return tx.appendPrunable(b)
+}
+
+// ringSize returns the number of ring members of each input, which RingCT
+// signatures take from the first input.
+func (tx *Transaction) ringSize() int {
+	if len(tx.Prefix.Inputs) == 0 {
+		return 0
+	}
+	return len(tx.Prefix.Inputs[0].KeyOffsets)
+}
+
+// appendBase appends the prefix and, for version 2 transactions, the
+// non-prunable RingCT signature base.
+func (tx *Transaction) appendBase(b []byte) ([]byte, error) {
+	b, err := tx.Prefix.AppendBinary(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if tx.Prefix.Version == TxVersion1 {
+		if tx.Rct != nil {
+			return nil, errUnexpectedRctSigs
+		}
+		return b, nil
+	}
+
+	if tx.Rct == nil {
+		return nil, errMissingRctSignatures
+	}
+	return tx.Rct.appendBase(b, len(tx.Prefix.Inputs), len(tx.Prefix.Outputs))
+}
+
+func (tx *Transaction) appendSignatures(b []byte) ([]byte, error) {
+	if len(tx.Signatures) != len(tx.Prefix.Inputs) {
+		return nil, errSignatureCount
+	}
+	for i, in := range tx.Prefix.Inputs {
+		if len(tx.Signatures[i]) != len(in.KeyOffsets) {
+			return nil, errSignatureCount
+		}
+		for _, sig := range tx.Signatures[i] {
+			b = append(b, sig[:]...)
+		}
+	}
+	return b, nil
+}
+
+func (tx *Transaction) appendPrunable(b []byte) ([]byte, error) {
+	if tx.Rct.Type == RctTypeNull {
+		return b, nil
+	}
+	if tx.Rct.Prunable == nil {
+		return nil, errMissingPrunableData
+	}
+	return tx.Rct.Prunable.appendPrunable(
+		b,
+		tx.Rct.Type,
+		len(tx.Prefix.Inputs),
+		len(tx.Prefix.Outputs),
+		tx.ringSize(),
+	)
+}
+
+func (tx *Transaction) decode(d *decoder) error {
+	if err := tx.Prefix.decode(d); err != nil {
+		return err
+	}
+	numInputs, numOutputs := len(tx.Prefix.Inputs), len(tx.Prefix.Outputs)
+
+	if tx.Prefix.Version == TxVersion1 {
+		if tx.Pruned {
+			return nil
+		}
+		tx.Signatures = make([][][V1SignatureLen]byte, numInputs)
+		for i, in := range tx.Prefix.Inputs {
+			if len(in.KeyOffsets) > len(d.b)/V1SignatureLen {
+				return errUnexpectedEOF
+			}
+			tx.Signatures[i] = make([][V1SignatureLen]byte, len(in.KeyOffsets))
+			for j := range tx.Signatures[i] {
+				copy(tx.Signatures[i][j][:], d.readBytes(V1SignatureLen))
+			}
+		}
+		return d.err
+	}
+
+	tx.Rct = new(RctSignatures)
+	if err := tx.Rct.decodeBase(d, numInputs, numOutputs); err != nil {
+		return err
+	}
+	if tx.Pruned || tx.Rct.Type == RctTypeNull {
+		return nil
+	}
+
+	tx.Rct.Prunable = new(RctSigPrunable)
+	return tx.Rct.Prunable.decodePrunable(d, tx.Rct.Type, numInputs, numOutputs, tx.ringSize())
+}
+
+// PrefixHash returns the hash of the transaction prefix. It is the message
+// signed by the transaction's ring signatures.
+func (tx *Transaction) PrefixHash() ([32]byte, error) {
+	b, err := tx.Prefix.MarshalBinary()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return [32]byte(ethcrypto.Keccak256(b)), nil
+}
+
+// Hash returns the transaction hash, also called the transaction ID. For
+// version 1 transactions, it is the hash of the serialized transaction. For
+// version 2 transactions, it is the hash of the prefix hash, the RingCT base
+// hash and the prunable data hash, so that it can be computed for pruned
+// transactions.
+func (tx *Transaction) Hash() ([32]byte, error) {
+	if tx.Prefix.Version == TxVersion1 {
+		if tx.Pruned {
+			return [32]byte{}, errPrunedV1Hash
+		}
+		b, err := tx.MarshalBinary()
+		if err != nil {
+			return [32]byte{}, err
+		}
+		return [32]byte(ethcrypto.Keccak256(b)), nil
+	}
+
+	prefixHash, err := tx.PrefixHash()
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	if tx.Rct == nil {
+		return [32]byte{}, errMissingRctSignatures
+	}
+	base, err := tx.Rct.appendBase(nil, len(tx.Prefix.Inputs), len(tx.Prefix.Outputs))
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	prunableHash, err := tx.prunableHash()
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return [32]byte(ethcrypto.Keccak256(prefixHash[:], ethcrypto.Keccak256(base), prunableHash[:])), nil
+}
+
+// Weight is Monero's get_transaction_weight, the size used for fees and the
+// block weight limit. It is the size of the serialized transaction, plus a
+// clawback for transactions with more than 2 outputs, as an aggregated
+// Bulletproof is smaller than the individual proofs it replaces, but takes
+// longer to verify. The transaction must not be pruned.
+func (tx *Transaction) Weight() (uint64, error) {
+	if tx.Pruned {
+		return 0, errPrunedWeight
+	}
+	b, err := tx.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	weight := uint64(len(b))
+	if tx.Prefix.Version == TxVersion1 || tx.Rct.Type < RctTypeBulletproof {
+		return weight, nil
+	}
+
+	// The number of amounts each proof covers, padded to a power of 2
+	var paddedOutputs uint64
+	var proofKeys uint64 // the scalars and points of a proof, excluding L and R
+	if tx.Rct.Type == RctTypeBulletproofPlus {
+		proofKeys = 6
+		for _, bp := range tx.Rct.Prunable.BulletproofsPlus {
+			paddedOutputs += proofAmounts(bp.L)
+		}
+	} else {
+		proofKeys = 9
+		for _, bp := range tx.Rct.Prunable.Bulletproofs {
+			paddedOutputs += proofAmounts(bp.L)
+		}
+	}
+	if paddedOutputs <= 2 {
+		return weight, nil
+	}
+
+	// The clawback is 80% of the difference between the size of 2-output
+	// proofs for each pair of outputs and the size of the aggregated proof.
+	var numLR uint64
+	for 1<<numLR < paddedOutputs {
+		numLR++
+	}
+	numLR += 6
+	base := 32 * (proofKeys + 7*2) / 2
+	size := 32 * (proofKeys + 2*numLR)
+	return weight + (base*paddedOutputs-size)*4/5, nil
+}
+
+// proofAmounts returns the number of amounts, padded to a power of 2, that a
+// Bulletproof with the passed L values covers. The proofs have 6 rounds for
+// a single 64-bit amount, plus one for each doubling of the amounts.
+func proofAmounts(l [][32]byte) uint64 {
+	if len(l) < 6 {
+		return 0
+	}
+	return 1 << uint(len(l)-6)
+}
+
+// prunableHash returns the hash of the prunable RingCT data, which is all
+// zeros for RctTypeNull transactions.
+func (tx *Transaction) prunableHash() ([32]byte, error) {
+	if tx.Rct.Type == RctTypeNull {
+		return [32]byte{}, nil
+	}
+	if tx.Pruned {
+		return tx.PrunableHash, nil
+	}
+
+	prunable, err := tx.appendPrunable(nil)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return [32]byte(ethcrypto.Keccak256(prunable)), nil
+}
+
+// Prune returns a pruned copy of the transaction, with the hash of the removed
+// prunable data, so that the pruned copy has the same transaction hash.
+func (tx *Transaction) Prune() (*Transaction, error) {
+	pruned := &Transaction{Prefix: tx.Prefix, Pruned: true}
+	if tx.Prefix.Version == TxVersion1 {
+		return pruned, nil
+	}
+	if tx.Rct == nil {
+		return nil, errMissingRctSignatures
+	}
+
+	var err error
+	if pruned.PrunableHash, err = tx.prunableHash(); err != nil {
+		return nil, err
+	}
+	pruned.Rct = &RctSignatures{RctSigBase: tx.Rct.RctSigBase}
+	return pruned, nil
+}
+
+// SignatureHash is Monero's get_pre_mlsag_hash, the message signed by the
+// MLSAG or CLSAG of each input. It hashes the prefix hash, the RingCT base
+// hash and the range proofs, so the signatures cover the whole transaction.
+// The transaction must not be pruned.
+func (tx *Transaction) SignatureHash() ([32]byte, error) {
+	if tx.Prefix.Version == TxVersion1 || tx.Rct == nil || tx.Rct.Type == RctTypeNull {
+		return [32]byte{}, errMissingRctSignatures
+	}
+	if tx.Rct.Prunable == nil {
+		return [32]byte{}, errMissingPrunableData
+	}
+
+	prefixHash, err := tx.PrefixHash()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	base, err := tx.Rct.appendBase(nil, len(tx.Prefix.Inputs), len(tx.Prefix.Outputs))
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	// The range proof keys are hashed without the vector lengths
+	var proofs []byte
+	p := tx.Rct.Prunable
+	for _, bp := range p.BulletproofsPlus {
+		proofs = appendKeys(proofs, [][32]byte{bp.A, bp.A1, bp.B, bp.R1, bp.S1, bp.D1})
+		proofs = appendKeys(proofs, bp.L)
+		proofs = appendKeys(proofs, bp.R)
+	}
+	for _, bp := range p.Bulletproofs {
+		proofs = appendKeys(proofs, [][32]byte{bp.A, bp.S, bp.T1, bp.T2, bp.Taux, bp.Mu})
+		proofs = appendKeys(proofs, bp.L)
+		proofs = appendKeys(proofs, bp.R)
+		proofs = appendKeys(proofs, [][32]byte{bp.ScalarA, bp.ScalarB, bp.ScalarT})
+	}
+	for _, rs := range p.RangeSigs {
+		proofs = appendKeys(proofs, rs.S0[:])
+		proofs = appendKeys(proofs, rs.S1[:])
+		proofs = append(proofs, rs.EE[:]...)
+		proofs = appendKeys(proofs, rs.Ci[:])
+	}
+
+	return [32]byte(ethcrypto.Keccak256(prefixHash[:], ethcrypto.Keccak256(base), ethcrypto.Keccak256(proofs))), nil
+}
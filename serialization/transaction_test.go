@@ -0,0 +1,315 @@
+package serialization
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransaction_genesis(t *testing.T) {
+	raw := fromHex(t, mainnetGenesisTx)
+	tx, err := DecodeTransaction(raw, false)
+	require.NoError(t, err)
+	require.Nil(t, tx.Rct)
+	require.Equal(t, [][][V1SignatureLen]byte{{}}, tx.Signatures)
+
+	hash, err := tx.Hash()
+	require.NoError(t, err)
+	require.Equal(t, "c88ce9783b4f11190d7b9c17a69c1c52200f9faaee8e98dd07e6811175177139", hex.EncodeToString(hash[:]))
+
+	encoded, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, raw, encoded)
+
+	// pruning a v1 tx removes the signatures, so the hash is lost
+	pruned, err := tx.Prune()
+	require.NoError(t, err)
+	_, err = pruned.Hash()
+	require.ErrorIs(t, err, errPrunedV1Hash)
+}
+
+// testKeys returns a source of distinct, non-zero test keys
+func testKeys() func() [32]byte {
+	var counter uint16
+	return func() [32]byte {
+		counter++
+		return [32]byte{byte(counter), byte(counter >> 8), 0xee}
+	}
+}
+
+func newTestRctTx(rctType RctType, numInputs, numOutputs, ringSize int) *Transaction {
+	nextKey := testKeys()
+	keys := func(n int) [][32]byte {
+		k := make([][32]byte, n)
+		for i := range k {
+			k[i] = nextKey()
+		}
+		return k
+	}
+
+	tx := &Transaction{Prefix: TxPrefix{Version: TxVersion2, Extra: []byte{0x01}}}
+	for i := 0; i < numInputs; i++ {
+		offsets := make([]uint64, ringSize)
+		for j := range offsets {
+			offsets[j] = uint64(j*1000 + i + 1)
+		}
+		tx.Prefix.Inputs = append(tx.Prefix.Inputs, &TxInput{
+			Type:       InputTypeToKey,
+			KeyOffsets: offsets,
+			KeyImage:   nextKey(),
+		})
+	}
+	for i := 0; i < numOutputs; i++ {
+		tx.Prefix.Outputs = append(tx.Prefix.Outputs, &TxOutput{
+			Type:    OutputTypeToTaggedKey,
+			Key:     nextKey(),
+			ViewTag: byte(i),
+		})
+	}
+
+	rct := &RctSignatures{RctSigBase: RctSigBase{Type: rctType, TxnFee: 30_720_000}}
+	tx.Rct = rct
+	for i := 0; i < numOutputs; i++ {
+		ecdh := &EcdhInfo{Amount: [32]byte{1, 2, 3, 4, 5, 6, 7, byte(i)}}
+		if !rctType.CompactEcdhInfo() {
+			ecdh.Mask = nextKey()
+			ecdh.Amount = nextKey()
+		}
+		rct.EcdhInfo = append(rct.EcdhInfo, ecdh)
+	}
+	rct.OutPk = keys(numOutputs)
+	if rctType == RctTypeSimple {
+		rct.PseudoOuts = keys(numInputs)
+	}
+
+	p := new(RctSigPrunable)
+	rct.Prunable = p
+	switch rctType {
+	case RctTypeBulletproofPlus:
+		p.BulletproofsPlus = []*BulletproofPlus{{
+			A: nextKey(), A1: nextKey(), B: nextKey(),
+			R1: nextKey(), S1: nextKey(), D1: nextKey(),
+			L: keys(7), R: keys(7),
+		}}
+	case RctTypeBulletproof, RctTypeBulletproof2, RctTypeCLSAG:
+		p.Bulletproofs = []*Bulletproof{{
+			A: nextKey(), S: nextKey(), T1: nextKey(), T2: nextKey(),
+			Taux: nextKey(), Mu: nextKey(),
+			L: keys(7), R: keys(7),
+			ScalarA: nextKey(), ScalarB: nextKey(), ScalarT: nextKey(),
+		}}
+	default:
+		for i := 0; i < numOutputs; i++ {
+			rs := &RangeSig{EE: nextKey()}
+			for j := 0; j < rangeSigBits; j++ {
+				rs.S0[j], rs.S1[j], rs.Ci[j] = nextKey(), nextKey(), nextKey()
+			}
+			p.RangeSigs = append(p.RangeSigs, rs)
+		}
+	}
+
+	if rctType.usesCLSAG() {
+		for i := 0; i < numInputs; i++ {
+			p.CLSAGs = append(p.CLSAGs, &CLSAG{S: keys(ringSize), C1: nextKey(), D: nextKey()})
+		}
+	} else {
+		numMLSAGs, rowSize := mlsagSize(rctType, numInputs)
+		for i := 0; i < numMLSAGs; i++ {
+			sig := &MLSAG{CC: nextKey()}
+			for j := 0; j < ringSize; j++ {
+				sig.SS = append(sig.SS, keys(rowSize))
+			}
+			p.MLSAGs = append(p.MLSAGs, sig)
+		}
+	}
+
+	if rctType.prunablePseudoOuts() {
+		p.PseudoOuts = keys(numInputs)
+	}
+
+	return tx
+}
+
+func TestTransaction_roundTrip(t *testing.T) {
+	rctTypes := []RctType{
+		RctTypeFull,
+		RctTypeSimple,
+		RctTypeBulletproof,
+		RctTypeBulletproof2,
+		RctTypeCLSAG,
+		RctTypeBulletproofPlus,
+	}
+	for _, rctType := range rctTypes {
+		tx := newTestRctTx(rctType, 2, 3, 16)
+		raw, err := tx.MarshalBinary()
+		require.NoError(t, err, rctType)
+
+		decoded, err := DecodeTransaction(raw, false)
+		require.NoError(t, err, rctType)
+		require.Equal(t, tx, decoded, rctType)
+
+		hash, err := decoded.Hash()
+		require.NoError(t, err)
+
+		// the pruned transaction keeps the same hash
+		pruned, err := decoded.Prune()
+		require.NoError(t, err)
+		prunedRaw, err := pruned.MarshalBinary()
+		require.NoError(t, err)
+		require.Less(t, len(prunedRaw), len(raw))
+		require.Equal(t, raw[:len(prunedRaw)], prunedRaw)
+
+		decodedPruned, err := DecodeTransaction(prunedRaw, true)
+		require.NoError(t, err)
+		require.Nil(t, decodedPruned.Rct.Prunable)
+		decodedPruned.PrunableHash = pruned.PrunableHash
+		require.Equal(t, pruned, decodedPruned)
+
+		prunedHash, err := decodedPruned.Hash()
+		require.NoError(t, err)
+		require.Equal(t, hash, prunedHash, rctType)
+
+		prefixHash, err := decodedPruned.PrefixHash()
+		require.NoError(t, err)
+		require.NotEqual(t, hash, prefixHash)
+
+		// an unpruned transaction can't be decoded as pruned, and vice versa
+		_, err = DecodeTransaction(raw, true)
+		require.ErrorIs(t, err, errTrailingBytes)
+		_, err = DecodeTransaction(prunedRaw, false)
+		require.Error(t, err)
+	}
+}
+
+func TestTransaction_coinbaseV2(t *testing.T) {
+	tx := &Transaction{
+		Prefix: TxPrefix{
+			Version:    TxVersion2,
+			UnlockTime: 3_000_060,
+			Extra:      append([]byte{byte(ExtraTagPubKey)}, make([]byte, 32)...),
+			Inputs:     []*TxInput{{Type: InputTypeGen, Height: 3_000_000}},
+			Outputs:    []*TxOutput{{Amount: 600_000_000_000, Type: OutputTypeToTaggedKey, Key: [32]byte{1}}},
+		},
+		Rct: &RctSignatures{RctSigBase: RctSigBase{Type: RctTypeNull}},
+	}
+	raw, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	decoded, err := DecodeTransaction(raw, false)
+	require.NoError(t, err)
+	require.Equal(t, tx, decoded)
+
+	// coinbase transactions have nothing to prune
+	pruned, err := decoded.Prune()
+	require.NoError(t, err)
+	hash, err := decoded.Hash()
+	require.NoError(t, err)
+	prunedHash, err := pruned.Hash()
+	require.NoError(t, err)
+	require.Equal(t, hash, prunedHash)
+}
+
+func TestDecodeTransaction_fail(t *testing.T) {
+	raw, err := newTestRctTx(RctTypeCLSAG, 1, 2, 11).MarshalBinary()
+	require.NoError(t, err)
+
+	// every truncation fails
+	for i := 0; i < len(raw); i++ {
+		_, err := DecodeTransaction(raw[:i], false)
+		require.Error(t, err, i)
+	}
+
+	tx := newTestRctTx(RctTypeCLSAG, 1, 2, 11)
+	prefixLen, err := tx.Prefix.AppendBinary(nil)
+	require.NoError(t, err)
+	bad := append([]byte{}, raw...)
+	bad[len(prefixLen)] = 7
+	_, err = DecodeTransaction(bad, false)
+	require.ErrorIs(t, err, errUnknownRctType)
+}
+
+func TestTransaction_MarshalBinary_fail(t *testing.T) {
+	tx := newTestRctTx(RctTypeBulletproofPlus, 2, 2, 16)
+	tx.Rct.Prunable.CLSAGs = tx.Rct.Prunable.CLSAGs[1:]
+	_, err := tx.MarshalBinary()
+	require.ErrorIs(t, err, errRctInputCount)
+
+	tx = newTestRctTx(RctTypeCLSAG, 2, 2, 16)
+	tx.Rct.EcdhInfo = tx.Rct.EcdhInfo[1:]
+	_, err = tx.MarshalBinary()
+	require.ErrorIs(t, err, errRctOutputCount)
+
+	tx = newTestRctTx(RctTypeCLSAG, 2, 2, 16)
+	tx.Rct.Prunable.BulletproofsPlus = []*BulletproofPlus{{}}
+	_, err = tx.MarshalBinary()
+	require.ErrorIs(t, err, errRctTypeMismatch)
+
+	tx = newTestRctTx(RctTypeCLSAG, 2, 2, 16)
+	tx.Rct.Prunable = nil
+	_, err = tx.MarshalBinary()
+	require.ErrorIs(t, err, errMissingPrunableData)
+}
+
+func TestTransaction_SignatureHash(t *testing.T) {
+	tx := newTestRctTx(RctTypeBulletproofPlus, 2, 2, 16)
+	hash, err := tx.SignatureHash()
+	require.NoError(t, err)
+
+	// the ring signatures are not covered, as they sign the hash
+	tx.Rct.Prunable.CLSAGs[0].C1 = [32]byte{0xff}
+	sameHash, err := tx.SignatureHash()
+	require.NoError(t, err)
+	require.Equal(t, hash, sameHash)
+
+	// the range proofs and the rest of the transaction are
+	tx.Rct.Prunable.BulletproofsPlus[0].L[0] = [32]byte{0xff}
+	otherHash, err := tx.SignatureHash()
+	require.NoError(t, err)
+	require.NotEqual(t, hash, otherHash)
+
+	tx.Rct.TxnFee++
+	feeHash, err := tx.SignatureHash()
+	require.NoError(t, err)
+	require.NotEqual(t, otherHash, feeHash)
+
+	pruned, err := tx.Prune()
+	require.NoError(t, err)
+	_, err = pruned.SignatureHash()
+	require.ErrorIs(t, err, errMissingPrunableData)
+}
+
+func TestTransaction_Weight(t *testing.T) {
+	testCases := []struct {
+		rctType  RctType
+		outputs  int
+		numLR    int
+		clawback uint64
+	}{
+		{RctTypeBulletproofPlus, 2, 7, 0},
+		{RctTypeBulletproofPlus, 3, 8, 460},
+		{RctTypeBulletproofPlus, 16, 10, (320*16 - 32*(6+2*10)) * 4 / 5},
+		{RctTypeCLSAG, 4, 8, 537},
+		{RctTypeSimple, 4, 0, 0},
+	}
+	for _, tc := range testCases {
+		tx := newTestRctTx(tc.rctType, 1, tc.outputs, 16)
+		p := tx.Rct.Prunable
+		for _, bp := range p.BulletproofsPlus {
+			bp.L, bp.R = make([][32]byte, tc.numLR), make([][32]byte, tc.numLR)
+		}
+		for _, bp := range p.Bulletproofs {
+			bp.L, bp.R = make([][32]byte, tc.numLR), make([][32]byte, tc.numLR)
+		}
+		b, err := tx.MarshalBinary()
+		require.NoError(t, err)
+
+		weight, err := tx.Weight()
+		require.NoError(t, err)
+		require.Equal(t, uint64(len(b))+tc.clawback, weight, tc.rctType)
+	}
+
+	pruned, err := newTestRctTx(RctTypeBulletproofPlus, 1, 2, 16).Prune()
+	require.NoError(t, err)
+	_, err = pruned.Weight()
+	require.ErrorIs(t, err, errPrunedWeight)
+}
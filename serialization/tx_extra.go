@@ -0,0 +1,258 @@
+package serialization
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ExtraTag is the tag that starts each field of a transaction's tx_extra
+type ExtraTag byte
+
+// tx_extra field tags
+const (
+	ExtraTagPadding           ExtraTag = 0x00
+	ExtraTagPubKey            ExtraTag = 0x01
+	ExtraTagNonce             ExtraTag = 0x02
+	ExtraTagMergeMining       ExtraTag = 0x03
+	ExtraTagAdditionalPubKeys ExtraTag = 0x04
+	ExtraTagMinergate         ExtraTag = 0xde
+)
+
+// Limits on the size of tx_extra fields
+const (
+	MaxExtraPaddingLen = 255
+	MaxExtraNonceLen   = 255
+)
+
+// The first byte of a nonce holding a payment ID gives the type of payment ID
+const (
+	nonceTagPaymentID          = 0x00
+	nonceTagEncryptedPaymentID = 0x01
+)
+
+// Payment ID lengths, in bytes
+const (
+	PaymentIDLen          = 32
+	EncryptedPaymentIDLen = 8
+)
+
+var (
+	errUnknownExtraTag     = errors.New("unknown tx_extra field tag")
+	errExtraPaddingTooLong = errors.New("tx_extra padding is too long")
+	errExtraPaddingNonZero = errors.New("tx_extra padding has non-zero bytes")
+	errExtraNonceTooLong   = errors.New("tx_extra nonce is too long")
+)
+
+// ExtraField is a single field of a transaction's tx_extra. Only the members
+// that match the field's tag are used.
+type ExtraField struct {
+	Tag ExtraTag
+	// PubKey is the transaction public key of an ExtraTagPubKey field
+	PubKey [32]byte
+	// AdditionalPubKeys are the per-output public keys of an
+	// ExtraTagAdditionalPubKeys field
+	AdditionalPubKeys [][32]byte
+	// Data is the nonce of an ExtraTagNonce field, the raw contents of
+	// ExtraTagMergeMining and ExtraTagMinergate fields, or the zero bytes
+	// following the tag of an ExtraTagPadding field.
+	Data []byte
+}
+
+// TxExtra holds the parsed fields of a transaction's tx_extra, in their
+// serialized order.
+type TxExtra struct {
+	Fields []*ExtraField
+}
+
+// ParseTxExtra parses a transaction's tx_extra. Since tx_extra is not
+// validated by consensus, some transactions have fields that can't be parsed.
+// Like Monero's parse_tx_extra, on error the fields parsed before the bad
+// field are still returned along with the error.
+func ParseTxExtra(extra []byte) (*TxExtra, error) {
+	e := new(TxExtra)
+	d := &decoder{b: extra}
+
+	for len(d.b) > 0 {
+		field := &ExtraField{Tag: ExtraTag(d.readByte())}
+		switch field.Tag {
+		case ExtraTagPadding:
+			// padding runs to the end of tx_extra
+			if len(d.b)+1 > MaxExtraPaddingLen {
+				return e, errExtraPaddingTooLong
+			}
+			if slices.ContainsFunc(d.b, func(c byte) bool { return c != 0 }) {
+				return e, errExtraPaddingNonZero
+			}
+			field.Data = slices.Clone(d.readBytes(len(d.b)))
+		case ExtraTagPubKey:
+			field.PubKey = d.readKey()
+		case ExtraTagNonce:
+			n := d.readCount(1)
+			if n > MaxExtraNonceLen {
+				return e, errExtraNonceTooLong
+			}
+			field.Data = slices.Clone(d.readBytes(n))
+		case ExtraTagMergeMining, ExtraTagMinergate:
+			field.Data = slices.Clone(d.readBytes(d.readCount(1)))
+		case ExtraTagAdditionalPubKeys:
+			field.AdditionalPubKeys = make([][32]byte, d.readCount(32))
+			for i := range field.AdditionalPubKeys {
+				field.AdditionalPubKeys[i] = d.readKey()
+			}
+		default:
+			return e, fmt.Errorf("%w: 0x%02x", errUnknownExtraTag, byte(field.Tag))
+		}
+		if d.err != nil {
+			return e, d.err
+		}
+		e.Fields = append(e.Fields, field)
+	}
+
+	return e, nil
+}
+
+// Bytes serializes the fields into a tx_extra
+func (e *TxExtra) Bytes() []byte {
+	var b []byte
+	for _, f := range e.Fields {
+		b = append(b, byte(f.Tag))
+		switch f.Tag {
+		case ExtraTagPadding:
+			b = append(b, f.Data...)
+		case ExtraTagPubKey:
+			b = append(b, f.PubKey[:]...)
+		case ExtraTagAdditionalPubKeys:
+			b = AppendVarint(b, uint64(len(f.AdditionalPubKeys)))
+			for _, key := range f.AdditionalPubKeys {
+				b = append(b, key[:]...)
+			}
+		default:
+			b = AppendVarint(b, uint64(len(f.Data)))
+			b = append(b, f.Data...)
+		}
+	}
+	return b
+}
+
+func (e *TxExtra) field(tag ExtraTag) *ExtraField {
+	for _, f := range e.Fields {
+		if f.Tag == tag {
+			return f
+		}
+	}
+	return nil
+}
+
+// TxPubKey returns the transaction public key. If there is more than one,
+// the first is returned, as Monero does.
+func (e *TxExtra) TxPubKey() ([32]byte, bool) {
+	f := e.field(ExtraTagPubKey)
+	if f == nil {
+		return [32]byte{}, false
+	}
+	return f.PubKey, true
+}
+
+// AdditionalPubKeys returns the additional, per-output, transaction public
+// keys. Transactions only have them when paying to a subaddress.
+func (e *TxExtra) AdditionalPubKeys() [][32]byte {
+	f := e.field(ExtraTagAdditionalPubKeys)
+	if f == nil {
+		return nil
+	}
+	return f.AdditionalPubKeys
+}
+
+// Nonce returns the contents of the extra nonce field
+func (e *TxExtra) Nonce() ([]byte, bool) {
+	f := e.field(ExtraTagNonce)
+	if f == nil {
+		return nil, false
+	}
+	return f.Data, true
+}
+
+// PaymentID returns the deprecated, unencrypted 32-byte payment ID stored in
+// the extra nonce.
+func (e *TxExtra) PaymentID() ([PaymentIDLen]byte, bool) {
+	var id [PaymentIDLen]byte
+	nonce, ok := e.Nonce()
+	if !ok || len(nonce) != PaymentIDLen+1 || nonce[0] != nonceTagPaymentID {
+		return id, false
+	}
+	copy(id[:], nonce[1:])
+	return id, true
+}
+
+// EncryptedPaymentID returns the 8-byte payment ID, encrypted for the
+// recipient, stored in the extra nonce. Transactions paying integrated
+// addresses have one, and wallets add a dummy one to other 2-output
+// transactions so that they are indistinguishable.
+func (e *TxExtra) EncryptedPaymentID() ([EncryptedPaymentIDLen]byte, bool) {
+	var id [EncryptedPaymentIDLen]byte
+	nonce, ok := e.Nonce()
+	if !ok || len(nonce) != EncryptedPaymentIDLen+1 || nonce[0] != nonceTagEncryptedPaymentID {
+		return id, false
+	}
+	copy(id[:], nonce[1:])
+	return id, true
+}
+
+// AddTxPubKey adds a transaction public key field
+func (e *TxExtra) AddTxPubKey(key [32]byte) {
+	e.Fields = append(e.Fields, &ExtraField{Tag: ExtraTagPubKey, PubKey: key})
+}
+
+// AddAdditionalPubKeys adds an additional transaction public keys field
+func (e *TxExtra) AddAdditionalPubKeys(keys [][32]byte) {
+	e.Fields = append(e.Fields, &ExtraField{
+		Tag:               ExtraTagAdditionalPubKeys,
+		AdditionalPubKeys: slices.Clone(keys),
+	})
+}
+
+// AddNonce adds an extra nonce field
+func (e *TxExtra) AddNonce(nonce []byte) error {
+	if len(nonce) > MaxExtraNonceLen {
+		return errExtraNonceTooLong
+	}
+	e.Fields = append(e.Fields, &ExtraField{Tag: ExtraTagNonce, Data: slices.Clone(nonce)})
+	return nil
+}
+
+// AddEncryptedPaymentID adds an extra nonce field holding an encrypted
+// payment ID.
+func (e *TxExtra) AddEncryptedPaymentID(id [EncryptedPaymentIDLen]byte) {
+	nonce := append([]byte{nonceTagEncryptedPaymentID}, id[:]...)
+	e.Fields = append(e.Fields, &ExtraField{Tag: ExtraTagNonce, Data: nonce})
+}
+
+// AddPadding adds a padding field of n bytes, including the tag. Padding must
+// be the last field.
+func (e *TxExtra) AddPadding(n int) error {
+	if n < 1 || n > MaxExtraPaddingLen {
+		return errExtraPaddingTooLong
+	}
+	e.Fields = append(e.Fields, &ExtraField{Tag: ExtraTagPadding, Data: make([]byte, n-1)})
+	return nil
+}
+
+// extraSortOrder is the field order used by Monero's sort_tx_extra
+var extraSortOrder = map[ExtraTag]int{
+	ExtraTagPubKey:            0,
+	ExtraTagAdditionalPubKeys: 1,
+	ExtraTagNonce:             2,
+	ExtraTagMergeMining:       3,
+	ExtraTagMinergate:         4,
+	ExtraTagPadding:           5,
+}
+
+// Sort orders the fields like Monero's sort_tx_extra, which wallets apply to
+// new transactions so that the field order does not reveal the wallet
+// software. Fields with the same tag keep their relative order.
+func (e *TxExtra) Sort() {
+	slices.SortStableFunc(e.Fields, func(a, b *ExtraField) int {
+		return extraSortOrder[a.Tag] - extraSortOrder[b.Tag]
+	})
+}
@@ -0,0 +1,89 @@
+package serialization
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxExtra_roundTrip(t *testing.T) {
+	e := new(TxExtra)
+	require.NoError(t, e.AddPadding(4))
+	e.AddEncryptedPaymentID([8]byte{8, 7, 6, 5, 4, 3, 2, 1})
+	e.AddAdditionalPubKeys([][32]byte{{1}, {2}, {3}})
+	e.AddTxPubKey([32]byte{9})
+	e.Sort()
+
+	tags := make([]ExtraTag, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		tags = append(tags, f.Tag)
+	}
+	require.Equal(t, []ExtraTag{ExtraTagPubKey, ExtraTagAdditionalPubKeys, ExtraTagNonce, ExtraTagPadding}, tags)
+
+	raw := e.Bytes()
+	parsed, err := ParseTxExtra(raw)
+	require.NoError(t, err)
+	require.Equal(t, e, parsed)
+	require.Equal(t, raw, parsed.Bytes())
+
+	txPubKey, ok := parsed.TxPubKey()
+	require.True(t, ok)
+	require.Equal(t, [32]byte{9}, txPubKey)
+	require.Equal(t, [][32]byte{{1}, {2}, {3}}, parsed.AdditionalPubKeys())
+
+	encPaymentID, ok := parsed.EncryptedPaymentID()
+	require.True(t, ok)
+	require.Equal(t, [8]byte{8, 7, 6, 5, 4, 3, 2, 1}, encPaymentID)
+	_, ok = parsed.PaymentID()
+	require.False(t, ok)
+}
+
+func TestTxExtra_paymentID(t *testing.T) {
+	e := new(TxExtra)
+	nonce := append([]byte{nonceTagPaymentID}, make([]byte, PaymentIDLen)...)
+	nonce[1] = 0xff
+	require.NoError(t, e.AddNonce(nonce))
+
+	paymentID, ok := e.PaymentID()
+	require.True(t, ok)
+	require.Equal(t, [32]byte{0xff}, paymentID)
+	_, ok = e.EncryptedPaymentID()
+	require.False(t, ok)
+
+	require.ErrorIs(t, e.AddNonce(make([]byte, MaxExtraNonceLen+1)), errExtraNonceTooLong)
+}
+
+func TestParseTxExtra_partial(t *testing.T) {
+	e := new(TxExtra)
+	e.AddTxPubKey([32]byte{1})
+	raw := append(e.Bytes(), 0x05, 0x00)
+
+	// the fields before the unknown tag are still returned
+	parsed, err := ParseTxExtra(raw)
+	require.ErrorIs(t, err, errUnknownExtraTag)
+	txPubKey, ok := parsed.TxPubKey()
+	require.True(t, ok)
+	require.Equal(t, [32]byte{1}, txPubKey)
+}
+
+func TestParseTxExtra_fail(t *testing.T) {
+	tests := []struct {
+		raw []byte
+		err error
+	}{
+		{[]byte{byte(ExtraTagPubKey), 0x01}, errUnexpectedEOF},
+		{[]byte{byte(ExtraTagNonce), 0x02, 0x01}, errUnexpectedEOF},
+		{[]byte{byte(ExtraTagAdditionalPubKeys), 0x02}, errUnexpectedEOF},
+		{[]byte{byte(ExtraTagPadding), 0x00, 0x01}, errExtraPaddingNonZero},
+		{make([]byte, MaxExtraPaddingLen+1), errExtraPaddingTooLong},
+	}
+	for _, tt := range tests {
+		_, err := ParseTxExtra(tt.raw)
+		require.ErrorIs(t, err, tt.err, "%x", tt.raw)
+	}
+
+	nonce := append([]byte{byte(ExtraTagNonce)}, AppendVarint(nil, MaxExtraNonceLen+1)...)
+	nonce = append(nonce, make([]byte, MaxExtraNonceLen+1)...)
+	_, err := ParseTxExtra(nonce)
+	require.ErrorIs(t, err, errExtraNonceTooLong)
+}
@@ -0,0 +1,251 @@
+package serialization
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Transaction versions. Version 1 transactions predate RingCT.
+const (
+	TxVersion1 = 1
+	TxVersion2 = 2
+)
+
+// InputType is the variant tag of a transaction input (txin_v)
+type InputType byte
+
+// Supported input types. Monero also defines script input types, but they
+// were never used on-chain.
+const (
+	InputTypeToKey InputType = 0x02
+	InputTypeGen   InputType = 0xff
+)
+
+// OutputType is the variant tag of a transaction output's target
+// (txout_target_v)
+type OutputType byte
+
+// Supported output types. Since the view tag hard fork, outputs use the tagged
+// key type.
+const (
+	OutputTypeToKey       OutputType = 0x02
+	OutputTypeToTaggedKey OutputType = 0x03
+)
+
+// Each element of these vectors takes at least this many bytes. Used to reject
+// impossible element counts before allocating.
+const (
+	minInputSize  = 2
+	minOutputSize = 34
+)
+
+var (
+	errInvalidTxVersion   = errors.New("invalid transaction version")
+	errUnknownInputType   = errors.New("unknown transaction input type")
+	errUnknownOutputType  = errors.New("unknown transaction output type")
+	errTrailingBytes      = errors.New("unexpected trailing bytes after serialized data")
+	errNoInputKeyOffsets  = errors.New("transaction input has no key offsets")
+	errKeyOffsetsOverflow = errors.New("transaction input key offsets overflow")
+)
+
+// TxInput is a transaction input. Coinbase transactions have a single input
+// of type InputTypeGen, all other inputs have type InputTypeToKey.
+type TxInput struct {
+	Type InputType
+	// Height is the block height of a coinbase input
+	Height uint64
+	// Amount is the amount spent by a key input. It is zero for RingCT
+	// inputs, which hide their amounts.
+	Amount uint64
+	// KeyOffsets are the global output indices of the input's ring members.
+	// The first offset is absolute, and each following offset is relative to
+	// the previous one.
+	KeyOffsets []uint64
+	// KeyImage is the key image of the output being spent
+	KeyImage [32]byte
+}
+
+// AbsoluteOffsets converts the input's relative key offsets into global
+// output indices.
+func (in *TxInput) AbsoluteOffsets() ([]uint64, error) {
+	offsets := make([]uint64, len(in.KeyOffsets))
+	var sum uint64
+	for i, offset := range in.KeyOffsets {
+		if sum+offset < sum {
+			return nil, errKeyOffsetsOverflow
+		}
+		sum += offset
+		offsets[i] = sum
+	}
+	return offsets, nil
+}
+
+// RelativeOffsets converts sorted global output indices into the relative key
+// offsets stored in an input.
+func RelativeOffsets(indices []uint64) []uint64 {
+	offsets := make([]uint64, len(indices))
+	var prev uint64
+	for i, idx := range indices {
+		if idx < prev {
+			panic("output indices are not sorted")
+		}
+		offsets[i] = idx - prev
+		prev = idx
+	}
+	return offsets
+}
+
+// TxOutput is a transaction output paying to a one-time public key
+type TxOutput struct {
+	// Amount is the amount of the output. It is zero for RingCT outputs,
+	// which hide their amounts.
+	Amount uint64
+	Type   OutputType
+	// Key is the one-time public key of the output
+	Key [32]byte
+	// ViewTag is the output's view tag, only set for OutputTypeToTaggedKey
+	// outputs.
+	ViewTag byte
+}
+
+// TxPrefix is Monero's transaction_prefix, the part of a transaction that
+// holds its inputs and outputs, but not the signatures.
+type TxPrefix struct {
+	Version uint64
+	// UnlockTime is the block height, or for values of 500000000 and above,
+	// the unix timestamp, before which the outputs can't be spent.
+	UnlockTime uint64
+	Inputs     []*TxInput
+	Outputs    []*TxOutput
+	// Extra is the raw tx_extra field. Use ParseTxExtra to decode it.
+	Extra []byte
+}
+
+// MarshalBinary serializes the transaction prefix
+func (p *TxPrefix) MarshalBinary() ([]byte, error) {
+	return p.AppendBinary(nil)
+}
+
+// AppendBinary appends the serialized transaction prefix to b
+func (p *TxPrefix) AppendBinary(b []byte) ([]byte, error) {
+	b = AppendVarint(b, p.Version)
+	b = AppendVarint(b, p.UnlockTime)
+
+	b = AppendVarint(b, uint64(len(p.Inputs)))
+	for _, in := range p.Inputs {
+		b = append(b, byte(in.Type))
+		switch in.Type {
+		case InputTypeGen:
+			b = AppendVarint(b, in.Height)
+		case InputTypeToKey:
+			b = AppendVarint(b, in.Amount)
+			b = AppendVarint(b, uint64(len(in.KeyOffsets)))
+			for _, offset := range in.KeyOffsets {
+				b = AppendVarint(b, offset)
+			}
+			b = append(b, in.KeyImage[:]...)
+		default:
+			return nil, fmt.Errorf("%w: 0x%02x", errUnknownInputType, byte(in.Type))
+		}
+	}
+
+	b = AppendVarint(b, uint64(len(p.Outputs)))
+	for _, out := range p.Outputs {
+		b = AppendVarint(b, out.Amount)
+		b = append(b, byte(out.Type))
+		switch out.Type {
+		case OutputTypeToKey:
+			b = append(b, out.Key[:]...)
+		case OutputTypeToTaggedKey:
+			b = append(b, out.Key[:]...)
+			b = append(b, out.ViewTag)
+		default:
+			return nil, fmt.Errorf("%w: 0x%02x", errUnknownOutputType, byte(out.Type))
+		}
+	}
+
+	b = AppendVarint(b, uint64(len(p.Extra)))
+	return append(b, p.Extra...), nil
+}
+
+// UnmarshalBinary deserializes a transaction prefix. All of b must be used.
+func (p *TxPrefix) UnmarshalBinary(b []byte) error {
+	n, err := p.Decode(b)
+	if err != nil {
+		return err
+	}
+	if n != len(b) {
+		return errTrailingBytes
+	}
+	return nil
+}
+
+// Decode deserializes the transaction prefix at the start of b, returning the
+// number of bytes used. Any remaining bytes, like the signatures of a full
+// transaction, are left for the caller.
+func (p *TxPrefix) Decode(b []byte) (int, error) {
+	d := &decoder{b: b}
+	newPrefix := new(TxPrefix)
+	if err := newPrefix.decode(d); err != nil {
+		return 0, err
+	}
+	*p = *newPrefix
+	return len(b) - len(d.b), nil
+}
+
+func (p *TxPrefix) decode(d *decoder) error {
+	p.Version = d.readVarint()
+	if d.err == nil && p.Version != TxVersion1 && p.Version != TxVersion2 {
+		return fmt.Errorf("%w: %d", errInvalidTxVersion, p.Version)
+	}
+	p.UnlockTime = d.readVarint()
+
+	numInputs := d.readCount(minInputSize)
+	p.Inputs = make([]*TxInput, 0, numInputs)
+	for i := 0; i < numInputs && d.err == nil; i++ {
+		in := &TxInput{Type: InputType(d.readByte())}
+		switch {
+		case d.err != nil:
+		case in.Type == InputTypeGen:
+			in.Height = d.readVarint()
+		case in.Type == InputTypeToKey:
+			in.Amount = d.readVarint()
+			numOffsets := d.readCount(1)
+			if d.err == nil && numOffsets == 0 {
+				return errNoInputKeyOffsets
+			}
+			in.KeyOffsets = make([]uint64, numOffsets)
+			for j := range in.KeyOffsets {
+				in.KeyOffsets[j] = d.readVarint()
+			}
+			in.KeyImage = d.readKey()
+		default:
+			return fmt.Errorf("%w: 0x%02x", errUnknownInputType, byte(in.Type))
+		}
+		p.Inputs = append(p.Inputs, in)
+	}
+
+	numOutputs := d.readCount(minOutputSize)
+	p.Outputs = make([]*TxOutput, 0, numOutputs)
+	for i := 0; i < numOutputs && d.err == nil; i++ {
+		out := &TxOutput{Amount: d.readVarint()}
+		out.Type = OutputType(d.readByte())
+		switch {
+		case d.err != nil:
+		case out.Type == OutputTypeToKey:
+			out.Key = d.readKey()
+		case out.Type == OutputTypeToTaggedKey:
+			out.Key = d.readKey()
+			out.ViewTag = d.readByte()
+		default:
+			return fmt.Errorf("%w: 0x%02x", errUnknownOutputType, byte(out.Type))
+		}
+		p.Outputs = append(p.Outputs, out)
+	}
+
+	extraLen := d.readCount(1)
+	p.Extra = bytes.Clone(d.readBytes(extraLen))
+
+	return d.err
+}
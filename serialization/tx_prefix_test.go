@@ -0,0 +1,127 @@
+package serialization
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mainnetGenesisTx is the coinbase transaction of Monero's genesis block
+const mainnetGenesisTx = "013c01ff0001ffffffffffff03029b2e4c0281c0b02e7c53291a94d1d0cbff8883f8024f5142ee494ffbbd08807121017767aafcde9be00dcfd098715ebcf7f410daebc582fda69d24a28e9d0bc890d1" //nolint:lll
+
+func fromHex(t *testing.T, h string) []byte {
+	b, err := hex.DecodeString(h)
+	require.NoError(t, err)
+	return b
+}
+
+func key32(t *testing.T, h string) [32]byte {
+	var key [32]byte
+	require.Equal(t, 32, copy(key[:], fromHex(t, h)))
+	return key
+}
+
+func TestTxPrefix_genesis(t *testing.T) {
+	raw := fromHex(t, mainnetGenesisTx)
+
+	p := new(TxPrefix)
+	require.NoError(t, p.UnmarshalBinary(raw))
+	require.Equal(t, uint64(TxVersion1), p.Version)
+	require.Equal(t, uint64(60), p.UnlockTime)
+
+	require.Len(t, p.Inputs, 1)
+	require.Equal(t, InputTypeGen, p.Inputs[0].Type)
+	require.Zero(t, p.Inputs[0].Height)
+
+	require.Len(t, p.Outputs, 1)
+	require.Equal(t, uint64(17592186044415), p.Outputs[0].Amount)
+	require.Equal(t, OutputTypeToKey, p.Outputs[0].Type)
+	require.Equal(t, key32(t, "9b2e4c0281c0b02e7c53291a94d1d0cbff8883f8024f5142ee494ffbbd088071"), p.Outputs[0].Key)
+
+	extra, err := ParseTxExtra(p.Extra)
+	require.NoError(t, err)
+	txPubKey, ok := extra.TxPubKey()
+	require.True(t, ok)
+	require.Equal(t, key32(t, "7767aafcde9be00dcfd098715ebcf7f410daebc582fda69d24a28e9d0bc890d1"), txPubKey)
+	require.Equal(t, p.Extra, extra.Bytes())
+
+	encoded, err := p.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, raw, encoded)
+}
+
+func newTestTxPrefix() *TxPrefix {
+	extra := new(TxExtra)
+	extra.AddTxPubKey([32]byte{0xaa})
+	extra.AddEncryptedPaymentID([8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+	extra.AddAdditionalPubKeys([][32]byte{{0xbb}, {0xcc}})
+	extra.Sort()
+
+	return &TxPrefix{
+		Version: TxVersion2,
+		Inputs: []*TxInput{{
+			Type:       InputTypeToKey,
+			KeyOffsets: RelativeOffsets([]uint64{100, 250, 251, 90000}),
+			KeyImage:   [32]byte{0x11},
+		}},
+		Outputs: []*TxOutput{
+			{Type: OutputTypeToTaggedKey, Key: [32]byte{0x21}, ViewTag: 0x7e},
+			{Type: OutputTypeToTaggedKey, Key: [32]byte{0x22}, ViewTag: 0x01},
+		},
+		Extra: extra.Bytes(),
+	}
+}
+
+func TestTxPrefix_roundTrip(t *testing.T) {
+	p := newTestTxPrefix()
+	encoded, err := p.MarshalBinary()
+	require.NoError(t, err)
+
+	decoded := new(TxPrefix)
+	require.NoError(t, decoded.UnmarshalBinary(encoded))
+	require.Equal(t, p, decoded)
+
+	offsets, err := decoded.Inputs[0].AbsoluteOffsets()
+	require.NoError(t, err)
+	require.Equal(t, []uint64{100, 250, 251, 90000}, offsets)
+
+	// Decode leaves trailing data, like the signatures, for the caller
+	n, err := decoded.Decode(append(encoded, 0x01, 0x02))
+	require.NoError(t, err)
+	require.Equal(t, len(encoded), n)
+	require.ErrorIs(t, decoded.UnmarshalBinary(append(encoded, 0x01)), errTrailingBytes)
+}
+
+func TestTxPrefix_UnmarshalBinary_fail(t *testing.T) {
+	encoded, err := newTestTxPrefix().MarshalBinary()
+	require.NoError(t, err)
+
+	// every truncation fails
+	for i := 0; i < len(encoded); i++ {
+		require.Error(t, new(TxPrefix).UnmarshalBinary(encoded[:i]), i)
+	}
+
+	bad := append([]byte{}, encoded...)
+	bad[0] = 3
+	require.ErrorIs(t, new(TxPrefix).UnmarshalBinary(bad), errInvalidTxVersion)
+
+	bad = append([]byte{}, encoded...)
+	bad[3] = 0x01 // input type
+	require.ErrorIs(t, new(TxPrefix).UnmarshalBinary(bad), errUnknownInputType)
+
+	// a huge input count must not cause a huge allocation
+	require.ErrorIs(t, new(TxPrefix).UnmarshalBinary([]byte{0x02, 0x00, 0xff, 0xff, 0xff, 0xff, 0x0f}), errUnexpectedEOF)
+
+	// failed decoding does not modify the prefix
+	p := newTestTxPrefix()
+	require.Error(t, p.UnmarshalBinary(encoded[:10]))
+	require.Equal(t, newTestTxPrefix(), p)
+}
+
+func TestTxPrefix_MarshalBinary_fail(t *testing.T) {
+	p := newTestTxPrefix()
+	p.Outputs[0].Type = 0x00
+	_, err := p.MarshalBinary()
+	require.ErrorIs(t, err, errUnknownOutputType)
+}
@@ -0,0 +1,65 @@
+// Package serialization implements Monero's binary serialization of
+// transactions: varints, the transaction prefix and the tx_extra field. Keys
+// are handled as raw 32-byte values. Validating that they are curve points is
+// left to the packages that use them.
+package serialization
+
+import (
+	"errors"
+)
+
+// MaxVarintLen is the maximum length, in bytes, of a varint encoded uint64
+const MaxVarintLen = 10
+
+var (
+	errVarintOverflow     = errors.New("varint overflows 64 bits")
+	errVarintNonCanonical = errors.New("varint is not canonically encoded")
+	errUnexpectedEOF      = errors.New("unexpected end of serialized data")
+)
+
+// AppendVarint appends the varint encoding of v to b. Monero's varints are
+// little-endian base-128, using the high bit of each byte to mark that
+// another byte follows.
+func AppendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// VarintLen returns the length, in bytes, of the varint encoding of v
+func VarintLen(v uint64) int {
+	n := 1
+	for v >= 0x80 {
+		v >>= 7
+		n++
+	}
+	return n
+}
+
+// ReadVarint decodes a varint from the start of b, returning the value and
+// the number of bytes read. Like Monero's read_varint, encodings with
+// trailing zero bytes, which would give the same value multiple
+// representations, are rejected.
+func ReadVarint(b []byte) (uint64, int, error) {
+	var v uint64
+	for i := 0; i < len(b); i++ {
+		if i == MaxVarintLen {
+			return 0, 0, errVarintOverflow
+		}
+		c := b[i]
+		// the 10th byte can only hold the top bit of a uint64
+		if i == MaxVarintLen-1 && c > 1 {
+			return 0, 0, errVarintOverflow
+		}
+		if c == 0 && i > 0 {
+			return 0, 0, errVarintNonCanonical
+		}
+		v |= uint64(c&0x7f) << (7 * i)
+		if c < 0x80 {
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, errUnexpectedEOF
+}
@@ -0,0 +1,50 @@
+package serialization
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVarint_roundTrip(t *testing.T) {
+	tests := []struct {
+		value   uint64
+		encoded []byte
+	}{
+		{0, []byte{0x00}},
+		{1, []byte{0x01}},
+		{0x7f, []byte{0x7f}},
+		{0x80, []byte{0x80, 0x01}},
+		{300, []byte{0xac, 0x02}},
+		{math.MaxUint64, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+	}
+	for _, tt := range tests {
+		encoded := AppendVarint(nil, tt.value)
+		require.Equal(t, tt.encoded, encoded)
+		require.Equal(t, len(tt.encoded), VarintLen(tt.value))
+
+		// trailing data is not consumed
+		value, n, err := ReadVarint(append(encoded, 0xaa))
+		require.NoError(t, err)
+		require.Equal(t, tt.value, value)
+		require.Equal(t, len(tt.encoded), n)
+	}
+}
+
+func TestReadVarint_fail(t *testing.T) {
+	tests := []struct {
+		encoded []byte
+		err     error
+	}{
+		{nil, errUnexpectedEOF},
+		{[]byte{0x80}, errUnexpectedEOF},
+		{[]byte{0x80, 0x00}, errVarintNonCanonical},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02}, errVarintOverflow},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x81, 0x01}, errVarintOverflow},
+	}
+	for _, tt := range tests {
+		_, _, err := ReadVarint(tt.encoded)
+		require.ErrorIs(t, err, tt.err, "%x", tt.encoded)
+	}
+}
@@ -0,0 +1,539 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+	"github.com/dimalinux/gopherphis/jamtis"
+	"github.com/dimalinux/gopherphis/polyseed"
+)
+
+// A wallet file is a header followed by the XChaCha20-Poly1305 encryption of
+// the wallet's state, serialized as a JSON object. The header holds the
+// file's magic and format version, and the Argon2id parameters and salt that
+// derive the encryption key from the password, followed by the nonce. It is
+// authenticated as the AEAD's additional data.
+//
+// The JSON object has a version of its own, which changes with its members.
+// Files with an older version are migrated when they are opened.
+const (
+	fileMagic         = "GPHWALLT"
+	fileFormatVersion = 1
+	fileSaltSize      = 16
+	fileHeaderSize    = len(fileMagic) + 1 + 4 + 4 + 1 + fileSaltSize + chacha20poly1305.NonceSizeX
+
+	// maxKDFTime, maxKDFMemory and maxKDFThreads limit the time, memory, in
+	// KiB, and threads that opening an untrusted file can use before it is
+	// authenticated. They are a few times the parameters that are written.
+	maxKDFTime    = 8
+	maxKDFMemory  = 1 << 20
+	maxKDFThreads = 16
+)
+
+var (
+	errNotWalletFile   = errors.New("not a gopherphis wallet file")
+	errFileFormat      = errors.New("unsupported wallet file format version")
+	errKDFParams       = errors.New("invalid wallet file key derivation parameters")
+	errWrongPassword   = errors.New("invalid password or corrupted wallet file")
+	errFileVersion     = errors.New("invalid wallet file version")
+	errNewerFile       = errors.New("wallet file was written by a newer version of gopherphis")
+	errFileTransferTx  = errors.New("wallet file transfer has an invalid transaction")
+	errFileRecentBlock = errors.New("wallet file has an invalid block hash")
+	errFileLabels      = errors.New("wallet file has an account with no or too many subaddresses")
+)
+
+// kdfParams are the Argon2id parameters of the wallet files being written
+type kdfParams struct {
+	time    uint32
+	memory  uint32 // in KiB
+	threads uint8
+}
+
+// fileKDFParams are RFC 9106's second recommended Argon2id parameters
+var fileKDFParams = kdfParams{time: 3, memory: 64 << 10, threads: 4}
+
+// migrations upgrade the JSON object of older wallet files: migrations[i]
+// upgrades version i+1 to version i+2. Members that weren't changed are
+// passed through.
+var migrations []func(members map[string]json.RawMessage) error
+
+// currentFileVersion is the version of the JSON object written by Save
+func currentFileVersion() int {
+	return len(migrations) + 1
+}
+
+// fileData is the JSON object of a wallet file
+type fileData struct {
+	Version int                `json:"version"`
+	Network cryptonote.Network `json:"network"`
+	// Seed is the polyseed in polyseed's storage format, if the wallet was
+	// created from one
+	Seed         []byte          `json:"seed,omitempty"`
+	SpendKey     []byte          `json:"spendKey"`
+	ViewKey      []byte          `json:"viewKey"`
+	Jamtis       *jamtisKeysData `json:"jamtis"`
+	Labels       [][]string      `json:"labels"`
+	Height       uint64          `json:"height"`
+	RecentHashes [][]byte        `json:"recentHashes"`
+	Txs          []*txData       `json:"txs"`
+	Transfers    []*transferData `json:"transfers"`
+}
+
+type jamtisKeysData struct {
+	ViewBalanceKey        []byte `json:"viewBalanceKey"`
+	UnlockAmountsKey      []byte `json:"unlockAmountsKey"`
+	FindReceivedKey       []byte `json:"findReceivedKey"`
+	GenerateAddressSecret []byte `json:"generateAddressSecret"`
+	SpendKeyBase          []byte `json:"spendKeyBase"`
+	UnlockAmountsPubKey   []byte `json:"unlockAmountsPubKey"`
+	FindReceivedPubKey    []byte `json:"findReceivedPubKey"`
+}
+
+// txData is a transaction that created some of the wallet's transfers
+type txData struct {
+	ID                []byte        `json:"id"`
+	PubKey            []byte        `json:"pubKey,omitempty"`
+	AdditionalPubKeys [][]byte      `json:"additionalPubKeys,omitempty"`
+	Outputs           []*outputData `json:"outputs"`
+	UnlockTime        uint64        `json:"unlockTime"`
+	KeyImages         [][]byte      `json:"keyImages,omitempty"`
+}
+
+type outputData struct {
+	Key             []byte `json:"key"`
+	ViewTag         *byte  `json:"viewTag,omitempty"`
+	Amount          uint64 `json:"amount,omitempty"`
+	Commitment      []byte `json:"commitment,omitempty"`
+	EncryptedAmount []byte `json:"encryptedAmount,omitempty"`
+}
+
+type transferData struct {
+	// Tx is the index of the transfer's transaction in the file's txs
+	Tx           int    `json:"tx"`
+	Index        uint64 `json:"index"`
+	AccountIndex uint32 `json:"accountIndex"`
+	SubAddrIndex uint32 `json:"subAddrIndex"`
+	Amount       uint64 `json:"amount"`
+	GlobalIndex  uint64 `json:"globalIndex"`
+	KeyImage     []byte `json:"keyImage"`
+	BlockHeight  uint64 `json:"blockHeight"`
+	UnlockTime   uint64 `json:"unlockTime"`
+	Spent        bool   `json:"spent,omitempty"`
+	SpentHeight  uint64 `json:"spentHeight,omitempty"`
+}
+
+// Save encrypts the wallet with the password and writes it to path. The file
+// is replaced atomically, so a crash leaves either the old or the new file.
+func (w *Wallet) Save(path string, password string) error {
+	jamtisKeys, err := w.JamtisKeys()
+	if err != nil {
+		return err
+	}
+	w.mu.RLock()
+	data := w.fileData(jamtisKeys)
+	w.mu.RUnlock()
+
+	plaintext, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	sealed, err := sealFile(plaintext, password, fileKDFParams)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, sealed)
+}
+
+// Open reads and decrypts the wallet file at path
+func Open(path string, password string) (*Wallet, error) {
+	sealed, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := openFile(sealed, password)
+	if err != nil {
+		return nil, err
+	}
+	data, err := migrateFile(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return newFromFileData(data)
+}
+
+// ChangePassword re-encrypts the wallet file at path with a new password
+func ChangePassword(path string, oldPassword string, newPassword string) error {
+	sealed, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	plaintext, err := openFile(sealed, oldPassword)
+	if err != nil {
+		return err
+	}
+	if sealed, err = sealFile(plaintext, newPassword, fileKDFParams); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, sealed)
+}
+
+// sealFile returns the wallet file holding the encrypted plaintext
+func sealFile(plaintext []byte, password string, params kdfParams) ([]byte, error) {
+	header := make([]byte, 0, fileHeaderSize)
+	header = append(header, fileMagic...)
+	header = append(header, fileFormatVersion)
+	header = binary.LittleEndian.AppendUint32(header, params.time)
+	header = binary.LittleEndian.AppendUint32(header, params.memory)
+	header = append(header, params.threads)
+	saltPos := len(header)
+	header = header[:fileHeaderSize]
+	if _, err := rand.Read(header[saltPos:]); err != nil {
+		return nil, err
+	}
+	salt, nonce := header[saltPos:saltPos+fileSaltSize], header[saltPos+fileSaltSize:]
+
+	aead := fileAEAD(password, salt, params)
+	return aead.Seal(header, nonce, plaintext, header), nil
+}
+
+// openFile returns the decrypted content of a wallet file
+func openFile(sealed []byte, password string) ([]byte, error) {
+	if len(sealed) < fileHeaderSize || !bytes.HasPrefix(sealed, []byte(fileMagic)) {
+		return nil, errNotWalletFile
+	}
+	header := sealed[:fileHeaderSize]
+	pos := len(fileMagic)
+	if header[pos] != fileFormatVersion {
+		return nil, fmt.Errorf("%w: %d", errFileFormat, header[pos])
+	}
+	pos++
+	params := kdfParams{
+		time:    binary.LittleEndian.Uint32(header[pos:]),
+		memory:  binary.LittleEndian.Uint32(header[pos+4:]),
+		threads: header[pos+8],
+	}
+	pos += 9
+	// Argon2 needs at least 8 KiB per thread, and silently raises lower
+	// memory parameters, so they are rejected
+	if params.time == 0 || params.time > maxKDFTime ||
+		params.threads == 0 || params.threads > maxKDFThreads ||
+		params.memory < 8*uint32(params.threads) || params.memory > maxKDFMemory {
+		return nil, errKDFParams
+	}
+	salt := header[pos : pos+fileSaltSize]
+	nonce := header[pos+fileSaltSize:]
+
+	plaintext, err := fileAEAD(password, salt, params).Open(nil, nonce, sealed[fileHeaderSize:], header)
+	if err != nil {
+		return nil, errWrongPassword
+	}
+	return plaintext, nil
+}
+
+func fileAEAD(password string, salt []byte, params kdfParams) cipher.AEAD {
+	key := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, chacha20poly1305.KeySize)
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		panic(err) // the key size is fixed
+	}
+	return aead
+}
+
+// writeFileAtomic writes the file to a temporary file in the same directory,
+// which is then renamed to path
+func writeFileAtomic(path string, data []byte) error {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, name+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath) // fails once renamed
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	// sync the directory, so that the rename is durable
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// migrateFile upgrades the JSON object of a wallet file to the current
+// version and decodes it
+func migrateFile(plaintext []byte) (*fileData, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(plaintext, &members); err != nil {
+		return nil, err
+	}
+	var version int
+	if err := json.Unmarshal(members["version"], &version); err != nil || version < 1 {
+		return nil, errFileVersion
+	}
+	if version > currentFileVersion() {
+		return nil, fmt.Errorf("%w: version %d", errNewerFile, version)
+	}
+
+	if version < currentFileVersion() {
+		for ; version < currentFileVersion(); version++ {
+			if err := migrations[version-1](members); err != nil {
+				return nil, fmt.Errorf("migrating version %d: %w", version, err)
+			}
+		}
+		members["version"] = json.RawMessage(fmt.Sprint(version))
+		var err error
+		if plaintext, err = json.Marshal(members); err != nil {
+			return nil, err
+		}
+	}
+
+	data := new(fileData)
+	if err := json.Unmarshal(plaintext, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// fileData returns a copy of the wallet's state to save, which can be
+// marshaled after the lock is released. The caller must hold the lock.
+func (w *Wallet) fileData(jamtisKeys *jamtis.Keys) *fileData {
+	data := &fileData{
+		Version:  currentFileVersion(),
+		Network:  w.net,
+		SpendKey: w.keys.SpendKey().Bytes(),
+		ViewKey:  w.keys.PrivateViewKey().Bytes(),
+		Jamtis: &jamtisKeysData{
+			ViewBalanceKey:        jamtisKeys.ViewBalanceKey,
+			UnlockAmountsKey:      jamtisKeys.UnlockAmountsKey,
+			FindReceivedKey:       jamtisKeys.FindReceivedKey,
+			GenerateAddressSecret: jamtisKeys.GenerateAddressSecret,
+			SpendKeyBase:          jamtisKeys.SpendKeyBase,
+			UnlockAmountsPubKey:   jamtisKeys.UnlockAmountsPubKey,
+			FindReceivedPubKey:    jamtisKeys.FindReceivedPubKey,
+		},
+		Labels: make([][]string, len(w.labels)),
+		Height: w.height,
+	}
+	for i, labels := range w.labels {
+		data.Labels[i] = slices.Clone(labels)
+	}
+	if w.seed != nil {
+		seed := w.seed.Store()
+		data.Seed = seed[:]
+	}
+	for _, hash := range w.recentHashes {
+		data.RecentHashes = append(data.RecentHashes, bytes.Clone(hash[:]))
+	}
+
+	txIndexes := make(map[*cryptonote.TxInfo]int)
+	for _, t := range w.transfers {
+		txIndex, ok := txIndexes[t.Tx]
+		if !ok {
+			txIndex = len(data.Txs)
+			txIndexes[t.Tx] = txIndex
+			data.Txs = append(data.Txs, newTxData(t.Tx))
+		}
+		data.Transfers = append(data.Transfers, &transferData{
+			Tx:           txIndex,
+			Index:        t.Index,
+			AccountIndex: t.AccountIndex,
+			SubAddrIndex: t.SubAddrIndex,
+			Amount:       t.Amount,
+			GlobalIndex:  t.GlobalIndex,
+			KeyImage:     t.KeyImage.Bytes(),
+			BlockHeight:  t.BlockHeight,
+			UnlockTime:   t.UnlockTime,
+			Spent:        t.Spent,
+			SpentHeight:  t.SpentHeight,
+		})
+	}
+	return data
+}
+
+func newTxData(tx *cryptonote.TxInfo) *txData {
+	data := &txData{ID: bytes.Clone(tx.ID[:]), UnlockTime: tx.UnlockTime}
+	if tx.PubKey != nil {
+		data.PubKey = tx.PubKey.Bytes()
+	}
+	for _, key := range tx.AdditionalPubKeys {
+		data.AdditionalPubKeys = append(data.AdditionalPubKeys, key.Bytes())
+	}
+	for _, out := range tx.Outputs {
+		o := &outputData{
+			Key:             out.Key.Bytes(),
+			ViewTag:         out.ViewTag,
+			Amount:          out.Amount,
+			EncryptedAmount: out.EncryptedAmount,
+		}
+		if out.Commitment != nil {
+			o.Commitment = out.Commitment.Bytes()
+		}
+		data.Outputs = append(data.Outputs, o)
+	}
+	for _, ki := range tx.KeyImages {
+		data.KeyImages = append(data.KeyImages, bytes.Clone(ki[:]))
+	}
+	return data
+}
+
+func (data *txData) txInfo() (*cryptonote.TxInfo, error) {
+	if len(data.ID) != len(cryptonote.TxInfo{}.ID) {
+		return nil, errFileTransferTx
+	}
+	tx := &cryptonote.TxInfo{ID: [32]byte(data.ID), UnlockTime: data.UnlockTime}
+	var err error
+	if data.PubKey != nil {
+		if tx.PubKey, err = cryptonote.NewPublicKey(data.PubKey); err != nil {
+			return nil, err
+		}
+	}
+	for _, b := range data.AdditionalPubKeys {
+		key, err := cryptonote.NewPublicKey(b)
+		if err != nil {
+			return nil, err
+		}
+		tx.AdditionalPubKeys = append(tx.AdditionalPubKeys, key)
+	}
+	for i, o := range data.Outputs {
+		out := &cryptonote.TxOutput{
+			Index:           uint64(i),
+			ViewTag:         o.ViewTag,
+			Amount:          o.Amount,
+			EncryptedAmount: o.EncryptedAmount,
+		}
+		if out.Key, err = cryptonote.NewPublicKey(o.Key); err != nil {
+			return nil, err
+		}
+		if o.Commitment != nil {
+			if out.Commitment, err = cryptonote.NewCommitment(o.Commitment); err != nil {
+				return nil, err
+			}
+		}
+		tx.Outputs = append(tx.Outputs, out)
+	}
+	for _, ki := range data.KeyImages {
+		if len(ki) != cryptonote.KeySize {
+			return nil, errFileTransferTx
+		}
+		tx.KeyImages = append(tx.KeyImages, [cryptonote.KeySize]byte(ki))
+	}
+	return tx, nil
+}
+
+// newFromFileData returns the wallet with the state of a wallet file
+func newFromFileData(data *fileData) (*Wallet, error) {
+	keys, err := cryptonote.NewPrivateKeyPairFromBytes(data.SpendKey, data.ViewKey)
+	if err != nil {
+		return nil, err
+	}
+
+	w := New(keys, data.Network, data.Height)
+	if data.Seed != nil {
+		if w.seed, err = polyseed.LoadSeedData(data.Seed); err != nil {
+			return nil, fmt.Errorf("wallet file seed: %w", err)
+		}
+	}
+	if data.Jamtis != nil {
+		w.jamtisKeys = &jamtis.Keys{
+			ViewBalanceKey:        data.Jamtis.ViewBalanceKey,
+			UnlockAmountsKey:      data.Jamtis.UnlockAmountsKey,
+			FindReceivedKey:       data.Jamtis.FindReceivedKey,
+			GenerateAddressSecret: data.Jamtis.GenerateAddressSecret,
+			SpendKeyBase:          data.Jamtis.SpendKeyBase,
+			UnlockAmountsPubKey:   data.Jamtis.UnlockAmountsPubKey,
+			FindReceivedPubKey:    data.Jamtis.FindReceivedPubKey,
+		}
+	}
+	for _, hash := range data.RecentHashes {
+		if len(hash) != 32 {
+			return nil, errFileRecentBlock
+		}
+		w.recentHashes = append(w.recentHashes, [32]byte(hash))
+	}
+	if len(data.Labels) > 0 {
+		w.labels = nil
+	}
+	for account, labels := range data.Labels {
+		if len(labels) == 0 {
+			return nil, errFileLabels
+		}
+		if account > cryptonote.MaxSubaddressAccount || len(labels) > cryptonote.MaxSubaddressIndex+1 {
+			return nil, errFileLabels
+		}
+		w.labels = append(w.labels, labels)
+		if err := w.table.Expand(uint32(account), uint32(len(labels)-1)); err != nil {
+			return nil, err
+		}
+	}
+
+	txs := make([]*cryptonote.TxInfo, len(data.Txs))
+	for i, txData := range data.Txs {
+		if txs[i], err = txData.txInfo(); err != nil {
+			return nil, fmt.Errorf("wallet file transaction %d: %w", i, err)
+		}
+	}
+	for _, td := range data.Transfers {
+		if td.Tx < 0 || td.Tx >= len(txs) || td.Index >= uint64(len(txs[td.Tx].Outputs)) {
+			return nil, errFileTransferTx
+		}
+		tx := txs[td.Tx]
+		keyImage, err := cryptonote.NewKeyImage(td.KeyImage)
+		if err != nil {
+			return nil, err
+		}
+		t := &Transfer{
+			ReceivedOutput: cryptonote.ReceivedOutput{
+				OwnedOutput: cryptonote.OwnedOutput{
+					Tx:           tx,
+					Index:        td.Index,
+					AccountIndex: td.AccountIndex,
+					SubAddrIndex: td.SubAddrIndex,
+				},
+				Amount: td.Amount,
+			},
+			GlobalIndex: td.GlobalIndex,
+			Key:         tx.Outputs[td.Index].Key,
+			KeyImage:    keyImage,
+			BlockHeight: td.BlockHeight,
+			UnlockTime:  td.UnlockTime,
+			Spent:       td.Spent,
+			SpentHeight: td.SpentHeight,
+		}
+		w.transfers = append(w.transfers, t)
+		w.keyImages[[cryptonote.KeySize]byte(td.KeyImage)] = t
+		if err := w.ensureSubaddress(td.AccountIndex, td.SubAddrIndex); err != nil {
+			return nil, fmt.Errorf("wallet file transfer: %w", err)
+		}
+	}
+	return w, nil
+}
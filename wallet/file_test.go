@@ -0,0 +1,254 @@
+package wallet
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+	"github.com/dimalinux/gopherphis/polyseed"
+)
+
+// the polyseed package's test vector
+const testPolyseed = "filter vocal snow cupboard volume avoid sign slot drum replace shrug resist pear kiwi bag bring"
+
+// setTestKDFParams makes the wallet files of the test fast to open
+func setTestKDFParams(t *testing.T) {
+	params := fileKDFParams
+	fileKDFParams = kdfParams{time: 1, memory: 64, threads: 1}
+	t.Cleanup(func() { fileKDFParams = params })
+}
+
+func TestWallet_Save(t *testing.T) {
+	setTestKDFParams(t)
+	w, c, server := newTestWallet(t)
+	ctx := context.Background()
+	require.NoError(t, w.Refresh(ctx, c))
+	sendTestTransfer(t, w, c, server, w.Address(1, 3), cryptonote.XMR/10)
+	require.NoError(t, w.Refresh(ctx, c))
+	_, err := w.CreateSubaddress(0, "savings")
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "wallet")
+	require.NoError(t, w.Save(path, "password"))
+	opened, err := Open(path, "password")
+	require.NoError(t, err)
+
+	require.Equal(t, w.Keys().SpendKey().Hex(), opened.Keys().SpendKey().Hex())
+	require.Equal(t, w.Keys().PrivateViewKey().Hex(), opened.Keys().PrivateViewKey().Hex())
+	require.Equal(t, cryptonote.Mainnet, opened.Network())
+	require.Equal(t, w.Height(), opened.Height())
+	require.Equal(t, w.labels, opened.labels)
+	require.Equal(t, w.recentHashes, opened.recentHashes)
+	require.Nil(t, opened.Seed())
+	label, err := opened.SubaddressLabel(0, 1)
+	require.NoError(t, err)
+	require.Equal(t, "savings", label)
+
+	transfers, openedTransfers := w.Transfers(), opened.Transfers()
+	require.Len(t, openedTransfers, 3)
+	for i, t1 := range transfers {
+		t2 := openedTransfers[i]
+		require.Equal(t, t1.Tx.ID, t2.Tx.ID)
+		require.Equal(t, newTxData(t1.Tx), newTxData(t2.Tx))
+		require.Equal(t, t1.OwnedOutput.Index, t2.OwnedOutput.Index)
+		require.Equal(t, t1.AccountIndex, t2.AccountIndex)
+		require.Equal(t, t1.SubAddrIndex, t2.SubAddrIndex)
+		require.Equal(t, t1.Amount, t2.Amount)
+		require.Equal(t, t1.GlobalIndex, t2.GlobalIndex)
+		require.Equal(t, t1.Key.Hex(), t2.Key.Hex())
+		require.True(t, t1.KeyImage.Equal(t2.KeyImage))
+		require.Equal(t, t1.BlockHeight, t2.BlockHeight)
+		require.Equal(t, t1.UnlockTime, t2.UnlockTime)
+		require.Equal(t, t1.Spent, t2.Spent)
+		require.Equal(t, t1.SpentHeight, t2.SpentHeight)
+	}
+	// the outputs of a transaction share it
+	require.Same(t, openedTransfers[1].Tx, openedTransfers[2].Tx)
+
+	// the opened wallet carries on refreshing
+	require.NoError(t, server.AddBlocks(spendableAge))
+	require.NoError(t, opened.Refresh(ctx, c))
+	require.Equal(t, server.Height(), opened.Height())
+	balance, err := opened.AccountBalance(1)
+	require.NoError(t, err)
+	require.Equal(t, &Balance{Total: cryptonote.XMR / 10, Unlocked: cryptonote.XMR / 10, NumUnspent: 1}, balance)
+
+	// the file is replaced without leaving temporary files
+	require.NoError(t, opened.Save(path, "password"))
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestWallet_Save_polyseed(t *testing.T) {
+	setTestKDFParams(t)
+	seed, err := polyseed.CreateSeedData(strings.Fields(testPolyseed))
+	require.NoError(t, err)
+	w, err := NewFromPolyseed(seed, "", cryptonote.Stagenet, 100)
+	require.NoError(t, err)
+	jamtisKeys, err := w.JamtisKeys()
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "wallet")
+	require.NoError(t, w.Save(path, ""))
+	opened, err := Open(path, "")
+	require.NoError(t, err)
+	require.Equal(t, seed.Store(), opened.Seed().Store())
+	require.Equal(t, w.Keys().SpendKey().Hex(), opened.Keys().SpendKey().Hex())
+	require.Equal(t, cryptonote.Stagenet, opened.Network())
+	require.EqualValues(t, 100, opened.Height())
+	openedJamtisKeys, err := opened.JamtisKeys()
+	require.NoError(t, err)
+	require.Equal(t, jamtisKeys, openedJamtisKeys)
+}
+
+func TestWallet_Save_concurrent(t *testing.T) {
+	setTestKDFParams(t)
+	keys, err := cryptonote.GenerateKeys()
+	require.NoError(t, err)
+	w := New(keys, cryptonote.Mainnet, 0)
+	path := filepath.Join(t.TempDir(), "wallet")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			_, err := w.CreateSubaddress(0, "label")
+			require.NoError(t, err)
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, w.Save(path, "password"))
+	}
+	<-done
+
+	require.NoError(t, w.Save(path, "password"))
+	opened, err := Open(path, "password")
+	require.NoError(t, err)
+	numSubaddresses, err := opened.NumSubaddresses(0)
+	require.NoError(t, err)
+	require.Equal(t, uint32(21), numSubaddresses)
+}
+
+func TestChangePassword(t *testing.T) {
+	setTestKDFParams(t)
+	keys, err := cryptonote.GenerateKeys()
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "wallet")
+	require.NoError(t, New(keys, cryptonote.Mainnet, 0).Save(path, "password"))
+
+	_, err = Open(path, "passw0rd")
+	require.ErrorIs(t, err, errWrongPassword)
+	require.ErrorIs(t, ChangePassword(path, "passw0rd", "new password"), errWrongPassword)
+
+	require.NoError(t, ChangePassword(path, "password", "new password"))
+	_, err = Open(path, "password")
+	require.ErrorIs(t, err, errWrongPassword)
+	opened, err := Open(path, "new password")
+	require.NoError(t, err)
+	require.Equal(t, keys.SpendKey().Hex(), opened.Keys().SpendKey().Hex())
+}
+
+func TestOpen_invalidFile(t *testing.T) {
+	setTestKDFParams(t)
+	keys, err := cryptonote.GenerateKeys()
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "wallet")
+	require.NoError(t, New(keys, cryptonote.Mainnet, 0).Save(path, "password"))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	writeAndOpen := func(data []byte) error {
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+		_, err := Open(path, "password")
+		return err
+	}
+
+	// the header is authenticated along with the content
+	for _, pos := range []int{len(fileMagic) + 10, fileHeaderSize - 1, len(data) - 1} {
+		tampered := append([]byte(nil), data...)
+		tampered[pos] ^= 1
+		require.ErrorIs(t, writeAndOpen(tampered), errWrongPassword)
+	}
+
+	// the KDF parameters are bounded
+	for _, params := range []kdfParams{
+		{time: maxKDFTime + 1, memory: 64, threads: 1},
+		{time: 1, memory: maxKDFMemory + 1, threads: 1},
+		{time: 1, memory: 64, threads: maxKDFThreads + 1},
+		{time: 1, memory: 8*4 - 1, threads: 4},
+		{time: 0, memory: 64, threads: 1},
+		{time: 1, memory: 64, threads: 0},
+	} {
+		tampered := append([]byte(nil), data...)
+		binary.LittleEndian.PutUint32(tampered[len(fileMagic)+1:], params.time)
+		binary.LittleEndian.PutUint32(tampered[len(fileMagic)+5:], params.memory)
+		tampered[len(fileMagic)+9] = params.threads
+		require.ErrorIs(t, writeAndOpen(tampered), errKDFParams)
+	}
+
+	tampered := append([]byte(nil), data...)
+	tampered[len(fileMagic)] = fileFormatVersion + 1
+	require.ErrorIs(t, writeAndOpen(tampered), errFileFormat)
+	require.ErrorIs(t, writeAndOpen(data[:fileHeaderSize-1]), errNotWalletFile)
+	require.ErrorIs(t, writeAndOpen([]byte(strings.Repeat("x", 100))), errNotWalletFile)
+}
+
+// testdata/wallet-v1 is a version 1 wallet file, written with the test KDF
+// parameters and the password "password", of a stagenet wallet restored from
+// testPolyseed at height 123
+const testFileV1 = "testdata/wallet-v1"
+
+func TestOpen_versionOne(t *testing.T) {
+	keys, err := KeysFromSeed(strings.Fields(testPolyseed), "")
+	require.NoError(t, err)
+	opened, err := Open(testFileV1, "password")
+	require.NoError(t, err)
+	require.Equal(t, keys.SpendKey().Hex(), opened.Keys().SpendKey().Hex())
+	require.Equal(t, cryptonote.Stagenet, opened.Network())
+	require.EqualValues(t, 123, opened.Height())
+}
+
+func TestOpen_migrations(t *testing.T) {
+	setTestKDFParams(t)
+	keys, err := KeysFromSeed(strings.Fields(testPolyseed), "")
+	require.NoError(t, err)
+	data, err := os.ReadFile(testFileV1)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "wallet")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	// version 2 renames the height, and version 3 renames it back
+	saved := migrations
+	t.Cleanup(func() { migrations = saved })
+	migrations = append(saved[:len(saved):len(saved)],
+		func(members map[string]json.RawMessage) error {
+			members["scannedHeight"] = members["height"]
+			delete(members, "height")
+			return nil
+		},
+		func(members map[string]json.RawMessage) error {
+			members["height"] = members["scannedHeight"]
+			delete(members, "scannedHeight")
+			return nil
+		},
+	)
+	require.Equal(t, 3, currentFileVersion())
+	opened, err := Open(path, "password")
+	require.NoError(t, err)
+	require.Equal(t, keys.SpendKey().Hex(), opened.Keys().SpendKey().Hex())
+	require.EqualValues(t, 123, opened.Height())
+
+	// files written by a newer version can't be opened
+	require.NoError(t, opened.Save(path, "password"))
+	migrations = saved
+	_, err = Open(path, "password")
+	require.ErrorIs(t, err, errNewerFile)
+}
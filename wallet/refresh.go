@@ -0,0 +1,233 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+	"github.com/dimalinux/gopherphis/daemon"
+	"github.com/dimalinux/gopherphis/serialization"
+)
+
+var (
+	errReorgTooDeep   = errors.New("chain reorganization is deeper than the wallet's block history")
+	errBlocksStart    = errors.New("daemon returned blocks from the wrong height")
+	errOutputIndices  = errors.New("daemon returned the wrong number of output indices")
+	errTxHashMismatch = errors.New("block transaction does not match the block's hashes")
+)
+
+// Refresh scans the blocks added to the daemon's chain since the last
+// refresh, to find the outputs received and spent by the wallet. If the chain
+// was reorganized, the wallet first rolls back the blocks that are no longer
+// in it.
+func (w *walletState) Refresh(ctx context.Context, client *daemon.Client) error {
+	w.refreshMu.Lock()
+	defer w.refreshMu.Unlock()
+
+	for {
+		info, err := client.GetInfo(ctx)
+		if err != nil {
+			return err
+		}
+
+		w.mu.Lock()
+		w.daemonHeight = info.Height
+		height := w.height
+		if height >= info.Height {
+			// the wallet is synced if it has the daemon's top block
+			if height == info.Height && (len(w.recentHashes) == 0 || w.topHash() == [32]byte(info.TopBlockHash)) {
+				w.mu.Unlock()
+				return nil
+			}
+			err = w.popBlock()
+			w.mu.Unlock()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		w.mu.Unlock()
+
+		blocks, err := fetchBlocks(ctx, client, height)
+		if err != nil {
+			return err
+		}
+		if blocks.StartHeight != height {
+			return fmt.Errorf("%w: %d instead of %d", errBlocksStart, blocks.StartHeight, height)
+		}
+		for _, entry := range blocks.Blocks {
+			if err := w.addBlock(entry); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// fetchBlocks gets the blocks starting at height. The daemon needs the hash
+// of the genesis block to return blocks from height zero.
+func fetchBlocks(ctx context.Context, client *daemon.Client, height uint64) (*daemon.Blocks, error) {
+	if height > 0 {
+		return client.GetBlocks(ctx, height, nil, true)
+	}
+	genesis, err := client.GetBlock(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetBlocks(ctx, 0, []daemon.Hash{genesis.Header.Hash}, true)
+}
+
+// topHash returns the hash of the last scanned block. The caller must hold
+// the lock, and recentHashes must not be empty.
+func (w *walletState) topHash() [32]byte {
+	return w.recentHashes[len(w.recentHashes)-1]
+}
+
+// addBlock scans the next block. If the block does not follow the last
+// scanned block, the chain was reorganized, and the last scanned block is
+// rolled back instead.
+func (w *walletState) addBlock(entry *daemon.BlockEntry) error {
+	block, err := entry.Decode()
+	if err != nil {
+		return err
+	}
+	hash, err := block.Hash()
+	if err != nil {
+		return err
+	}
+	txs, err := blockTxs(entry, block)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.recentHashes) > 0 && w.topHash() != block.Header.PrevID {
+		return w.popBlock()
+	}
+	if err := w.scanBlock(txs, entry.OutputIndices); err != nil {
+		return fmt.Errorf("block %d: %w", w.height, err)
+	}
+
+	w.height++
+	w.recentHashes = append(w.recentHashes, hash)
+	if len(w.recentHashes) > maxReorgDepth {
+		w.recentHashes = w.recentHashes[1:]
+	}
+	return nil
+}
+
+// blockTxs returns the decoded transactions of a block, starting with the
+// miner transaction
+func blockTxs(entry *daemon.BlockEntry, block *serialization.Block) ([]*cryptonote.TxInfo, error) {
+	if len(entry.Txs) != len(block.TxHashes) {
+		return nil, errTxHashMismatch
+	}
+	txs := make([]*cryptonote.TxInfo, 0, len(entry.Txs)+1)
+	minerTx, err := cryptonote.NewTxInfo(block.MinerTx)
+	if err != nil {
+		return nil, err
+	}
+	txs = append(txs, minerTx)
+
+	for i, txBlob := range entry.Txs {
+		tx, err := txBlob.Decode()
+		if err != nil {
+			return nil, err
+		}
+		info, err := cryptonote.NewTxInfo(tx)
+		if err != nil {
+			return nil, err
+		}
+		if info.ID != block.TxHashes[i] {
+			return nil, errTxHashMismatch
+		}
+		txs = append(txs, info)
+	}
+	return txs, nil
+}
+
+// scanBlock adds the outputs of the transactions received by the wallet, and
+// marks the wallet's outputs spent by them. The caller must hold the lock.
+func (w *walletState) scanBlock(txs []*cryptonote.TxInfo, outputIndices [][]uint64) error {
+	if len(outputIndices) != len(txs) {
+		return errOutputIndices
+	}
+
+	// the block's transfers are only added once all are found, so that an
+	// error leaves the wallet unchanged
+	var received []*Transfer
+	for i, tx := range txs {
+		for _, out := range w.table.ScanTx(tx) {
+			if out.Index >= uint64(len(outputIndices[i])) {
+				return errOutputIndices
+			}
+			t := &Transfer{
+				ReceivedOutput: *out,
+				GlobalIndex:    outputIndices[i][out.Index],
+				Key:            tx.Outputs[out.Index].Key,
+				BlockHeight:    w.height,
+				UnlockTime:     tx.UnlockTime,
+			}
+			if w.keyImage != nil {
+				keyImage, err := w.keyImage(&out.OwnedOutput)
+				if err != nil {
+					return err
+				}
+				t.KeyImage = keyImage
+			}
+			received = append(received, t)
+		}
+	}
+
+	for _, t := range received {
+		// an output with the key image of a known output, which can only
+		// happen if the sender reused the one-time key, can't be spent
+		if t.KeyImage != nil {
+			ki := [cryptonote.KeySize]byte(t.KeyImage.Bytes())
+			if _, ok := w.keyImages[ki]; ok {
+				continue
+			}
+			w.keyImages[ki] = t
+		}
+		w.transfers = append(w.transfers, t)
+		if err := w.ensureSubaddress(t.AccountIndex, t.SubAddrIndex); err != nil {
+			return err
+		}
+	}
+	for _, tx := range txs {
+		for _, ki := range tx.KeyImages {
+			if t, ok := w.keyImages[ki]; ok && !t.Spent {
+				t.Spent = true
+				t.SpentHeight = w.height
+			}
+		}
+	}
+	return nil
+}
+
+// popBlock rolls back the last scanned block. The caller must hold the lock.
+func (w *walletState) popBlock() error {
+	if len(w.recentHashes) == 0 {
+		return errReorgTooDeep
+	}
+	w.height--
+	w.recentHashes = w.recentHashes[:len(w.recentHashes)-1]
+
+	kept := w.transfers[:0]
+	for _, t := range w.transfers {
+		if t.BlockHeight >= w.height {
+			if t.KeyImage != nil {
+				delete(w.keyImages, [cryptonote.KeySize]byte(t.KeyImage.Bytes()))
+			}
+			continue
+		}
+		if t.Spent && t.SpentHeight >= w.height {
+			t.Spent = false
+			t.SpentHeight = 0
+		}
+		kept = append(kept, t)
+	}
+	w.transfers = kept
+	return nil
+}
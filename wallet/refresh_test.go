@@ -0,0 +1,167 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+	"github.com/dimalinux/gopherphis/daemon"
+	"github.com/dimalinux/gopherphis/daemon/fakedaemon"
+)
+
+const minerReward = 600_000_000_000
+
+// newTestWallet returns a wallet with new keys and a fake daemon whose chain
+// has a block mined to the wallet at height 201, followed by enough blocks to
+// unlock its reward and to pick decoys from
+func newTestWallet(t *testing.T) (*Wallet, *daemon.Client, *fakedaemon.Server) {
+	server := fakedaemon.New()
+	t.Cleanup(server.Close)
+	c, err := daemon.NewClient(server.URL)
+	require.NoError(t, err)
+
+	keys, err := cryptonote.GenerateKeys()
+	require.NoError(t, err)
+	require.NoError(t, server.AddBlocks(200))
+	server.SetMiner(keys.PublicKeyPair())
+	_, err = server.AddBlock()
+	require.NoError(t, err)
+	server.SetMiner(nil)
+	require.NoError(t, server.AddBlocks(100))
+
+	return New(keys, cryptonote.Mainnet, 0), c, server
+}
+
+// sendTestTransfer sends amount from the wallet's first transfer to address
+// and mines it
+func sendTestTransfer(
+	t *testing.T,
+	w *Wallet,
+	c *daemon.Client,
+	server *fakedaemon.Server,
+	address *cryptonote.Address,
+	amount cryptonote.Amount,
+) *cryptonote.SignedTx {
+	ctx := context.Background()
+	dist, err := c.GetOutputDistribution(ctx, 0, 0)
+	require.NoError(t, err)
+	decoys, err := cryptonote.NewGammaDecoyProvider(dist.Distribution, c, nil)
+	require.NoError(t, err)
+	fees, err := c.GetFeeEstimate(ctx)
+	require.NoError(t, err)
+
+	source := w.Transfers()[0]
+	tx, err := w.Keys().BuildTransaction(ctx, &cryptonote.TxParams{
+		Sources:             []*cryptonote.TxSource{{Output: &source.OwnedOutput, GlobalIndex: source.GlobalIndex}},
+		Destinations:        []*cryptonote.TxDestination{{Address: address, Amount: amount}},
+		FeePerByte:          fees.FeePerByte,
+		FeeQuantizationMask: fees.QuantizationMask,
+		RingSize:            cryptonote.DefaultRingSize,
+		Decoys:              decoys,
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.SendRawTransaction(ctx, tx.Blob, false))
+	_, err = server.MinePool()
+	require.NoError(t, err)
+	return tx
+}
+
+func TestWallet_Refresh(t *testing.T) {
+	w, c, server := newTestWallet(t)
+	ctx := context.Background()
+
+	require.NoError(t, w.Refresh(ctx, c))
+	require.Equal(t, server.Height(), w.Height())
+	transfers := w.Transfers()
+	require.Len(t, transfers, 1)
+	mined := transfers[0]
+	require.Equal(t, uint64(minerReward), mined.Amount)
+	require.Equal(t, uint64(201), mined.BlockHeight)
+	require.Equal(t, uint64(201), mined.GlobalIndex)
+	require.Equal(t, uint64(261), mined.UnlockTime)
+	require.False(t, mined.Spent)
+	require.True(t, w.IsUnlocked(mined))
+
+	balance, err := w.AccountBalance(0)
+	require.NoError(t, err)
+	require.Equal(t, &Balance{Total: minerReward, Unlocked: minerReward, NumUnspent: 1}, balance)
+
+	// refreshing again without new blocks changes nothing
+	require.NoError(t, w.Refresh(ctx, c))
+	require.Equal(t, transfers, w.Transfers())
+
+	// send to a subaddress of an account that wasn't created
+	tx := sendTestTransfer(t, w, c, server, w.Address(1, 3), cryptonote.XMR/10)
+	require.NoError(t, w.Refresh(ctx, c))
+	transfers = w.Transfers()
+	require.Len(t, transfers, 3)
+	require.True(t, transfers[0].Spent)
+	require.Equal(t, server.Height()-1, transfers[0].SpentHeight)
+	for _, transfer := range transfers[1:] {
+		require.Equal(t, tx.ID, transfer.Tx.ID)
+		require.False(t, w.IsUnlocked(transfer))
+	}
+
+	// the subaddresses up to the one that received the output are created
+	require.Equal(t, uint32(2), w.NumAccounts())
+	numSubaddresses, err := w.NumSubaddresses(1)
+	require.NoError(t, err)
+	require.Equal(t, uint32(4), numSubaddresses)
+	require.True(t, w.SubaddressUsed(1, 3))
+	require.False(t, w.SubaddressUsed(1, 2))
+
+	balances, err := w.SubaddressBalances(1)
+	require.NoError(t, err)
+	require.Len(t, balances, 4)
+	require.Equal(t, &Balance{Total: cryptonote.XMR / 10, NumUnspent: 1, BlocksToUnlock: spendableAge - 1}, balances[3])
+	balance, err = w.AccountBalance(0)
+	require.NoError(t, err)
+	require.Equal(t, &Balance{Total: tx.Change, NumUnspent: 1, BlocksToUnlock: spendableAge - 1}, balance)
+
+	require.NoError(t, server.AddBlocks(spendableAge-1))
+	require.NoError(t, w.Refresh(ctx, c))
+	balance, err = w.AccountBalance(1)
+	require.NoError(t, err)
+	require.Equal(t, &Balance{Total: cryptonote.XMR / 10, Unlocked: cryptonote.XMR / 10, NumUnspent: 1}, balance)
+}
+
+func TestWallet_Refresh_reorg(t *testing.T) {
+	w, c, server := newTestWallet(t)
+	ctx := context.Background()
+	require.NoError(t, w.Refresh(ctx, c))
+	sendTestTransfer(t, w, c, server, w.Address(0, 1), cryptonote.XMR/10)
+	require.NoError(t, w.Refresh(ctx, c))
+	require.Len(t, w.Transfers(), 3)
+
+	// replace the block with the transfer by two blocks without it
+	server.PopBlocks(1)
+	require.NoError(t, server.AddBlocks(2))
+	require.NoError(t, w.Refresh(ctx, c))
+	require.Equal(t, server.Height(), w.Height())
+	transfers := w.Transfers()
+	require.Len(t, transfers, 1)
+	require.False(t, transfers[0].Spent)
+	require.Zero(t, transfers[0].SpentHeight)
+
+	// the transfer went back to the pool
+	_, err := server.MinePool()
+	require.NoError(t, err)
+	require.NoError(t, w.Refresh(ctx, c))
+	require.Len(t, w.Transfers(), 3)
+
+	// a chain that is shorter than the wallet's is also a reorganization
+	server.PopBlocks(3)
+	require.NoError(t, w.Refresh(ctx, c))
+	require.Equal(t, server.Height(), w.Height())
+	require.Len(t, w.Transfers(), 1)
+}
+
+func TestWallet_Refresh_restoreHeight(t *testing.T) {
+	w, c, server := newTestWallet(t)
+	w = New(w.Keys(), cryptonote.Mainnet, 202)
+	require.NoError(t, w.Refresh(context.Background(), c))
+	require.Equal(t, server.Height(), w.Height())
+	require.Empty(t, w.Transfers())
+}
@@ -0,0 +1,76 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+	"github.com/dimalinux/gopherphis/mcrypto"
+	"github.com/dimalinux/gopherphis/mnemonic"
+	"github.com/dimalinux/gopherphis/polyseed"
+)
+
+var errSeedEncrypted = errors.New("polyseed is encrypted, but no password was passed")
+
+// KeysFromSeed returns the keys of a polyseed mnemonic or a legacy 25 or 13
+// word mnemonic, in any of their languages. The password is the seed offset
+// of a legacy mnemonic, or the passphrase of an encrypted polyseed. It is
+// ignored if empty, except that encrypted polyseeds require it.
+func KeysFromSeed(words []string, password string) (*cryptonote.PrivateKeyPair, error) {
+	if len(words) == polyseed.NumSeedWords {
+		seed, err := polyseed.CreateSeedData(words)
+		if err != nil {
+			return nil, err
+		}
+		defer seed.Clear()
+		return keysFromPolyseed(seed, password)
+	}
+
+	key, err := mnemonic.CreateKeyFromSeedsAndPassword(words, password)
+	if err != nil {
+		return nil, err
+	}
+	spendKey, err := cryptonote.NewPrivateSpendKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return spendKey.AsPrivateKeyPair()
+}
+
+// keysFromPolyseed returns the keys of the seed, which is decrypted with the
+// password if it's encrypted
+func keysFromPolyseed(seed *polyseed.SeedData, password string) (*cryptonote.PrivateKeyPair, error) {
+	decrypted := *seed
+	defer decrypted.Clear()
+	if decrypted.IsEncrypted() {
+		if password == "" {
+			return nil, errSeedEncrypted
+		}
+		decrypted.Crypt(password)
+	}
+
+	// like polyseed's keygen in monero, the key is reduced to a scalar
+	spendKey, err := cryptonote.NewPrivateSpendKey(mcrypto.ScReduce32(decrypted.KeyGen()))
+	if err != nil {
+		return nil, err
+	}
+	return spendKey.AsPrivateKeyPair()
+}
+
+// NewFromPolyseed returns a wallet with the keys of the seed, which is
+// decrypted with the password if it's encrypted, like New. The wallet keeps
+// the seed, as it was passed, to save it in the wallet file.
+func NewFromPolyseed(
+	seed *polyseed.SeedData,
+	password string,
+	net cryptonote.Network,
+	restoreHeight uint64,
+) (*Wallet, error) {
+	keys, err := keysFromPolyseed(seed, password)
+	if err != nil {
+		return nil, err
+	}
+	w := New(keys, net, restoreHeight)
+	stored := *seed
+	w.seed = &stored
+	return w, nil
+}
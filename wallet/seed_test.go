@@ -0,0 +1,41 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/mcrypto"
+)
+
+func TestKeysFromSeed_polyseed(t *testing.T) {
+	// the polyseed package's test vector, whose keys are reduced to scalars
+	words := strings.Fields(
+		"filter vocal snow cupboard volume avoid sign slot drum replace shrug resist pear kiwi bag bring")
+	key, err := hex.DecodeString("3b02f326adfd6f20e8599ead565390685add9551dd4c421743dbd5f40028ee28")
+	require.NoError(t, err)
+
+	keys, err := KeysFromSeed(words, "")
+	require.NoError(t, err)
+	require.Equal(t, hex.EncodeToString(mcrypto.ScReduce32(key)), keys.SpendKey().Hex())
+
+	// an unencrypted polyseed ignores the password
+	withPassword, err := KeysFromSeed(words, "password")
+	require.NoError(t, err)
+	require.Equal(t, keys.SpendKey().Hex(), withPassword.SpendKey().Hex())
+
+	words[0] = "bring"
+	_, err = KeysFromSeed(words, "")
+	require.Error(t, err)
+}
+
+func TestKeysFromSeed_legacy(t *testing.T) {
+	keys, err := KeysFromSeed(strings.Fields("abbey "+strings.Repeat("abbey ", 23)+"abbey"), "")
+	require.NoError(t, err)
+	require.Equal(t, strings.Repeat("00", 32), keys.SpendKey().Hex())
+
+	_, err = KeysFromSeed([]string{"abbey"}, "")
+	require.Error(t, err)
+}
@@ -0,0 +1,41 @@
+package wallet
+
+import (
+	"github.com/dimalinux/gopherphis/cryptonote"
+)
+
+// ViewOnlyWallet is a wallet with the private view key and the public spend
+// key, which finds the outputs received by its subaddresses. Without the
+// private spend key, it can't spend them or compute their key images, so it
+// can't tell when they are spent: its balances are incoming balances, the
+// total received. It is safe for concurrent use.
+type ViewOnlyWallet struct {
+	walletState
+}
+
+// NewViewOnly returns a view-only wallet with the passed keys, which starts
+// scanning the chain at restoreHeight, like New.
+func NewViewOnly(keys *cryptonote.ViewKeyPair, net cryptonote.Network, restoreHeight uint64) *ViewOnlyWallet {
+	w := new(ViewOnlyWallet)
+	w.init(keys, net, restoreHeight)
+	return w
+}
+
+// NewViewOnlyFromAddress returns the view-only wallet of the primary address,
+// whose network is the wallet's, and its private view key
+func NewViewOnlyFromAddress(
+	addr *cryptonote.Address,
+	viewKey *cryptonote.PrivateViewKey,
+	restoreHeight uint64,
+) (*ViewOnlyWallet, error) {
+	keys, err := cryptonote.NewViewKeyPairFromAddress(addr, viewKey)
+	if err != nil {
+		return nil, err
+	}
+	return NewViewOnly(keys, addr.Network(), restoreHeight), nil
+}
+
+// Keys returns the wallet's keys
+func (w *ViewOnlyWallet) Keys() *cryptonote.ViewKeyPair {
+	return w.viewKeys
+}
@@ -0,0 +1,75 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+)
+
+func TestViewOnlyWallet_Refresh(t *testing.T) {
+	w, c, server := newTestWallet(t)
+	ctx := context.Background()
+	vw, err := NewViewOnlyFromAddress(w.Address(0, 0), w.Keys().PrivateViewKey(), 0)
+	require.NoError(t, err)
+	require.Equal(t, cryptonote.Mainnet, vw.Network())
+	require.True(t, w.Address(2, 5).Equal(vw.Address(2, 5)))
+
+	require.NoError(t, w.Refresh(ctx, c))
+	tx := sendTestTransfer(t, w, c, server, w.Address(1, 3), cryptonote.XMR/10)
+	require.NoError(t, w.Refresh(ctx, c))
+	require.NoError(t, vw.Refresh(ctx, c))
+	require.Equal(t, server.Height(), vw.Height())
+
+	// the view-only wallet finds the same outputs, without key images
+	transfers, viewTransfers := w.Transfers(), vw.Transfers()
+	require.Len(t, viewTransfers, len(transfers))
+	for i, transfer := range viewTransfers {
+		require.Equal(t, transfers[i].Tx.ID, transfer.Tx.ID)
+		require.Equal(t, transfers[i].OwnedOutput.Index, transfer.OwnedOutput.Index)
+		require.Equal(t, transfers[i].AccountIndex, transfer.AccountIndex)
+		require.Equal(t, transfers[i].SubAddrIndex, transfer.SubAddrIndex)
+		require.Equal(t, transfers[i].Amount, transfer.Amount)
+		require.Equal(t, transfers[i].GlobalIndex, transfer.GlobalIndex)
+		require.Nil(t, transfer.KeyImage)
+		require.False(t, transfer.Spent)
+	}
+	numSubaddresses, err := vw.NumSubaddresses(1)
+	require.NoError(t, err)
+	require.Equal(t, uint32(4), numSubaddresses)
+
+	// the spent output still counts in the incoming balance
+	balance, err := vw.AccountBalance(0)
+	require.NoError(t, err)
+	require.Equal(t, minerReward+tx.Change, balance.Total)
+	require.Equal(t, cryptonote.Amount(minerReward), balance.Unlocked)
+	balance, err = vw.AccountBalance(1)
+	require.NoError(t, err)
+	require.Equal(t, &Balance{Total: cryptonote.XMR / 10, NumUnspent: 1, BlocksToUnlock: spendableAge - 1}, balance)
+
+	// the chain is reorganized, dropping the transfer
+	server.PopBlocks(1)
+	require.NoError(t, server.AddBlocks(2))
+	require.NoError(t, vw.Refresh(ctx, c))
+	require.Len(t, vw.Transfers(), 1)
+}
+
+func TestNewViewOnlyFromAddress(t *testing.T) {
+	keys, err := cryptonote.GenerateKeys()
+	require.NoError(t, err)
+	other, err := cryptonote.GenerateKeys()
+	require.NoError(t, err)
+
+	addr := keys.PublicKeyPair().Address(cryptonote.Stagenet)
+	_, err = NewViewOnlyFromAddress(addr, other.PrivateViewKey(), 0)
+	require.Error(t, err)
+
+	vw, err := NewViewOnlyFromAddress(addr, keys.PrivateViewKey(), 100)
+	require.NoError(t, err)
+	require.Equal(t, cryptonote.Stagenet, vw.Network())
+	require.EqualValues(t, 100, vw.Height())
+	require.True(t, keys.PublicKeyPair().SpendKey().Equal(vw.Keys().SpendKey()))
+	require.True(t, addr.Equal(vw.Address(0, 0)))
+}
@@ -0,0 +1,365 @@
+// Package wallet is a Monero wallet that tracks the outputs received and spent
+// by its keys, by scanning the chain with a daemon, and manages the
+// subaddresses given out to receive funds.
+package wallet
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+	"github.com/dimalinux/gopherphis/jamtis"
+	"github.com/dimalinux/gopherphis/polyseed"
+)
+
+const (
+	// spendableAge is the number of blocks before a received output can be
+	// spent
+	spendableAge = 10
+	// maxBlockNumber is the unlock time below which unlock times are block
+	// heights, and above which they are unix timestamps
+	maxBlockNumber = 500_000_000
+	// blockTime is Monero's target block time in seconds
+	blockTime = 120
+	// maxReorgDepth is the number of recent block hashes kept to detect
+	// reorganizations of the chain
+	maxReorgDepth = 100
+
+	// PrimaryAccountLabel is the label of the primary address, like wallet2
+	PrimaryAccountLabel = "Primary account"
+)
+
+var (
+	errAccountIndex    = errors.New("account index is out of bounds")
+	errSubaddressIndex = errors.New("subaddress index is out of bounds")
+)
+
+// Transfer is an output received by the wallet
+type Transfer struct {
+	cryptonote.ReceivedOutput
+	// GlobalIndex is the index of the output among all RingCT outputs, which
+	// is used to reference it in rings
+	GlobalIndex uint64
+	// Key is the output's one-time public key
+	Key *cryptonote.PublicKey
+	// KeyImage is nil in view-only wallets, which can't compute it
+	KeyImage    *cryptonote.KeyImage
+	BlockHeight uint64
+	// UnlockTime is the unlock time of the transaction that created the
+	// output, which is either a block height or a unix timestamp
+	UnlockTime uint64
+	Spent      bool
+	// SpentHeight is the height of the block with the spending transaction
+	SpentHeight uint64
+}
+
+// Balance is the balance of a subaddress or account
+type Balance struct {
+	// Total is the sum of the unspent outputs, including the locked ones
+	Total    cryptonote.Amount
+	Unlocked cryptonote.Amount
+	// NumUnspent is the number of unspent outputs
+	NumUnspent int
+	// BlocksToUnlock is the number of blocks until all the unspent outputs
+	// are unlocked, for outputs locked by a block height
+	BlocksToUnlock uint64
+}
+
+// add adds the balance b to a, or returns an error if an amount overflows
+func (a *Balance) add(b *Balance) error {
+	total, err := a.Total.Add(b.Total)
+	if err != nil {
+		return err
+	}
+	unlocked, err := a.Unlocked.Add(b.Unlocked)
+	if err != nil {
+		return err
+	}
+	a.Total, a.Unlocked = total, unlocked
+	a.NumUnspent += b.NumUnspent
+	a.BlocksToUnlock = max(a.BlocksToUnlock, b.BlocksToUnlock)
+	return nil
+}
+
+// walletState is the part of a wallet that only needs the view keys: the
+// scanned chain, the received outputs and the created subaddresses. Wallet and
+// ViewOnlyWallet embed it, and add the methods that their keys allow.
+type walletState struct {
+	viewKeys *cryptonote.ViewKeyPair
+	net      cryptonote.Network
+	table    *cryptonote.SubaddressTable
+	// keyImage returns the key image of a received output. It is nil when the
+	// wallet doesn't have the private spend key, so spends aren't detected.
+	keyImage func(o *cryptonote.OwnedOutput) (*cryptonote.KeyImage, error)
+
+	// refreshMu serializes refreshes
+	refreshMu sync.Mutex
+	mu        sync.RWMutex
+	// height is the number of scanned blocks, or the restore height when
+	// nothing was scanned
+	height uint64
+	// daemonHeight is the number of blocks of the daemon's chain, as of the
+	// last refresh
+	daemonHeight uint64
+	// recentHashes holds the hashes of the last scanned blocks, up to
+	// maxReorgDepth, the last of which is at height-1
+	recentHashes [][32]byte
+	transfers    []*Transfer
+	keyImages    map[[cryptonote.KeySize]byte]*Transfer
+	// labels holds the labels of the subaddresses of each account. The
+	// number of labels is the number of subaddresses created.
+	labels [][]string
+}
+
+// init initializes the state of a new wallet, which starts scanning the chain
+// at restoreHeight with one account, with the primary address
+func (w *walletState) init(viewKeys *cryptonote.ViewKeyPair, net cryptonote.Network, restoreHeight uint64) {
+	w.viewKeys = viewKeys
+	w.net = net
+	w.table = viewKeys.SubaddressTable()
+	w.height = restoreHeight
+	w.keyImages = make(map[[cryptonote.KeySize]byte]*Transfer)
+	w.labels = [][]string{{PrimaryAccountLabel}}
+}
+
+// Wallet is a wallet with both private keys. It is safe for concurrent use.
+type Wallet struct {
+	walletState
+	keys *cryptonote.PrivateKeyPair
+	// seed is the polyseed of the keys, if the wallet was created from one
+	seed *polyseed.SeedData
+	// jamtisKeys are derived from the private spend key when first used
+	jamtisKeys *jamtis.Keys
+}
+
+// New returns a wallet with the passed keys, which starts scanning the chain
+// at restoreHeight. The wallet starts with one account, with the primary
+// address.
+func New(keys *cryptonote.PrivateKeyPair, net cryptonote.Network, restoreHeight uint64) *Wallet {
+	w := &Wallet{keys: keys}
+	w.init(keys.ViewKeyPair(), net, restoreHeight)
+	w.keyImage = keys.OutputKeyImage
+	return w
+}
+
+// Keys returns the wallet's keys
+func (w *Wallet) Keys() *cryptonote.PrivateKeyPair {
+	return w.keys
+}
+
+// Seed returns a copy of the polyseed that the wallet was created from, as it
+// was passed to NewFromPolyseed, or nil if the wallet was created from keys.
+func (w *Wallet) Seed() *polyseed.SeedData {
+	if w.seed == nil {
+		return nil
+	}
+	seed := *w.seed
+	return &seed
+}
+
+// JamtisKeys returns the wallet's Jamtis keys, whose master key is the
+// private spend key
+func (w *Wallet) JamtisKeys() (*jamtis.Keys, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.jamtisKeys == nil {
+		keys, err := jamtis.DeriveKeys(w.keys.SpendKey().Bytes())
+		if err != nil {
+			return nil, err
+		}
+		w.jamtisKeys = keys
+	}
+	return w.jamtisKeys, nil
+}
+
+// Network returns the wallet's network
+func (w *walletState) Network() cryptonote.Network {
+	return w.net
+}
+
+// Height returns the number of blocks scanned by the wallet, which is the
+// height of the next block to scan
+func (w *walletState) Height() uint64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.height
+}
+
+// Address returns the address of a subaddress, or the primary address for
+// account 0, index 0. Any subaddress can be computed, including ones that
+// were not created.
+func (w *walletState) Address(account uint32, index uint32) *cryptonote.Address {
+	return w.viewKeys.SubAddrPubKeyPair(account, index).Address(w.net)
+}
+
+// NumAccounts returns the number of accounts
+func (w *walletState) NumAccounts() uint32 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return uint32(len(w.labels))
+}
+
+// NumSubaddresses returns the number of subaddresses created in the account,
+// including index 0, which is the account's main address
+func (w *walletState) NumSubaddresses(account uint32) (uint32, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if account >= uint32(len(w.labels)) {
+		return 0, errAccountIndex
+	}
+	return uint32(len(w.labels[account])), nil
+}
+
+// SubaddressLabel returns the label of a created subaddress
+func (w *walletState) SubaddressLabel(account uint32, index uint32) (string, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if account >= uint32(len(w.labels)) {
+		return "", errAccountIndex
+	}
+	if index >= uint32(len(w.labels[account])) {
+		return "", errSubaddressIndex
+	}
+	return w.labels[account][index], nil
+}
+
+// CreateSubaddress creates the next subaddress of the account, and returns
+// its index
+func (w *walletState) CreateSubaddress(account uint32, label string) (uint32, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if account >= uint32(len(w.labels)) {
+		return 0, errAccountIndex
+	}
+	index := uint32(len(w.labels[account]))
+	if err := w.table.Expand(account, index); err != nil {
+		return 0, err
+	}
+	w.labels[account] = append(w.labels[account], label)
+	return index, nil
+}
+
+// CreateAccount creates a new account, whose main address has the passed
+// label, and returns its index
+func (w *walletState) CreateAccount(label string) (uint32, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	account := uint32(len(w.labels))
+	if err := w.table.Expand(account, 0); err != nil {
+		return 0, err
+	}
+	w.labels = append(w.labels, []string{label})
+	return account, nil
+}
+
+// ensureSubaddress creates the subaddresses up to the passed one, when it
+// receives an output, like wallet2. The caller must hold the write lock.
+func (w *walletState) ensureSubaddress(account uint32, index uint32) error {
+	if err := w.table.Expand(account, index); err != nil {
+		return err
+	}
+	for uint32(len(w.labels)) <= account {
+		w.labels = append(w.labels, []string{""})
+	}
+	for uint32(len(w.labels[account])) <= index {
+		w.labels[account] = append(w.labels[account], "")
+	}
+	return nil
+}
+
+// Transfers returns copies of the outputs received by the wallet, in the
+// order they were received
+func (w *walletState) Transfers() []*Transfer {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	transfers := make([]*Transfer, len(w.transfers))
+	for i, t := range w.transfers {
+		c := *t
+		transfers[i] = &c
+	}
+	return transfers
+}
+
+// IsUnlocked returns true if the transfer can be spent in the next block,
+// like wallet2's is_transfer_unlocked
+func (w *walletState) IsUnlocked(t *Transfer) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.blocksToUnlock(t) == 0 && w.timeUnlocked(t)
+}
+
+// blocksToUnlock returns the number of blocks until the transfer is spendable
+// and its unlock height, if any, has passed. The caller must hold the lock.
+func (w *walletState) blocksToUnlock(t *Transfer) uint64 {
+	unlockHeight := t.BlockHeight + spendableAge
+	if t.UnlockTime < maxBlockNumber {
+		unlockHeight = max(unlockHeight, t.UnlockTime)
+	}
+	if unlockHeight <= w.daemonHeight {
+		return 0
+	}
+	return unlockHeight - w.daemonHeight
+}
+
+// timeUnlocked returns true if the transfer's unlock time is not a timestamp
+// or has passed, allowing for the time until the next block
+func (w *walletState) timeUnlocked(t *Transfer) bool {
+	return t.UnlockTime < maxBlockNumber || uint64(time.Now().Unix())+blockTime >= t.UnlockTime
+}
+
+// SubaddressBalances returns the balance of each created subaddress of the
+// account, by subaddress index
+func (w *walletState) SubaddressBalances(account uint32) ([]*Balance, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if account >= uint32(len(w.labels)) {
+		return nil, errAccountIndex
+	}
+
+	balances := make([]*Balance, len(w.labels[account]))
+	for i := range balances {
+		balances[i] = new(Balance)
+	}
+	for _, t := range w.transfers {
+		if t.Spent || t.AccountIndex != account {
+			continue
+		}
+		blocks := w.blocksToUnlock(t)
+		output := &Balance{Total: cryptonote.Amount(t.Amount), NumUnspent: 1, BlocksToUnlock: blocks}
+		if blocks == 0 && w.timeUnlocked(t) {
+			output.Unlocked = output.Total
+		}
+		if err := balances[t.SubAddrIndex].add(output); err != nil {
+			return nil, err
+		}
+	}
+	return balances, nil
+}
+
+// AccountBalance returns the total balance of the account's subaddresses
+func (w *walletState) AccountBalance(account uint32) (*Balance, error) {
+	balances, err := w.SubaddressBalances(account)
+	if err != nil {
+		return nil, err
+	}
+	total := new(Balance)
+	for _, b := range balances {
+		if err := total.add(b); err != nil {
+			return nil, err
+		}
+	}
+	return total, nil
+}
+
+// SubaddressUsed returns true if the subaddress received an output
+func (w *walletState) SubaddressUsed(account uint32, index uint32) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	for _, t := range w.transfers {
+		if t.AccountIndex == account && t.SubAddrIndex == index {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package wallet
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+)
+
+func TestWallet_subaddresses(t *testing.T) {
+	keys, err := cryptonote.GenerateKeys()
+	require.NoError(t, err)
+	w := New(keys, cryptonote.Stagenet, 0)
+
+	require.Equal(t, keys.PublicKeyPair().Address(cryptonote.Stagenet), w.Address(0, 0))
+	require.Equal(t, uint32(1), w.NumAccounts())
+	label, err := w.SubaddressLabel(0, 0)
+	require.NoError(t, err)
+	require.Equal(t, PrimaryAccountLabel, label)
+
+	index, err := w.CreateSubaddress(0, "donations")
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), index)
+	label, err = w.SubaddressLabel(0, 1)
+	require.NoError(t, err)
+	require.Equal(t, "donations", label)
+	_, err = w.SubaddressLabel(0, 2)
+	require.ErrorIs(t, err, errSubaddressIndex)
+
+	account, err := w.CreateAccount("savings")
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), account)
+	numSubaddresses, err := w.NumSubaddresses(1)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), numSubaddresses)
+	require.Equal(t, keys.SubAddrPubKeyPair(1, 0).Address(cryptonote.Stagenet), w.Address(1, 0))
+
+	_, err = w.CreateSubaddress(2, "")
+	require.ErrorIs(t, err, errAccountIndex)
+	_, err = w.NumSubaddresses(2)
+	require.ErrorIs(t, err, errAccountIndex)
+	_, err = w.SubaddressBalances(2)
+	require.ErrorIs(t, err, errAccountIndex)
+
+	balances, err := w.SubaddressBalances(0)
+	require.NoError(t, err)
+	require.Equal(t, []*Balance{{}, {}}, balances)
+
+	// indices past the subaddress table's limits are rejected, without
+	// creating any subaddresses
+	w.mu.Lock()
+	require.Error(t, w.ensureSubaddress(math.MaxUint32, 0))
+	require.Error(t, w.ensureSubaddress(0, math.MaxUint32))
+	w.mu.Unlock()
+	require.Equal(t, uint32(2), w.NumAccounts())
+	numSubaddresses, err = w.NumSubaddresses(0)
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), numSubaddresses)
+}
+
+func TestWallet_IsUnlocked(t *testing.T) {
+	keys, err := cryptonote.GenerateKeys()
+	require.NoError(t, err)
+	w := New(keys, cryptonote.Mainnet, 0)
+	w.daemonHeight = 100
+
+	for _, test := range []struct {
+		blockHeight uint64
+		unlockTime  uint64
+		unlocked    bool
+	}{
+		{blockHeight: 90, unlocked: true},
+		{blockHeight: 91},
+		{blockHeight: 50, unlockTime: 100, unlocked: true},
+		{blockHeight: 50, unlockTime: 101},
+		{blockHeight: 50, unlockTime: maxBlockNumber, unlocked: true},
+		{blockHeight: 50, unlockTime: 1 << 40},
+	} {
+		transfer := &Transfer{BlockHeight: test.blockHeight, UnlockTime: test.unlockTime}
+		require.Equal(t, test.unlocked, w.IsUnlocked(transfer), test)
+	}
+}
+
+func TestBalance_add(t *testing.T) {
+	a := &Balance{Total: 5, Unlocked: 3, NumUnspent: 2, BlocksToUnlock: 4}
+	require.NoError(t, a.add(&Balance{Total: 7, Unlocked: 7, NumUnspent: 1, BlocksToUnlock: 1}))
+	require.Equal(t, &Balance{Total: 12, Unlocked: 10, NumUnspent: 3, BlocksToUnlock: 4}, a)
+
+	// overflowing amounts are rejected and leave the balance unchanged
+	require.Error(t, a.add(&Balance{Total: math.MaxUint64, NumUnspent: 1}))
+	require.Equal(t, &Balance{Total: 12, Unlocked: 10, NumUnspent: 3, BlocksToUnlock: 4}, a)
+}
@@ -0,0 +1,117 @@
+package wallet2
+
+import (
+	"encoding/hex"
+	"errors"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const hexDigits = "0123456789ABCDEF"
+
+var errJSONString = errors.New("invalid JSON string")
+
+// wallet2 writes binary blobs, like the serialized account keys, as JSON
+// strings holding the raw bytes, which usually aren't valid UTF-8. The
+// encoding/json package would replace those bytes with U+FFFD, so the strings
+// are quoted and unquoted here, the way rapidjson does.
+
+// appendJSONBytes appends s to b as a JSON string. Like rapidjson, only
+// quotes, backslashes and control characters are escaped.
+func appendJSONBytes(b []byte, s []byte) []byte {
+	b = append(b, '"')
+	for _, c := range s {
+		switch c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\b':
+			b = append(b, '\\', 'b')
+		case '\f':
+			b = append(b, '\\', 'f')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			if c < 0x20 {
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			} else {
+				b = append(b, c)
+			}
+		}
+	}
+	return append(b, '"')
+}
+
+// unquoteJSONBytes returns the bytes of the JSON string s, keeping bytes that
+// aren't valid UTF-8 as they are
+func unquoteJSONBytes(s []byte) ([]byte, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return nil, errJSONString
+	}
+	s = s[1 : len(s)-1]
+
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			b = append(b, c)
+			continue
+		}
+		if i++; i == len(s) {
+			return nil, errJSONString
+		}
+		switch s[i] {
+		case '"', '\\', '/':
+			b = append(b, s[i])
+		case 'b':
+			b = append(b, '\b')
+		case 'f':
+			b = append(b, '\f')
+		case 'n':
+			b = append(b, '\n')
+		case 'r':
+			b = append(b, '\r')
+		case 't':
+			b = append(b, '\t')
+		case 'u':
+			r, ok := readUTF16(s[i+1:])
+			if !ok {
+				return nil, errJSONString
+			}
+			i += 4
+			if utf16.IsSurrogate(r) {
+				// the low surrogate must follow as another \u escape
+				if i+2 >= len(s) || s[i+1] != '\\' || s[i+2] != 'u' {
+					return nil, errJSONString
+				}
+				r2, ok := readUTF16(s[i+3:])
+				if !ok {
+					return nil, errJSONString
+				}
+				if r = utf16.DecodeRune(r, r2); r == utf8.RuneError {
+					return nil, errJSONString
+				}
+				i += 6
+			}
+			b = utf8.AppendRune(b, r)
+		default:
+			return nil, errJSONString
+		}
+	}
+	return b, nil
+}
+
+// readUTF16 reads the 4 hex digits of a \u escape
+func readUTF16(s []byte) (rune, bool) {
+	if len(s) < 4 {
+		return 0, false
+	}
+	var u [2]byte
+	if _, err := hex.Decode(u[:], s[:4]); err != nil {
+		return 0, false
+	}
+	return rune(u[0])<<8 | rune(u[1]), true
+}
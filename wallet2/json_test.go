@@ -0,0 +1,32 @@
+package wallet2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONBytes(t *testing.T) {
+	var all []byte
+	for c := 0; c < 256; c++ {
+		all = append(all, byte(c))
+	}
+	quoted := appendJSONBytes(nil, all)
+	require.True(t, json.Valid(quoted))
+	require.Contains(t, string(quoted), `\u001F`)
+
+	unquoted, err := unquoteJSONBytes(quoted)
+	require.NoError(t, err)
+	require.Equal(t, all, unquoted)
+
+	// escapes that rapidjson doesn't write, but parses
+	unquoted, err = unquoteJSONBytes([]byte(`"\/é😀"`))
+	require.NoError(t, err)
+	require.Equal(t, "/é😀", string(unquoted))
+
+	for _, invalid := range []string{`"`, `x`, `"\"`, `"\x"`, `"\u12"`, `"\ud83d"`, `"\ud83dx\ude00"`} {
+		_, err = unquoteJSONBytes([]byte(invalid))
+		require.ErrorIs(t, err, errJSONString, invalid)
+	}
+}
@@ -0,0 +1,423 @@
+// Package wallet2 reads and writes the .keys files of monero's wallet2, the
+// wallet of monero-wallet-cli and monero-wallet-rpc, so that existing wallets
+// can be migrated into gopherphis.
+//
+// A keys file holds a random IV followed by the varint length and the
+// chacha20 encryption of a JSON object. The key is the cn_slow_hash of the
+// password, hashed again for each additional KDF round. The JSON object holds
+// the wallet's settings and, in its key_data member, the epee portable
+// storage serialization of the account keys. The secret keys in key_data are
+// encrypted a second time, with a key stream derived from the same key.
+//
+// Keys files written before 2018 with chacha8 are not supported.
+package wallet2
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"ekyu.moe/cryptonight"
+	"golang.org/x/crypto/chacha20"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+	"github.com/dimalinux/gopherphis/epee"
+	"github.com/dimalinux/gopherphis/serialization"
+)
+
+const (
+	// DefaultKDFRounds is wallet2's default for --kdf-rounds, the number of
+	// cn_slow_hash rounds that derive the encryption key from the password
+	DefaultKDFRounds = 1
+
+	ivSize = 8
+
+	// hashKeyMemory is appended to the file's key to derive the key that
+	// encrypts the secret keys, config::HASH_KEY_MEMORY in monero
+	hashKeyMemory = 'k'
+
+	defaultSeedLanguage = "English"
+)
+
+var (
+	errKDFRounds     = errors.New("kdf rounds must be at least 1")
+	errTruncated     = errors.New("keys file is truncated")
+	errWrongPassword = errors.New("invalid password or keys file")
+	errNoKeyData     = errors.New("keys file has no key_data")
+	errNetType       = errors.New("unknown network")
+	errSigners       = errors.New("invalid multisig_signers")
+)
+
+// netTypes maps wallet2's nettype values to networks
+var netTypes = []cryptonote.Network{cryptonote.Mainnet, cryptonote.Testnet, cryptonote.Stagenet}
+
+// keysFields are the JSON members of the keys file that KeysFile exposes as
+// fields, all other members are kept in its Settings
+var keysFields = []string{
+	"key_data",
+	"seed_language",
+	"watch_only",
+	"multisig",
+	"multisig_threshold",
+	"multisig_signers",
+	"nettype",
+	"refresh_height",
+	"encrypted_secret_keys",
+}
+
+// KeysFile is the content of a wallet2 .keys file
+type KeysFile struct {
+	// Keys are the private keys of the wallet. The spend key is zero in
+	// watch-only wallets, and is the sum of the multisig keys in multisig
+	// wallets.
+	Keys *cryptonote.PrivateKeyPair
+	// PublicKeys are the keys of the wallet's primary address
+	PublicKeys        *cryptonote.PublicKeyPair
+	Network           cryptonote.Network
+	CreationTime      time.Time
+	SeedLanguage      string
+	RefreshHeight     uint64
+	WatchOnly         bool
+	Multisig          bool
+	MultisigThreshold uint32
+	MultisigSigners   [][cryptonote.KeySize]byte
+	MultisigKeys      [][cryptonote.KeySize]byte
+	// Settings holds the other members of the JSON object, like
+	// always_confirm_transfers, so that they survive writing the file back
+	Settings map[string]json.RawMessage
+}
+
+// keysJSON holds the members of the keys file's JSON object that KeysFile
+// exposes
+type keysJSON struct {
+	KeyData             json.RawMessage `json:"key_data"`
+	SeedLanguage        string          `json:"seed_language"`
+	WatchOnly           int             `json:"watch_only"`
+	Multisig            int             `json:"multisig"`
+	MultisigThreshold   uint32          `json:"multisig_threshold"`
+	MultisigSigners     json.RawMessage `json:"multisig_signers"`
+	NetType             uint8           `json:"nettype"`
+	RefreshHeight       uint64          `json:"refresh_height"`
+	EncryptedSecretKeys int             `json:"encrypted_secret_keys"`
+}
+
+// account is monero's account_base
+type account struct {
+	Keys              accountKeys `epee:"m_keys"`
+	CreationTimestamp uint64      `epee:"m_creation_timestamp"`
+}
+
+// accountKeys is monero's account_keys
+type accountKeys struct {
+	Address        accountAddress             `epee:"m_account_address"`
+	SpendSecretKey [cryptonote.KeySize]byte   `epee:"m_spend_secret_key"`
+	ViewSecretKey  [cryptonote.KeySize]byte   `epee:"m_view_secret_key"`
+	MultisigKeys   [][cryptonote.KeySize]byte `epee:"m_multisig_keys,blob,omitempty"`
+	EncryptionIV   [ivSize]byte               `epee:"m_encryption_iv"`
+}
+
+// accountAddress is monero's account_public_address
+type accountAddress struct {
+	SpendPublicKey [cryptonote.KeySize]byte `epee:"m_spend_public_key"`
+	ViewPublicKey  [cryptonote.KeySize]byte `epee:"m_view_public_key"`
+}
+
+// NewKeysFile returns the keys file of a new wallet with the passed keys
+func NewKeysFile(keys *cryptonote.PrivateKeyPair, net cryptonote.Network) *KeysFile {
+	return &KeysFile{
+		Keys:         keys,
+		PublicKeys:   keys.PublicKeyPair(),
+		Network:      net,
+		CreationTime: time.Now(),
+		SeedLanguage: defaultSeedLanguage,
+	}
+}
+
+// Address returns the wallet's primary address
+func (f *KeysFile) Address() *cryptonote.Address {
+	return f.PublicKeys.Address(f.Network)
+}
+
+// chachaKey is monero's generate_chacha_key
+func chachaKey(password []byte, kdfRounds uint64) [32]byte {
+	hash := cryptonight.Sum(password, 0)
+	for n := uint64(1); n < kdfRounds; n++ {
+		hash = cryptonight.Sum(hash, 0)
+	}
+	var key [32]byte
+	copy(key[:], hash)
+	return key
+}
+
+// chacha20XOR encrypts or decrypts data with the original chacha20, which
+// has an 8-byte IV and a 64-bit block counter
+func chacha20XOR(key [32]byte, iv [ivSize]byte, data []byte) []byte {
+	// the IETF nonce's first 4 bytes are the high half of the original
+	// cipher's block counter
+	var nonce [chacha20.NonceSize]byte
+	copy(nonce[4:], iv[:])
+	cipher, err := chacha20.NewUnauthenticatedCipher(key[:], nonce[:])
+	if err != nil {
+		panic(err) // the key and nonce sizes are fixed
+	}
+	out := make([]byte, len(data))
+	cipher.XORKeyStream(out, data)
+	return out
+}
+
+// xorSecretKeys encrypts or decrypts the secret keys, like account_keys'
+// xor_with_key_stream
+func (k *accountKeys) xorSecretKeys(key [32]byte) {
+	derivedKey := chachaKey(append(key[:], hashKeyMemory), 1)
+	stream := chacha20XOR(derivedKey, k.EncryptionIV, make([]byte, cryptonote.KeySize*(2+len(k.MultisigKeys))))
+	xorKey := func(secret *[cryptonote.KeySize]byte) {
+		for i := range secret {
+			secret[i] ^= stream[i]
+		}
+		stream = stream[cryptonote.KeySize:]
+	}
+	xorKey(&k.SpendSecretKey)
+	xorKey(&k.ViewSecretKey)
+	for i := range k.MultisigKeys {
+		xorKey(&k.MultisigKeys[i])
+	}
+}
+
+// ReadKeysFile reads and decrypts the keys file at path
+func ReadKeysFile(path string, password string, kdfRounds uint64) (*KeysFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptKeysFile(data, password, kdfRounds)
+}
+
+// DecryptKeysFile decrypts the content of a keys file. The kdfRounds must be
+// the value of --kdf-rounds that the file was written with, which isn't
+// stored in the file.
+func DecryptKeysFile(data []byte, password string, kdfRounds uint64) (*KeysFile, error) {
+	if kdfRounds < 1 {
+		return nil, errKDFRounds
+	}
+	if len(data) < ivSize {
+		return nil, errTruncated
+	}
+	var iv [ivSize]byte
+	copy(iv[:], data)
+	size, n, err := serialization.ReadVarint(data[ivSize:])
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := data[ivSize+n:]
+	if uint64(len(ciphertext)) != size {
+		return nil, errTruncated
+	}
+
+	key := chachaKey([]byte(password), kdfRounds)
+	plaintext := chacha20XOR(key, iv, ciphertext)
+
+	var meta keysJSON
+	var settings map[string]json.RawMessage
+	keyData := plaintext
+	switch {
+	case json.Unmarshal(plaintext, &settings) == nil:
+		if err := json.Unmarshal(plaintext, &meta); err != nil {
+			return nil, err
+		}
+		if meta.KeyData == nil {
+			return nil, errNoKeyData
+		}
+		if keyData, err = unquoteJSONBytes(meta.KeyData); err != nil {
+			return nil, fmt.Errorf("key_data: %w", err)
+		}
+		for _, name := range keysFields {
+			delete(settings, name)
+		}
+	case bytes.HasPrefix(plaintext, epee.Signature):
+		// the oldest files only have the serialized account
+	default:
+		return nil, errWrongPassword
+	}
+
+	var acc account
+	if err := epee.Unmarshal(keyData, &acc); err != nil {
+		return nil, fmt.Errorf("key_data: %w", err)
+	}
+	if meta.EncryptedSecretKeys != 0 {
+		acc.Keys.xorSecretKeys(key)
+	}
+
+	f := &KeysFile{
+		CreationTime:      time.Unix(int64(acc.CreationTimestamp), 0),
+		SeedLanguage:      meta.SeedLanguage,
+		RefreshHeight:     meta.RefreshHeight,
+		WatchOnly:         meta.WatchOnly != 0,
+		Multisig:          meta.Multisig != 0,
+		MultisigThreshold: meta.MultisigThreshold,
+		MultisigKeys:      acc.Keys.MultisigKeys,
+		Settings:          settings,
+	}
+	if int(meta.NetType) >= len(netTypes) {
+		return nil, errNetType
+	}
+	f.Network = netTypes[meta.NetType]
+	if f.Multisig && meta.MultisigSigners != nil {
+		if f.MultisigSigners, err = decodeSigners(meta.MultisigSigners); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.setKeys(&acc.Keys); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// setKeys sets the keys of the file from the decrypted account keys, after
+// checking that the secret keys match the public ones, which is how wallet2
+// detects a wrong password
+func (f *KeysFile) setKeys(k *accountKeys) error {
+	spendKey, err := cryptonote.NewPublicKey(k.Address.SpendPublicKey[:])
+	if err != nil {
+		return err
+	}
+	viewKey, err := cryptonote.NewPublicKey(k.Address.ViewPublicKey[:])
+	if err != nil {
+		return err
+	}
+	keys, err := cryptonote.NewPrivateKeyPairFromBytes(k.SpendSecretKey[:], k.ViewSecretKey[:])
+	if err != nil {
+		return errWrongPassword
+	}
+
+	if !keys.PrivateViewKey().Public().Equal(viewKey) {
+		return errWrongPassword
+	}
+	// multisig wallets' spend keys don't match, and watch-only wallets
+	// don't have one
+	if !f.WatchOnly && !f.Multisig && !keys.SpendKey().Public().Equal(spendKey) {
+		return errWrongPassword
+	}
+
+	f.Keys = keys
+	f.PublicKeys = cryptonote.NewPublicKeyPair(spendKey, viewKey)
+	return nil
+}
+
+// decodeSigners decodes the binary serialization of the multisig signers'
+// public keys, a varint count followed by the keys
+func decodeSigners(raw json.RawMessage) ([][cryptonote.KeySize]byte, error) {
+	b, err := unquoteJSONBytes(raw)
+	if err != nil {
+		return nil, fmt.Errorf("multisig_signers: %w", err)
+	}
+	count, n, err := serialization.ReadVarint(b)
+	if err != nil {
+		return nil, fmt.Errorf("multisig_signers: %w", err)
+	}
+	b = b[n:]
+	if uint64(len(b)) != count*cryptonote.KeySize {
+		return nil, errSigners
+	}
+	signers := make([][cryptonote.KeySize]byte, count)
+	for i := range signers {
+		copy(signers[i][:], b[i*cryptonote.KeySize:])
+	}
+	return signers, nil
+}
+
+func encodeSigners(signers [][cryptonote.KeySize]byte) json.RawMessage {
+	b := serialization.AppendVarint(nil, uint64(len(signers)))
+	for _, signer := range signers {
+		b = append(b, signer[:]...)
+	}
+	return appendJSONBytes(nil, b)
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Encrypt returns the content of a keys file holding f, which wallet2 opens
+// with the password and its --kdf-rounds set to kdfRounds
+func (f *KeysFile) Encrypt(password string, kdfRounds uint64) ([]byte, error) {
+	if kdfRounds < 1 {
+		return nil, errKDFRounds
+	}
+	key := chachaKey([]byte(password), kdfRounds)
+
+	acc := account{CreationTimestamp: uint64(f.CreationTime.Unix())}
+	copy(acc.Keys.Address.SpendPublicKey[:], f.PublicKeys.SpendKey().Bytes())
+	copy(acc.Keys.Address.ViewPublicKey[:], f.PublicKeys.ViewKey().Bytes())
+	copy(acc.Keys.SpendSecretKey[:], f.Keys.SpendKey().Bytes())
+	copy(acc.Keys.ViewSecretKey[:], f.Keys.PrivateViewKey().Bytes())
+	acc.Keys.MultisigKeys = append([][cryptonote.KeySize]byte(nil), f.MultisigKeys...)
+	if _, err := rand.Read(acc.Keys.EncryptionIV[:]); err != nil {
+		return nil, err
+	}
+	acc.Keys.xorSecretKeys(key)
+	keyData, err := epee.Marshal(&acc)
+	if err != nil {
+		return nil, err
+	}
+
+	netType := slices.Index(netTypes, f.Network)
+	if netType < 0 {
+		return nil, errNetType
+	}
+
+	members := maps.Clone(f.Settings)
+	if members == nil {
+		members = make(map[string]json.RawMessage)
+	}
+	for name, value := range map[string]any{
+		"seed_language":         f.SeedLanguage,
+		"watch_only":            boolInt(f.WatchOnly),
+		"multisig":              boolInt(f.Multisig),
+		"multisig_threshold":    f.MultisigThreshold,
+		"nettype":               netType,
+		"refresh_height":        f.RefreshHeight,
+		"encrypted_secret_keys": 1,
+	} {
+		if members[name], err = json.Marshal(value); err != nil {
+			return nil, err
+		}
+	}
+	members["key_data"] = appendJSONBytes(nil, keyData)
+	if f.Multisig {
+		members["multisig_signers"] = encodeSigners(f.MultisigSigners)
+	}
+
+	// encoding/json would escape the raw bytes of key_data as HTML
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(members); err != nil {
+		return nil, err
+	}
+	plaintext := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+
+	var iv [ivSize]byte
+	if _, err := rand.Read(iv[:]); err != nil {
+		return nil, err
+	}
+	data := append(iv[:], serialization.AppendVarint(nil, uint64(len(plaintext)))...)
+	return append(data, chacha20XOR(key, iv, plaintext)...), nil
+}
+
+// WriteFile encrypts f and writes it to path, readable only by its owner
+func (f *KeysFile) WriteFile(path string, password string, kdfRounds uint64) error {
+	data, err := f.Encrypt(password, kdfRounds)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
@@ -0,0 +1,134 @@
+package wallet2
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimalinux/gopherphis/cryptonote"
+	"github.com/dimalinux/gopherphis/epee"
+	"github.com/dimalinux/gopherphis/serialization"
+)
+
+func TestKeysFile_roundTrip(t *testing.T) {
+	keys, err := cryptonote.GenerateKeys()
+	require.NoError(t, err)
+	f := NewKeysFile(keys, cryptonote.Stagenet)
+	f.CreationTime = time.Unix(1700000000, 0)
+	f.RefreshHeight = 1234
+	f.Settings = map[string]json.RawMessage{
+		"always_confirm_transfers": json.RawMessage(`1`),
+		"device_name":              json.RawMessage(`""`),
+	}
+
+	path := filepath.Join(t.TempDir(), "wallet.keys")
+	require.NoError(t, f.WriteFile(path, "password", 3))
+
+	read, err := ReadKeysFile(path, "password", 3)
+	require.NoError(t, err)
+	require.Equal(t, keys.SpendKey().Hex(), read.Keys.SpendKey().Hex())
+	require.Equal(t, keys.PrivateViewKey().Hex(), read.Keys.PrivateViewKey().Hex())
+	require.Equal(t, f.Address().String(), read.Address().String())
+	require.Equal(t, cryptonote.Stagenet, read.Network)
+	require.Equal(t, f.CreationTime, read.CreationTime)
+	require.Equal(t, "English", read.SeedLanguage)
+	require.EqualValues(t, 1234, read.RefreshHeight)
+	require.False(t, read.WatchOnly)
+	require.False(t, read.Multisig)
+	require.Equal(t, f.Settings, read.Settings)
+
+	// the password and the kdf rounds must both match
+	_, err = ReadKeysFile(path, "passw0rd", 3)
+	require.ErrorIs(t, err, errWrongPassword)
+	_, err = ReadKeysFile(path, "password", DefaultKDFRounds)
+	require.ErrorIs(t, err, errWrongPassword)
+	_, err = ReadKeysFile(path, "password", 0)
+	require.ErrorIs(t, err, errKDFRounds)
+
+	// the files are encrypted with random IVs
+	data1, err := f.Encrypt("password", DefaultKDFRounds)
+	require.NoError(t, err)
+	data2, err := f.Encrypt("password", DefaultKDFRounds)
+	require.NoError(t, err)
+	require.NotEqual(t, data1, data2)
+
+	_, err = DecryptKeysFile(data1[:len(data1)-1], "password", DefaultKDFRounds)
+	require.ErrorIs(t, err, errTruncated)
+}
+
+func TestKeysFile_watchOnly(t *testing.T) {
+	keys, err := cryptonote.GenerateKeys()
+	require.NoError(t, err)
+	zeroKey := make([]byte, cryptonote.KeySize)
+	viewOnlyKeys, err := cryptonote.NewPrivateKeyPairFromBytes(zeroKey, keys.PrivateViewKey().Bytes())
+	require.NoError(t, err)
+
+	f := NewKeysFile(viewOnlyKeys, cryptonote.Mainnet)
+	f.PublicKeys = keys.PublicKeyPair()
+	f.WatchOnly = true
+	data, err := f.Encrypt("", DefaultKDFRounds)
+	require.NoError(t, err)
+
+	read, err := DecryptKeysFile(data, "", DefaultKDFRounds)
+	require.NoError(t, err)
+	require.True(t, read.WatchOnly)
+	require.Equal(t, keys.PublicKeyPair().Address(cryptonote.Mainnet).String(), read.Address().String())
+	require.Equal(t, viewOnlyKeys.SpendKey().Hex(), read.Keys.SpendKey().Hex())
+
+	// without the watch-only flag, the spend key has to match the address
+	f.WatchOnly = false
+	data, err = f.Encrypt("", DefaultKDFRounds)
+	require.NoError(t, err)
+	_, err = DecryptKeysFile(data, "", DefaultKDFRounds)
+	require.ErrorIs(t, err, errWrongPassword)
+}
+
+func TestKeysFile_multisig(t *testing.T) {
+	keys, err := cryptonote.GenerateKeys()
+	require.NoError(t, err)
+	f := NewKeysFile(keys, cryptonote.Testnet)
+	f.Multisig = true
+	f.MultisigThreshold = 2
+	f.MultisigSigners = [][cryptonote.KeySize]byte{{1}, {2}, {'"', '\n', 0x80}}
+	f.MultisigKeys = [][cryptonote.KeySize]byte{{3}, {4}}
+
+	data, err := f.Encrypt("password", DefaultKDFRounds)
+	require.NoError(t, err)
+	read, err := DecryptKeysFile(data, "password", DefaultKDFRounds)
+	require.NoError(t, err)
+	require.Equal(t, cryptonote.Testnet, read.Network)
+	require.True(t, read.Multisig)
+	require.EqualValues(t, 2, read.MultisigThreshold)
+	require.Equal(t, f.MultisigSigners, read.MultisigSigners)
+	require.Equal(t, f.MultisigKeys, read.MultisigKeys)
+}
+
+func TestDecryptKeysFile_oldFormat(t *testing.T) {
+	keys, err := cryptonote.GenerateKeys()
+	require.NoError(t, err)
+
+	// the oldest files encrypt the serialized account without a JSON object
+	// or encrypted secret keys
+	var acc account
+	copy(acc.Keys.Address.SpendPublicKey[:], keys.PublicKeyPair().SpendKey().Bytes())
+	copy(acc.Keys.Address.ViewPublicKey[:], keys.PublicKeyPair().ViewKey().Bytes())
+	copy(acc.Keys.SpendSecretKey[:], keys.SpendKey().Bytes())
+	copy(acc.Keys.ViewSecretKey[:], keys.PrivateViewKey().Bytes())
+	acc.CreationTimestamp = 1500000000
+	plaintext, err := epee.Marshal(&acc)
+	require.NoError(t, err)
+
+	iv := [ivSize]byte{1, 2, 3, 4, 5, 6, 7, 8}
+	data := serialization.AppendVarint(iv[:], uint64(len(plaintext)))
+	data = append(data, chacha20XOR(chachaKey([]byte("password"), 1), iv, plaintext)...)
+
+	read, err := DecryptKeysFile(data, "password", DefaultKDFRounds)
+	require.NoError(t, err)
+	require.Equal(t, keys.SpendKey().Hex(), read.Keys.SpendKey().Hex())
+	require.Equal(t, cryptonote.Mainnet, read.Network)
+	require.Equal(t, time.Unix(1500000000, 0), read.CreationTime)
+	require.Nil(t, read.Settings)
+}